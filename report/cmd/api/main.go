@@ -6,20 +6,31 @@ import (
 	"log/slog"
 	"net/http"
 	"opg-reports/report/internal/account/accountapi/accountapi"
+	"opg-reports/report/internal/alarms/alarmsapi/alarmsapinoisiest"
+	"opg-reports/report/internal/alarms/alarmsapi/alarmsapiteam"
 	"opg-reports/report/internal/codebasereleases/codebasereleasesapi"
 	"opg-reports/report/internal/codebasestats/codebasestatsapi"
 	"opg-reports/report/internal/codeowners/codeownersapi"
+	"opg-reports/report/internal/codeowners/codeownersapi/codeownersapiissues"
+	"opg-reports/report/internal/codeowners/codeownersapi/codeownersapipath"
 	"opg-reports/report/internal/cost/costapi/costapiaccount"
 	"opg-reports/report/internal/cost/costapi/costapidetailed"
 	"opg-reports/report/internal/cost/costapi/costapidiff"
 	"opg-reports/report/internal/cost/costapi/costapiteam"
+	"opg-reports/report/internal/dependabot/dependabotapi/dependabotapiopen"
+	"opg-reports/report/internal/dependabot/dependabotapi/dependabotapiremediation"
+	"opg-reports/report/internal/dora/doraapi"
 	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/config"
 	"opg-reports/report/internal/global/migrations"
 	"opg-reports/report/internal/headline/headlineapi/headlineapi"
+	"opg-reports/report/internal/standards/standardsapi"
+	"opg-reports/report/internal/status/statusapi/statusapifreshness"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/internal/uptime/uptimeapi/uptimeapiteam"
+	"opg-reports/report/internal/workflowreliability/workflowreliabilityapi"
+	"opg-reports/report/internal/workflowusage/workflowusageapi"
 	"opg-reports/report/package/cntxt"
-	"opg-reports/report/package/env"
 	"opg-reports/report/package/logger"
 	"os"
 
@@ -33,6 +44,8 @@ type cli struct {
 	ApiHost string `json:"api"`     // --api-host ; this is the server address to run from
 	Version string `json:"version"` // --version ; the semver tag, used as part of signature
 	SHA     string `json:"sha"`     // --sha ; the git commit sha used as part of signature
+	// config file only
+	ExcludedServices []string `json:"excluded_services"` // services left out of all cost totals
 }
 
 // default values for the args
@@ -42,8 +55,13 @@ var flags = &cli{
 	ApiHost: ":8081",
 	Version: "v0.0.0",
 	SHA:     "abcde",
+	// this is the tax we pay on our services, so is removed
+	ExcludedServices: apimodels.DefaultExcludedServices,
 }
 
+// configFile is the optional shared config file (--config)
+var configFile string = ""
+
 // main root command
 var root *cobra.Command = &cobra.Command{
 	Use:   "api",
@@ -63,9 +81,14 @@ func registerEndpoints(ctx context.Context, mux *http.ServeMux, in *cli) {
 		Params:  in.Params,
 		Version: in.Version,
 		SHA:     in.SHA,
+		// cost settings
+		ExcludedServices: in.ExcludedServices,
 	}
 
 	registerPingAndHome(ctx, mux, in)
+	// status
+	// - when each dataset was last imported and if it is stale
+	statusapifreshness.Register(ctx, mux, args)
 	// teams
 	// - all
 	teamapiall.Register(ctx, mux, args)
@@ -86,13 +109,35 @@ func registerEndpoints(ctx context.Context, mux *http.ServeMux, in *cli) {
 	// uptime
 	// - uptime grouped by team name / optional team filter
 	uptimeapiteam.Register(ctx, mux, args)
+	// alarms
+	// - alarm counts / mean time in alarm grouped by team name / optional team filter
+	alarmsapiteam.Register(ctx, mux, args)
+	// - noisiest alarms / optional team filter
+	alarmsapinoisiest.Register(ctx, mux, args)
 	// codebases
 	// - stats / optional team filter
 	codebasestatsapi.Register(ctx, mux, args)
 	// - ownership / optional team filter
 	codeownersapi.Register(ctx, mux, args)
+	// - owner of a path within a codebase, using the CODEOWNERS rules
+	codeownersapipath.Register(ctx, mux, args)
+	// - codebases whose CODEOWNERS has errors or no catch-all rule / optional team filter
+	codeownersapiissues.Register(ctx, mux, args)
 	// - release data group by month between dates - no grouping by team as thats misleading (repo attached to more than one team)
 	codebasereleasesapi.Register(ctx, mux, args)
+	// - dora metrics by month between dates / optional team rollup via codeowners
+	doraapi.Register(ctx, mux, args)
+	// - workflow run durations & billable minutes by workflow between dates / optional team rollup via codeowners
+	workflowusageapi.Register(ctx, mux, args)
+	// - failure rate, re-run rate & time to green by workflow between dates / optional team rollup via codeowners
+	workflowreliabilityapi.Register(ctx, mux, args)
+	// security
+	// - open dependabot alerts by severity per codebase / optional team rollup via codeowners
+	dependabotapiopen.Register(ctx, mux, args)
+	// - dependabot alerts raised & fixed by month between dates / optional team rollup via codeowners
+	dependabotapiremediation.Register(ctx, mux, args)
+	// - result of each repository standard per codebase / optional team rollup via codeowners
+	standardsapi.Register(ctx, mux, args)
 }
 
 // runAPI the main run command
@@ -103,8 +148,15 @@ func runAPI(cmd *cobra.Command, args []string) (err error) {
 		ctx    context.Context = cmd.Context()
 		log    *slog.Logger    = cntxt.GetLogger(ctx)
 	)
-	// overwrite arg flags from env values
-	if err = env.OverwriteStruct(&flags); err != nil {
+	// overwrite arg flags from the config file & env values
+	_, err = config.Resolve(cmd, configFile, &flags, func(cfg *config.Config) {
+		config.Set(cfg, &flags.Driver, &cfg.Database.Driver)
+		config.Set(cfg, &flags.DB, &cfg.Database.DB)
+		config.Set(cfg, &flags.Params, &cfg.Database.Params)
+		config.Set(cfg, &flags.ApiHost, &cfg.API.Host)
+		config.Set(cfg, &flags.ExcludedServices, &cfg.Costs.ExcludedServices)
+	})
+	if err != nil {
 		return
 	}
 	// run db migrations
@@ -135,6 +187,7 @@ func runAPI(cmd *cobra.Command, args []string) (err error) {
 }
 
 func init() {
+	root.PersistentFlags().StringVar(&configFile, "config", configFile, "Config file (yaml or json) with shared settings")
 	root.PersistentFlags().StringVar(&flags.Driver, "driver", flags.Driver, "Database driver")
 	root.PersistentFlags().StringVar(&flags.DB, "db", flags.DB, "Database path")
 	root.PersistentFlags().StringVar(&flags.Params, "params", flags.Params, "Database params")
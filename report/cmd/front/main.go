@@ -11,14 +11,19 @@ import (
 	"opg-reports/report/internal/cost/costfront/costsbyteam"
 	"opg-reports/report/internal/cost/costfront/costsdetailed"
 	"opg-reports/report/internal/cost/costfront/costsdiff"
+	"opg-reports/report/internal/dependabot/dependabotfront"
+	"opg-reports/report/internal/dora/dorafront"
 	"opg-reports/report/internal/front/landingpage"
 	"opg-reports/report/internal/front/portfolio"
 	"opg-reports/report/internal/front/statics"
+	"opg-reports/report/internal/global/config"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/standards/standardsfront"
 	"opg-reports/report/internal/uptime/uptimefront/uptime"
+	"opg-reports/report/internal/workflowreliability/workflowreliabilityfront"
 	"opg-reports/report/package/cntxt"
-	"opg-reports/report/package/env"
 	"opg-reports/report/package/logger"
+	"opg-reports/report/package/retry"
 	"os"
 	"path/filepath"
 
@@ -32,6 +37,11 @@ type cli struct {
 	SHA          string `json:"sha"`           // --sha ; the git commit sha used as part of signature
 	RootDir      string `json:"root_dir"`      // --root-dir
 	GovUKVersion string `json:"govuk_version"` // --govuk_version
+	BillingDay   int    `json:"billing_day"`   // config file only; day of the month the previous months costs become stable
+	// config file only; retries of api calls
+	RetryAttempts  int    `json:"retry_attempts"`
+	RetryBaseDelay string `json:"retry_base_delay"`
+	RetryMaxDelay  string `json:"retry_max_delay"`
 	// fixed, based on root
 	GovUKDir       string `json:"govuk_dir"`
 	LocalAssetsDir string `json:"local_assets_dir"`
@@ -49,8 +59,16 @@ var flags = &cli{
 	LocalAssetsDir: "web",
 	TemplateDir:    "templates",
 	GovUKDir:       "govuk",
+	BillingDay:     15,
+	// pages are interactive, so only retry once and quickly
+	RetryAttempts:  2,
+	RetryBaseDelay: "200ms",
+	RetryMaxDelay:  "1s",
 }
 
+// configFile is the optional shared config file (--config)
+var configFile string = ""
+
 // main root command
 var root *cobra.Command = &cobra.Command{
 	Use:   "front",
@@ -67,6 +85,7 @@ func registerEndpoints(ctx context.Context, mux *http.ServeMux, in *cli) {
 		SemVer:       in.Version,
 		RootDir:      in.RootDir,
 		TemplateDir:  in.TemplateDir,
+		BillingDay:   in.BillingDay,
 	}
 	// static assets
 	statics.Register(ctx, mux, &statics.Args{
@@ -95,6 +114,16 @@ func registerEndpoints(ctx context.Context, mux *http.ServeMux, in *cli) {
 	codebasesstatsfront.Register(ctx, mux, args)
 	// - codeownership
 	codeownersfront.Register(ctx, mux, args)
+	// releases
+	// - dora metrics
+	dorafront.Register(ctx, mux, args)
+	// - workflow reliability, worst offenders
+	workflowreliabilityfront.Register(ctx, mux, args)
+	// security
+	// - dependabot alerts
+	dependabotfront.Register(ctx, mux, args)
+	// - repository standards matrix
+	standardsfront.Register(ctx, mux, args)
 
 }
 
@@ -110,13 +139,28 @@ func runFront(cmd *cobra.Command, args []string) (err error) {
 	var (
 		mux    *http.ServeMux
 		server *http.Server
+		policy *retry.Policy
 		ctx    context.Context = cmd.Context()
 		log    *slog.Logger    = cntxt.GetLogger(ctx)
 	)
-	// overwrite arg flags from env values
-	if err = env.OverwriteStruct(&flags); err != nil {
+	// overwrite arg flags from the config file & env values
+	_, err = config.Resolve(cmd, configFile, &flags, func(cfg *config.Config) {
+		config.Set(cfg, &flags.FrontHost, &cfg.Front.Host)
+		config.Set(cfg, &flags.ApiHost, &cfg.Front.API)
+		config.Set(cfg, &flags.RootDir, &cfg.Front.RootDir)
+		config.Set(cfg, &flags.BillingDay, &cfg.Costs.BillingDay)
+		config.Set(cfg, &flags.RetryAttempts, &cfg.Retry.Attempts)
+		config.Set(cfg, &flags.RetryBaseDelay, &cfg.Retry.BaseDelay)
+		config.Set(cfg, &flags.RetryMaxDelay, &cfg.Retry.MaxDelay)
+	})
+	if err != nil {
+		return
+	}
+	// retry policy for api calls
+	if policy, err = config.RetryPolicy(flags.RetryAttempts, flags.RetryBaseDelay, flags.RetryMaxDelay); err != nil {
 		return
 	}
+	ctx = retry.WithPolicy(ctx, policy)
 	// fix directories
 	appendRoot(flags)
 	// setup mux & server
@@ -140,6 +184,7 @@ func runFront(cmd *cobra.Command, args []string) (err error) {
 }
 
 func init() {
+	root.PersistentFlags().StringVar(&configFile, "config", configFile, "Config file (yaml or json) with shared settings")
 	root.PersistentFlags().StringVar(&flags.FrontHost, "front-host", flags.FrontHost, "Address to run this front from")
 	root.PersistentFlags().StringVar(&flags.ApiHost, "api-host", flags.ApiHost, "Address of the api")
 	root.PersistentFlags().StringVar(&flags.Version, "version", flags.Version, "The semver")
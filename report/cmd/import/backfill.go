@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"opg-reports/report/internal/global/backfill"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// backfillDataset contains the import to run for each chunk and how
+// to respect the rate limits of the api it uses
+type backfillDataset struct {
+	Import importF
+	Pause  time.Duration // fixed pause between each chunk
+	GitHub bool          // if true, wait for the github rate limit before each chunk
+}
+
+// backfillDatasets are the date based importers that can be backfilled
+var backfillDatasets = map[string]*backfillDataset{
+	"costs":                {Import: importCosts, Pause: 2 * time.Second}, // cost explorer has a low request rate
+	"uptime":               {Import: importUptime},
+	"uptime-canaries":      {Import: importUptimeCanaries},
+	"alarms":               {Import: importAlarms},
+	"codebase-releases":    {Import: importCodebaseReleases, GitHub: true},
+	"dora":                 {Import: importDora, GitHub: true},
+	"workflow-usage":       {Import: importWorkflowUsage, GitHub: true},
+	"workflow-reliability": {Import: importWorkflowReliability, GitHub: true},
+}
+
+// backfill specific flags
+var backfillFlags = &struct {
+	From    string
+	To      string
+	Restart bool
+	Pause   time.Duration
+}{
+	Pause: -1,
+}
+
+// backfill import command
+var backfillCmd = &cobra.Command{
+	Use:       `backfill <dataset>`,
+	Short:     `import a long date range of a dataset, one month at a time`,
+	Long:      `import a long date range of a dataset, one month at a time. Completed months are recorded so an interrupted backfill will resume. Datasets: ` + strings.Join(backfillNames(), ", "),
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: backfillNames(),
+	RunE:      runBackfill,
+}
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillFlags.From, "from", backfillFlags.From, "Start of the backfill (YYYY-MM or YYYY-MM-DD)")
+	backfillCmd.Flags().StringVar(&backfillFlags.To, "to", backfillFlags.To, "End of the backfill, exclusive (YYYY-MM or YYYY-MM-DD); defaults to today")
+	backfillCmd.Flags().BoolVar(&backfillFlags.Restart, "restart", backfillFlags.Restart, "Ignore previously completed months and start again")
+	backfillCmd.Flags().DurationVar(&backfillFlags.Pause, "pause", backfillFlags.Pause, "Pause between each month; defaults to a suitable value for the dataset")
+	backfillCmd.MarkFlagRequired("from")
+}
+
+// backfillNames returns the sorted names of datasets that can be backfilled
+func backfillNames() (names []string) {
+	names = []string{}
+	for k := range backfillDatasets {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return
+}
+
+// runBackfill runs the named dataset import for each month between --from and --to
+func runBackfill(cmd *cobra.Command, args []string) (err error) {
+	var (
+		name    string           = args[0]
+		dataset *backfillDataset = backfillDatasets[name]
+		to      time.Time        = times.Today()
+		ctx                      = cmd.Context()
+		in      *backfill.Args
+	)
+	// overwrite arg flags from the config file & env values and set the retry policy
+	if ctx, err = resolveConfig(cmd); err != nil {
+		return
+	}
+	if backfillFlags.To != "" {
+		to = times.MustFromString(backfillFlags.To)
+	}
+	in = &backfill.Args{
+		DB:      flags.DB,
+		Driver:  flags.Driver,
+		Params:  flags.Params,
+		Dataset: name,
+		From:    times.MustFromString(backfillFlags.From),
+		To:      to,
+		Restart: backfillFlags.Restart,
+		Pause:   dataset.Pause,
+	}
+	if backfillFlags.Pause >= 0 {
+		in.Pause = backfillFlags.Pause
+	}
+	// wait for the github rate limit before each chunk; githubWait is nil when replaying
+	if dataset.GitHub {
+		var wait workers.WaitF
+		if wait, err = githubWait(ctx); err != nil {
+			return
+		}
+		in.Wait = backfill.WaitF(wait)
+	}
+	// run the migrations
+	cleanup, err := migrate(ctx)
+	defer cleanup()
+	if err != nil {
+		return
+	}
+
+	err = dryRun(ctx, func(ctx context.Context) error {
+		return backfill.Run(ctx, func(ctx context.Context, chunk *backfill.Chunk) error {
+			// the importers read their dates from the flags
+			flags.DateStart = times.AsYMDString(chunk.Start)
+			flags.DateStartCosts = times.AsYMDString(chunk.Start)
+			flags.DateEnd = times.AsYMDString(chunk.End)
+			return record(name, dataset.Import)(ctx)
+		}, in)
+	})
+	return
+}
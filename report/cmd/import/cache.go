@@ -0,0 +1,28 @@
+package main
+
+import (
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/httpcache"
+
+	"github.com/spf13/cobra"
+)
+
+// clear cache command
+var clearCacheCmd = &cobra.Command{
+	Use:   `clear-cache`,
+	Short: `remove all cached github responses from the --http-cache directory`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var cache *httpcache.Cache
+		var removed int
+		// the directory can be set in the config file
+		if _, err = resolveConfig(cmd); err != nil {
+			return
+		}
+		if cache, err = httpcache.New(flags.HTTPCache); err != nil {
+			return
+		}
+		removed, err = cache.Clear()
+		cntxt.GetLogger(cmd.Context()).Info("cleared http cache", "dir", cache.Dir, "removed", removed)
+		return
+	},
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"opg-reports/report/package/awsclients"
+	"opg-reports/report/package/awsid"
+	"opg-reports/report/package/ghclients"
+	"opg-reports/report/package/ghgraphql"
+	"opg-reports/report/package/replay"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/workers"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/google/go-github/v84/github"
+)
+
+// replayStore returns the store for --record or --replay; nil when neither
+// is set so clients call the apis directly
+func replayStore() (store *replay.Store, err error) {
+	return replay.New(flags.Record, flags.Replay)
+}
+
+// replaying returns true when responses are being served from disk, so
+// no real clients (or credentials) are needed
+func replaying(store *replay.Store) bool {
+	return store != nil && store.Replay
+}
+
+// newGitHubClient authenticates as a github app when GITHUB_APP_ID is set and
+// with the GITHUB_TOKEN personal access token otherwise.
+//
+// App settings:
+//   - GITHUB_APP_ID: app id
+//   - GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_FILE: pem private key, or path to it
+//   - GITHUB_APP_INSTALLATION_ID: optional, the installation on --org is used when not set
+//
+// An app installation only covers a single org, so every --sources entry must
+// be within --org when using an app.
+func newGitHubClient(ctx context.Context) (client *github.Client, err error) {
+	var app = &ghclients.App{Org: flags.OrgSlug, PrivateKey: []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))}
+
+	if os.Getenv("GITHUB_APP_ID") == "" {
+		return ghclients.New(ctx, os.Getenv("GITHUB_TOKEN"))
+	}
+	if err = appSources(flags.OrgSlug, flags.Sources); err != nil {
+		return
+	}
+	if app.ID, err = strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64); err != nil {
+		return nil, errors.Join(ghclients.ErrInvalidApp, fmt.Errorf("GITHUB_APP_ID: %w", err))
+	}
+	if id := os.Getenv("GITHUB_APP_INSTALLATION_ID"); id != "" {
+		if app.InstallationID, err = strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, errors.Join(ghclients.ErrInvalidApp, fmt.Errorf("GITHUB_APP_INSTALLATION_ID: %w", err))
+		}
+	}
+	if file := os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE"); len(app.PrivateKey) == 0 && file != "" {
+		if app.PrivateKey, err = os.ReadFile(file); err != nil {
+			return
+		}
+	}
+	return ghclients.NewApp(ctx, app)
+}
+
+// the github client is created once and shared by every importer and the rate
+// limit waiter of the process, so they use the same transports & app token
+var (
+	githubOnce   sync.Once
+	githubShared *github.Client
+	githubErr    error
+)
+
+// sharedGitHubClient returns the github client of this process, calling
+// newGitHubClient on first use
+func sharedGitHubClient(ctx context.Context) (*github.Client, error) {
+	githubOnce.Do(func() {
+		githubShared, githubErr = newGitHubClient(ctx)
+	})
+	return githubShared, githubErr
+}
+
+var ErrAppSourceOrg = errors.New("github app installations only cover --org, all --sources must be within it.")
+
+// appSources returns an error when any of the sources are in an org other than
+// the one the app installation is for
+func appSources(org string, values []string) (err error) {
+	var sources []*repos.Source
+	if sources, err = repos.ParseSources(values); err != nil {
+		return
+	}
+	for _, source := range sources {
+		if !strings.EqualFold(source.Org, org) {
+			err = errors.Join(err, fmt.Errorf("source [%s/%s] is not in [%s]", source.Org, source.Team, org))
+		}
+	}
+	if err != nil {
+		err = errors.Join(ErrAppSourceOrg, err)
+	}
+	return
+}
+
+// githubClient returns the github services used by the importers; with
+// --github-api graphql the team and repository services are the graphql
+// versions (see ghgraphql)
+func githubClient(ctx context.Context) (gh *replay.GitHub, err error) {
+	var (
+		client *github.Client
+		store  *replay.Store
+	)
+	if flags.GitHubAPI != githubREST && flags.GitHubAPI != githubGraphQL {
+		err = errors.Join(ErrUnknownGitHubAPI, fmt.Errorf("api: [%s]", flags.GitHubAPI))
+		return
+	}
+	if store, err = replayStore(); err != nil {
+		return
+	}
+	if !replaying(store) {
+		if client, err = sharedGitHubClient(ctx); err != nil {
+			return
+		}
+	}
+	gh = replay.NewGitHub(client, store)
+	if client != nil && flags.GitHubAPI == githubGraphQL {
+		gql := ghgraphql.New(client.Client(), "")
+		gh.Teams.Client = gql.Teams()
+		gh.Repositories.Client = gql.Repositories()
+	}
+	return
+}
+
+// github apis that can be used for repository metadata (--github-api)
+const (
+	githubREST    string = "rest"
+	githubGraphQL string = "graphql"
+)
+
+var ErrUnknownGitHubAPI = errors.New("unknown github api, expected rest or graphql.")
+
+// githubMinRemaining is the number of core api calls that must be left
+// before more github work is started
+const githubMinRemaining int = 500
+
+// githubRateLimitInterval is the shortest time between rate limit checks
+const githubRateLimitInterval time.Duration = 30 * time.Second
+
+// githubWait returns a function that blocks while the github rate limit is
+// low; nil when replaying as no rate limits apply
+func githubWait(ctx context.Context) (wait workers.WaitF, err error) {
+	var (
+		client *github.Client
+		store  *replay.Store
+	)
+	if store, err = replayStore(); err != nil || replaying(store) {
+		return
+	}
+	if client, err = sharedGitHubClient(ctx); err != nil {
+		return
+	}
+	wait = ghclients.RateLimitWaiter(client.RateLimit, githubMinRemaining, githubRateLimitInterval)
+	return
+}
+
+// cloudwatchClient returns the cloudwatch client for the region
+func cloudwatchClient(ctx context.Context, region string) (client *replay.CloudWatch, err error) {
+	client = &replay.CloudWatch{Region: region}
+	if client.Store, err = replayStore(); err != nil || replaying(client.Store) {
+		return
+	}
+	client.Client, err = awsclients.New[*cloudwatch.Client](ctx, region)
+	return
+}
+
+// route53Client returns the route53 client for the region
+func route53Client(ctx context.Context, region string) (client *replay.Route53, err error) {
+	client = &replay.Route53{}
+	if client.Store, err = replayStore(); err != nil || replaying(client.Store) {
+		return
+	}
+	client.Client, err = awsclients.New[*route53.Client](ctx, region)
+	return
+}
+
+// costExplorerClient returns the cost explorer client for the region
+func costExplorerClient(ctx context.Context, region string) (client *replay.CostExplorer, err error) {
+	client = &replay.CostExplorer{}
+	if client.Store, err = replayStore(); err != nil || replaying(client.Store) {
+		return
+	}
+	client.Client, err = awsclients.New[*costexplorer.Client](ctx, region)
+	return
+}
+
+// accountID returns the account id of the current aws session, which is
+// also recorded so replays use the same account
+func accountID(ctx context.Context, region string) (id string) {
+	var store, _ = replayStore()
+	id, _ = replay.Call(ctx, store, "aws.STS.AccountID", region, func() (string, error) {
+		return awsid.AccountID(ctx, region), nil
+	})
+	return
+}
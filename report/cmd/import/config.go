@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"opg-reports/report/internal/global/config"
+	"opg-reports/report/package/httpcache"
+	"opg-reports/report/package/retry"
+
+	"github.com/spf13/cobra"
+)
+
+// configFile is the optional shared config file (--config)
+var configFile string = ""
+
+// resolveConfig updates the flags from the config file, env values and
+// then any explicitly passed flags, returning the command context with the
+// retry policy and http cache from those flags attached
+func resolveConfig(cmd *cobra.Command) (ctx context.Context, err error) {
+	var policy *retry.Policy
+	var cache *httpcache.Cache
+
+	ctx = cmd.Context()
+	_, err = config.Resolve(cmd, configFile, &flags, func(cfg *config.Config) {
+		config.Set(cfg, &flags.Driver, &cfg.Database.Driver)
+		config.Set(cfg, &flags.DB, &cfg.Database.DB)
+		config.Set(cfg, &flags.Params, &cfg.Database.Params)
+		config.Set(cfg, &flags.OrgSlug, &cfg.GitHub.Org)
+		config.Set(cfg, &flags.ParentSlug, &cfg.GitHub.Parent)
+		config.Set(cfg, &flags.Sources, &cfg.GitHub.Sources)
+		config.Set(cfg, &flags.Recursive, &cfg.GitHub.Recursive)
+		config.Set(cfg, &flags.Concurrency, &cfg.GitHub.Concurrency)
+		config.Set(cfg, &flags.HTTPCache, &cfg.GitHub.HTTPCache)
+		config.Set(cfg, &flags.GitHubAPI, &cfg.GitHub.API)
+		config.Set(cfg, &flags.OwnerToTeam, &cfg.GitHub.OwnerToTeam)
+		config.Set(cfg, &flags.Region, &cfg.AWS.Region)
+		config.Set(cfg, &flags.DateStart, &cfg.Dates.Start)
+		config.Set(cfg, &flags.DateEnd, &cfg.Dates.End)
+		config.Set(cfg, &flags.DateStartCosts, &cfg.Dates.StartCosts)
+		config.Set(cfg, &flags.ComplianceBaseURL, &cfg.Compliance.BaseURL)
+		config.Set(cfg, &flags.BranchChecks, &cfg.Branches.Checks)
+		config.Set(cfg, &flags.BranchMinReviews, &cfg.Branches.MinReviews)
+		config.Set(cfg, &flags.Standards, &cfg.Standards)
+		config.Set(cfg, &flags.RetryAttempts, &cfg.Retry.Attempts)
+		config.Set(cfg, &flags.RetryBaseDelay, &cfg.Retry.BaseDelay)
+		config.Set(cfg, &flags.RetryMaxDelay, &cfg.Retry.MaxDelay)
+	})
+	if err != nil {
+		return
+	}
+	if policy, err = config.RetryPolicy(flags.RetryAttempts, flags.RetryBaseDelay, flags.RetryMaxDelay); err != nil {
+		return
+	}
+	ctx = retry.WithPolicy(ctx, policy)
+	if flags.HTTPCache != "" {
+		if cache, err = httpcache.New(flags.HTTPCache); err != nil {
+			return
+		}
+		ctx = httpcache.WithCache(ctx, cache)
+	}
+	return
+}
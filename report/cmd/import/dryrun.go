@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"os"
+	"path/filepath"
+)
+
+// dry run output formats
+const (
+	formatTable string = "table"
+	formatJSON  string = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown dry run format.")
+
+// dryRun calls run directly unless --dry-run is set, in which case nothing
+// is written to the database and the rows that would have been inserted,
+// updated or deleted are written to stdout instead
+func dryRun(ctx context.Context, run importF) (err error) {
+	var (
+		diff *dbx.DryRun
+		res  *dbx.Diff
+		e    error
+	)
+	if !flags.DryRun {
+		return run(ctx)
+	}
+	if flags.DryRunFormat != formatTable && flags.DryRunFormat != formatJSON {
+		return errors.Join(ErrUnknownFormat, fmt.Errorf("format: [%s]", flags.DryRunFormat))
+	}
+	cntxt.GetLogger(ctx).Info("dry run, no changes will be made to the database ...")
+
+	ctx, diff = dbx.WithDryRun(ctx)
+	// show changes even when the run fails part way
+	err = run(ctx)
+	if res, e = diff.Diff(ctx, &dbx.DiffArgs{DB: flags.DB, Driver: flags.Driver, Params: flags.Params}); e != nil {
+		err = errors.Join(err, e)
+		return
+	}
+	if flags.DryRunFormat == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = errors.Join(err, enc.Encode(res))
+		return
+	}
+	dbx.WriteDiff(os.Stdout, res)
+	return
+}
+
+// dryRunCopy copies the database to a temporary file and points --db at it, so
+// a dry run can migrate and read from an up to date schema without changing the
+// real database. cleanup removes the copy and restores --db.
+func dryRunCopy(ctx context.Context) (cleanup func(), err error) {
+	var (
+		db       *sql.DB
+		dir      string
+		original string = flags.DB
+	)
+	cleanup = func() {}
+	if dir, err = os.MkdirTemp("", "dry-run-"); err != nil {
+		return
+	}
+	cleanup = func() {
+		flags.DB = original
+		os.RemoveAll(dir)
+	}
+	flags.DB = filepath.Join(dir, filepath.Base(original))
+	cntxt.GetLogger(ctx).Info("dry run, using a migrated copy of the database ...", "db", original, "copy", flags.DB)
+	// a database that does not exist yet is the same as an empty copy
+	if _, e := os.Stat(original); errors.Is(e, os.ErrNotExist) {
+		return
+	}
+	// open read only, as the connection defaults would otherwise touch the file
+	if db, err = sql.Open(flags.Driver, "file:"+original+"?mode=ro&_busy_timeout=5000"); err != nil {
+		return
+	}
+	defer db.Close()
+	_, err = db.ExecContext(ctx, `VACUUM INTO ?;`, flags.DB)
+	return
+}
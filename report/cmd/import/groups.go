@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"opg-reports/report/package/pipeline"
+	"opg-reports/report/package/workers"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// all import command
+var allCmd = &cobra.Command{
+	Use:   `all`,
+	Short: `run all aws and github imports in dependency order`,
+	RunE:  runGroup(awsTasks, githubTasks),
+}
+
+// aws import command
+var awsCmd = &cobra.Command{
+	Use:   `aws`,
+	Short: `run all aws imports (including teams & accounts) in dependency order`,
+	RunE:  runGroup(awsTasks),
+}
+
+// github import command
+var githubCmd = &cobra.Command{
+	Use:   `github`,
+	Short: `run all github imports in dependency order`,
+	RunE:  runGroup(githubTasks),
+}
+
+// awsTasks returns the importers that use the aws account data:
+//
+//	teams → accounts → costs / uptime / uptime-canaries / alarms
+//
+// teams & accounts are skipped when --teams-file / --accounts-file are not set
+func awsTasks() []*pipeline.Task {
+	var after = []string{"accounts"}
+	return []*pipeline.Task{
+		{Name: "teams", Run: fromFile(&flags.TeamsFile, record("teams", importTeams))},
+		{Name: "accounts", After: []string{"teams"}, Run: fromFile(&flags.AccountsFile, record("accounts", importAccounts))},
+		{Name: "costs", After: after, Run: pipeline.TaskF(record("costs", importCosts))},
+		{Name: "uptime", After: after, Run: pipeline.TaskF(record("uptime", importUptime))},
+		{Name: "uptime-canaries", After: after, Run: pipeline.TaskF(record("uptime-canaries", importUptimeCanaries))},
+		{Name: "alarms", After: after, Run: pipeline.TaskF(record("alarms", importAlarms))},
+	}
+}
+
+// githubTasks returns the importers that use github:
+//
+//	codebases → codeowners / codebase-stats / codebase-releases / dora / workflow-usage / workflow-reliability / dependabot / code-scanning / secret-scanning / branch-protection / standards
+func githubTasks() []*pipeline.Task {
+	var after = []string{"codebases"}
+	return []*pipeline.Task{
+		{Name: "codebases", Run: pipeline.TaskF(record("codebases", importCodebases))},
+		{Name: "codeowners", After: after, Run: pipeline.TaskF(record("codeowners", importCodeowners))},
+		{Name: "codebase-stats", After: after, Run: pipeline.TaskF(record("codebase-stats", importCodebaseStats))},
+		{Name: "codebase-releases", After: after, Run: pipeline.TaskF(record("codebase-releases", importCodebaseReleases))},
+		{Name: "dora", After: after, Run: pipeline.TaskF(record("dora", importDora))},
+		{Name: "workflow-usage", After: after, Run: pipeline.TaskF(record("workflow-usage", importWorkflowUsage))},
+		{Name: "workflow-reliability", After: after, Run: pipeline.TaskF(record("workflow-reliability", importWorkflowReliability))},
+		{Name: "dependabot", After: after, Run: pipeline.TaskF(record("dependabot", importDependabot))},
+		{Name: "code-scanning", After: after, Run: pipeline.TaskF(record("code-scanning", importCodeScanning))},
+		{Name: "secret-scanning", After: after, Run: pipeline.TaskF(record("secret-scanning", importSecretScanning))},
+		{Name: "branch-protection", After: after, Run: pipeline.TaskF(record("branch-protection", importBranchProtection))},
+		{Name: "standards", After: after, Run: pipeline.TaskF(record("standards", importStandards))},
+	}
+}
+
+// fromFile wraps file based importers so they are skipped when no file is set
+func fromFile(file *string, importer importF) pipeline.TaskF {
+	return func(ctx context.Context) (err error) {
+		if *file == "" {
+			return pipeline.ErrSkip
+		}
+		return importer(ctx)
+	}
+}
+
+// runGroup returns a cobra RunE func that runs the migrations once and then
+// all of the tasks from each group via the pipeline, writing a summary of
+// the results to stdout.
+//
+// The github importers run at the same time, so they share a single limit of
+// --concurrency repositories at once rather than each using their own.
+// Writes from all of the importers go to the same sqlite file, dbx only lets
+// one of them write at a time.
+func runGroup(groups ...func() []*pipeline.Task) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) (err error) {
+		var (
+			results []*pipeline.Result
+			tasks   []*pipeline.Task = []*pipeline.Task{}
+			ctx                      = cmd.Context()
+		)
+		// overwrite arg flags from the config file & env values and set the retry policy
+		if ctx, err = resolveConfig(cmd); err != nil {
+			return
+		}
+		// run the migrations
+		cleanup, err := migrate(ctx)
+		defer cleanup()
+		if err != nil {
+			return
+		}
+		for _, group := range groups {
+			tasks = append(tasks, group()...)
+		}
+		ctx = workers.WithLimit(ctx, flags.Concurrency)
+		err = dryRun(ctx, func(ctx context.Context) (err error) {
+			results, err = pipeline.Run(ctx, tasks)
+			pipeline.WriteSummary(os.Stdout, results)
+			return
+		})
+		return
+	}
+}
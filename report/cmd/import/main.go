@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"opg-reports/report/internal/branchprotection/branchprotectionimport"
 	"opg-reports/report/internal/global"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/logger"
+	"opg-reports/report/package/retry"
 	"opg-reports/report/package/times"
 	"os"
 	"time"
@@ -32,10 +34,25 @@ func main() {
 		accountsCmd,
 		costsCmd,
 		uptimeCmd,
+		uptimeCanariesCmd,
+		alarmsCmd,
 		codebasesCmd,
 		codeownersCmd,
 		codebaseStatsCmd,
 		codebaseReleasesCmd,
+		doraCmd,
+		workflowUsageCmd,
+		workflowReliabilityCmd,
+		dependabotCmd,
+		codeScanningCmd,
+		secretScanningCmd,
+		branchProtectionCmd,
+		standardsCmd,
+		allCmd,
+		awsCmd,
+		githubCmd,
+		backfillCmd,
+		clearCacheCmd,
 	)
 
 	err = root.ExecuteContext(ctx)
@@ -64,6 +81,16 @@ func getFlags(end time.Time) *global.ImportArgs {
 		OrgSlug:        "ministryofjustice",
 		ParentSlug:     "opg",
 		Filter:         "",
+		Concurrency:    4,
+		HTTPCache:      "./database/http-cache",
+		GitHubAPI:      githubREST,
+		DryRunFormat:   "table",
+		RetryAttempts:  retry.Default.Attempts,
+		RetryBaseDelay: retry.Default.BaseDelay.String(),
+		RetryMaxDelay:  retry.Default.MaxDelay.String(),
+		// branch protection policy; config file only
+		BranchChecks:     branchprotectionimport.DefaultPolicy.Checks,
+		BranchMinReviews: branchprotectionimport.DefaultPolicy.MinReviews,
 	}
 
 }
@@ -72,6 +99,7 @@ func init() {
 	var today = times.Today()
 
 	flags = getFlags(today)
+	root.PersistentFlags().StringVar(&configFile, "config", configFile, "Config file (yaml or json) with shared settings")
 	root.PersistentFlags().StringVar(&flags.Driver, "driver", flags.Driver, "Database driver")
 	root.PersistentFlags().StringVar(&flags.DB, "db", flags.DB, "Database path")
 	root.PersistentFlags().StringVar(&flags.Params, "params", flags.Params, "Database params")
@@ -79,10 +107,29 @@ func init() {
 	root.PersistentFlags().StringVar(&flags.DateEnd, "date-end", flags.DateEnd, "End date")
 	root.PersistentFlags().StringVar(&flags.Region, "region", flags.Region, "AWS Region")
 	root.PersistentFlags().StringVar(&flags.SrcFile, "src-file", flags.SrcFile, "Source file to import data from")
+	root.PersistentFlags().StringVar(&flags.TeamsFile, "teams-file", flags.TeamsFile, "Source file to import teams from")
+	root.PersistentFlags().StringVar(&flags.AccountsFile, "accounts-file", flags.AccountsFile, "Source file to import accounts from")
 	root.PersistentFlags().StringVar(&flags.OrgSlug, "org", flags.OrgSlug, "GitHub organisation")
 	root.PersistentFlags().StringVar(&flags.ParentSlug, "parent", flags.ParentSlug, "GitHub parent team")
+	root.PersistentFlags().StringSliceVar(&flags.Sources, "sources", flags.Sources, "GitHub org/team slugs to list repositories from, replaces --org & --parent (eg ministryofjustice/opg,ministryofjustice/digideps)")
+	root.PersistentFlags().BoolVar(&flags.Recursive, "recursive", flags.Recursive, "Include repositories from all child teams")
+	root.PersistentFlags().IntVar(&flags.Concurrency, "concurrency", flags.Concurrency, "Number of github repositories processed at once; shared by all importers when running a group")
+	root.PersistentFlags().StringVar(&flags.HTTPCache, "http-cache", flags.HTTPCache, "Directory to cache github responses in, unchanged responses do not count against the rate limit (empty to disable)")
+	root.PersistentFlags().StringVar(&flags.GitHubAPI, "github-api", flags.GitHubAPI, "GitHub api used for repository metadata, teams & files (rest or graphql)")
+
+	root.PersistentFlags().StringVar(&flags.UptimeMapping, "uptime-mapping", flags.UptimeMapping, "File mapping health checks to accounts for uptime")
 
 	root.PersistentFlags().StringVar(&flags.Filter, "filter", flags.Filter, "Text filter")
 	// needs a winder range for cost stability
 	root.PersistentFlags().StringVar(&flags.DateStartCosts, "date-start-costs", flags.DateStartCosts, "Start date for cost data")
+
+	root.PersistentFlags().BoolVar(&flags.DryRun, "dry-run", flags.DryRun, "Fetch data but do not write to the database; shows the rows that would change")
+	root.PersistentFlags().StringVar(&flags.DryRunFormat, "dry-run-format", flags.DryRunFormat, "Output format for --dry-run (table or json)")
+
+	root.PersistentFlags().IntVar(&flags.RetryAttempts, "retry-attempts", flags.RetryAttempts, "Number of attempts for each api call before failing")
+	root.PersistentFlags().StringVar(&flags.RetryBaseDelay, "retry-base-delay", flags.RetryBaseDelay, "Delay before the first retry of an api call, doubled for each retry after")
+	root.PersistentFlags().StringVar(&flags.RetryMaxDelay, "retry-max-delay", flags.RetryMaxDelay, "Longest delay between retries of an api call")
+
+	root.PersistentFlags().StringVar(&flags.Record, "record", flags.Record, "Directory to save every api response to")
+	root.PersistentFlags().StringVar(&flags.Replay, "replay", flags.Replay, "Directory of recorded api responses to use instead of calling the apis")
 }
@@ -1,263 +1,392 @@
 package main
 
 import (
+	"context"
 	"opg-reports/report/internal/account/accountimport"
+	"opg-reports/report/internal/alarms/alarmsimport"
+	"opg-reports/report/internal/branchprotection/branchprotectionimport"
 	"opg-reports/report/internal/codebasereleases/codebasereleasesimport"
 	"opg-reports/report/internal/codebases/codebasesimport"
 	"opg-reports/report/internal/codebasestats/codebasestatsimport"
 	"opg-reports/report/internal/codeowners/codeownersimport"
+	"opg-reports/report/internal/codescanning/codescanningimport"
 	"opg-reports/report/internal/cost/costimport"
+	"opg-reports/report/internal/dependabot/dependabotimport"
+	"opg-reports/report/internal/dora/doraimport"
+	"opg-reports/report/internal/global/importruns"
 	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/internal/secretscanning/secretscanningimport"
+	"opg-reports/report/internal/standards/standardsimport"
 	"opg-reports/report/internal/team/teamimport"
 	"opg-reports/report/internal/uptime/uptimeimport"
-	"opg-reports/report/package/awsclients"
-	"opg-reports/report/package/awsid"
-	"opg-reports/report/package/env"
-	"opg-reports/report/package/ghclients"
+	"opg-reports/report/internal/workflowreliability/workflowreliabilityimport"
+	"opg-reports/report/internal/workflowusage/workflowusageimport"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/httpcache"
+	"opg-reports/report/package/replay"
 	"opg-reports/report/package/times"
-	"os"
+	"opg-reports/report/package/workers"
 
-	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
-	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
-	"github.com/google/go-github/v84/github"
 	"github.com/spf13/cobra"
 )
 
+// importF is the signature of each of the import functions; these expect
+// the flags to be set and migrations to have been run already
+type importF func(ctx context.Context) (err error)
+
 // team import command
 var teamsCmd = &cobra.Command{
 	Use:   `teams`,
 	Short: `import teams`,
-	RunE:  runTeamsImport,
+	RunE:  runImport(importTeams),
 }
 
 // accounts import command
 var accountsCmd = &cobra.Command{
 	Use:   `accounts`,
 	Short: `import accounts`,
-	RunE:  runAccountsImport,
+	RunE:  runImport(importAccounts),
 }
 
 // costs import command
 var costsCmd = &cobra.Command{
 	Use:   `costs`,
 	Short: `import costs`,
-	RunE:  runCostsImport,
+	RunE:  runImport(importCosts),
 }
 
 // uptime import command
 var uptimeCmd = &cobra.Command{
 	Use:   `uptime`,
 	Short: `import uptime`,
-	RunE:  runUptimeImport,
+	RunE:  runImport(importUptime),
+}
+
+// uptime canaries import command
+var uptimeCanariesCmd = &cobra.Command{
+	Use:   `uptime-canaries`,
+	Short: `import uptime from synthetics canaries`,
+	RunE:  runImport(importUptimeCanaries),
+}
+
+// alarms import command
+var alarmsCmd = &cobra.Command{
+	Use:   `alarms`,
+	Short: `import cloudwatch alarm history`,
+	RunE:  runImport(importAlarms),
 }
 
 // codebase import command
 var codebasesCmd = &cobra.Command{
 	Use:   `codebases`,
 	Short: `import codebases`,
-	RunE:  runCodebaseImport,
+	RunE:  runImport(importCodebases),
 }
 
 // codeowner import command
 var codeownersCmd = &cobra.Command{
 	Use:   `codeowners`,
 	Short: `import codeowners`,
-	RunE:  runCodeownersImport,
+	RunE:  runImport(importCodeowners),
 }
 
 // codebase stats import command
 var codebaseStatsCmd = &cobra.Command{
 	Use:   `codebase-stats`,
 	Short: `import codebase stats`,
-	RunE:  runCodebaseStatsImport,
+	RunE:  runImport(importCodebaseStats),
 }
 
 // codebase releases import command
 var codebaseReleasesCmd = &cobra.Command{
 	Use:   `codebase-releases`,
 	Short: `import codebase releases`,
-	RunE:  runCodebaseReleasesImport,
+	RunE:  runImport(importCodebaseReleases),
+}
+
+// dora metrics import command
+var doraCmd = &cobra.Command{
+	Use:   `dora`,
+	Short: `import dora metrics from path to live workflow runs and pull requests`,
+	RunE:  runImport(importDora),
+}
+
+// workflow usage import command
+var workflowUsageCmd = &cobra.Command{
+	Use:   `workflow-usage`,
+	Short: `import workflow run durations and billable minutes`,
+	RunE:  runImport(importWorkflowUsage),
 }
 
-// runTeamsImport runs the teams
-func runTeamsImport(cmd *cobra.Command, args []string) (err error) {
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
+// workflow reliability import command
+var workflowReliabilityCmd = &cobra.Command{
+	Use:   `workflow-reliability`,
+	Short: `import workflow run conclusions and attempts to report failure, re-run rate and time to green`,
+	RunE:  runImport(importWorkflowReliability),
+}
+
+// dependabot alerts import command
+var dependabotCmd = &cobra.Command{
+	Use:   `dependabot`,
+	Short: `import open and closed dependabot alerts with their advisories`,
+	RunE:  runImport(importDependabot),
+}
+
+// code scanning alerts import command
+var codeScanningCmd = &cobra.Command{
+	Use:   `code-scanning`,
+	Short: `import open and closed code scanning alerts from codeql and third party sarif uploads`,
+	RunE:  runImport(importCodeScanning),
+}
+
+// secret scanning alerts import command
+var secretScanningCmd = &cobra.Command{
+	Use:   `secret-scanning`,
+	Short: `import open and resolved secret scanning alerts`,
+	RunE:  runImport(importSecretScanning),
+}
+
+// branch protection import command
+var branchProtectionCmd = &cobra.Command{
+	Use:   `branch-protection`,
+	Short: `import default branch protection & ruleset settings and check them against the branch_protection policy`,
+	RunE:  runImport(importBranchProtection),
+}
+
+// repository standards import command
+var standardsCmd = &cobra.Command{
+	Use:   `standards`,
+	Short: `check each codebase against the repository standards in the config file`,
+	RunE:  runImport(importStandards),
+}
+
+// runImport returns a cobra RunE func that overwrites flags with env values,
+// runs the migrations and then calls the import function (or a dry run of it)
+func runImport(importer importF) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) (err error) {
+		var ctx = cmd.Context()
+		// overwrite arg flags from the config file & env values and set the retry policy
+		if ctx, err = resolveConfig(cmd); err != nil {
+			return
+		}
+		// run the migrations
+		cleanup, err := migrate(ctx)
+		defer cleanup()
+		if err != nil {
+			return
+		}
+		err = dryRun(ctx, record(cmd.Name(), importer))
 		return
 	}
-	// run the migrations
+}
+
+// record wraps the importer so each run is written to the import_runs table
+// and logs how the http cache was used by it. New commands also need adding to
+// importruns.Commands.
+func record(command string, importer importF) importF {
+	return func(ctx context.Context) (err error) {
+		var cache = httpcache.GetCache(ctx)
+		var before httpcache.Stats
+		if cache != nil {
+			before = cache.Stats()
+		}
+		err = importruns.Record(ctx, importruns.RunF(importer), &importruns.Args{
+			DB:        flags.DB,
+			Driver:    flags.Driver,
+			Params:    flags.Params,
+			Command:   command,
+			Arguments: flags,
+		})
+		if cache != nil {
+			stats := cache.Stats().Sub(before)
+			cntxt.GetLogger(ctx).Info("http cache usage", "command", command,
+				"requests", stats.Requests, "hits", stats.Hits, "misses", stats.Misses, "stored", stats.Stored, "errors", stats.Errors)
+		}
+		return
+	}
+}
+
+// migrate runs the database migrations using the flag values. Dry runs migrate
+// a copy of the database instead (see dryRunCopy) and use that for the rest of
+// the run; cleanup removes the copy and should always be called.
+func migrate(ctx context.Context) (cleanup func(), err error) {
+	cleanup = func() {}
+	if flags.DryRun {
+		if cleanup, err = dryRunCopy(ctx); err != nil {
+			return
+		}
+	}
 	err = migrations.Migrate(ctx, &migrations.Args{
 		DB:     flags.DB,
 		Driver: flags.Driver,
 		Params: flags.Params,
 	})
-	if err != nil {
-		return
+	return
+}
+
+// srcFile returns the specific file if set, otherwise falls back to --src-file
+func srcFile(specific string) string {
+	if specific != "" {
+		return specific
 	}
-	// run the import
+	return flags.SrcFile
+}
+
+// importTeams runs the teams import
+func importTeams(ctx context.Context) (err error) {
 	err = teamimport.Import(ctx, &teamimport.Args{
 		DB:      flags.DB,
 		Driver:  flags.Driver,
 		Params:  flags.Params,
-		SrcFile: flags.SrcFile,
+		SrcFile: srcFile(flags.TeamsFile),
 	})
 	return
 }
 
-// runAccountsImport runs the accounts import
-func runAccountsImport(cmd *cobra.Command, args []string) (err error) {
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
-		return
-	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
-	})
-	if err != nil {
-		return
-	}
-	// run the import
+// importAccounts runs the accounts import
+func importAccounts(ctx context.Context) (err error) {
 	err = accountimport.Import(ctx, &accountimport.Args{
 		DB:      flags.DB,
 		Driver:  flags.Driver,
 		Params:  flags.Params,
-		SrcFile: flags.SrcFile,
+		SrcFile: srcFile(flags.AccountsFile),
 	})
 	return
 }
 
-// runCostsImport runs the costs
-func runCostsImport(cmd *cobra.Command, args []string) (err error) {
-	var client *costexplorer.Client
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
-		return
-	}
-	client, err = awsclients.New[*costexplorer.Client](ctx, flags.Region)
-	if err != nil {
-		return
-	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
-	})
+// importCosts runs the costs import
+func importCosts(ctx context.Context) (err error) {
+	var client *replay.CostExplorer
+
+	client, err = costExplorerClient(ctx, flags.Region)
 	if err != nil {
 		return
 	}
-
 	err = costimport.Import(ctx, client, &costimport.Args{
 		DB:        flags.DB,
 		Driver:    flags.Driver,
 		Params:    flags.Params,
 		DateStart: times.MustFromString(flags.DateStartCosts), // use the other start date thats further back in time
 		DateEnd:   times.MustFromString(flags.DateEnd),
-		AccountID: awsid.AccountID(ctx, flags.Region),
+		AccountID: accountID(ctx, flags.Region),
 	})
 	return
 }
 
-// runUptimeImport runs the uptime import
-func runUptimeImport(cmd *cobra.Command, args []string) (err error) {
-	var client *cloudwatch.Client
+// importUptime runs the uptime import using route53 health checks
+func importUptime(ctx context.Context) (err error) {
+	var client *replay.CloudWatch
+	var tagClient *replay.Route53
 	var region = "us-east-1" // forced region
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
+
+	client, err = cloudwatchClient(ctx, region)
+	if err != nil {
 		return
 	}
-	client, err = awsclients.New[*cloudwatch.Client](ctx, region)
+	tagClient, err = route53Client(ctx, region)
 	if err != nil {
 		return
 	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
+
+	clients := &uptimeimport.Clients{
+		Metrics: client,
+		Tags:    tagClient,
+	}
+
+	err = uptimeimport.Import(ctx, clients, &uptimeimport.Args{
+		DB:          flags.DB,
+		Driver:      flags.Driver,
+		Params:      flags.Params,
+		DateStart:   times.MustFromString(flags.DateStart),
+		DateEnd:     times.MustFromString(flags.DateEnd),
+		AccountID:   accountID(ctx, flags.Region),
+		MappingFile: flags.UptimeMapping,
+		Source:      uptimeimport.SourceHealthCheck,
 	})
+	return
+}
+
+// importUptimeCanaries runs the uptime import using synthetics canaries
+// from the configured region; these are always attributed to the account
+// the import is running within
+func importUptimeCanaries(ctx context.Context) (err error) {
+	var client *replay.CloudWatch
+
+	client, err = cloudwatchClient(ctx, flags.Region)
 	if err != nil {
 		return
 	}
-
-	err = uptimeimport.Import(ctx, client, &uptimeimport.Args{
+	err = uptimeimport.Import(ctx, &uptimeimport.Clients{Metrics: client}, &uptimeimport.Args{
 		DB:        flags.DB,
 		Driver:    flags.Driver,
 		Params:    flags.Params,
 		DateStart: times.MustFromString(flags.DateStart),
 		DateEnd:   times.MustFromString(flags.DateEnd),
-		AccountID: awsid.AccountID(ctx, flags.Region),
+		AccountID: accountID(ctx, flags.Region),
+		Source:    uptimeimport.SourceCanary,
 	})
 	return
 }
 
-// runCodebaseImport runs the codebase import with stats
-func runCodebaseImport(cmd *cobra.Command, arglist []string) (err error) {
-	var client *github.Client
-	var tk = os.Getenv("GITHUB_TOKEN")
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
-		return
-	}
-	client, err = ghclients.New(ctx, tk)
+// importAlarms runs the cloudwatch alarm history import
+func importAlarms(ctx context.Context) (err error) {
+	var client *replay.CloudWatch
+
+	client, err = cloudwatchClient(ctx, flags.Region)
 	if err != nil {
 		return
 	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
+	err = alarmsimport.Import(ctx, client, &alarmsimport.Args{
+		DB:        flags.DB,
+		Driver:    flags.Driver,
+		Params:    flags.Params,
+		DateStart: times.MustFromString(flags.DateStart),
+		DateEnd:   times.MustFromString(flags.DateEnd),
+		AccountID: accountID(ctx, flags.Region),
 	})
+	return
+}
+
+// importCodebases runs the codebase import
+func importCodebases(ctx context.Context) (err error) {
+	var client *replay.GitHub
+
+	client, err = githubClient(ctx)
 	if err != nil {
 		return
 	}
-
 	err = codebasesimport.Import(ctx, client.Teams, &codebasesimport.Args{
 		DB:           flags.DB,
 		Driver:       flags.Driver,
 		Params:       flags.Params,
 		OrgSlug:      flags.OrgSlug,
 		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
 		FilterByName: flags.Filter,
 	})
 	return
 }
 
-// runCodeownersImport runs the codebase and codeowner import - this is a bit slower due to fetching files
-func runCodeownersImport(cmd *cobra.Command, arglist []string) (err error) {
-	var client *github.Client
-	var tk = os.Getenv("GITHUB_TOKEN")
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
-		return
-	}
-	client, err = ghclients.New(ctx, tk)
+// importCodeowners runs the codeowner import - this is a bit slower due to fetching files
+func importCodeowners(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
 	if err != nil {
 		return
 	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
-	})
-	if err != nil {
+	if wait, err = githubWait(ctx); err != nil {
 		return
 	}
 
 	clients := &codeownersimport.Clients{
-		Teams: client.Teams,
-		Repos: client.Repositories,
+		Teams:      client.Teams,
+		Repos:      client.Repositories,
+		TeamLookup: client.TeamLookup,
+		Users:      client.Users,
 	}
 
 	err = codeownersimport.Import(ctx, clients, &codeownersimport.Args{
@@ -266,31 +395,26 @@ func runCodeownersImport(cmd *cobra.Command, arglist []string) (err error) {
 		Params:       flags.Params,
 		OrgSlug:      flags.OrgSlug,
 		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
 		FilterByName: flags.Filter,
+		OwnerToTeam:  flags.OwnerToTeam,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
 	})
 	return
 }
 
-// runCodebaseStatsImport runs code base import with stats data
-func runCodebaseStatsImport(cmd *cobra.Command, arglist []string) (err error) {
-	var client *github.Client
-	var tk = os.Getenv("GITHUB_TOKEN")
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
-		return
-	}
-	client, err = ghclients.New(ctx, tk)
+// importCodebaseStats runs code base import with stats data
+func importCodebaseStats(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
 	if err != nil {
 		return
 	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
-	})
-	if err != nil {
+	if wait, err = githubWait(ctx); err != nil {
 		return
 	}
 
@@ -300,54 +424,336 @@ func runCodebaseStatsImport(cmd *cobra.Command, arglist []string) (err error) {
 	}
 
 	err = codebasestatsimport.Import(ctx, clients, &codebasestatsimport.Args{
+		DB:                flags.DB,
+		Driver:            flags.Driver,
+		Params:            flags.Params,
+		OrgSlug:           flags.OrgSlug,
+		ParentSlug:        flags.ParentSlug,
+		Sources:           flags.Sources,
+		Recursive:         flags.Recursive,
+		FilterByName:      flags.Filter,
+		ComplianceBaseURL: flags.ComplianceBaseURL,
+		Concurrency:       flags.Concurrency,
+		Wait:              wait,
+	})
+	return
+}
+
+// importCodebaseReleases runs the codebase release import
+func importCodebaseReleases(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &codebasereleasesimport.Clients{
+		Teams:   client.Teams,
+		Actions: client.Actions,
+		PR:      client.PullRequests,
+	}
+
+	err = codebasereleasesimport.Import(ctx, clients, &codebasereleasesimport.Args{
 		DB:           flags.DB,
 		Driver:       flags.Driver,
 		Params:       flags.Params,
 		OrgSlug:      flags.OrgSlug,
 		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		DateStart:    times.MustFromString(flags.DateStart),
+		DateEnd:      times.MustFromString(flags.DateEnd),
 		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
 	})
 	return
 }
 
-// runCodebaseReleasesImport
-func runCodebaseReleasesImport(cmd *cobra.Command, arglist []string) (err error) {
-	var client *github.Client
-	var tk = os.Getenv("GITHUB_TOKEN")
-	var ctx = cmd.Context()
-	// overwrite arg flags from env values
-	if e := env.OverwriteStruct(&flags); e != nil {
+// importDora runs the dora metrics import
+func importDora(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
 		return
 	}
-	client, err = ghclients.New(ctx, tk)
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &doraimport.Clients{
+		Teams:   client.Teams,
+		Actions: client.Actions,
+		PR:      client.PullRequests,
+	}
+
+	err = doraimport.Import(ctx, clients, &doraimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		DateStart:    times.MustFromString(flags.DateStart),
+		DateEnd:      times.MustFromString(flags.DateEnd),
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+	})
+	return
+}
+
+// importWorkflowUsage runs the workflow usage import
+func importWorkflowUsage(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
 	if err != nil {
 		return
 	}
-	// run the migrations
-	err = migrations.Migrate(ctx, &migrations.Args{
-		DB:     flags.DB,
-		Driver: flags.Driver,
-		Params: flags.Params,
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &workflowusageimport.Clients{
+		Teams:   client.Teams,
+		Actions: client.Actions,
+	}
+
+	err = workflowusageimport.Import(ctx, clients, &workflowusageimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		DateStart:    times.MustFromString(flags.DateStart),
+		DateEnd:      times.MustFromString(flags.DateEnd),
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
 	})
+	return
+}
+
+// importWorkflowReliability runs the workflow reliability import
+func importWorkflowReliability(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
 	if err != nil {
 		return
 	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
 
-	clients := &codebasereleasesimport.Clients{
+	clients := &workflowreliabilityimport.Clients{
 		Teams:   client.Teams,
 		Actions: client.Actions,
-		PR:      client.PullRequests,
 	}
 
-	err = codebasereleasesimport.Import(ctx, clients, &codebasereleasesimport.Args{
+	err = workflowreliabilityimport.Import(ctx, clients, &workflowreliabilityimport.Args{
 		DB:           flags.DB,
 		Driver:       flags.Driver,
 		Params:       flags.Params,
 		OrgSlug:      flags.OrgSlug,
 		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
 		DateStart:    times.MustFromString(flags.DateStart),
 		DateEnd:      times.MustFromString(flags.DateEnd),
 		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+	})
+	return
+}
+
+// importDependabot runs the dependabot alerts import
+func importDependabot(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &dependabotimport.Clients{
+		Teams:      client.Teams,
+		Dependabot: client.Dependabot,
+	}
+
+	err = dependabotimport.Import(ctx, clients, &dependabotimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+	})
+	return
+}
+
+// importCodeScanning runs the code scanning alerts import
+func importCodeScanning(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &codescanningimport.Clients{
+		Teams:        client.Teams,
+		CodeScanning: client.CodeScanning,
+	}
+
+	err = codescanningimport.Import(ctx, clients, &codescanningimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+	})
+	return
+}
+
+// importSecretScanning runs the secret scanning alerts import
+func importSecretScanning(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &secretscanningimport.Clients{
+		Teams:          client.Teams,
+		SecretScanning: client.SecretScanning,
+	}
+
+	err = secretscanningimport.Import(ctx, clients, &secretscanningimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+	})
+	return
+}
+
+// importBranchProtection runs the branch protection import using the
+// branch_protection policy from the config file
+func importBranchProtection(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+
+	clients := &branchprotectionimport.Clients{
+		Teams:    client.Teams,
+		Branches: client.Branches,
+	}
+
+	err = branchprotectionimport.Import(ctx, clients, &branchprotectionimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+		Policy: &branchprotectionimport.Policy{
+			Checks:     flags.BranchChecks,
+			MinReviews: flags.BranchMinReviews,
+		},
+	})
+	return
+}
+
+// importStandards runs the repository standards import using the standards
+// from the config file
+func importStandards(ctx context.Context) (err error) {
+	var client *replay.GitHub
+	var wait workers.WaitF
+	var rules = []*standardsimport.Rule{}
+
+	client, err = githubClient(ctx)
+	if err != nil {
+		return
+	}
+	if wait, err = githubWait(ctx); err != nil {
+		return
+	}
+	if err = cnv.Convert(flags.Standards, &rules); err != nil {
+		return
+	}
+
+	clients := &standardsimport.Clients{
+		Teams: client.Teams,
+		Repos: client.Repositories,
+		Git:   client.Git,
+	}
+
+	err = standardsimport.Import(ctx, clients, &standardsimport.Args{
+		DB:           flags.DB,
+		Driver:       flags.Driver,
+		Params:       flags.Params,
+		OrgSlug:      flags.OrgSlug,
+		ParentSlug:   flags.ParentSlug,
+		Sources:      flags.Sources,
+		Recursive:    flags.Recursive,
+		FilterByName: flags.Filter,
+		Concurrency:  flags.Concurrency,
+		Wait:         wait,
+		Rules:        rules,
 	})
 	return
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"opg-reports/report/internal/global/config"
 	"opg-reports/report/internal/global/migrations"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/logger"
@@ -17,6 +18,9 @@ var flags = &migrations.Args{
 
 var runConversion bool = false
 
+// configFile is the optional shared config file (--config)
+var configFile string = ""
+
 var root *cobra.Command = &cobra.Command{
 	Use:   "migrate",
 	Short: `run migrations for the database`,
@@ -25,6 +29,15 @@ var root *cobra.Command = &cobra.Command{
 
 func runCMD(cmd *cobra.Command, args []string) (err error) {
 	var ctx = cmd.Context()
+	// overwrite arg flags from the config file & env values
+	_, err = config.Resolve(cmd, configFile, &flags, func(cfg *config.Config) {
+		config.Set(cfg, &flags.Driver, &cfg.Database.Driver)
+		config.Set(cfg, &flags.DB, &cfg.Database.DB)
+		config.Set(cfg, &flags.Params, &cfg.Database.Params)
+	})
+	if err != nil {
+		return
+	}
 	err = migrations.Migrate(ctx, flags)
 	if err != nil {
 		return
@@ -36,11 +49,11 @@ func runCMD(cmd *cobra.Command, args []string) (err error) {
 }
 
 func init() {
+	root.PersistentFlags().StringVar(&configFile, "config", configFile, "Config file (yaml or json) with shared settings")
 	root.PersistentFlags().StringVar(&flags.Driver, "driver", flags.Driver, "Database driver")
 	root.PersistentFlags().StringVar(&flags.DB, "db", flags.DB, "Database path")
 	root.PersistentFlags().StringVar(&flags.Params, "params", flags.Params, "Database params")
 	root.PersistentFlags().BoolVar(&runConversion, "convert", runConversion, "Run DB conversion to upgrade from older structure")
-
 }
 
 func main() {
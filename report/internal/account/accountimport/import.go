@@ -16,29 +16,33 @@ INSERT INTO accounts (
 	name,
 	label,
 	environment,
+	uptime_tracking,
 	team_name
 ) VALUES (
 	:id,
 	:name,
 	:label,
 	:environment,
+	IIF(:uptime_tracking, 'true', 'false'),
 	:billing_unit
 ) ON CONFLICT (id) DO UPDATE SET
 	name=excluded.name,
 	team_name=excluded.team_name,
 	label=excluded.label,
-	environment=excluded.environment
+	environment=excluded.environment,
+	uptime_tracking=excluded.uptime_tracking
 RETURNING id
 ;
 `
 
 // Model represents a simple, joinless, db row in the team table; used by imports and seeding commands
 type Model struct {
-	ID          string `json:"id,omitempty"`            // This is the Account ID as a string - they can have leading 0
-	Name        string `json:"name,omitempty" `         // account name as used internally
-	Label       string `json:"label,omitempty" `        // internal label
-	Environment string `json:"environment,omitempty" `  // environment type
-	TeamName    string `json:"billing_unit,omitempty" ` // team associated with the account; uses builling_unit due to the source data in opg-metadata
+	ID             string `json:"id,omitempty"`            // This is the Account ID as a string - they can have leading 0
+	Name           string `json:"name,omitempty" `         // account name as used internally
+	Label          string `json:"label,omitempty" `        // internal label
+	Environment    string `json:"environment,omitempty" `  // environment type
+	UptimeTracking bool   `json:"uptime_tracking"`         // flag to say if uptime should be imported for this account
+	TeamName       string `json:"billing_unit,omitempty" ` // team associated with the account; uses builling_unit due to the source data in opg-metadata
 }
 
 type Args struct {
@@ -2,8 +2,10 @@ package accountimport
 
 import (
 	"context"
+	"database/sql"
 	"opg-reports/report/internal/global/migrations"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
 	"opg-reports/report/package/files"
 	"opg-reports/report/package/logger"
 	"path/filepath"
@@ -28,11 +30,12 @@ func TestAccountImportWithoutMock(t *testing.T) {
 			TeamName:    "team-dev",
 		},
 		{
-			ID:          "A002",
-			Name:        "Test 02",
-			Label:       "test",
-			Environment: "production",
-			TeamName:    "team-production",
+			ID:             "A002",
+			Name:           "Test 02",
+			Label:          "test",
+			Environment:    "production",
+			UptimeTracking: true,
+			TeamName:       "team-production",
 		},
 	}
 	err = files.WriteAsJSON(ctx, srcfile, data)
@@ -57,4 +60,22 @@ func TestAccountImportWithoutMock(t *testing.T) {
 		t.Errorf("unexpected error:\n%s", err.Error())
 	}
 
+	// check the uptime tracking flag is converted
+	tracking := map[string]string{}
+	dbx.Select(ctx, `SELECT id, uptime_tracking FROM accounts;`, &dbx.SelectArgs{
+		DB:      dbpath,
+		Driver:  "sqlite3",
+		BindMap: map[string]interface{}{},
+		ScanF: func(rows *sql.Rows) (e error) {
+			var id, val string
+			if e = rows.Scan(&id, &val); e == nil {
+				tracking[id] = val
+			}
+			return
+		},
+	})
+	if tracking["A001"] != "false" || tracking["A002"] != "true" {
+		t.Errorf("uptime tracking not set correctly: %v", tracking)
+	}
+
 }
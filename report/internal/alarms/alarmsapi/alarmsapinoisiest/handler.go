@@ -0,0 +1,175 @@
+package alarmsapinoisiest
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// defaultLimit is the number of alarms returned when `limit` is not set
+const defaultLimit int = 10
+
+// selectStmt is the sql used to fetch data including
+// and params (`:name`) that will be replaced by values
+// from `Request` (by configuring `Filter`)
+const selectStmt string = `
+SELECT
+	alarms.alarm_name as alarm_name,
+	alarms.account_id as account_id,
+	IIF(accounts.team_name != "", accounts.team_name, "")  as team,
+	COUNT(*) as count,
+	CAST(COALESCE(AVG(alarms.seconds_in_alarm), 0) as REAL) as mean_seconds_in_alarm,
+	COALESCE(SUM(alarms.seconds_in_alarm), 0) as seconds_in_alarm
+FROM alarms
+LEFT JOIN accounts on accounts.id = alarms.account_id
+WHERE
+	alarms.state_to = 'ALARM'
+	AND alarms.month IN (:months)
+GROUP BY
+	alarms.account_id,
+	alarms.alarm_name
+ORDER BY
+	count DESC,
+	seconds_in_alarm DESC,
+	alarms.alarm_name ASC
+LIMIT :limit
+;
+`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"`
+	Limit     string `json:"limit"` // optional query string; number of alarms to return
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"` // the actual data results, noisiest first
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+	Limit  int      `json:"limit"`
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	AlarmName          string  `json:"alarm_name"`
+	AccountID          string  `json:"account_id"`
+	Team               string  `json:"team"`
+	Count              int     `json:"count"`                 // number of times the alarm went into ALARM
+	MeanSecondsInAlarm float64 `json:"mean_seconds_in_alarm"` // mean time spent in ALARM each time
+	SecondsInAlarm     int     `json:"seconds_in_alarm"`      // total time spent in ALARM
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.AlarmName,
+		&self.AccountID,
+		&self.Team,
+		&self.Count,
+		&self.MeanSecondsInAlarm,
+		&self.SecondsInAlarm,
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		months   []string
+		filter   *Filter                = &Filter{Limit: defaultLimit}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "alarmsapinoisiest", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	filter.Months = months
+	// look for the optional limit
+	if l, e := strconv.Atoi(in.Limit); e == nil && l > 0 {
+		filter.Limit = l
+	}
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE accounts.team_name = :team AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	// make the db call via the Select helper that handles row scanning.
+	// No return value as local values are updates within ScanF lambda
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
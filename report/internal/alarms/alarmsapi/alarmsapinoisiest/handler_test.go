@@ -0,0 +1,61 @@
+package alarmsapinoisiest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlarmsAPINoisiestHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+		end    = times.AsYMString(times.Today())
+		start  = times.AsYMString(times.Add(times.Today(), -3, times.YEAR))
+	)
+	// run seeds
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	// setup the server and items
+	url := "/v1/alarms/noisiest/between/" + start + "/" + end + "/?limit=5"
+	mux := http.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	writer := httptest.NewRecorder()
+
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	mux.ServeHTTP(writer, req)
+
+	rec := &Response{}
+	err = response.As(writer.Result(), &rec)
+	if err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(rec.Data) != 5 {
+		t.Errorf("expected 5 rows, found [%d]", len(rec.Data))
+	}
+	for i := 1; i < len(rec.Data); i++ {
+		if rec.Data[i].Count > rec.Data[i-1].Count {
+			t.Errorf("results not ordered by noisiest first")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package alarmsapiteam
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/tabulate"
+	"opg-reports/report/package/times"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt is the sql used to fetch data including
+// and params (`:name`) that will be replaced by values
+// from `Request` (by configuring `Filter`).
+//
+// `{MEASURE}` is swapped out for the sql of the measure requested
+const selectStmt string = `
+SELECT
+	alarms.month as month,
+	{MEASURE} as value,
+	IIF(accounts.team_name != "", accounts.team_name, "")  as team
+FROM alarms
+LEFT JOIN accounts on accounts.id = alarms.account_id
+WHERE
+	alarms.state_to = 'ALARM'
+	AND alarms.month IN (:months)
+GROUP BY
+	alarms.month,
+	accounts.team_name
+ORDER BY
+	accounts.team_name ASC
+;
+`
+
+// Measures that can be requested via the `measure` query string
+const (
+	MeasureCount    string = "count"     // number of times alarms went into ALARM
+	MeasureMeanTime string = "mean_time" // mean number of seconds spent in ALARM
+)
+
+// measures maps the measure name to its sql and how rows / table are summarised
+var measures = map[string]*measure{
+	MeasureCount: {
+		Stmt:   `CAST(COUNT(*) as REAL)`,
+		End:    "total",
+		RowF:   tabulate.RowTotalF,
+		TableF: tabulate.TableTotalF,
+	},
+	MeasureMeanTime: {
+		Stmt:   `CAST(COALESCE(AVG(alarms.seconds_in_alarm), 0) as REAL)`,
+		End:    "average",
+		RowF:   tabulate.RowAverageF,
+		TableF: tabulate.TableAverageF,
+	},
+}
+
+type measure struct {
+	Stmt   string
+	End    string
+	RowF   tabulate.RowEndFunc
+	TableF tabulate.TableEndFunc
+}
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"`
+	Measure   string `json:"measure"` // optional query string; `count` (default) or `mean_time`
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string                        `json:"version"`
+	SHA     string                        `json:"sha"`
+	Request *Request                      `json:"request"`
+	Headers map[tabulate.ColType][]string `json:"headers"` // headers contains details for table headers / rendering
+	Data    []map[string]interface{}      `json:"data"`    // the actual data results
+	Summary map[string]interface{}        `json:"summary"` // used to contain table totals etc
+}
+
+// Filter is with the sql to replace the `:name` named parameters within the
+// statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	Month string  `json:"month"`
+	Value float64 `json:"value"`
+	Team  string  `json:"team"`
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Month, &self.Value, &self.Team,
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+//
+// Data is formatted as a table for easier display.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		filter   *Filter
+		months   []string
+		ms       *measure
+		ok       bool
+		in       *Request                      = &Request{}
+		bindMap  map[string]interface{}        = map[string]interface{}{}
+		all      []*Model                      = []*Model{}
+		log      *slog.Logger                  = cntxt.GetLogger(ctx).With("package", "alarmsapiteam", "func", "Responder")
+		stmt     string                        = selectStmt
+		headings map[tabulate.ColType][]string = map[tabulate.ColType][]string{
+			tabulate.KEY:   {"team"},
+			tabulate.EXTRA: {"trend"},
+		}
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	// work out the measure to use
+	if in.Measure == "" {
+		in.Measure = MeasureCount
+	}
+	if ms, ok = measures[in.Measure]; !ok {
+		log.Error("unknown measure requested", "measure", in.Measure)
+		return
+	}
+	stmt = strings.ReplaceAll(stmt, "{MEASURE}", ms.Stmt)
+	// setup months
+	headings[tabulate.DATA] = months
+	headings[tabulate.END] = []string{ms.End}
+	filter = &Filter{Months: months}
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE accounts.team_name = :team AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	// make the db call via the Select helper that handles row scanning.
+	// No return value as local values are updates within ScanF lambda
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	// get the body
+	tableBody := tabulate.TableBody(ctx, all, &tabulate.Args{
+		Headers:   headings,
+		ColumnKey: "month",
+		ValueKey:  "value"})
+	// add row end value
+	tabulate.RowEnd(tableBody, headings, ms.RowF)
+	// swap to slice
+	tbl := tabulate.TableMapToTable(tableBody)
+	// table sort
+	tbl = tabulate.SortAscending[string](tbl, "team")
+	// do table end values
+	summary := tabulate.TableEnd(tbl, headings, ms.TableF)
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Headers: headings,
+		Data:    tbl,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
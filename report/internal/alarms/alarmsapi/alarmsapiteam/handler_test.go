@@ -0,0 +1,59 @@
+package alarmsapiteam
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlarmsAPITeamHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+		end    = times.AsYMString(times.Today())
+		start  = times.AsYMString(times.Add(times.Today(), -3, times.YEAR))
+	)
+	// run seeds
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	for measure, col := range map[string]string{"": "total", MeasureCount: "total", MeasureMeanTime: "average"} {
+		url := "/v1/alarms/between/" + start + "/" + end + "/?measure=" + measure
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		writer := httptest.NewRecorder()
+		mux.ServeHTTP(writer, req)
+
+		rec := &Response{}
+		err = response.As(writer.Result(), &rec)
+		if err != nil {
+			t.Errorf("[%s] error converting ...", measure)
+		}
+		if len(rec.Data) < 1 {
+			t.Errorf("[%s] incorrect number of data rows; might be due to seed data using random date", measure)
+		}
+		if len(rec.Headers["end"]) != 1 || rec.Headers["end"][0] != col {
+			t.Errorf("[%s] incorrect end column: [%v]", measure, rec.Headers["end"])
+		}
+	}
+}
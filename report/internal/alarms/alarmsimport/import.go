@@ -0,0 +1,232 @@
+// Package alarmsimport fetches cloudwatch alarm state change history and stores
+// each transition along with the time spent in the ALARM state.
+//
+// CloudWatch only retains alarm history for 30 days, so this is expected to be
+// run frequently (daily) to build up a longer history.
+package alarmsimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/ptr"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const InsertStatement string = `
+INSERT INTO alarms (
+	account_id,
+	alarm_name,
+	month,
+	transitioned_at,
+	state_from,
+	state_to,
+	seconds_in_alarm
+) VALUES (
+	:account_id,
+	:alarm_name,
+	:month,
+	:transitioned_at,
+	:state_from,
+	:state_to,
+	:seconds_in_alarm
+) ON CONFLICT (account_id,alarm_name,transitioned_at)
+ 	DO UPDATE SET
+		state_from=excluded.state_from,
+		state_to=excluded.state_to,
+		seconds_in_alarm=excluded.seconds_in_alarm
+RETURNING id
+;
+`
+
+// StateAlarm is the cloudwatch state value used to track time in alarm
+const StateAlarm string = string(types.StateValueAlarm)
+
+var (
+	ErrFailedGettingHistory = errors.New("failed to get alarm history with error.")
+	ErrFailedParsingHistory = errors.New("failed to parse alarm history data with error.")
+)
+
+// Model represents a simple, joinless, db row in the alarms table; used by imports and seeding commands
+type Model struct {
+	AccountID      string `json:"account_id,omitempty"`
+	AlarmName      string `json:"alarm_name,omitempty"`
+	Month          string `json:"month,omitempty"`
+	TransitionedAt string `json:"transitioned_at,omitempty"`
+	StateFrom      string `json:"state_from,omitempty"`
+	StateTo        string `json:"state_to,omitempty"`
+	SecondsInAlarm int64  `json:"seconds_in_alarm"`
+}
+
+// historyData is the structure of the `HistoryData` json string for
+// state update history items
+type historyData struct {
+	OldState *historyState `json:"oldState"`
+	NewState *historyState `json:"newState"`
+}
+
+type historyState struct {
+	StateValue string `json:"stateValue"`
+}
+
+// Client is used to allow mocking and is a proxy for *cloudwatch.Client
+// and the methods the function calls
+type Client interface {
+	DescribeAlarmHistory(ctx context.Context, params *cloudwatch.DescribeAlarmHistoryInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmHistoryOutput, error)
+}
+
+type Args struct {
+	DB     string `json:"db"`     // database path
+	Driver string `json:"driver"` // database driver
+	Params string `json:"params"` // database connection params
+
+	DateStart time.Time `json:"date_start"` // start date, this will be reset to start of the month
+	DateEnd   time.Time `json:"date_end"`   // end date; alarms still in ALARM state are measured up to this point (or now if sooner)
+	AccountID string    `json:"account_id"` // AccountID provided by awsid.AccountID
+}
+
+// Import fetches all alarm state changes between the dates, works out how long
+// each alarm spent in ALARM and writes each transition to the database.
+func Import[T Client](ctx context.Context, client T, in *Args) (err error) {
+	var (
+		items []types.AlarmHistoryItem
+		data  []*Model
+		log   *slog.Logger = cntxt.GetLogger(ctx).With("package", "alarmsimport", "func", "Import")
+	)
+	log.With("options", in).Info("starting ...")
+	in.DateStart = times.ResetMonth(in.DateStart)
+
+	log.Debug("getting alarm history ...")
+	items, err = getHistory(ctx, client, in)
+	if err != nil {
+		return
+	}
+	log.Debug("converting to models ...")
+	data, err = toModels(ctx, items, in)
+	if err != nil {
+		return
+	}
+	// now write to db
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+	log.With("count", len(data)).Info("complete.")
+	return
+}
+
+// getHistory returns all state update history items between the dates, handling pagination
+func getHistory[T Client](ctx context.Context, client T, in *Args) (items []types.AlarmHistoryItem, err error) {
+	var (
+		paginator *cloudwatch.DescribeAlarmHistoryPaginator
+		log       *slog.Logger = cntxt.GetLogger(ctx).With("package", "alarmsimport", "func", "getHistory")
+	)
+	items = []types.AlarmHistoryItem{}
+	paginator = cloudwatch.NewDescribeAlarmHistoryPaginator(client, &cloudwatch.DescribeAlarmHistoryInput{
+		HistoryItemType: types.HistoryItemTypeStateUpdate,
+		StartDate:       ptr.Ptr(in.DateStart),
+		EndDate:         ptr.Ptr(in.DateEnd),
+		ScanBy:          types.ScanByTimestampAscending,
+		AlarmTypes:      []types.AlarmType{types.AlarmTypeMetricAlarm, types.AlarmTypeCompositeAlarm},
+	})
+	for paginator.HasMorePages() {
+		var page *cloudwatch.DescribeAlarmHistoryOutput
+		// a failed page does not move the paginator on, so it can be retried
+		page, err = retry.Get(ctx, func() (*cloudwatch.DescribeAlarmHistoryOutput, error) {
+			return paginator.NextPage(ctx)
+		})
+		if err != nil {
+			log.Error("error getting alarm history", "err", err.Error())
+			err = errors.Join(ErrFailedGettingHistory, err)
+			return
+		}
+		items = append(items, page.AlarmHistoryItems...)
+	}
+	log.With("count", len(items)).Debug("complete.")
+	return
+}
+
+// toModels converts the history items into Models, grouped by alarm and ordered
+// by time so the duration of each ALARM period can be calculated.
+//
+// The duration is recorded against the transition into ALARM and lasts until the
+// next transition out of it; alarms that are still in ALARM are measured up to
+// the end date (or now, if that is sooner).
+func toModels(ctx context.Context, items []types.AlarmHistoryItem, in *Args) (models []*Model, err error) {
+	var (
+		names   []string                            = []string{}
+		grouped map[string][]types.AlarmHistoryItem = map[string][]types.AlarmHistoryItem{}
+		end     time.Time                           = in.DateEnd
+		log     *slog.Logger                        = cntxt.GetLogger(ctx).With("package", "alarmsimport", "func", "toModels")
+	)
+	models = []*Model{}
+	if now := time.Now().UTC(); end.IsZero() || end.After(now) {
+		end = now
+	}
+	for _, item := range items {
+		var name = *item.AlarmName
+		if _, ok := grouped[name]; !ok {
+			names = append(names, name)
+		}
+		grouped[name] = append(grouped[name], item)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		var inAlarm *Model
+		var set = grouped[name]
+
+		slices.SortFunc(set, func(a types.AlarmHistoryItem, b types.AlarmHistoryItem) int {
+			return a.Timestamp.Compare(*b.Timestamp)
+		})
+		for _, item := range set {
+			var data = &historyData{}
+			var ts = item.Timestamp.UTC()
+
+			if e := json.Unmarshal([]byte(*item.HistoryData), data); e != nil || data.OldState == nil || data.NewState == nil {
+				log.Error("failed to parse history data", "alarm", name, "data", *item.HistoryData)
+				err = errors.Join(ErrFailedParsingHistory, e)
+				return
+			}
+			// close off the current alarm period
+			if inAlarm != nil && data.NewState.StateValue != StateAlarm {
+				inAlarm.SecondsInAlarm = int64(ts.Sub(times.MustFromString(inAlarm.TransitionedAt)).Seconds())
+				inAlarm = nil
+			}
+			model := &Model{
+				AccountID:      in.AccountID,
+				AlarmName:      name,
+				Month:          times.AsYMString(ts),
+				TransitionedAt: times.AsString(ts, times.FULL),
+				StateFrom:      strings.ToUpper(data.OldState.StateValue),
+				StateTo:        strings.ToUpper(data.NewState.StateValue),
+			}
+			if model.StateTo == StateAlarm && inAlarm == nil {
+				inAlarm = model
+			}
+			models = append(models, model)
+		}
+		// still in alarm at the end of the period
+		if inAlarm != nil {
+			inAlarm.SecondsInAlarm = int64(end.Sub(times.MustFromString(inAlarm.TransitionedAt)).Seconds())
+		}
+	}
+	log.With("count", len(models)).Debug("complete.")
+	return
+}
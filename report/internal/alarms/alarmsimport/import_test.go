@@ -0,0 +1,114 @@
+package alarmsimport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/ptr"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// mockHistory returns the history items split over pages of size 2
+type mockHistory struct {
+	items []types.AlarmHistoryItem
+}
+
+func (self *mockHistory) DescribeAlarmHistory(ctx context.Context, params *cloudwatch.DescribeAlarmHistoryInput, optFns ...func(*cloudwatch.Options)) (out *cloudwatch.DescribeAlarmHistoryOutput, err error) {
+	var start, end = 0, 0
+	if params.NextToken != nil {
+		fmt.Sscanf(*params.NextToken, "%d", &start)
+	}
+	end = min(start+2, len(self.items))
+	out = &cloudwatch.DescribeAlarmHistoryOutput{AlarmHistoryItems: self.items[start:end]}
+	if end < len(self.items) {
+		out.NextToken = ptr.Ptr(fmt.Sprintf("%d", end))
+	}
+	return
+}
+
+func historyItem(name string, ts time.Time, from string, to string) types.AlarmHistoryItem {
+	return types.AlarmHistoryItem{
+		AlarmName:       ptr.Ptr(name),
+		Timestamp:       ptr.Ptr(ts),
+		HistoryItemType: types.HistoryItemTypeStateUpdate,
+		HistoryData:     ptr.Ptr(fmt.Sprintf(`{"version":"1.0","oldState":{"stateValue":"%s"},"newState":{"stateValue":"%s"}}`, from, to)),
+	}
+}
+
+func TestAlarmsImportMock(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-alarms.db")
+		base   = time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+		end    = time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+		found  = map[string]int64{}
+		client = &mockHistory{items: []types.AlarmHistoryItem{
+			// returned out of order to check sorting
+			historyItem("alarm-a", base.Add(10*time.Minute), "ALARM", "OK"),
+			historyItem("alarm-a", base, "OK", "ALARM"),
+			historyItem("alarm-a", base.Add(time.Hour), "OK", "ALARM"),
+			historyItem("alarm-a", base.Add(time.Hour+5*time.Minute), "ALARM", "INSUFFICIENT_DATA"),
+			// never recovers, so measured to end date
+			historyItem("alarm-b", end.Add(-time.Hour), "OK", "ALARM"),
+		}}
+	)
+
+	err = migrations.Migrate(ctx, &migrations.Args{DB: dbpath, Driver: driver})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	err = Import(ctx, client, &Args{
+		DB:        dbpath,
+		Driver:    driver,
+		DateStart: base,
+		DateEnd:   end,
+		AccountID: "A001",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+
+	err = dbx.Select(ctx, `SELECT alarm_name, transitioned_at, seconds_in_alarm FROM alarms WHERE state_to = 'ALARM';`, &dbx.SelectArgs{
+		DB:     dbpath,
+		Driver: driver,
+		ScanF: func(rows *sql.Rows) (e error) {
+			var name, ts string
+			var seconds int64
+			if e = rows.Scan(&name, &ts, &seconds); e == nil {
+				found[name+"@"+ts] = seconds
+			}
+			return
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	expected := map[string]int64{
+		"alarm-a@2026-01-10T09:00:00Z": 600,
+		"alarm-a@2026-01-10T10:00:00Z": 300,
+		"alarm-b@2026-01-30T23:00:00Z": 3600,
+	}
+	if len(found) != len(expected) {
+		t.Errorf("expected [%d] alarm transitions, found [%d]", len(expected), len(found))
+	}
+	for k, v := range expected {
+		if found[k] != v {
+			t.Errorf("[%s] expected [%d] seconds in alarm, found [%d]", k, v, found[k])
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Package branchprotectionimport records the branch protection and ruleset settings
+// of the default branch of each repository and checks them against a policy.
+//
+// Classic branch protection and the rules of every active ruleset that targets the
+// branch are combined; a setting is on when either turns it on and the review count
+// is the higher of the two. Enforcing for admins is only known for classic protection,
+// as the rules api does not include ruleset bypass actors.
+//
+// Archived and empty repositories are skipped.
+package branchprotectionimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/workers"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement adds or updates the branch protection of a codebase
+const InsertStatement string = `
+INSERT INTO codebase_branch_protection (
+	codebase,
+	branch,
+	source,
+	protected,
+	required_reviews,
+	code_owner_reviews,
+	dismiss_stale_reviews,
+	signed_commits,
+	status_checks,
+	enforce_admins,
+	force_push_blocked,
+	deletion_blocked,
+	linear_history,
+	policy_passed,
+	policy_failures
+) VALUES (
+	:codebase,
+	:branch,
+	:source,
+	:protected,
+	:required_reviews,
+	:code_owner_reviews,
+	:dismiss_stale_reviews,
+	:signed_commits,
+	:status_checks,
+	:enforce_admins,
+	:force_push_blocked,
+	:deletion_blocked,
+	:linear_history,
+	:policy_passed,
+	:policy_failures
+) ON CONFLICT (codebase) DO UPDATE SET
+	branch=excluded.branch,
+	source=excluded.source,
+	protected=excluded.protected,
+	required_reviews=excluded.required_reviews,
+	code_owner_reviews=excluded.code_owner_reviews,
+	dismiss_stale_reviews=excluded.dismiss_stale_reviews,
+	signed_commits=excluded.signed_commits,
+	status_checks=excluded.status_checks,
+	enforce_admins=excluded.enforce_admins,
+	force_push_blocked=excluded.force_push_blocked,
+	deletion_blocked=excluded.deletion_blocked,
+	linear_history=excluded.linear_history,
+	policy_passed=excluded.policy_passed,
+	policy_failures=excluded.policy_failures
+RETURNING id
+;
+`
+
+// where the protection of the branch comes from
+const (
+	SourceNone    string = "none"
+	SourceClassic string = "classic"
+	SourceRuleset string = "ruleset"
+	SourceBoth    string = "both"
+)
+
+var (
+	ErrFailedGettingProtection = errors.New("error getting branch protection.")
+	ErrFailedGettingRules      = errors.New("error getting branch rules.")
+)
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// branchClient wrapper around the branch methods of *github.RepositoriesService
+type branchClient interface {
+	// api docs - https://docs.github.com/rest/branches/branch-protection#get-branch-protection
+	GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error)
+	// api docs - https://docs.github.com/rest/repos/rules#get-rules-for-a-branch
+	GetRulesForBranch(ctx context.Context, owner, repo, branch string, opts *github.ListOptions) (*github.BranchRules, *github.Response, error)
+}
+
+type Args struct {
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+
+	Policy *Policy `json:"policy"` // optional; checks to evaluate, uses DefaultPolicy when nil
+}
+
+type Clients struct {
+	Teams    teamClient   // *github.TeamsService
+	Branches branchClient // *github.RepositoriesService
+}
+
+// Model is the combined protection of the default branch of a codebase
+type Model struct {
+	Codebase            string `json:"codebase"`              // full name of codebase
+	Branch              string `json:"branch"`                // default branch name
+	Source              string `json:"source"`                // none, classic, ruleset or both
+	Protected           int    `json:"protected"`             // boolean flag for any protection
+	RequiredReviews     int    `json:"required_reviews"`      // approvals needed to merge
+	CodeOwnerReviews    int    `json:"code_owner_reviews"`    // boolean flag for code owner approval
+	DismissStaleReviews int    `json:"dismiss_stale_reviews"` // boolean flag for dismissing approvals on new commits
+	SignedCommits       int    `json:"signed_commits"`        // boolean flag for verified signatures
+	StatusChecks        int    `json:"status_checks"`         // count of required status checks
+	EnforceAdmins       int    `json:"enforce_admins"`        // boolean flag for admins following classic protection
+	ForcePushBlocked    int    `json:"force_push_blocked"`    // boolean flag for blocking force pushes
+	DeletionBlocked     int    `json:"deletion_blocked"`      // boolean flag for blocking deletion
+	LinearHistory       int    `json:"linear_history"`        // boolean flag for linear history
+	PolicyPassed        int    `json:"policy_passed"`         // boolean flag for passing every policy check
+	PolicyFailures      string `json:"policy_failures"`       // comma separated list of failed checks
+}
+
+// Import finds all github repositories and writes the protection of their default branch
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "branchprotectionimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+
+	log.Info("starting ...")
+	if in.Policy == nil {
+		in.Policy = DefaultPolicy
+	}
+	if err = in.Policy.Validate(); err != nil {
+		return
+	}
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.", "codebases", len(data))
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*Model, err error) {
+	var results []*workers.Result[*Model]
+	var found []*Model
+
+	data = []*Model{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (m *Model, err error) {
+		if m, err = repoProtection(ctx, clients.Branches, repo, in.Policy); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	// skipped repositories have no model
+	for _, m := range found {
+		if m != nil {
+			data = append(data, m)
+		}
+	}
+	return
+}
+
+// repoProtection fetches the classic protection and ruleset rules of the default
+// branch, combines them and evaluates the result against the policy
+func repoProtection(ctx context.Context, client branchClient, repo *github.Repository, policy *Policy) (m *Model, err error) {
+	var (
+		log        *slog.Logger = cntxt.GetLogger(ctx).With("package", "branchprotectionimport", "func", "repoProtection", "repo", repo.GetName())
+		branch     string       = repo.GetDefaultBranch()
+		protection *github.Protection
+		rules      *github.BranchRules
+		failures   []string
+	)
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	if branch == "" {
+		log.Warn("repository has no default branch, skipping.")
+		return
+	}
+	log.Info("getting branch protection ...", "branch", branch)
+	if protection, err = branchProtection(ctx, client, repo, branch); err != nil {
+		return
+	}
+	if rules, err = branchRules(ctx, client, repo, branch); err != nil {
+		return
+	}
+	m = ToModel(repo.GetFullName(), branch, protection, rules)
+	failures = policy.Evaluate(m)
+	if len(failures) == 0 {
+		m.PolicyPassed = 1
+	} else {
+		m.PolicyFailures = strings.Join(failures, ",") + ","
+	}
+	return
+}
+
+// branchProtection returns the classic protection of the branch, or nil when
+// the branch is not protected
+func branchProtection(ctx context.Context, client branchClient, repo *github.Repository, branch string) (protection *github.Protection, err error) {
+	err = retry.Do(ctx, func() (e error) {
+		protection, _, e = client.GetBranchProtection(ctx, repo.GetOwner().GetLogin(), repo.GetName(), branch)
+		return
+	})
+	// not protected is a 404, which is not a failure; any other 404 (such as no
+	// access to the repository) is
+	if err != nil && errors.Is(err, github.ErrBranchNotProtected) {
+		return nil, nil
+	} else if err != nil {
+		err = errors.Join(ErrFailedGettingProtection, err)
+	}
+	return
+}
+
+// branchRules fetches all pages of rules from active rulesets that target the branch
+func branchRules(ctx context.Context, client branchClient, repo *github.Repository, branch string) (rules *github.BranchRules, err error) {
+	var opts = &github.ListOptions{PerPage: 100, Page: 1}
+
+	rules = &github.BranchRules{}
+	for opts.Page > 0 {
+		var (
+			page     *github.BranchRules
+			response *github.Response
+		)
+		err = retry.Do(ctx, func() (e error) {
+			page, response, e = client.GetRulesForBranch(ctx, repo.GetOwner().GetLogin(), repo.GetName(), branch, opts)
+			return
+		})
+		if err != nil {
+			err = errors.Join(ErrFailedGettingRules, err)
+			return
+		}
+		if page != nil {
+			rules.Deletion = append(rules.Deletion, page.Deletion...)
+			rules.NonFastForward = append(rules.NonFastForward, page.NonFastForward...)
+			rules.RequiredLinearHistory = append(rules.RequiredLinearHistory, page.RequiredLinearHistory...)
+			rules.RequiredSignatures = append(rules.RequiredSignatures, page.RequiredSignatures...)
+			rules.PullRequest = append(rules.PullRequest, page.PullRequest...)
+			rules.RequiredStatusChecks = append(rules.RequiredStatusChecks, page.RequiredStatusChecks...)
+			rules.Update = append(rules.Update, page.Update...)
+			rules.Creation = append(rules.Creation, page.Creation...)
+		}
+		opts.Page = 0
+		if response != nil {
+			opts.Page = response.NextPage
+		}
+	}
+	return
+}
+
+// ToModel combines the classic protection (can be nil) and ruleset rules of the branch;
+// the policy is not evaluated
+func ToModel(codebase string, branch string, protection *github.Protection, rules *github.BranchRules) (m *Model) {
+	var classic = protection != nil
+	var ruleset = rules != nil && (len(rules.Deletion)+len(rules.NonFastForward)+len(rules.RequiredLinearHistory)+
+		len(rules.RequiredSignatures)+len(rules.PullRequest)+len(rules.RequiredStatusChecks)+
+		len(rules.Update)+len(rules.Creation)) > 0
+
+	m = &Model{Codebase: codebase, Branch: branch, Source: SourceNone}
+	switch {
+	case classic && ruleset:
+		m.Source = SourceBoth
+	case classic:
+		m.Source = SourceClassic
+	case ruleset:
+		m.Source = SourceRuleset
+	}
+	m.Protected = flag(classic || ruleset)
+
+	if classic {
+		if reviews := protection.GetRequiredPullRequestReviews(); reviews != nil {
+			m.RequiredReviews = max(m.RequiredReviews, reviews.RequiredApprovingReviewCount)
+			m.CodeOwnerReviews = max(m.CodeOwnerReviews, flag(reviews.RequireCodeOwnerReviews))
+			m.DismissStaleReviews = max(m.DismissStaleReviews, flag(reviews.DismissStaleReviews))
+		}
+		if checks := protection.GetRequiredStatusChecks(); checks != nil {
+			m.StatusChecks += max(len(checks.GetChecks()), len(checks.GetContexts()))
+		}
+		m.SignedCommits = max(m.SignedCommits, flag(protection.GetRequiredSignatures().GetEnabled()))
+		// these settings are not pointers, so there are no nil safe getters
+		m.EnforceAdmins = flag(protection.EnforceAdmins != nil && protection.EnforceAdmins.Enabled)
+		m.ForcePushBlocked = flag(protection.AllowForcePushes == nil || !protection.AllowForcePushes.Enabled)
+		m.DeletionBlocked = flag(protection.AllowDeletions == nil || !protection.AllowDeletions.Enabled)
+		m.LinearHistory = flag(protection.RequireLinearHistory != nil && protection.RequireLinearHistory.Enabled)
+	}
+	if ruleset {
+		for _, pr := range rules.PullRequest {
+			m.RequiredReviews = max(m.RequiredReviews, pr.Parameters.RequiredApprovingReviewCount)
+			m.CodeOwnerReviews = max(m.CodeOwnerReviews, flag(pr.Parameters.RequireCodeOwnerReview))
+			m.DismissStaleReviews = max(m.DismissStaleReviews, flag(pr.Parameters.DismissStaleReviewsOnPush))
+		}
+		for _, sc := range rules.RequiredStatusChecks {
+			m.StatusChecks += len(sc.Parameters.RequiredStatusChecks)
+		}
+		m.SignedCommits = max(m.SignedCommits, flag(len(rules.RequiredSignatures) > 0))
+		m.ForcePushBlocked = max(m.ForcePushBlocked, flag(len(rules.NonFastForward) > 0))
+		m.DeletionBlocked = max(m.DeletionBlocked, flag(len(rules.Deletion) > 0))
+		m.LinearHistory = max(m.LinearHistory, flag(len(rules.RequiredLinearHistory) > 0))
+	}
+	return
+}
+
+// flag converts the bool into the 0 / 1 used in the database
+func flag(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
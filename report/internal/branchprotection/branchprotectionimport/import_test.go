@@ -0,0 +1,123 @@
+package branchprotectionimport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockBranches has classic protection on "classic", a ruleset on "ruleset" (over two
+// pages) and nothing on any other repo
+type mockBranches struct {
+	calls int
+}
+
+func (self *mockBranches) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error) {
+	self.calls++
+	if repo == "missing" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+	}
+	if repo != "classic" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return nil, resp, github.ErrBranchNotProtected
+	}
+	return &github.Protection{
+		RequiredPullRequestReviews: &github.PullRequestReviewsEnforcement{RequiredApprovingReviewCount: 1, RequireCodeOwnerReviews: true},
+		RequiredStatusChecks:       &github.RequiredStatusChecks{Contexts: &[]string{"test", "lint"}},
+		EnforceAdmins:              &github.AdminEnforcement{Enabled: true},
+	}, &github.Response{}, nil
+}
+
+func (self *mockBranches) GetRulesForBranch(ctx context.Context, owner, repo, branch string, opts *github.ListOptions) (*github.BranchRules, *github.Response, error) {
+	self.calls++
+	if repo != "ruleset" {
+		return &github.BranchRules{}, &github.Response{}, nil
+	}
+	if opts.Page == 1 {
+		return &github.BranchRules{
+			PullRequest:    []*github.PullRequestBranchRule{{Parameters: github.PullRequestRuleParameters{RequiredApprovingReviewCount: 2}}},
+			NonFastForward: []*github.BranchRuleMetadata{{RulesetID: 1}},
+		}, &github.Response{NextPage: 2}, nil
+	}
+	return &github.BranchRules{
+		RequiredSignatures:   []*github.BranchRuleMetadata{{RulesetID: 1}},
+		RequiredStatusChecks: []*github.RequiredStatusChecksBranchRule{{Parameters: github.RequiredStatusChecksRuleParameters{RequiredStatusChecks: []*github.RuleStatusCheck{{Context: "test"}}}}},
+	}, &github.Response{}, nil
+}
+
+func TestBranchProtectionImportHandler(t *testing.T) {
+	var (
+		ctx      = cntxt.AddLogger(t.Context(), logger.New("error"))
+		owner    = &github.User{Login: github.Ptr("org")}
+		client   = &mockBranches{}
+		repoList = []*github.Repository{
+			{Name: github.Ptr("classic"), FullName: github.Ptr("org/classic"), Owner: owner, DefaultBranch: github.Ptr("main")},
+			{Name: github.Ptr("ruleset"), FullName: github.Ptr("org/ruleset"), Owner: owner, DefaultBranch: github.Ptr("main")},
+			{Name: github.Ptr("open"), FullName: github.Ptr("org/open"), Owner: owner, DefaultBranch: github.Ptr("main")},
+			{Name: github.Ptr("empty"), FullName: github.Ptr("org/empty"), Owner: owner},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, DefaultBranch: github.Ptr("main"), Archived: github.Ptr(true)},
+			{Name: github.Ptr("missing"), FullName: github.Ptr("org/missing"), Owner: owner, DefaultBranch: github.Ptr("main")},
+		}
+		byName = map[string]*Model{}
+	)
+	data, err := handler(ctx, &Clients{Branches: client}, &Args{Policy: DefaultPolicy}, repoList)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// empty and archived are skipped, missing fails as only a not protected 404 is
+	// treated as unprotected
+	if _, err = repoProtection(ctx, client, repoList[5], DefaultPolicy); !errors.Is(err, ErrFailedGettingProtection) {
+		t.Errorf("expected other 404s to fail, got [%v]", err)
+	}
+	if len(data) != 3 {
+		t.Fatalf("expected 3 codebases, found [%d]", len(data))
+	}
+	for _, m := range data {
+		byName[m.Codebase] = m
+	}
+
+	classic := byName["org/classic"]
+	if classic.Source != SourceClassic || classic.RequiredReviews != 1 || classic.StatusChecks != 2 || classic.EnforceAdmins != 1 || classic.ForcePushBlocked != 1 {
+		t.Errorf("unexpected classic settings: %+v", classic)
+	}
+	if classic.PolicyPassed != 0 || classic.PolicyFailures != "signed_commits," {
+		t.Errorf("expected classic to fail signed commits only: %+v", classic)
+	}
+
+	ruleset := byName["org/ruleset"]
+	if ruleset.Source != SourceRuleset || ruleset.RequiredReviews != 2 || ruleset.SignedCommits != 1 || ruleset.StatusChecks != 1 || ruleset.ForcePushBlocked != 1 || ruleset.DeletionBlocked != 0 {
+		t.Errorf("unexpected ruleset settings: %+v", ruleset)
+	}
+	if ruleset.PolicyPassed != 1 || ruleset.PolicyFailures != "" {
+		t.Errorf("expected ruleset to pass the policy: %+v", ruleset)
+	}
+
+	open := byName["org/open"]
+	if open.Source != SourceNone || open.Protected != 0 || open.PolicyPassed != 0 {
+		t.Errorf("expected unprotected branch to fail: %+v", open)
+	}
+}
+
+func TestBranchProtectionPolicy(t *testing.T) {
+	var m = &Model{Protected: 1, RequiredReviews: 1, StatusChecks: 1}
+
+	if err := (&Policy{Checks: []string{CheckProtected, "unknown"}}).Validate(); err == nil {
+		t.Errorf("expected unknown check to be invalid")
+	}
+	if err := (&Policy{}).Validate(); err == nil {
+		t.Errorf("expected empty policy to be invalid")
+	}
+	if err := DefaultPolicy.Validate(); err != nil {
+		t.Errorf("unexpected error with default policy: [%s]", err.Error())
+	}
+	failures := (&Policy{Checks: []string{CheckReviews, CheckStatusChecks, CheckNoDeletion}, MinReviews: 2}).Evaluate(m)
+	if len(failures) != 2 || failures[0] != CheckReviews || failures[1] != CheckNoDeletion {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+}
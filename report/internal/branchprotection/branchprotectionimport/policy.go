@@ -0,0 +1,94 @@
+package branchprotectionimport
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checks that can be required by a policy
+const (
+	CheckProtected        string = "protected"             // default branch has classic protection or ruleset rules
+	CheckReviews          string = "reviews"               // pull requests need at least the minimum number of approvals
+	CheckCodeOwnerReviews string = "code_owner_reviews"    // code owners have to approve changes to their files
+	CheckDismissStale     string = "dismiss_stale_reviews" // approvals are dismissed by new commits
+	CheckSignedCommits    string = "signed_commits"        // commits must have verified signatures
+	CheckStatusChecks     string = "status_checks"         // at least one status check must pass
+	CheckEnforceAdmins    string = "enforce_admins"        // admins cannot bypass classic protection
+	CheckNoForcePush      string = "no_force_push"         // force pushes are blocked
+	CheckNoDeletion       string = "no_deletion"           // the branch cannot be deleted
+	CheckLinearHistory    string = "linear_history"        // merge commits are blocked
+)
+
+// Checks is every check a policy can use
+var Checks = []string{
+	CheckProtected,
+	CheckReviews,
+	CheckCodeOwnerReviews,
+	CheckDismissStale,
+	CheckSignedCommits,
+	CheckStatusChecks,
+	CheckEnforceAdmins,
+	CheckNoForcePush,
+	CheckNoDeletion,
+	CheckLinearHistory,
+}
+
+// DefaultPolicy matches the internal standards; a protected default branch with at
+// least one approving review, signed commits and status checks
+var DefaultPolicy = &Policy{
+	Checks:     []string{CheckProtected, CheckReviews, CheckSignedCommits, CheckStatusChecks},
+	MinReviews: 1,
+}
+
+var ErrInvalidPolicy = errors.New("branch protection policy is invalid.")
+
+// Policy is the set of checks the default branch of each codebase has to pass
+type Policy struct {
+	Checks     []string `json:"checks"`      // checks to run, see Checks
+	MinReviews int      `json:"min_reviews"` // approvals needed to pass the reviews check
+}
+
+// Validate makes sure every check is known and at least one is set
+func (self *Policy) Validate() (err error) {
+	var errs = []error{}
+	if len(self.Checks) == 0 {
+		errs = append(errs, fmt.Errorf("no checks set"))
+	}
+	for _, check := range self.Checks {
+		if !slices.Contains(Checks, check) {
+			errs = append(errs, fmt.Errorf("unknown check [%s], expected one of [%s]", check, strings.Join(Checks, ", ")))
+		}
+	}
+	if self.MinReviews < 0 {
+		errs = append(errs, fmt.Errorf("min reviews must be 0 or more, got [%d]", self.MinReviews))
+	}
+	if len(errs) > 0 {
+		err = errors.Join(ErrInvalidPolicy, errors.Join(errs...))
+	}
+	return
+}
+
+// Evaluate returns the checks of the policy the settings fail, in policy order
+func (self *Policy) Evaluate(m *Model) (failures []string) {
+	var passed = map[string]bool{
+		CheckProtected:        m.Protected == 1,
+		CheckReviews:          m.RequiredReviews >= self.MinReviews && m.RequiredReviews > 0,
+		CheckCodeOwnerReviews: m.CodeOwnerReviews == 1,
+		CheckDismissStale:     m.DismissStaleReviews == 1,
+		CheckSignedCommits:    m.SignedCommits == 1,
+		CheckStatusChecks:     m.StatusChecks > 0,
+		CheckEnforceAdmins:    m.EnforceAdmins == 1,
+		CheckNoForcePush:      m.ForcePushBlocked == 1,
+		CheckNoDeletion:       m.DeletionBlocked == 1,
+		CheckLinearHistory:    m.LinearHistory == 1,
+	}
+	failures = []string{}
+	for _, check := range self.Checks {
+		if !passed[check] {
+			failures = append(failures, check)
+		}
+	}
+	return
+}
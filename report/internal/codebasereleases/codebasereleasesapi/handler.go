@@ -12,6 +12,7 @@ import (
 	"opg-reports/report/package/requested"
 	"opg-reports/report/package/respond"
 	"opg-reports/report/package/times"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -41,6 +42,7 @@ ORDER BY
 type Request struct {
 	DateStart string `json:"date_start"`
 	DateEnd   string `json:"date_end"`
+	Org       string `json:"org"` // optional github org filter (?org=)
 }
 
 func (self *Request) Start() (t time.Time) {
@@ -65,6 +67,7 @@ type Response struct {
 // within the statement.
 type Filter struct {
 	Months []string `json:"months"`
+	Org    string   `json:"org"`
 }
 
 // Model is the data struct to use when fetching the select
@@ -107,6 +110,12 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 		return
 	}
 	filter.Months = months
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
 	// now convert to a map for use in bound statements
 	err = cnv.Convert(filter, &bindMap)
 	if err != nil {
@@ -12,12 +12,14 @@ package codebasereleasesimport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/dbx"
 	"opg-reports/report/package/repos"
 	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
 	"strings"
 	"time"
 
@@ -49,6 +51,7 @@ RETURNING id
 // teamClient wrapper around *github.TeamsService
 type teamClient interface {
 	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
 }
 
 // ActionClient wrapper for *github.ActionsService
@@ -68,9 +71,14 @@ type Args struct {
 	Params       string    `json:"params"`         // database connection params
 	OrgSlug      string    `json:"org_slug"`       // github org name
 	ParentSlug   string    `json:"parent_slug"`    // parent slug
+	Sources      []string  `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool      `json:"recursive"`      // include repositories of all child teams
 	FilterByName string    `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
 	DateStart    time.Time `json:"date_start"`     // start date
 	DateEnd      time.Time `json:"date_end"`       // end date
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
 }
 
 type Clients struct {
@@ -103,6 +111,8 @@ func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
 	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
 		OrgSlug:      in.OrgSlug,
 		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
 		FilterByName: in.FilterByName,
 	})
 	if err != nil {
@@ -130,48 +140,60 @@ func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
 	return
 }
 
-// handler looks at both workflows & pull requests to get release data
+// handler looks at both workflows & pull requests to get release data.
+//
+// Repositories are processed concurrently; failed repositories are logged and
+// skipped unless all of them fail.
 func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*CodebaseMetric, err error) {
-	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasereleasesimport", "func", "Import")
-	var byMonth = map[string][]*CodebaseMetric{}
-	var l = len(repoList)
+	var results []*workers.Result[[]*CodebaseMetric]
+	var found [][]*CodebaseMetric
 
 	data = []*CodebaseMetric{}
-	// now loop over each repo to then call helper methods
-	for i, repo := range repoList {
-		var lg = log.With("repo", *repo.Name)
-		var found = []*CodebaseMetric{}
-		log.Info(fmt.Sprintf("[%d/%d - %s]", i+1, l, *repo.Name))
-		// dont get any release info on archived code bases
-		if *repo.Archived {
-			log.Warn("repository is archived, skipping fetching compliance details.")
-			continue
-		}
-		found, err = workflowRunReleases(ctx, clients.Actions, in, repo)
-		// return on error?
-		if err != nil {
-			return
+	// now process each repo, calling helper methods
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (metrics []*CodebaseMetric, err error) {
+		if metrics, err = repoReleases(ctx, clients, in, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
 		}
-		// if found runs, then set the data and continue the loop
-		if len(found) > 0 {
-			lg.Info("found workflow runs ... ", "count", len(found))
-		} else {
-			lg.Info("no workflow runs found, looking for pull requests ... ")
-			// get pull request data if theres no run data (runs will have triggered the break)
-			found, err = mergedPullRequestReleases(ctx, clients.PR, in, repo)
-			if err != nil {
-				return
-			}
-			lg.Info("found pull requests ... ", "count", len(found))
-		}
-		// append the found data
-		data = append(data, found...)
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, metrics := range found {
+		data = append(data, metrics...)
 	}
+	return
+}
 
-	// flattern byMonth to a slice for insert
-	for _, v := range byMonth {
-		data = append(data, v...)
+// repoReleases returns the release data for a single repository, using
+// workflow runs when there are any and merged pull requests when not
+func repoReleases(ctx context.Context, clients *Clients, in *Args, repo *github.Repository) (found []*CodebaseMetric, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasereleasesimport", "func", "repoReleases", "repo", *repo.Name)
+
+	found = []*CodebaseMetric{}
+	log.Info("getting releases ...")
+	// dont get any release info on archived code bases
+	if *repo.Archived {
+		log.Warn("repository is archived, skipping fetching compliance details.")
+		return
+	}
+	found, err = workflowRunReleases(ctx, clients.Actions, in, repo)
+	if err != nil {
+		return
+	}
+	// if found runs, then use those
+	if len(found) > 0 {
+		log.Info("found workflow runs ... ", "count", len(found))
+		return
 	}
+	log.Info("no workflow runs found, looking for pull requests ... ")
+	// get pull request data if theres no run data
+	found, err = mergedPullRequestReleases(ctx, clients.PR, in, repo)
+	if err != nil {
+		return
+	}
+	log.Info("found pull requests ... ", "count", len(found))
 	return
 }
 
@@ -249,39 +271,15 @@ func workflowRunReleases(ctx context.Context, client actionClient, in *Args, rep
 		// get stats
 		byMonth[when].Releases += 1
 		byMonth[when].ReleasesSecurityish += isSecurityishRun(run)
-		// updated[when].Dur += runDuration(ctx, client, repo, run)
 	}
 	// flattern the months
 	for _, v := range byMonth {
 		metrics = append(metrics, v)
 	}
 
-	// // work out averages
-	// for _, v := range updated {
-	// 	var avg = v.Dur / int64(v.Releases)
-	// 	v.ReleasesAverageTime = fmt.Sprintf("%d", avg)
-	// }
-
-	// dump out workflow data
-	// dump.Now(updated)
-
 	return
 }
 
-// // runDuration returns the total run time of the job in milliseconds (as a time.Duration)
-// func runDuration(ctx context.Context, client actionClient, repo *github.Repository, run *github.WorkflowRun) (duration int64) {
-// 	var err error
-// 	var usage *github.WorkflowRunUsage
-// 	usage, _, err = client.GetWorkflowRunUsageByID(ctx, *repo.Owner.Login, *repo.Name, *run.ID)
-
-// 	if err != nil {
-// 		return
-// 	}
-// 	duration = *usage.RunDurationMS
-// 	// dur = time.Millisecond * time.Duration(ms)
-// 	return
-// }
-
 // isSecurityishRun returns a 0 or 1 to say if its likely to be security related
 // workflow.
 //
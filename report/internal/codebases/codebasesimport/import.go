@@ -16,17 +16,23 @@ INSERT INTO codebases (
 	name,
 	full_name,
 	url,
-	archived
+	archived,
+	org,
+	team
 ) VALUES (
 	:name,
 	:full_name,
 	:url,
-	:archived
+	:archived,
+	:org,
+	:team
 )
 ON CONFLICT (full_name) DO UPDATE SET
 	name=excluded.name,
 	url=excluded.url,
-	archived=excluded.archived
+	archived=excluded.archived,
+	org=excluded.org,
+	team=excluded.team
 RETURNING id
 ;
 `
@@ -36,6 +42,7 @@ const truncateStmt string = `DELETE FROM codebases;`
 // TeamClient wrapper around *github.TeamsService
 type teamClient interface {
 	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
 }
 
 type Args struct {
@@ -43,9 +50,11 @@ type Args struct {
 	Driver string `json:"driver"` // database driver
 	Params string `json:"params"` // database connection params
 
-	OrgSlug      string `json:"org_slug"`       // github org name
-	ParentSlug   string `json:"parent_slug"`    // parent slug
-	FilterByName string `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
 }
 
 // Codebase represents a simple, joinless, db row in the cost table; used by imports and seeding commands
@@ -54,19 +63,23 @@ type Codebase struct {
 	FullName string `json:"full_name,omitempty" ` // full name including the owner
 	Url      string `json:"url,omitempty" `       // url to access the codebase
 	Archived int    `json:"archived"`             // int version of the archived flag on the repo
+	Org      string `json:"org"`                  // github org the codebase was found in
+	Team     string `json:"team"`                 // github team the codebase was found in
 }
 
 // Import finds all github repositories and returns them for the moj/opg team
 func Import(ctx context.Context, client teamClient, in *Args) (err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "Import")
-	var list []*github.Repository
+	var list []*repos.Sourced
 
 	log.Info("starting ...")
 	// fetch all the repos
 	log.Debug("getting repository list ...")
-	list, err = repos.GetList(ctx, client, &repos.Args{
+	list, err = repos.GetSourcedList(ctx, client, &repos.Args{
 		OrgSlug:      in.OrgSlug,
 		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
 		FilterByName: in.FilterByName,
 	})
 	if err != nil {
@@ -82,7 +95,7 @@ func Import(ctx context.Context, client teamClient, in *Args) (err error) {
 	return
 }
 
-func handleCodebases(ctx context.Context, repositories []*github.Repository, in *Args) (err error) {
+func handleCodebases(ctx context.Context, repositories []*repos.Sourced, in *Args) (err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "handleCodebases")
 	var data []*Codebase = []*Codebase{}
 	log.Info("starting codebase import ...")
@@ -117,13 +130,14 @@ func handleCodebases(ctx context.Context, repositories []*github.Repository, in
 }
 
 // generateCodebases converts the api results into local structs
-func generateCodebases(ctx context.Context, list []*github.Repository) (repos []*Codebase, err error) {
+func generateCodebases(ctx context.Context, list []*repos.Sourced) (codebases []*Codebase, err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "toCodebases")
 
-	repos = []*Codebase{}
+	codebases = []*Codebase{}
 	log.Debug("starting ...")
 
-	for _, item := range list {
+	for _, sourced := range list {
+		var item = sourced.Repository
 		var archived = 0
 		if *item.Archived {
 			archived = 1
@@ -133,9 +147,11 @@ func generateCodebases(ctx context.Context, list []*github.Repository) (repos []
 			FullName: *item.FullName,
 			Url:      *item.HTMLURL,
 			Archived: archived,
+			Org:      sourced.Org,
+			Team:     sourced.Team,
 		}
-		repos = append(repos, repo)
-		log.Debug("adding codebase", "full_name", repo.FullName, "archived", repo.Archived)
+		codebases = append(codebases, repo)
+		log.Debug("adding codebase", "full_name", repo.FullName, "archived", repo.Archived, "org", repo.Org, "team", repo.Team)
 	}
 	log.Debug("complete.")
 	return
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"opg-reports/report/internal/codebasestats/codebasestatsapi"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -79,7 +80,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 	var (
 		team          = request.PathValue("team")
 		statsEndpoint = codebasestatsapi.ENDPOINT_BASE
-		params        = []*rest.Param{}
+		params        = []*rest.Param{
+			// optional filters passed through from the front end request
+			{Type: rest.QUERY, Key: "org"},
+			{Type: rest.QUERY, Key: "tool"},
+		}
 	)
 
 	// add team filter values and url
@@ -96,6 +101,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get list of all codebases
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*codebasestatsapi.Response](ctx, args.ApiHost, statsEndpoint, request, params...)
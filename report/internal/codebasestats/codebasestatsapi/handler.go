@@ -22,16 +22,49 @@ SELECT
 	codebases.name,
 	codebases.full_name,
 	codebases.url,
+	codebases.org,
+	codebases.team,
 	codebase_stats.visibility,
 	codebase_stats.compliance_level,
 	codebase_stats.compliance_report_url,
 	codebase_stats.compliance_badge,
 	codebase_stats.compliance_grade,
-	codebase_stats.trivy_usage,
-	codebase_stats.trivy_sbom_usage,
-	codebase_stats.trivy_locations
+	COALESCE(security_tools.tools,'') as security_tools,
+	COALESCE(security_tools.locations,'') as security_tool_locations,
+	COALESCE(security_tools.sbom,0) as sbom_usage,
+	COALESCE(codebase_stats.workflow_errors,'') as workflow_errors,
+	COALESCE(codebase_branch_protection.branch,'') as branch,
+	COALESCE(codebase_branch_protection.protected,0) as branch_protected,
+	COALESCE(codebase_branch_protection.required_reviews,0) as required_reviews,
+	COALESCE(codebase_branch_protection.signed_commits,0) as signed_commits,
+	COALESCE(codebase_branch_protection.status_checks,0) as status_checks,
+	COALESCE(codebase_branch_protection.policy_passed,0) as branch_policy_passed,
+	COALESCE(codebase_branch_protection.policy_failures,'') as branch_policy_failures
 FROM codebases
 LEFT JOIN codebase_stats on codebase_stats.codebase = codebases.full_name
+LEFT JOIN (
+	SELECT
+		tools_by_codebase.codebase,
+		group_concat(tools_by_codebase.tool || ',', '') as tools,
+		group_concat(tools_by_codebase.locations, '') as locations,
+		MAX(tools_by_codebase.sbom) as sbom
+	FROM (
+		SELECT
+			codebase_security_tools.codebase,
+			codebase_security_tools.tool,
+			group_concat(codebase_security_tools.tool || ': ' || codebase_security_tools.location || ',', '') as locations,
+			MAX(codebase_security_tools.sbom) as sbom
+		FROM codebase_security_tools
+		GROUP BY
+			codebase_security_tools.codebase,
+			codebase_security_tools.tool
+		ORDER BY
+			codebase_security_tools.tool ASC
+	) as tools_by_codebase
+	GROUP BY
+		tools_by_codebase.codebase
+) as security_tools on security_tools.codebase = codebases.full_name
+LEFT JOIN codebase_branch_protection on codebase_branch_protection.codebase = codebases.full_name
 LEFT JOIN codebase_owners ON codebase_owners.codebase = codebases.full_name
 WHERE
 	codebases.archived = 0
@@ -43,10 +76,15 @@ ORDER BY
 ;
 `
 
+// toolFilter limits the codebases to those using the security tool
+const toolFilter string = `WHERE codebases.full_name IN (SELECT codebase_security_tools.codebase FROM codebase_security_tools WHERE codebase_security_tools.tool = :tool) AND`
+
 // Request contains the url path / query string values that we will use
 // in this handler
 type Request struct {
 	Team string `json:"team"` // option team filter for this handler
+	Org  string `json:"org"`  // optional github org filter (?org=)
+	Tool string `json:"tool"` // optional security tool filter (?tool=codeql)
 }
 
 // Response is the end result thats sent back from the handler via the writter
@@ -61,13 +99,17 @@ type Response struct {
 // within the statement.
 type Filter struct {
 	Team string `json:"team"`
+	Org  string `json:"org"`
+	Tool string `json:"tool"`
 }
 
 // Model is the data struct to use when fetching the select
 type Model struct {
-	Name     string `json:"name,omitempty"`       // short name of codebase (without owner)
-	FullName string `json:"full_name,omitempty" ` // full name including the owner
-	Url      string `json:"url,omitempty" `       // url to access the codebase
+	Name       string `json:"name,omitempty"`       // short name of codebase (without owner)
+	FullName   string `json:"full_name,omitempty" ` // full name including the owner
+	Url        string `json:"url,omitempty" `       // url to access the codebase
+	Org        string `json:"org"`                  // github org the codebase was found in
+	GitHubTeam string `json:"github_team"`          // github team the codebase was found in
 
 	Visibility          string `json:"visibility,omityempty"`           // visibility status
 	ComplianceLevel     string `json:"compliance_level,omitempty"`      // compliance level (moj based)
@@ -75,10 +117,18 @@ type Model struct {
 	ComplianceBadge     string `json:"compliance_badge,omitempty"`      // compliance badge url
 	ComplianceGrade     int    `json:"compliance_grade,omitempty"`
 
-	TrivyUsage     int    `json:"trivy_usage"`      // boolean flag to show if the codebase is using trivy in workflows
-	TrivySBOMUsage int    `json:"trivy_sbom_usage"` // boolean flag to show if trivy is being used to generate sboms
-	TrivyLocations string `json:"trivy_locations"`  // tracks files where trivy has been utilised
+	SecurityTools         string `json:"security_tools"`          // comma separated list of the security tools used in workflows
+	SecurityToolLocations string `json:"security_tool_locations"` // comma separated list of `tool: file` where each tool was found
+	SBOMUsage             int    `json:"sbom_usage"`              // boolean flag to show if any tool is being used to generate sboms
+	WorkflowErrors        string `json:"workflow_errors"`         // comma separated list of workflow / action files that could not be parsed
 
+	Branch               string `json:"branch"`                 // default branch checked by the branch protection import
+	BranchProtected      int    `json:"branch_protected"`       // boolean flag for classic protection or rulesets on the default branch
+	RequiredReviews      int    `json:"required_reviews"`       // approvals needed to merge to the default branch
+	SignedCommits        int    `json:"signed_commits"`         // boolean flag for signed commits on the default branch
+	StatusChecks         int    `json:"status_checks"`          // count of required status checks on the default branch
+	BranchPolicyPassed   int    `json:"branch_policy_passed"`   // boolean flag for passing the branch protection policy
+	BranchPolicyFailures string `json:"branch_policy_failures"` // comma separated list of the policy checks that failed
 }
 
 // Sequence is used to return the columns in the order they are selected
@@ -87,14 +137,24 @@ func (self *Model) Sequence() []any {
 		&self.Name,
 		&self.FullName,
 		&self.Url,
+		&self.Org,
+		&self.GitHubTeam,
 		&self.Visibility,
 		&self.ComplianceLevel,
 		&self.ComplianceReportUrl,
 		&self.ComplianceBadge,
 		&self.ComplianceGrade,
-		&self.TrivyUsage,
-		&self.TrivySBOMUsage,
-		&self.TrivyLocations,
+		&self.SecurityTools,
+		&self.SecurityToolLocations,
+		&self.SBOMUsage,
+		&self.WorkflowErrors,
+		&self.Branch,
+		&self.BranchProtected,
+		&self.RequiredReviews,
+		&self.SignedCommits,
+		&self.StatusChecks,
+		&self.BranchPolicyPassed,
+		&self.BranchPolicyFailures,
 	}
 }
 
@@ -119,6 +179,18 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 		filter.Team = in.Team
 		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebase_owners.team_name = :team AND")
 	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// look for the optional security tool
+	if in.Tool != "" {
+		log.Info("optional tool filter found ...", "tool", in.Tool)
+		filter.Tool = in.Tool
+		stmt = strings.ReplaceAll(stmt, "WHERE", toolFilter)
+	}
 	// now convert to a map for use in bound statements
 	err = cnv.Convert(filter, &bindMap)
 	if err != nil {
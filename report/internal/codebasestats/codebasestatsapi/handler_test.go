@@ -0,0 +1,74 @@
+package codebasestatsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodebaseStatsAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE, nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 {
+		t.Fatalf("expected codebases")
+	}
+
+	// tool filter only returns codebases using that tool
+	tool := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE+"?tool=trivy", nil))
+	if err = response.As(writer.Result(), &tool); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(tool.Data) == 0 || len(tool.Data) >= len(all.Data) {
+		t.Errorf("expected a subset of codebases to use trivy, got [%d] of [%d]", len(tool.Data), len(all.Data))
+	}
+	for _, cb := range tool.Data {
+		if !strings.Contains(cb.SecurityTools, "trivy,") || !strings.Contains(cb.SecurityToolLocations, "trivy: ") {
+			t.Errorf("expected trivy to be listed: %+v", cb)
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(ENDPOINT_TEAM, "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(team.Data) == 0 || len(team.Data) >= len(all.Data) {
+		t.Errorf("expected team to have a subset of codebases, got [%d] of [%d]", len(team.Data), len(all.Data))
+	}
+}
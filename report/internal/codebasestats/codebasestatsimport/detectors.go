@@ -0,0 +1,144 @@
+package codebasestatsimport
+
+import (
+	"opg-reports/report/package/ghworkflows"
+	"regexp"
+)
+
+// Detector finds usage of a single security tool within parsed workflow & composite
+// action files (see ghworkflows), either by the actions it provides or by patterns
+// of its cli being run.
+type Detector struct {
+	Tool     string           // name stored against each usage
+	Actions  []string         // actions (owner/name, any version) that run the tool
+	Commands []*regexp.Regexp // cli usage within a line
+	SBOM     []*regexp.Regexp // within the cli command or the action `with` values, shows an sbom is generated
+}
+
+// Match is what a detector found within a single file
+type Match struct {
+	Tool   string // name of the tool
+	Action bool   // the tool is used via an action
+	CLI    bool   // the tool is used via its cli
+	SBOM   bool   // the tool is used to generate an sbom
+}
+
+// sbomPatterns are shared by the tools that can generate an sbom; either the
+// word sbom (the command / scan type, or an output file such as `app.sbom.json`
+// as `.` is a word boundary) or an sbom format
+var sbomPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsbom\b`),
+	regexp.MustCompile(`format\s*[:= ]\s*['"]?(cyclonedx|spdx)`),
+}
+
+// Detectors is the registry of security tools looked for, in the order they are
+// checked; use Register to add others
+var Detectors = []*Detector{
+	{
+		Tool:     "trivy",
+		Actions:  []string{"aquasecurity/trivy-action"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\btrivy\s`)},
+		SBOM:     sbomPatterns,
+	},
+	{
+		Tool:     "codeql",
+		Actions:  []string{"github/codeql-action"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\bcodeql\s+(database|github)\b`)},
+	},
+	{
+		Tool:     "snyk",
+		Actions:  []string{"snyk/actions"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\bsnyk\s+(test|monitor|code|container|iac|sbom)\b`)},
+		SBOM:     sbomPatterns,
+	},
+	{
+		Tool:     "gitleaks",
+		Actions:  []string{"gitleaks/gitleaks-action"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\bgitleaks\s+(detect|protect|git|dir)\b`)},
+	},
+	{
+		Tool:     "checkov",
+		Actions:  []string{"bridgecrewio/checkov-action"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\bcheckov\s`)},
+	},
+	{
+		Tool:     "tfsec",
+		Actions:  []string{"aquasecurity/tfsec-action", "aquasecurity/tfsec-sarif-action", "aquasecurity/tfsec-pr-commenter-action"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`\btfsec\s`)},
+	},
+	{
+		Tool:     "zap",
+		Actions:  []string{"zaproxy/action-baseline", "zaproxy/action-full-scan", "zaproxy/action-api-scan", "zaproxy/action-af"},
+		Commands: []*regexp.Regexp{regexp.MustCompile(`zap-(baseline|full-scan|api-scan)\.py`)},
+	},
+}
+
+// Register adds detectors to the registry; call before running an import as the
+// registry is read concurrently
+func Register(detectors ...*Detector) {
+	Detectors = append(Detectors, detectors...)
+}
+
+// Detect runs every registered detector over the parsed file, returning a match
+// for each tool found
+func Detect(file *ghworkflows.File) (found []*Match) {
+	found = []*Match{}
+	for _, d := range Detectors {
+		if m := d.Match(file); m != nil {
+			found = append(found, m)
+		}
+	}
+	return
+}
+
+// Match looks over each step of the parsed file for the tool, returning nil when
+// it is not used.
+//
+// Steps that use one of the actions of the detector have their `with` values
+// checked for sbom generation, while each command of a `run` script is checked
+// against the cli patterns. Comments are never part of the parsed file.
+func (self *Detector) Match(file *ghworkflows.File) (m *Match) {
+	var found = &Match{Tool: self.Tool}
+	for _, step := range file.Steps() {
+		if self.usesAction(step) {
+			found.Action = true
+			for key, value := range step.With {
+				if matchAny(self.SBOM, key+": "+value) {
+					found.SBOM = true
+				}
+			}
+		}
+		for _, cmd := range step.Commands() {
+			if matchAny(self.Commands, cmd) {
+				found.CLI = true
+				if matchAny(self.SBOM, cmd) {
+					found.SBOM = true
+				}
+			}
+		}
+	}
+	if found.Action || found.CLI {
+		m = found
+	}
+	return
+}
+
+// usesAction checks if the step uses any of the actions of the detector
+func (self *Detector) usesAction(step *ghworkflows.Step) bool {
+	for _, action := range self.Actions {
+		if step.UsesAction(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny returns true when any of the patterns match the line
+func matchAny(patterns []*regexp.Regexp, line string) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package codebasestatsimport
+
+import (
+	"opg-reports/report/package/ghworkflows"
+	"regexp"
+	"testing"
+)
+
+const testWorkflow string = `
+name: scan
+on: [push]
+jobs:
+  scan:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: trivy sbom
+        uses: aquasecurity/trivy-action@0.28.0
+        with:
+          scan-type: fs
+          format: cyclonedx
+          output: "app.sbom.json"
+      - uses: github/codeql-action/init@v3
+      # - uses: snyk/actions/node@master
+      - name: gitleaks
+        run: |
+          # snyk test
+          gitleaks detect --source .
+      - name: zap
+        uses: zaproxy/action-baseline@v0.12.0
+        with:
+          target: https://example.com
+`
+
+// parse is a test helper that fails the test when content is not a valid workflow
+func parse(t *testing.T, content string) *ghworkflows.File {
+	t.Helper()
+	file, err := ghworkflows.Parse(".github/workflows/scan.yml", []byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	return file
+}
+
+func TestCodebaseStatsDetect(t *testing.T) {
+	var expected = map[string]*Match{
+		"trivy":    {Tool: "trivy", Action: true, SBOM: true},
+		"codeql":   {Tool: "codeql", Action: true},
+		"gitleaks": {Tool: "gitleaks", CLI: true},
+		"zap":      {Tool: "zap", Action: true},
+	}
+	var found = Detect(parse(t, testWorkflow))
+
+	if len(found) != len(expected) {
+		t.Fatalf("expected [%d] tools, found [%d]", len(expected), len(found))
+	}
+	for _, m := range found {
+		exp, ok := expected[m.Tool]
+		if !ok {
+			t.Errorf("unexpected tool found, commented out lines should be ignored: %+v", m)
+			continue
+		}
+		if *m != *exp {
+			t.Errorf("tool [%s] expected %+v, found %+v", m.Tool, exp, m)
+		}
+	}
+}
+
+func TestCodebaseStatsDetectCLISBOM(t *testing.T) {
+	var found = Detect(parse(t, "jobs:\n  scan:\n    steps:\n      - run: trivy sbom --format spdx-json .\n"))
+	if len(found) != 1 || !found[0].CLI || !found[0].SBOM {
+		t.Errorf("expected trivy cli generating an sbom: %+v", found)
+	}
+	// action without sbom settings in its own step
+	found = Detect(parse(t, `
+jobs:
+  scan:
+    steps:
+      - uses: aquasecurity/trivy-action@master
+        with:
+          scan-type: image
+      - run: echo output=app.sbom.json
+`))
+	if len(found) != 1 || found[0].SBOM {
+		t.Errorf("expected trivy action without an sbom: %+v", found)
+	}
+	// only the output file name shows an sbom is generated
+	found = Detect(parse(t, `
+jobs:
+  scan:
+    steps:
+      - uses: aquasecurity/trivy-action@master
+        with:
+          output: app.sbom.json
+`))
+	if len(found) != 1 || !found[0].SBOM {
+		t.Errorf("expected trivy action generating an sbom from the output file: %+v", found)
+	}
+}
+
+func TestCodebaseStatsRegister(t *testing.T) {
+	var original = Detectors
+	defer func() { Detectors = original }()
+
+	Register(&Detector{Tool: "semgrep", Actions: []string{"semgrep/semgrep-action"}, Commands: []*regexp.Regexp{regexp.MustCompile(`\bsemgrep\s`)}})
+	found := Detect(parse(t, "jobs:\n  scan:\n    steps:\n      - run: semgrep scan --config auto\n"))
+	if len(found) != 1 || found[0].Tool != "semgrep" {
+		t.Errorf("expected registered detector to be used: %+v", found)
+	}
+}
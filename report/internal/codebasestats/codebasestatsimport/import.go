@@ -8,9 +8,11 @@ import (
 	"log/slog"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/dbx"
-	"opg-reports/report/package/files"
+	"opg-reports/report/package/ghworkflows"
 	"opg-reports/report/package/repos"
 	"opg-reports/report/package/rest"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/workers"
 	"regexp"
 	"strings"
 	"time"
@@ -27,9 +29,7 @@ INSERT INTO codebase_stats (
 	compliance_grade,
 	compliance_report_url,
 	compliance_badge,
-	trivy_usage,
-	trivy_sbom_usage,
-	trivy_locations
+	workflow_errors
 ) VALUES (
 	:codebase,
 	:visibility,
@@ -37,23 +37,49 @@ INSERT INTO codebase_stats (
 	:compliance_grade,
 	:compliance_report_url,
 	:compliance_badge,
-	:trivy_usage,
-	:trivy_sbom_usage,
-	:trivy_locations
+	:workflow_errors
 )
 ON CONFLICT (codebase) DO UPDATE SET
 	compliance_level=excluded.compliance_level,
 	compliance_report_url=excluded.compliance_report_url,
 	compliance_badge=excluded.compliance_badge,
 	compliance_grade=excluded.compliance_grade,
-	trivy_usage=excluded.trivy_usage,
-	trivy_sbom_usage=excluded.trivy_sbom_usage,
-	trivy_locations=excluded.trivy_locations,
+	workflow_errors=excluded.workflow_errors,
 	visibility=excluded.visibility
 RETURNING id
 ;
 `
 const truncateStmt string = `DELETE FROM codebase_stats;`
+const deleteCodebaseStmt string = `DELETE FROM codebase_stats WHERE codebase = ?;`
+const deleteRemovedStmt string = `DELETE FROM codebase_stats WHERE codebase NOT IN (:codebases);`
+
+// InsertToolsStatement adds a security tool found within a file of a codebase
+const InsertToolsStatement string = `
+INSERT INTO codebase_security_tools (
+	codebase,
+	tool,
+	location,
+	action,
+	cli,
+	sbom
+) VALUES (
+	:codebase,
+	:tool,
+	:location,
+	:action,
+	:cli,
+	:sbom
+)
+ON CONFLICT (codebase,tool,location) DO UPDATE SET
+	action=excluded.action,
+	cli=excluded.cli,
+	sbom=excluded.sbom
+RETURNING id
+;
+`
+const truncateToolsStmt string = `DELETE FROM codebase_security_tools;`
+const deleteCodebaseToolsStmt string = `DELETE FROM codebase_security_tools WHERE codebase = ?;`
+const deleteRemovedToolsStmt string = `DELETE FROM codebase_security_tools WHERE codebase NOT IN (:codebases);`
 
 // leave some space incase of new grades
 var gradeMap = map[string]int{
@@ -67,6 +93,7 @@ var gradeMap = map[string]int{
 // teamClient wrapper around *github.TeamsService
 type teamClient interface {
 	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
 }
 
 // repoClient wrapper around *github.RepositoriesService
@@ -85,14 +112,25 @@ type Clients struct {
 }
 
 type Args struct {
-	DB           string `json:"db"`             // database path
-	Driver       string `json:"driver"`         // database driver
-	Params       string `json:"params"`         // database connection params
-	OrgSlug      string `json:"org_slug"`       // github org name
-	ParentSlug   string `json:"parent_slug"`    // parent slug
-	FilterByName string `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+
+	ComplianceBaseURL string `json:"compliance_base_url"` // optional; repository standards site, uses DefaultComplianceBaseURL when empty
 }
 
+// DefaultComplianceBaseURL is the repository standards site used for
+// compliance reports and badges when none is configured
+const DefaultComplianceBaseURL string = "https://github-community.service.justice.gov.uk/repository-standards"
+
 // Codebase represents a simple, joinless, db row in the cost table; used by imports and seeding commands
 type CodebaseStats struct {
 	Codebase   string `json:"codebase,omitempty"`    // full name of codebase
@@ -103,9 +141,19 @@ type CodebaseStats struct {
 	ComplianceBadge     string `json:"compliance_badge,omitempty"`      // compliance badge url
 	ComplianceGrade     int    `json:"compliance_grade,omitempty"`      // numeric version of compliance_level so sorting can be done on this
 
-	TrivyUsage     int    `json:"trivy_usage"`      // boolean flag to show if the codebase is using trivy in workflows
-	TrivySBOMUsage int    `json:"trivy_sbom_usage"` // boolean flag to show if trivy is being used to generate sboms
-	TrivyLocations string `json:"trivy_locations"`  // tracks files where trivy has been utilised
+	WorkflowErrors string `json:"workflow_errors"` // comma separated list of workflow / action files that could not be parsed
+
+	Tools []*ToolUsage `json:"-"` // security tools found in workflows, stored seperately
+}
+
+// ToolUsage is a security tool found within a single workflow / action file of a codebase
+type ToolUsage struct {
+	Codebase string `json:"codebase"` // full name of codebase
+	Tool     string `json:"tool"`     // name of the tool, see Detectors
+	Location string `json:"location"` // path of the file the tool was found in
+	Action   int    `json:"action"`   // boolean flag for usage via an action
+	CLI      int    `json:"cli"`      // boolean flag for usage via the cli
+	SBOM     int    `json:"sbom"`     // boolean flag for the tool generating an sbom
 }
 
 // the badge layout puts the value in the title
@@ -123,6 +171,8 @@ func Import(ctx context.Context, client *Clients, in *Args) (err error) {
 	list, err = repos.GetList(ctx, client.Teams, &repos.Args{
 		OrgSlug:      in.OrgSlug,
 		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
 		FilterByName: in.FilterByName,
 	})
 
@@ -141,91 +191,157 @@ func Import(ctx context.Context, client *Clients, in *Args) (err error) {
 func handleCodebaseStats(ctx context.Context, client repoClient, repositories []*github.Repository, in *Args) (err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "handleCodebaseStats")
 	var data []*CodebaseStats = []*CodebaseStats{}
+	var tools []*ToolUsage = []*ToolUsage{}
+	var done []string = []string{}
 	log.Info("starting codebase stats import ...")
 	// convert to local structs
 	log.Debug("converting to codebase models ...")
-	data, err = generateCodebasesStats(ctx, client, repositories)
+	data, err = generateCodebasesStats(ctx, client, repositories, in)
 	if err != nil {
 		return
 	}
-	// truncate the table
-	err = dbx.Exec(ctx, truncateStmt, &dbx.ExecArgs{
+	for _, stats := range data {
+		done = append(done, stats.Codebase)
+		tools = append(tools, stats.Tools...)
+	}
+	// remove the existing rows
+	if err = removeExisting(ctx, in, done, repositories); err != nil {
+		log.Error("error removing existing stats", "err", err.Error())
+		return
+	}
+
+	// now write to db
+	err = dbx.Insert(ctx, InsertStatsStatement, data, &dbx.InsertArgs{
 		DB:     in.DB,
 		Driver: in.Driver,
 		Params: in.Params,
 	})
 	if err != nil {
-		log.Error("error truncating table", "err", err.Error())
+		log.Error("error write data during import", "err", err.Error())
 		return
 	}
-
-	// now write to db
-	err = dbx.Insert(ctx, InsertStatsStatement, data, &dbx.InsertArgs{
+	err = dbx.Insert(ctx, InsertToolsStatement, tools, &dbx.InsertArgs{
 		DB:     in.DB,
 		Driver: in.Driver,
 		Params: in.Params,
 	})
 	if err != nil {
-		log.Error("error write data during import", "err", err.Error())
+		log.Error("error write security tool data during import", "err", err.Error())
 		return
 	}
-	log.With("count", len(data)).Info("complete.")
+	log.With("count", len(data), "tools", len(tools)).Info("complete.")
+	return
+}
+
+// removeExisting deletes the stored stats & tools ahead of writing the new ones.
+// The tables are emptied when every repository was processed, otherwise the
+// rows of the processed repositories (done) and of any codebase no longer in
+// the repository list are removed, so a repository that failed keeps its
+// previous rows.
+func removeExisting(ctx context.Context, in *Args, done []string, repositories []*github.Repository) (err error) {
+	var (
+		args  = &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}
+		names = []string{}
+	)
+	if len(done) == len(repositories) {
+		for _, stmt := range []string{truncateStmt, truncateToolsStmt} {
+			if err = dbx.Exec(ctx, stmt, args); err != nil {
+				return
+			}
+		}
+		return
+	}
+	for _, repo := range repositories {
+		names = append(names, repo.GetFullName())
+	}
+	for _, removed := range []string{deleteRemovedStmt, deleteRemovedToolsStmt} {
+		stmt, binds, e := dbx.Bind(ctx, removed, map[string]interface{}{"codebases": names})
+		if e != nil {
+			return e
+		}
+		if err = dbx.Exec(ctx, stmt, args, binds...); err != nil {
+			return
+		}
+	}
+	for _, codebase := range done {
+		for _, stmt := range []string{deleteCodebaseStmt, deleteCodebaseToolsStmt} {
+			if err = dbx.Exec(ctx, stmt, args, codebase); err != nil {
+				return
+			}
+		}
+	}
 	return
 }
 
 // generateCodebasesStats mixes the api values of the repos with other infomations
 // such as the moj compliance values and links to those reports.
-func generateCodebasesStats(ctx context.Context, client repoClient, list []*github.Repository) (data []*CodebaseStats, err error) {
+//
+// Repositories are processed concurrently (see workers.Map); a repository that
+// fails is logged and skipped so one bad repository does not stop the import.
+// An error is only returned when every repository fails.
+func generateCodebasesStats(ctx context.Context, client repoClient, list []*github.Repository, in *Args) (data []*CodebaseStats, err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "toCodebasesStats")
+	var results []*workers.Result[*CodebaseStats]
 
 	data = []*CodebaseStats{}
 	log.Debug("starting ...")
 
-	for _, repo := range list {
-		log.Info("fetching data ... ", "repository", *repo.Name)
-
-		// create a baseline entry with non-empty values for
-		var stats = &CodebaseStats{
-			Codebase:            *repo.FullName,
-			Visibility:          *repo.Visibility,
-			ComplianceLevel:     "unknown",
-			ComplianceReportUrl: "na",
-			ComplianceBadge:     "na",
-			ComplianceGrade:     1,
-			TrivyUsage:          0,
-			TrivySBOMUsage:      0,
-			TrivyLocations:      "na",
-		}
-		// set compliance data
-		err = setComplianceData(ctx, client, repo, stats)
-		if err != nil {
-			log.Error("error getting compliance data", "err", err.Error())
-			return
-		}
-
-		// set trivy values
-		err = setTrivyData(ctx, client, repo, stats)
-		if err != nil {
-			log.Error("error getting trivy data", "err", err.Error())
-			return
+	results = workers.Map(ctx, list, func(ctx context.Context, repo *github.Repository) (stats *CodebaseStats, err error) {
+		if stats, err = generateCodebaseStats(ctx, client, repo, in); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
 		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
 
-		data = append(data, stats)
+	if data, err = workers.Collect(ctx, results); err != nil {
+		return
 	}
 	log.Debug("complete.")
 	return
 }
 
+// generateCodebaseStats creates the stats for a single repository
+func generateCodebaseStats(ctx context.Context, client repoClient, repo *github.Repository, in *Args) (stats *CodebaseStats, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "generateCodebaseStats")
+
+	log.Info("fetching data ... ", "repository", *repo.Name)
+	// create a baseline entry with non-empty values for
+	stats = &CodebaseStats{
+		Codebase:            *repo.FullName,
+		Visibility:          *repo.Visibility,
+		ComplianceLevel:     "unknown",
+		ComplianceReportUrl: "na",
+		ComplianceBadge:     "na",
+		ComplianceGrade:     1,
+		Tools:               []*ToolUsage{},
+	}
+	// set compliance data
+	err = setComplianceData(ctx, client, repo, stats, in.ComplianceBaseURL)
+	if err != nil {
+		log.Error("error getting compliance data", "err", err.Error())
+		return
+	}
+	// set security tool usage
+	err = setToolData(ctx, client, repo, stats)
+	if err != nil {
+		log.Error("error getting security tool data", "err", err.Error())
+		return
+	}
+	return
+}
+
 // setComplianceData takes care of handling compliance stats about the code base.
 //
 // Sets default values for compliance data and then calls & processes the moj compliance badge to determine the
 // level the codebases is at
-func setComplianceData(ctx context.Context, client repoClient, repo *github.Repository, stats *CodebaseStats) (err error) {
+func setComplianceData(ctx context.Context, client repoClient, repo *github.Repository, stats *CodebaseStats, base string) (err error) {
 	var (
-		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "setComplianceData", "repo", *repo.Name)
-		base string       = "https://github-community.service.justice.gov.uk/repository-standards"
-		lvl  string       = "unknown"
+		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "setComplianceData", "repo", *repo.Name)
+		lvl string       = "unknown"
 	)
+	if base == "" {
+		base = DefaultComplianceBaseURL
+	}
 	log.Debug("starting ...")
 	if *repo.Archived {
 		log.Warn("repository is archived, skipping fetching compliance details.")
@@ -283,195 +399,109 @@ func complianceLevelFromBadge(ctx context.Context, badge string) (level string,
 	return
 }
 
-// setTrivyData gets all files from `.github/` folder and looks at each for usage of trivy (via cli or action) and
-// configures the flags accordingly.
+// setToolData gets all files from `.github/` folder, parses the workflow & action files
+// (see ghworkflows) and runs each of the registered security tool detectors (see Detectors)
+// over them, tracking a usage for every tool and file found.
 //
 // Starts in the `./.github/` directory path and recursively calls `GetContents` finding all files and returning only
-// yaml / yml extensions.
-//
-// Looks for `trivy ` or the trivy action `aquasecurity/trivy-action` in each line of the file to decide it trivy is
-// being used.
-//
-// If the action version is found, then it gets teh action definition (via chunking of lines) and looks for the `output:`
-// property in the action, then looks for `.sbom.` being used in the output value - which is what triggers sbom generation
-// for the aciton
-//
-// For cli usage it looks for `trivy sbom` in the line
-func setTrivyData(ctx context.Context, client repoClient, repo *github.Repository, stats *CodebaseStats) (err error) {
+// yaml / yml extensions. Files that fail to parse are tracked in WorkflowErrors rather than
+// being skipped silently.
+func setToolData(ctx context.Context, client repoClient, repo *github.Repository, stats *CodebaseStats) (err error) {
 	var (
-		log       *slog.Logger                = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "setTrivyData", "repo", *repo.Name)
-		contents  []*github.RepositoryContent = []*github.RepositoryContent{}
-		dir       string                      = "./.github/"
-		locations string                      = ""
+		log      *slog.Logger                = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "setToolData", "repo", *repo.Name)
+		contents []*github.RepositoryContent = []*github.RepositoryContent{}
+		dir      string                      = "./.github/"
 	)
 	log.Debug("starting ...")
 	// set the defaults
-	stats.TrivyLocations = ""
-	stats.TrivyUsage = 0
-	stats.TrivySBOMUsage = 0
+	stats.Tools = []*ToolUsage{}
+	stats.WorkflowErrors = ""
 	//
 	if *repo.Archived {
-		log.Warn("repository is archived, skipping fetching trivy details.")
+		log.Warn("repository is archived, skipping fetching security tool details.")
 		return
 	}
-	// loop over all files and find trivy usage & sbom generation
+	// loop over all files and find the tools used
 	contents, err = getAllGithubFiles(ctx, client, repo, dir)
 	if err != nil {
 		return
 	}
-	// parse all `.github` files looking for trivy
 	for _, file := range contents {
-		var trivy = false
-		var sbom = false
-		// look for trivy data
-		trivy, sbom, err = findTrivyInFile(ctx, client, repo, file)
-		if err != nil {
-			log.Error("error checking for trivy", "err", err.Error())
+		var content []byte
+		var parsed *ghworkflows.File
+		if !ghworkflows.IsWorkflowFile(*file.Path) {
+			continue
+		}
+		if content, err = fileContent(ctx, client, repo, file); err != nil {
+			log.Error("error checking for security tools", "err", err.Error())
 			return
 		}
-		// if either trivy or sbom are true, then track the file path
-		if trivy || sbom {
-			locations += fmt.Sprintf("%s,", *file.Path)
-			stats.TrivyUsage = 1
+		if parsed, err = ghworkflows.Parse(*file.Path, content); err != nil {
+			log.Warn("workflow file could not be parsed", "file", *file.Path, "err", err.Error())
+			stats.WorkflowErrors += fmt.Sprintf("%s,", *file.Path)
+			err = nil
+			continue
 		}
-		if sbom {
-			stats.TrivySBOMUsage = 1
+		for _, m := range Detect(parsed) {
+			stats.Tools = append(stats.Tools, &ToolUsage{
+				Codebase: stats.Codebase,
+				Tool:     m.Tool,
+				Location: *file.Path,
+				Action:   flag(m.Action),
+				CLI:      flag(m.CLI),
+				SBOM:     flag(m.SBOM),
+			})
 		}
 	}
-	stats.TrivyLocations = locations
 
-	log.With("stats", stats).Debug("complete.")
+	log.With("tools", len(stats.Tools)).Debug("complete.")
 	return
 }
 
-// findTrivyInFile looks for trivy usage within file content by downloading it and scanning lines
-func findTrivyInFile(ctx context.Context, client repoClient, repo *github.Repository, file *github.RepositoryContent) (hasTrivy bool, hasSBOM bool, err error) {
+// fileContent downloads the content of the file
+func fileContent(ctx context.Context, client repoClient, repo *github.Repository, file *github.RepositoryContent) (content []byte, err error) {
 	var (
-		buff  io.ReadCloser
-		lines []string
-		log   *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "findTrivyInFile", "repo", *repo.Name, "file", *file.Path)
+		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "fileContent", "repo", *repo.Name, "file", *file.Path)
 	)
 	log.Debug("starting ...")
 	// download the file content
-	buff, _, err = client.DownloadContents(ctx, *repo.Owner.Login, *repo.Name, *file.Path, nil)
+	err = retry.Do(ctx, func() (e error) {
+		var buff io.ReadCloser
+		if buff, _, e = client.DownloadContents(ctx, *repo.Owner.Login, *repo.Name, *file.Path, nil); e != nil {
+			return
+		}
+		defer buff.Close()
+		content, e = io.ReadAll(buff)
+		return
+	})
 	if err != nil {
 		log.Error("error downloading content", "err", err.Error())
 		return
 	}
-	// split into lines to make searching easier
-	lines = files.Lines(buff)
-	// now look for trivy in the file lines
-	hasTrivy, hasSBOM = checkFileLinesForTrivy(ctx, lines)
-
-	log.Debug("complete.")
-	return
-}
-
-// checkFileLinesForTrivy processes the workflow / action yanl files line by line, looking for trivy usage
-// via the cli or an action.
-//
-// Will also check for sbom usage as well
-func checkFileLinesForTrivy(ctx context.Context, lines []string) (trivy bool, sbom bool) {
-	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "checkFileLines")
-	log.Debug("starting ...")
-	trivy = false
-	sbom = false
-
-	// look over each line....
-	//
-	// - look for the cli usage first
-	for i, line := range lines {
-		// if the cli version is found
-		if isTrivyCLIInLine(line) {
-			trivy = true
-			// look for sbom
-			if strings.Contains(line, " sbom") {
-				sbom = true
-			}
-		} else if isTrivyActionInLine(line) {
-			trivy = true
-			if l := actionHasSBOM(lines[i+1:]); l >= 0 {
-				sbom = true
-			}
-		}
 
-	}
 	log.Debug("complete.")
 	return
 }
 
-// actionHasSBOM looks for the output setting in the action config
-//
-// It does this by finding the end of the current action in the file and
-// then scanning that chunk for the `output` attribute which should have
-// a `${file}.sbom.${ext}` file name for sbom usage
-func actionHasSBOM(remaining []string) (found int) {
-	var nextPos = -1
-	var chunk = remaining
-
-	found = -1
-	// find the next action and generate a chunk of content lines
-	// to then look for hte output setting
-	for i, line := range remaining {
-		line = strings.TrimSpace(line)
-		// fmt.Println(">>[" + line + "]<<")
-		if len(line) > 0 && line[0] == '-' {
-			nextPos = i
-			break
-		}
-	}
-	if nextPos >= 0 {
-		chunk = remaining[0:nextPos]
-	}
-	for i, line := range chunk {
-		line = strings.TrimSpace(line)
-		// only look for `output` as there might be a space around the `:` (` : ` etc)
-		if len(line) >= 6 && line[0:6] == "output" && strings.Contains(line, ".sbom.") {
-			found = i
-		}
-	}
-
-	return
-}
-
-// isTrivyActionInLine looks for the action version of trivy within a
-// single line of a file
-func isTrivyActionInLine(line string) (found bool) {
-	var (
-		trivyAction string = "aquasecurity/trivy-action"
-		idx         int    = strings.Index(line, trivyAction)
-	)
-	found = false
-	// if we found it, check the string before to make sure its not commentted out
-	if idx >= 0 {
-		found = !strings.Contains(line[0:idx], "#")
+// flag converts a bool to the 1 / 0 stored in the database
+func flag(b bool) int {
+	if b {
+		return 1
 	}
-
-	return
-}
-
-// isTrivyCLIInLine looks for cli version of trivy
-func isTrivyCLIInLine(line string) (found bool) {
-	var (
-		trivyCLI string = "trivy "
-		idx      int    = strings.Index(line, trivyCLI)
-	)
-	found = false
-	// if we found it, check the string before to make sure its not commentted out
-	if idx >= 0 {
-		found = !strings.Contains(line[0:idx], "#")
-	}
-	return
+	return 0
 }
 
 // getAllGithubFiles recurisvely finds all files within a starting directory path - used to fetch all the `.github` sub files
-// so we can then check content of those for trivy etc
+// so we can then check content of those for security tools
 func getAllGithubFiles(ctx context.Context, client repoClient, repo *github.Repository, dir string) (contents []*github.RepositoryContent, err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasestatsimport", "func", "getAllGithubFiles", "repo", *repo.Name)
 	var found = []*github.RepositoryContent{}
 
 	log.Debug("getting files in path ... ")
-	_, found, _, err = client.GetContents(ctx, *repo.Owner.Login, *repo.Name, dir, &github.RepositoryContentGetOptions{})
+	err = retry.Do(ctx, func() (e error) {
+		_, found, _, e = client.GetContents(ctx, *repo.Owner.Login, *repo.Name, dir, &github.RepositoryContentGetOptions{})
+		return
+	})
 	// repo may not have a `.github` folder, retun nil, causing a skip rather than fatal end
 	if err != nil && strings.Contains(err.Error(), "404 Not Found") {
 		log.Warn("directory was not found in this repository", "dir", dir)
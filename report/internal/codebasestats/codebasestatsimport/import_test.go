@@ -2,8 +2,10 @@ package codebasestatsimport
 
 import (
 	"context"
+	"database/sql"
 	"opg-reports/report/internal/global/migrations"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
 	"opg-reports/report/package/ghclients"
 	"opg-reports/report/package/logger"
 	"os"
@@ -53,3 +55,61 @@ func TestCodebasesImportStatsWithoutMock(t *testing.T) {
 	}
 
 }
+
+func TestCodebaseStatsRemoveExisting(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-stats.db")
+		in     = &Args{DB: dbpath, Driver: "sqlite3"}
+		stats  = []*CodebaseStats{}
+		tools  = []*ToolUsage{
+			{Codebase: "org/a", Tool: "trivy", Location: "a.yml"},
+			{Codebase: "org/b", Tool: "trivy", Location: "b.yml"},
+			{Codebase: "org/archived", Tool: "trivy", Location: "c.yml"},
+		}
+		repoList = []*github.Repository{
+			{FullName: github.Ptr("org/a")},
+			{FullName: github.Ptr("org/b")},
+			{FullName: github.Ptr("org/c")},
+		}
+		dbArgs   = &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}
+		codebase = func(table string) (codebases []string) {
+			codebases = []string{}
+			dbx.Select(ctx, `SELECT codebase FROM `+table+` ORDER BY codebase ASC;`, &dbx.SelectArgs{
+				DB: in.DB, Driver: in.Driver,
+				ScanF: func(rows *sql.Rows) (err error) {
+					var c string
+					if err = rows.Scan(&c); err == nil {
+						codebases = append(codebases, c)
+					}
+					return
+				},
+			})
+			return
+		}
+	)
+	for _, name := range []string{"org/a", "org/b", "org/archived"} {
+		stats = append(stats, &CodebaseStats{
+			Codebase: name, Visibility: "public", ComplianceLevel: "standard",
+			ComplianceReportUrl: "report", ComplianceBadge: "badge", ComplianceGrade: gradeMap["standard"],
+		})
+	}
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertStatsStatement, stats, dbArgs); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertToolsStatement, tools, dbArgs); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// org/b failed, so org/a is replaced and org/archived is no longer listed
+	if err := removeExisting(ctx, in, []string{"org/a", "org/c"}, repoList); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	for _, table := range []string{"codebase_stats", "codebase_security_tools"} {
+		if found := codebase(table); len(found) != 1 || found[0] != "org/b" {
+			t.Errorf("expected only the failed codebase to keep its rows in [%s], found [%v]", table, found)
+		}
+	}
+}
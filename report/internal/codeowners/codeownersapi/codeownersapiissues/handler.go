@@ -0,0 +1,190 @@
+package codeownersapiissues
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt returns active codebases whose CODEOWNERS file has errors or no
+// catch-all rule; codebases without a file have no catch-all rule either
+const selectStmt string = `
+SELECT
+	codebases.name,
+	codebases.full_name,
+	codebases.url,
+	codebases.org,
+	codebases.team,
+	COALESCE(codebase_codeowners_files.location,'') as location,
+	COALESCE(codebase_codeowners_files.rules,0) as rules,
+	COALESCE(codebase_codeowners_files.catch_all,0) as catch_all,
+	COALESCE(codebase_codeowners_files.errors,'') as errors
+FROM codebases
+LEFT JOIN codebase_codeowners_files ON codebase_codeowners_files.codebase = codebases.full_name
+WHERE
+	codebases.archived = 0
+	AND (
+		COALESCE(codebase_codeowners_files.errors,'') != ''
+		OR COALESCE(codebase_codeowners_files.catch_all,0) = 0
+	)
+ORDER BY
+	codebases.full_name ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE codebases.full_name IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	Team string `json:"team"` // optional team filter, rolled up via codebase_owners
+	Org  string `json:"org"`  // optional github org filter (?org=)
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Summary `json:"summary"` // counts of each kind of issue
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Team string `json:"team"`
+	Org  string `json:"org"`
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	Name       string `json:"name,omitempty"`       // short name of codebase (without owner)
+	FullName   string `json:"full_name,omitempty" ` // full name including the owner
+	Url        string `json:"url,omitempty" `       // url to access the codebase
+	Org        string `json:"org"`                  // github org the codebase was found in
+	GitHubTeam string `json:"github_team"`          // github team the codebase was found in
+
+	Location string `json:"location"`  // path of the CODEOWNERS file, empty when there is not one
+	Rules    int    `json:"rules"`     // count of rules within the file
+	CatchAll int    `json:"catch_all"` // boolean flag for a rule that owns every path
+	Errors   string `json:"errors"`    // comma separated list of errors, with line numbers
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Name,
+		&self.FullName,
+		&self.Url,
+		&self.Org,
+		&self.GitHubTeam,
+		&self.Location,
+		&self.Rules,
+		&self.CatchAll,
+		&self.Errors,
+	}
+}
+
+// Summary counts the codebases with each kind of issue; a codebase can be in
+// more than one
+type Summary struct {
+	Codebases  int `json:"codebases"`    // codebases with any issue
+	Missing    int `json:"missing"`      // codebases without a CODEOWNERS file
+	WithErrors int `json:"with_errors"`  // codebases whose file has errors
+	NoCatchAll int `json:"no_catch_all"` // codebases whose file has no catch-all rule
+}
+
+// Summarise counts the issues of each codebase
+func Summarise(all []*Model) (summary *Summary) {
+	summary = &Summary{Codebases: len(all)}
+	for _, m := range all {
+		if m.Location == "" {
+			summary.Missing++
+			continue
+		}
+		if m.Errors != "" {
+			summary.WithErrors++
+		}
+		if m.CatchAll == 0 {
+			summary.NoCatchAll++
+		}
+	}
+	return
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "codeownersapiissues", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// look for the optional team; applied last as the sub query has its own WHERE
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	// make the db call via the Select helper that handles row scanning.
+	// No return value as local values are updates within ScanF lambda
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: Summarise(all),
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
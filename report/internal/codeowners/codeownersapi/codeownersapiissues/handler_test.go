@@ -0,0 +1,65 @@
+package codeownersapiissues
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeownersAPIIssuesHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE, nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 || all.Summary.Codebases != len(all.Data) {
+		t.Fatalf("expected codebases with issues: %+v", all.Summary)
+	}
+	if all.Summary.Missing == 0 || all.Summary.WithErrors == 0 || all.Summary.NoCatchAll == 0 {
+		t.Errorf("expected each kind of issue: %+v", all.Summary)
+	}
+	for _, cb := range all.Data {
+		if cb.Errors == "" && cb.CatchAll == 1 {
+			t.Errorf("codebase without issues returned: %+v", cb)
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(ENDPOINT_TEAM, "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(team.Data) == 0 || len(team.Data) >= len(all.Data) {
+		t.Errorf("expected team to have a subset of codebases, got [%d] of [%d]", len(team.Data), len(all.Data))
+	}
+}
@@ -0,0 +1,157 @@
+package codeownersapipath
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/ghcodeowners"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt fetches every rule of the CODEOWNERS file of the codebase in file
+// order; matching the path happens after, as the patterns are not sql
+const selectStmt string = `
+SELECT
+	codebase_codeowners_rules.codebase,
+	codebase_codeowners_rules.location,
+	codebase_codeowners_rules.line,
+	codebase_codeowners_rules.pattern,
+	codebase_codeowners_rules.owners
+FROM codebase_codeowners_rules
+WHERE
+	codebase_codeowners_rules.codebase = :codebase
+ORDER BY
+	codebase_codeowners_rules.line ASC
+;
+`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	Codebase string `json:"codebase"` // full name of the codebase (?codebase=org/repo)
+	Path     string `json:"path"`     // path within the codebase to find the owners of (?path=src/main.go)
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"` // the rule that owns the path; empty when none do
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Codebase string `json:"codebase"`
+}
+
+// Model is the rule that decides the owners of the requested path
+type Model struct {
+	Codebase string `json:"codebase"` // full name of the codebase
+	Path     string `json:"path"`     // the requested path
+	Location string `json:"location"` // path of the CODEOWNERS file
+	Line     int    `json:"line"`     // line number of the rule within the file
+	Pattern  string `json:"pattern"`  // pattern that matched the path
+	Owners   string `json:"owners"`   // comma separated list of owners; empty when the rule removes ownership
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Codebase,
+		&self.Location,
+		&self.Line,
+		&self.Pattern,
+		&self.Owners,
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		data     []*Model               = []*Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "codeownersapipath", "func", "Responder")
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	filter.Codebase = in.Codebase
+
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	// both the codebase and path are needed
+	if in.Codebase != "" && in.Path != "" {
+		dbx.Select(ctx, selectStmt, &dbx.SelectArgs{
+			DB:      conf.DB,
+			Driver:  conf.Driver,
+			Params:  conf.Params,
+			BindMap: bindMap,
+			ScanF: func(rows *sql.Rows) error {
+				var r = &Model{}
+				var seq = r.Sequence()
+				if err = rows.Scan(seq...); err == nil {
+					all = append(all, r)
+				} else {
+					log.Error("row scan failed", "err", err.Error())
+				}
+				return err
+			},
+		})
+		if m := Owner(all, in.Path); m != nil {
+			data = append(data, m)
+		}
+	} else {
+		log.Warn("codebase and path are required ...", "codebase", in.Codebase, "path", in.Path)
+	}
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    data,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
+
+// Owner returns the rule that decides the owners of the path, using the same
+// precedence as github (the last matching rule), or nil when no rule matches
+func Owner(rules []*Model, path string) (owner *Model) {
+	var file = &ghcodeowners.File{Rules: []*ghcodeowners.Rule{}}
+	var match *ghcodeowners.Rule
+
+	for _, r := range rules {
+		file.Rules = append(file.Rules, ghcodeowners.NewRule(r.Line, r.Pattern, strings.FieldsFunc(r.Owners, func(c rune) bool { return c == ',' })))
+	}
+	if match = file.Match(path); match == nil {
+		return
+	}
+	for _, r := range rules {
+		if r.Line == match.Line {
+			owner = r
+			owner.Path = path
+		}
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package codeownersapipath
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeownersAPIPathHandler(t *testing.T) {
+	var (
+		err      error
+		ctx      = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir      = t.TempDir()
+		driver   = "sqlite3"
+		dbpath   = filepath.Join(dir, "test-handler.db")
+		codebase = ""
+	)
+	res, err := seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	for _, f := range res.CodeFiles {
+		if f.CatchAll == 1 {
+			codebase = f.Codebase
+			break
+		}
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	var tests = map[string]string{
+		"docs/index.md":    "/docs/",
+		"infra/main.tf":    "*.tf",
+		"src/main.go":      "*",
+		"docs/env/prod.tf": "*.tf",
+	}
+	for path, expected := range tests {
+		found := &Response{}
+		writer := httptest.NewRecorder()
+		query := url.Values{"codebase": {codebase}, "path": {path}}
+		mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT+"?"+query.Encode(), nil))
+		if err = response.As(writer.Result(), &found); err != nil {
+			t.Errorf("error converting ...")
+		}
+		if len(found.Data) != 1 || found.Data[0].Pattern != expected || found.Data[0].Path != path || found.Data[0].Owners == "" {
+			t.Errorf("[%s] expected pattern [%s], found %+v", path, expected, found.Data)
+		}
+	}
+
+	// unknown codebase has no owner
+	found := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT+"?codebase=org/unknown&path=README.md", nil))
+	if err = response.As(writer.Result(), &found); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(found.Data) != 0 {
+		t.Errorf("expected no owner: %+v", found.Data)
+	}
+}
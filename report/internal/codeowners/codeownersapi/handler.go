@@ -24,6 +24,8 @@ SELECT
 	codebases.full_name,
 	codebases.name,
 	codebases.url,
+	codebases.org,
+	codebases.team,
 	json_group_array(
 		DISTINCT json_object(
 			'owner', codebase_owners.owner,
@@ -47,6 +49,7 @@ ORDER BY
 // in this handler
 type Request struct {
 	Team string `json:"team"` // option team filter for this handler
+	Org  string `json:"org"`  // optional github org filter (?org=)
 }
 
 // Response is the end result thats sent back from the handler via the writter
@@ -61,14 +64,17 @@ type Response struct {
 // within the statement.
 type Filter struct {
 	Team string `json:"team"`
+	Org  string `json:"org"`
 }
 
 // Model is the data struct to use when fetching the select
 type Model struct {
-	FullName string            `json:"full_name,omitempty" ` // full name including the owner
-	Name     string            `json:"name,omitempty"`       // short name of codebase (without owner)
-	Url      string            `json:"url,omitempty" `       // url to access the codebase
-	Owners   hasManyCodeowners `json:"owners"`               // list of codeowners
+	FullName   string            `json:"full_name,omitempty" ` // full name including the owner
+	Name       string            `json:"name,omitempty"`       // short name of codebase (without owner)
+	Url        string            `json:"url,omitempty" `       // url to access the codebase
+	Org        string            `json:"org"`                  // github org the codebase was found in
+	GitHubTeam string            `json:"github_team"`          // github team the codebase was found in
+	Owners     hasManyCodeowners `json:"owners"`               // list of codeowners
 }
 
 // Sequence is used to return the columns in the order they are selected
@@ -78,6 +84,8 @@ func (self *Model) Sequence() []any {
 		&self.FullName,
 		&self.Name,
 		&self.Url,
+		&self.Org,
+		&self.GitHubTeam,
 		&self.Owners,
 	}
 }
@@ -127,6 +135,12 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 		filter.Team = in.Team
 		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebase_owners.team_name = :team AND")
 	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
 	// now convert to a map for use in bound statements
 	err = cnv.Convert(filter, &bindMap)
 	if err != nil {
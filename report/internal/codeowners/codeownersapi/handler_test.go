@@ -0,0 +1,60 @@
+package codeownersapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeownersAPIHandlerOrgFilter(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	// run seeds
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE, nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+
+	filtered := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE+"?org=mock-org-other", nil))
+	if err = response.As(writer.Result(), &filtered); err != nil {
+		t.Errorf("error converting ...")
+	}
+
+	if len(filtered.Data) == 0 || len(filtered.Data) >= len(all.Data) {
+		t.Errorf("expected org filter to return a subset, got [%d] of [%d]", len(filtered.Data), len(all.Data))
+	}
+	for _, m := range filtered.Data {
+		if m.Org != "mock-org-other" {
+			t.Errorf("unexpected org in filtered results: %+v", m)
+		}
+	}
+}
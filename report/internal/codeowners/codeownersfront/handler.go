@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"opg-reports/report/internal/codeowners/codeownersapi"
+	"opg-reports/report/internal/codeowners/codeownersapi/codeownersapiissues"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -79,12 +81,14 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 	var (
 		team          = request.PathValue("team")
 		ownerEndpoint = codeownersapi.ENDPOINT_BASE
+		issueEndpoint = codeownersapiissues.ENDPOINT_BASE
 		params        = []*rest.Param{}
 	)
 
 	// add team filter values and url
 	if team != "" {
 		ownerEndpoint = codeownersapi.ENDPOINT_TEAM
+		issueEndpoint = codeownersapiissues.ENDPOINT_TEAM
 		params = append(params, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
 	}
 	funcs = []dataCallerF{
@@ -96,6 +100,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get list of all codebases
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*codeownersapi.Response](ctx, args.ApiHost, ownerEndpoint, request, params...)
@@ -106,6 +115,16 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get codebases with CODEOWNERS errors or no catch-all rule
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*codeownersapiissues.Response](ctx, args.ApiHost, issueEndpoint, request, params...)
+			if err == nil {
+				issues := []*frontmodels.CodeownersIssue{}
+				cnv.Convert(resp.Data, &issues)
+				page.CodebaseData.CodeownersIssues = issues
+			}
+			wg.Done()
+		},
 	}
 	return
 }
@@ -8,8 +8,10 @@ import (
 	"log/slog"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/dbx"
-	"opg-reports/report/package/files"
+	"opg-reports/report/package/ghcodeowners"
 	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/workers"
 	"slices"
 	"strings"
 
@@ -35,10 +37,66 @@ RETURNING id
 ;
 `
 const truncateStmt string = `DELETE FROM codebase_owners;`
+const deleteCodebaseStmt string = `DELETE FROM codebase_owners WHERE codebase = ?;`
+const deleteRemovedStmt string = `DELETE FROM codebase_owners WHERE codebase NOT IN (:codebases);`
+
+// InsertFilesStatement adds the CODEOWNERS file details of a codebase
+const InsertFilesStatement string = `
+INSERT INTO codebase_codeowners_files (
+	codebase,
+	location,
+	rules,
+	catch_all,
+	errors
+) VALUES (
+	:codebase,
+	:location,
+	:rules,
+	:catch_all,
+	:errors
+)
+ON CONFLICT (codebase) DO UPDATE SET
+	location=excluded.location,
+	rules=excluded.rules,
+	catch_all=excluded.catch_all,
+	errors=excluded.errors
+RETURNING id
+;
+`
+const truncateFilesStmt string = `DELETE FROM codebase_codeowners_files;`
+const deleteCodebaseFilesStmt string = `DELETE FROM codebase_codeowners_files WHERE codebase = ?;`
+const deleteRemovedFilesStmt string = `DELETE FROM codebase_codeowners_files WHERE codebase NOT IN (:codebases);`
+
+// InsertRulesStatement adds a single rule of the CODEOWNERS file of a codebase
+const InsertRulesStatement string = `
+INSERT INTO codebase_codeowners_rules (
+	codebase,
+	location,
+	line,
+	pattern,
+	owners
+) VALUES (
+	:codebase,
+	:location,
+	:line,
+	:pattern,
+	:owners
+)
+ON CONFLICT (codebase,line) DO UPDATE SET
+	location=excluded.location,
+	pattern=excluded.pattern,
+	owners=excluded.owners
+RETURNING id
+;
+`
+const truncateRulesStmt string = `DELETE FROM codebase_codeowners_rules;`
+const deleteCodebaseRulesStmt string = `DELETE FROM codebase_codeowners_rules WHERE codebase = ?;`
+const deleteRemovedRulesStmt string = `DELETE FROM codebase_codeowners_rules WHERE codebase NOT IN (:codebases);`
 
 // teamClient wrapper around *github.TeamsService
 type teamClient interface {
 	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
 }
 
 // repoClient wrapper around *github.RepositoriesService
@@ -51,18 +109,37 @@ type repoClient interface {
 	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (fileContent *github.RepositoryContent, directoryContent []*github.RepositoryContent, resp *github.Response, err error)
 }
 
+// teamLookupClient wrapper around *github.TeamsService, used to validate team owners
+type teamLookupClient interface {
+	GetTeamBySlug(ctx context.Context, org, slug string) (*github.Team, *github.Response, error)
+}
+
+// userClient wrapper around *github.UsersService, used to validate user owners
+type userClient interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
 type Clients struct {
-	Teams teamClient // *github.TeamsService
-	Repos repoClient // *github.RepositoriesService
+	Teams      teamClient       // *github.TeamsService
+	Repos      repoClient       // *github.RepositoriesService
+	TeamLookup teamLookupClient // optional; *github.TeamsService, owners are not validated when nil
+	Users      userClient       // optional; *github.UsersService, owners are not validated when nil
 }
 
 type Args struct {
-	DB           string `json:"db"`             // database path
-	Driver       string `json:"driver"`         // database driver
-	Params       string `json:"params"`         // database connection params
-	OrgSlug      string `json:"org_slug"`       // github org name
-	ParentSlug   string `json:"parent_slug"`    // parent slug
-	FilterByName string `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+
+	OwnerToTeam map[string]string `json:"owner_to_team"` // optional; codeowner to service team mapping, uses DefaultOwnerToTeam when empty
 }
 
 type CodebaseOwner struct {
@@ -71,8 +148,35 @@ type CodebaseOwner struct {
 	TeamName string `json:"team_name"`
 }
 
-// mapping of codeowner / github teams to service teams (teams)
-var codeOwnerToTeamName map[string]string = map[string]string{
+// CodeownersFile is the CODEOWNERS file of a codebase; Location is empty when
+// the codebase does not have one
+type CodeownersFile struct {
+	Codebase string `json:"codebase"`  // full name of codebase
+	Location string `json:"location"`  // path of the file github uses, see ghcodeowners.Locations
+	Rules    int    `json:"rules"`     // count of rules within the file
+	CatchAll int    `json:"catch_all"` // boolean flag for a rule that owns every path
+	Errors   string `json:"errors"`    // comma separated list of syntax & owner errors, with line numbers
+}
+
+// CodeownersRule is a single pattern of a CODEOWNERS file and its owners
+type CodeownersRule struct {
+	Codebase string `json:"codebase"` // full name of codebase
+	Location string `json:"location"` // path of the CODEOWNERS file
+	Line     int    `json:"line"`     // line number of the rule; later lines take precedence
+	Pattern  string `json:"pattern"`  // path pattern as written
+	Owners   string `json:"owners"`   // comma separated list of owners as written (with the @)
+}
+
+// ownership is everything found for a single codebase
+type ownership struct {
+	Owners []*CodebaseOwner
+	File   *CodeownersFile
+	Rules  []*CodeownersRule
+}
+
+// DefaultOwnerToTeam is the mapping of codeowner / github teams to service
+// teams (teams) used when none is configured
+var DefaultOwnerToTeam map[string]string = map[string]string{
 	"ministryofjustice/digideps":                 "digideps",
 	"ministryofjustice/opg-lpa-team":             "make",
 	"ministryofjustice/opg-modernising-lpa-team": "modernise",
@@ -95,13 +199,15 @@ func Import(ctx context.Context, client *Clients, in *Args) (err error) {
 	list, err = repos.GetList(ctx, client.Teams, &repos.Args{
 		OrgSlug:      in.OrgSlug,
 		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
 		FilterByName: in.FilterByName,
 	})
 	if err != nil {
 		return
 	}
 
-	if err = handleCodebaseOwners(ctx, client.Repos, list, in); err != nil {
+	if err = handleCodebaseOwners(ctx, client, list, in); err != nil {
 		return
 	}
 
@@ -109,116 +215,242 @@ func Import(ctx context.Context, client *Clients, in *Args) (err error) {
 	return
 }
 
-// handleCodebaseOwners
-func handleCodebaseOwners(ctx context.Context, client repoClient, repositories []*github.Repository, in *Args) (err error) {
+// handleCodebaseOwners generates the owners, CODEOWNERS files & rules of each
+// repository and replaces the content of each table with them; when some
+// repositories fail only the rows of those that worked are replaced
+func handleCodebaseOwners(ctx context.Context, client *Clients, repositories []*github.Repository, in *Args) (err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "handleCodebaseOwners")
 	var data []*CodebaseOwner = []*CodebaseOwner{}
+	var files []*CodeownersFile = []*CodeownersFile{}
+	var rules []*CodeownersRule = []*CodeownersRule{}
+	var done []string = []string{}
+	var dbArgs = &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	}
+
 	log.Info("starting codebase owner import ...")
 	// convert to local structs
 	log.Debug("converting to codeowner models ...")
-	data, err = generateCodebaseOwners(ctx, client, repositories, in)
+	data, files, rules, done, err = generateCodebaseOwners(ctx, client, repositories, in)
 	if err != nil {
 		return
 	}
-	// truncate table first
-	err = dbx.Exec(ctx, truncateStmt, &dbx.ExecArgs{
-		DB:     in.DB,
-		Driver: in.Driver,
-		Params: in.Params,
-	})
-	if err != nil {
-		log.Error("error truncating table", "err", err.Error())
+
+	// remove the existing rows first
+	if err = removeExisting(ctx, in, done, repositories); err != nil {
+		log.Error("error removing existing owners", "err", err.Error())
 		return
 	}
+
 	// now write to db
-	err = dbx.Insert(ctx, InsertOwnersStatement, data, &dbx.InsertArgs{
-		DB:     in.DB,
-		Driver: in.Driver,
-		Params: in.Params,
-	})
-	if err != nil {
+	if err = dbx.Insert(ctx, InsertOwnersStatement, data, dbArgs); err != nil {
 		log.Error("error write data during import", "err", err.Error())
 		return
 	}
-	log.With("count", len(data)).Info("complete.")
+	if err = dbx.Insert(ctx, InsertFilesStatement, files, dbArgs); err != nil {
+		log.Error("error writing codeowners files during import", "err", err.Error())
+		return
+	}
+	if err = dbx.Insert(ctx, InsertRulesStatement, rules, dbArgs); err != nil {
+		log.Error("error writing codeowners rules during import", "err", err.Error())
+		return
+	}
+
+	log.With("count", len(data), "files", len(files), "rules", len(rules)).Info("complete.")
+	return
+}
+
+// removeExisting deletes the stored owners, files & rules ahead of writing the
+// new ones. The tables are emptied when every repository was processed,
+// otherwise the rows of the processed repositories (done) and of any codebase
+// no longer in the repository list are removed, so a repository that failed
+// keeps its owners and stays within the team rollups.
+func removeExisting(ctx context.Context, in *Args, done []string, repositories []*github.Repository) (err error) {
+	var (
+		args  = &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}
+		names = []string{}
+	)
+	if len(done) == len(repositories) {
+		for _, stmt := range []string{truncateStmt, truncateFilesStmt, truncateRulesStmt} {
+			if err = dbx.Exec(ctx, stmt, args); err != nil {
+				return
+			}
+		}
+		return
+	}
+	for _, repo := range repositories {
+		names = append(names, repo.GetFullName())
+	}
+	for _, removed := range []string{deleteRemovedStmt, deleteRemovedFilesStmt, deleteRemovedRulesStmt} {
+		stmt, binds, e := dbx.Bind(ctx, removed, map[string]interface{}{"codebases": names})
+		if e != nil {
+			return e
+		}
+		if err = dbx.Exec(ctx, stmt, args, binds...); err != nil {
+			return
+		}
+	}
+	for _, codebase := range done {
+		for _, stmt := range []string{deleteCodebaseStmt, deleteCodebaseFilesStmt, deleteCodebaseRulesStmt} {
+			if err = dbx.Exec(ctx, stmt, args, codebase); err != nil {
+				return
+			}
+		}
+	}
 	return
 }
 
 // generateCodebaseOwners converts the repo data and then fetches extra data via api;
 // in this case we pull teams and content of CODEOWNER files to determine all of our
-// codeowner information
-func generateCodebaseOwners(ctx context.Context, client repoClient, list []*github.Repository, in *Args) (data []*CodebaseOwner, err error) {
+// codeowner information. done is the full name of each repository that was processed.
+func generateCodebaseOwners(ctx context.Context, client *Clients, list []*github.Repository, in *Args) (data []*CodebaseOwner, files []*CodeownersFile, rules []*CodeownersRule, done []string, err error) {
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "toCodebaseOwners")
+	var results []*workers.Result[*ownership]
+	var found []*ownership
+	var check = newValidator(client.TeamLookup, client.Users)
 
 	data = []*CodebaseOwner{}
+	files = []*CodeownersFile{}
+	rules = []*CodeownersRule{}
+	done = []string{}
 	log.Debug("starting ...")
 
-	for _, item := range list {
-		log.Info("getting codeowners ...", "codebase", *item.FullName, "archived", *item.Archived)
-		var teams []*github.Team = []*github.Team{}
-		var owners []string = []string{}
-		var merged []string = []string{}
-		// only do this for active code bases, so if its archived, skip
-		if *item.Archived {
-			log.Info("archived, skipping", "codebase", *item.FullName)
-			continue
+	results = workers.Map(ctx, list, func(ctx context.Context, item *github.Repository) (res *ownership, err error) {
+		if res, err = generateOwnersForCodebase(ctx, client.Repos, check, item, in); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", item.GetFullName()), err)
 		}
-		// fetch teams for this code base
-		teams, err = getTeams(ctx, client, item, in)
-		if err != nil {
-			return
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, repo := range workers.Succeeded(list, results) {
+		done = append(done, repo.GetFullName())
+	}
+	for _, res := range found {
+		data = append(data, res.Owners...)
+		rules = append(rules, res.Rules...)
+		if res.File != nil {
+			files = append(files, res.File)
 		}
-		// fetch content from code owner files
-		owners, err = getCodeownersFromFiles(ctx, client, item, in)
-		if err != nil {
+	}
+
+	log.Debug("complete.")
+	return
+}
+
+// generateOwnersForCodebase returns an entry for each owner of a single
+// repository along with its parsed & validated CODEOWNERS file; archived
+// repositories have none
+func generateOwnersForCodebase(ctx context.Context, client repoClient, check *validator, item *github.Repository, in *Args) (res *ownership, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "generateOwnersForCodebase")
+	var teams []*github.Team = []*github.Team{}
+	var parsed *ghcodeowners.File
+	var merged []string = []string{}
+	var owners []string = []string{}
+
+	res = &ownership{Owners: []*CodebaseOwner{}, Rules: []*CodeownersRule{}}
+	log.Info("getting codeowners ...", "codebase", *item.FullName, "archived", *item.Archived)
+	// only do this for active code bases, so if its archived, skip
+	if *item.Archived {
+		log.Info("archived, skipping", "codebase", *item.FullName)
+		return
+	}
+	// fetch teams for this code base
+	teams, err = getTeams(ctx, client, item)
+	if err != nil {
+		return
+	}
+	// fetch & parse the codeowner file
+	parsed, err = getCodeownersFile(ctx, client, item)
+	if err != nil {
+		return
+	}
+	res.File = &CodeownersFile{Codebase: *item.FullName}
+	if parsed != nil {
+		// check the owners exist and can own the repository
+		if err = check.File(ctx, item, teams, parsed); err != nil {
 			return
 		}
-		merged = filter(merge(teams, owners))
-		// now make entry for each codeowner found
-		for _, row := range merged {
-			data = append(data, &CodebaseOwner{
-				Codebase: *item.FullName,
-				Owner:    row,
-				TeamName: strings.ToLower(ownerToServiceTeam(row)),
-			})
+		owners = parsed.Owners()
+		res.File, res.Rules = toModels(*item.FullName, parsed)
+	}
+
+	merged = filter(merge(parentTeams(teams, in.ParentSlug), owners))
+	// now make entry for each codeowner found
+	for _, row := range merged {
+		res.Owners = append(res.Owners, &CodebaseOwner{
+			Codebase: *item.FullName,
+			Owner:    row,
+			TeamName: strings.ToLower(ownerToServiceTeam(row, in.OwnerToTeam)),
+		})
+	}
+	return
+}
+
+// toModels converts the parsed CODEOWNERS file into the file & rule rows stored
+func toModels(codebase string, parsed *ghcodeowners.File) (file *CodeownersFile, rules []*CodeownersRule) {
+	file = &CodeownersFile{
+		Codebase: codebase,
+		Location: parsed.Path,
+		Rules:    len(parsed.Rules),
+	}
+	if parsed.CatchAll() != nil {
+		file.CatchAll = 1
+	}
+	for _, e := range parsed.Errors {
+		file.Errors += fmt.Sprintf("%s,", e.Error())
+	}
+	rules = []*CodeownersRule{}
+	for _, r := range parsed.Rules {
+		var owners = ""
+		for _, o := range r.Owners {
+			owners += fmt.Sprintf("%s,", o)
 		}
+		rules = append(rules, &CodeownersRule{
+			Codebase: codebase,
+			Location: parsed.Path,
+			Line:     r.Line,
+			Pattern:  r.Pattern,
+			Owners:   owners,
+		})
 	}
-	log.Debug("complete.")
 	return
 }
 
 // getTeams returns all attached teams for this code repository and deals with pagination
-// of github results
-func getTeams(ctx context.Context, client repoClient, code *github.Repository, in *Args) (teams []*github.Team, err error) {
+// of github results; see parentTeams for those under the parent team
+func getTeams(ctx context.Context, client repoClient, code *github.Repository) (teams []*github.Team, err error) {
 	var (
 		log  *slog.Logger        = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "getTeams")
 		page int                 = 1
 		opts *github.ListOptions = &github.ListOptions{PerPage: 200}
 	)
 	teams = []*github.Team{}
-
 	log.With("codebase", code.FullName).Debug("starting ...")
+
 	for page > 0 {
 		var response *github.Response
 		var list []*github.Team
-		opts.Page = page
 
+		opts.Page = page
 		log = log.With("page", page)
 		log.Debug("getting team list ... ")
 		// fetch team data
-		list, response, err = client.ListTeams(ctx, in.OrgSlug, *code.Name, opts)
+		err = retry.Do(ctx, func() (e error) {
+			list, response, e = client.ListTeams(ctx, code.GetOwner().GetLogin(), *code.Name, opts)
+			return
+		})
 		if err != nil {
 			log.Error("error getting team list")
 			err = errors.Join(ErrFailedGettingRepositoryTeams, err)
 			return
 		}
 		log.With("count", len(list)).Debug("found teams ...")
-		// attach teams to the list
-		for _, team := range list {
-			if team.Parent != nil && *team.Parent.Slug == in.ParentSlug {
-				teams = append(teams, team)
-			}
-		}
+		teams = append(teams, list...)
 		// next loop
 		page = response.NextPage
 	}
@@ -227,66 +459,51 @@ func getTeams(ctx context.Context, client repoClient, code *github.Repository, i
 	return
 }
 
-// getCodeownersFromFiles tries to fetch CODEOWNER file content from set locations and
-// will process the content into just the team names, removing duplicates.
-func getCodeownersFromFiles(ctx context.Context, client repoClient, code *github.Repository, in *Args) (owners []string, err error) {
-	var (
-		log           *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "getCodeownersFromFiles")
-		fileLocations []string     = []string{
-			"./CODEOWNERS",
-			"./.github/CODEOWNERS",
+// parentTeams returns only the teams that are children of the parent
+func parentTeams(teams []*github.Team, parent string) (children []*github.Team) {
+	children = []*github.Team{}
+	for _, team := range teams {
+		if team.Parent != nil && team.Parent.GetSlug() == parent {
+			children = append(children, team)
 		}
-	)
-	owners = []string{}
+	}
+	return
+}
 
+// getCodeownersFile tries to fetch the CODEOWNERS file from the locations github
+// uses, in the same order, and parses the first one found; nil is returned when the
+// repository does not have one
+func getCodeownersFile(ctx context.Context, client repoClient, code *github.Repository) (parsed *ghcodeowners.File, err error) {
+	var (
+		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "getCodeownersFile")
+	)
 	log.With("codebase", code.FullName).Debug("starting ...")
-	for _, filename := range fileLocations {
+
+	for _, filename := range ghcodeowners.Locations {
 		var (
-			e     error
-			lines []string
-			buff  io.ReadCloser
+			e       error
+			content []byte
 		)
 		log.With("codeowner", filename).Debug("getting codeowner file ...")
 		// fetch
-		buff, _, e = client.DownloadContents(ctx, in.OrgSlug, *code.Name, filename, nil)
-		lines = files.Lines(buff)
+		e = retry.Do(ctx, func() (err error) {
+			var buff io.ReadCloser
+			if buff, _, err = client.DownloadContents(ctx, code.GetOwner().GetLogin(), *code.Name, filename, nil); err != nil {
+				return
+			}
+			defer buff.Close()
+			content, err = io.ReadAll(buff)
+			return
+		})
 		// if there is an error, file might not be present, so ignore rather than return
-		if e == nil && len(lines) > 0 {
-			owners = append(owners, ownersFromLines(lines)...)
-			break
+		if e == nil {
+			parsed = ghcodeowners.Parse(filename, content)
+			log.With("codeowner", filename, "rules", len(parsed.Rules), "errors", len(parsed.Errors)).Debug("complete.")
+			return
 		}
-
 	}
-	// remove duplicates
-	slices.Sort(owners)
-	owners = slices.Compact(owners)
-	log.With("count", len(owners)).Debug("complete.")
-	return
-}
 
-// ownersFromLines find all the code owners from lines in the codeowners file
-// Note: strips lead @ from the team slug name and removes duplicates
-func ownersFromLines(lines []string) (owners []string) {
-	owners = []string{}
-	for _, line := range lines {
-		exploded := strings.Split(line, " ")
-		if len(exploded) > 1 {
-			for _, segment := range exploded[1:] {
-				if len(segment) > 0 && segment != " " {
-					owners = append(owners, segment)
-				}
-			}
-		}
-	}
-	// remove duplicates
-	slices.Sort(owners)
-	owners = slices.Compact(owners)
-	// remove the @prefix
-	for i, o := range owners {
-		if o[0] == '@' {
-			owners[i] = o[1:]
-		}
-	}
+	log.Debug("no codeowner file found.")
 	return
 }
 
@@ -346,10 +563,13 @@ func teamSlug(team *github.Team) string {
 	return teamSlug
 }
 
-// ownerToServiceTeam fetches service team where possible, or returns empty
-func ownerToServiceTeam(owner string) (serviceTeam string) {
+// ownerToServiceTeam fetches service team where possible, or returns none
+func ownerToServiceTeam(owner string, mapping map[string]string) (serviceTeam string) {
 	serviceTeam = "none"
-	if team, ok := codeOwnerToTeamName[owner]; ok {
+	if len(mapping) == 0 {
+		mapping = DefaultOwnerToTeam
+	}
+	if team, ok := mapping[owner]; ok {
 		serviceTeam = team
 	}
 	return
@@ -0,0 +1,202 @@
+package codeownersimport
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockRepos returns the same teams for every repo; repos named "broken" fail
+type mockRepos struct {
+	files map[string]string
+}
+
+func (self *mockRepos) ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	if repo == "broken" {
+		return nil, &github.Response{}, errors.New("broken repository")
+	}
+	return []*github.Team{
+		{Slug: github.Ptr("team-a"), HTMLURL: github.Ptr("https://github.com/orgs/org/teams/team-a"), Parent: &github.Team{Slug: github.Ptr("parent")}},
+		{Slug: github.Ptr("webops"), HTMLURL: github.Ptr("https://github.com/orgs/org/teams/webops")},
+	}, &github.Response{}, nil
+}
+
+func (self *mockRepos) DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error) {
+	if content, ok := self.files[repo+":"+filepath]; ok {
+		return io.NopCloser(bytes.NewBufferString(content)), &github.Response{}, nil
+	}
+	return nil, &github.Response{}, errors.New("no file found")
+}
+
+func (self *mockRepos) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	return nil, nil, &github.Response{}, nil
+}
+
+// mockLookup knows a single team and user, counting the lookups made
+type mockLookup struct {
+	calls int
+}
+
+func notFound() (*github.Response, error) {
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	return resp, &github.ErrorResponse{Response: resp.Response}
+}
+
+func (self *mockLookup) GetTeamBySlug(ctx context.Context, org, slug string) (*github.Team, *github.Response, error) {
+	self.calls++
+	if slug == "no-access" {
+		return &github.Team{Slug: github.Ptr(slug)}, &github.Response{}, nil
+	}
+	resp, err := notFound()
+	return nil, resp, err
+}
+
+func (self *mockLookup) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	self.calls++
+	if user == "octocat" {
+		return &github.User{Login: github.Ptr(user)}, &github.Response{}, nil
+	}
+	resp, err := notFound()
+	return nil, resp, err
+}
+
+func TestCodeownersImportGenerate(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		owner  = &github.User{Login: github.Ptr("org")}
+		lookup = &mockLookup{}
+		repos  = &mockRepos{files: map[string]string{
+			// root file is ignored as .github is read first
+			"repo:.github/CODEOWNERS": "* @org/team-a\n/src/ @org/webops @octocat\n/docs/ @org/no-access @org/missing @ghost\n/infra/ @other/team\n",
+			"repo:CODEOWNERS":         "* @org/ignored\n",
+			"partial:docs/CODEOWNERS": "/src/ @octocat invalid\n",
+		}}
+		list = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner, Archived: github.Ptr(false)},
+			{Name: github.Ptr("partial"), FullName: github.Ptr("org/partial"), Owner: owner, Archived: github.Ptr(false)},
+			{Name: github.Ptr("none"), FullName: github.Ptr("org/none"), Owner: owner, Archived: github.Ptr(false)},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, Archived: github.Ptr(true)},
+		}
+		clients = &Clients{Repos: repos, TeamLookup: lookup, Users: lookup}
+	)
+	owners, files, rules, done, err := generateCodebaseOwners(ctx, clients, list, &Args{ParentSlug: "parent", Concurrency: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if len(done) != len(list) {
+		t.Errorf("expected every repo to be processed, found [%v]", done)
+	}
+	// archived repos have no file row
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, found [%d]", len(files))
+	}
+	var byCodebase = map[string]*CodeownersFile{}
+	for _, f := range files {
+		byCodebase[f.Codebase] = f
+	}
+
+	repo := byCodebase["org/repo"]
+	if repo.Location != ".github/CODEOWNERS" || repo.Rules != 4 || repo.CatchAll != 1 {
+		t.Errorf("unexpected file: %+v", repo)
+	}
+	var expectedErrors = []string{
+		"line 3: team [@org/no-access] does not have access to the repository",
+		"line 3: unknown team [@org/missing]",
+		"line 3: unknown user [@ghost]",
+		"line 4: team [@other/team] is not in the [org] organisation",
+	}
+	if repo.Errors != strings.Join(expectedErrors, ",")+"," {
+		t.Errorf("unexpected errors: %s", repo.Errors)
+	}
+
+	partial := byCodebase["org/partial"]
+	// the line with an invalid owner is not used as a rule
+	if partial.Location != "docs/CODEOWNERS" || partial.Rules != 0 || partial.CatchAll != 0 || partial.Errors != "line 1: invalid owner [invalid]," {
+		t.Errorf("unexpected file: %+v", partial)
+	}
+	if none := byCodebase["org/none"]; none.Location != "" || none.Rules != 0 {
+		t.Errorf("expected no file: %+v", none)
+	}
+
+	if len(rules) != 4 {
+		t.Errorf("expected 4 rules, found [%d]", len(rules))
+	}
+	for _, r := range rules {
+		if r.Codebase == "org/repo" && r.Line == 2 && (r.Pattern != "/src/" || r.Owners != "@org/webops,@octocat,") {
+			t.Errorf("unexpected rule: %+v", r)
+		}
+	}
+	// team & user lookups are cached across repositories; team-a & webops have access
+	if lookup.calls != 4 {
+		t.Errorf("expected 4 lookups, found [%d]", lookup.calls)
+	}
+	// owners still include the parent teams and CODEOWNERS entries
+	var found = map[string]bool{}
+	for _, o := range owners {
+		found[o.Codebase+":"+o.Owner] = true
+	}
+	if !found["org/repo:org/team-a"] || !found["org/repo:octocat"] || !found["org/none:org/team-a"] || found["org/none:org/webops"] {
+		t.Errorf("unexpected owners: %v", found)
+	}
+}
+
+// TestCodeownersImportPartial checks a failed repository keeps its rows while
+// those of repositories no longer listed are removed
+func TestCodeownersImportPartial(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-codeowners.db")
+		in     = &Args{DB: dbpath, Driver: "sqlite3", ParentSlug: "parent", Concurrency: 1}
+		owner  = &github.User{Login: github.Ptr("org")}
+		list   = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner, Archived: github.Ptr(false)},
+			{Name: github.Ptr("broken"), FullName: github.Ptr("org/broken"), Owner: owner, Archived: github.Ptr(false)},
+		}
+		existing = []*CodebaseOwner{
+			{Owner: "org/old-team", Codebase: "org/repo", TeamName: "old"},
+			{Owner: "org/team-a", Codebase: "org/broken", TeamName: "team-a"},
+			{Owner: "org/team-a", Codebase: "org/removed", TeamName: "team-a"},
+		}
+		found = map[string]bool{}
+	)
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertOwnersStatement, existing, &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := handleCodebaseOwners(ctx, &Clients{Repos: &mockRepos{}}, list, in); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	dbx.Select(ctx, `SELECT codebase, owner FROM codebase_owners;`, &dbx.SelectArgs{
+		DB: in.DB, Driver: in.Driver,
+		ScanF: func(rows *sql.Rows) (err error) {
+			var codebase, owner string
+			if err = rows.Scan(&codebase, &owner); err == nil {
+				found[codebase+":"+owner] = true
+			}
+			return
+		},
+	})
+	if !found["org/broken:org/team-a"] {
+		t.Errorf("expected the failed repository to keep its owners: %v", found)
+	}
+	if found["org/removed:org/team-a"] {
+		t.Errorf("expected the removed repository to have no owners: %v", found)
+	}
+	if found["org/repo:org/old-team"] || !found["org/repo:org/team-a"] {
+		t.Errorf("expected the processed repository to be replaced: %v", found)
+	}
+}
@@ -0,0 +1,146 @@
+package codeownersimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/ghcodeowners"
+	"opg-reports/report/package/retry"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+)
+
+var ErrFailedValidatingOwner = errors.New("failed to validate codeowner.")
+
+// validator checks the owners within CODEOWNERS files exist on github, the same
+// as github does when showing the file:
+//   - teams have to be in the organisation of the repository and have access to it
+//   - users have to exist
+//   - emails are not checked, as they can not be looked up
+//
+// Lookups are cached for the whole import as the same owners are used across
+// most repositories.
+type validator struct {
+	teams teamLookupClient
+	users userClient
+
+	mu    sync.Mutex
+	known map[string]bool // owner -> exists on github
+}
+
+// newValidator returns a validator using the clients; when either is nil owners
+// are not validated
+func newValidator(teams teamLookupClient, users userClient) *validator {
+	return &validator{teams: teams, users: users, known: map[string]bool{}}
+}
+
+// File checks each owner of each rule, adding an error to the file for every
+// owner that github would not accept
+func (self *validator) File(ctx context.Context, repo *github.Repository, teams []*github.Team, file *ghcodeowners.File) (err error) {
+	var (
+		log    *slog.Logger = cntxt.GetLogger(ctx).With("package", "codeownersimport", "func", "validator.File", "repo", repo.GetFullName())
+		org                 = repo.GetOwner().GetLogin()
+		access              = []string{}
+	)
+	if self.teams == nil || self.users == nil {
+		log.Debug("no clients, skipping owner validation.")
+		return
+	}
+	for _, team := range teams {
+		access = append(access, strings.ToLower(teamSlug(team)))
+	}
+
+	for _, rule := range file.Rules {
+		for _, owner := range rule.Owners {
+			var msg string
+			if msg, err = self.owner(ctx, org, access, owner); err != nil {
+				return
+			}
+			if msg != "" {
+				file.Errors = append(file.Errors, &ghcodeowners.Error{Line: rule.Line, Message: msg})
+			}
+		}
+	}
+	slices.SortStableFunc(file.Errors, func(a, b *ghcodeowners.Error) int { return a.Line - b.Line })
+	log.Debug("complete.", "errors", len(file.Errors))
+	return
+}
+
+// owner returns a message explaining why the owner is invalid, or an empty string
+// when it is valid
+func (self *validator) owner(ctx context.Context, org string, access []string, owner string) (msg string, err error) {
+	var exists bool
+	var name = strings.TrimPrefix(owner, "@")
+
+	switch ghcodeowners.OwnerKind(owner) {
+	case ghcodeowners.KindTeam:
+		var teamOrg, slug, _ = strings.Cut(name, "/")
+		if !strings.EqualFold(teamOrg, org) {
+			return fmt.Sprintf("team [%s] is not in the [%s] organisation", owner, org), nil
+		}
+		if slices.Contains(access, strings.ToLower(name)) {
+			return
+		}
+		if exists, err = self.exists(ctx, owner, func() (*github.Response, error) {
+			_, resp, e := self.teams.GetTeamBySlug(ctx, teamOrg, slug)
+			return resp, e
+		}); err != nil {
+			return
+		}
+		msg = fmt.Sprintf("team [%s] does not have access to the repository", owner)
+		if !exists {
+			msg = fmt.Sprintf("unknown team [%s]", owner)
+		}
+	case ghcodeowners.KindUser:
+		if exists, err = self.exists(ctx, owner, func() (*github.Response, error) {
+			_, resp, e := self.users.Get(ctx, name)
+			return resp, e
+		}); err != nil {
+			return
+		}
+		if !exists {
+			msg = fmt.Sprintf("unknown user [%s]", owner)
+		}
+	}
+	return
+}
+
+// exists runs the lookup for the owner, unless it has been looked up already; a
+// not found response means the owner does not exist, other errors are returned
+func (self *validator) exists(ctx context.Context, owner string, lookup func() (*github.Response, error)) (exists bool, err error) {
+	var found bool
+	var key = strings.ToLower(owner)
+
+	self.mu.Lock()
+	exists, found = self.known[key]
+	self.mu.Unlock()
+	if found {
+		return
+	}
+
+	err = retry.Do(ctx, func() (e error) {
+		var resp *github.Response
+		resp, e = lookup()
+		if e != nil && resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound {
+			e = nil
+			return
+		}
+		exists = e == nil
+		return
+	})
+	if err != nil {
+		err = errors.Join(ErrFailedValidatingOwner, fmt.Errorf("owner [%s]", owner), err)
+		return
+	}
+
+	self.mu.Lock()
+	self.known[key] = exists
+	self.mu.Unlock()
+	return
+}
@@ -0,0 +1,264 @@
+// Package codescanningimport fetches every code scanning alert, open or closed, of
+// each repository; this covers codeql and any third party tool uploading sarif results.
+//
+// Severity is the security severity of the rule (critical, high, medium, low) when it
+// has one, otherwise the rule severity (error, warning, note). Age is how long the alert
+// has been open at the time of import, or was open for once closed.
+//
+// Repositories without code scanning enabled, or without any analysis, are skipped.
+package codescanningimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement adds or updates a code scanning alert
+const InsertStatement string = `
+INSERT INTO code_scanning_alerts (
+	codebase,
+	number,
+	state,
+	tool,
+	rule,
+	severity,
+	opened_at,
+	closed_at,
+	age_seconds
+) VALUES (
+	:codebase,
+	:number,
+	:state,
+	:tool,
+	:rule,
+	:severity,
+	:opened_at,
+	:closed_at,
+	:age_seconds
+) ON CONFLICT (codebase,number) DO UPDATE SET
+	state=excluded.state,
+	tool=excluded.tool,
+	rule=excluded.rule,
+	severity=excluded.severity,
+	opened_at=excluded.opened_at,
+	closed_at=excluded.closed_at,
+	age_seconds=excluded.age_seconds
+RETURNING id
+;
+`
+
+// alert states used by github
+const (
+	StateOpen      string = "open"
+	StateFixed     string = "fixed"
+	StateDismissed string = "dismissed"
+)
+
+var ErrFailedGettingAlerts = errors.New("error getting code scanning alerts.")
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// codeScanningClient wrapper around *github.CodeScanningService
+type codeScanningClient interface {
+	// api docs - https://docs.github.com/rest/code-scanning/code-scanning#list-code-scanning-alerts-for-a-repository
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error)
+}
+
+type Args struct {
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams        teamClient         // *github.TeamsService
+	CodeScanning codeScanningClient // *github.CodeScanningService
+}
+
+// Model is a single code scanning alert of a codebase
+type Model struct {
+	Codebase   string `json:"codebase"`    // full name of codebase
+	Number     int    `json:"number"`      // alert number, unique within the codebase
+	State      string `json:"state"`       // open, fixed or dismissed
+	Tool       string `json:"tool"`        // tool that raised the alert (CodeQL, trivy etc)
+	Rule       string `json:"rule"`        // id of the rule that raised the alert
+	Severity   string `json:"severity"`    // see package docs
+	OpenedAt   string `json:"opened_at"`   // when the alert was raised
+	ClosedAt   string `json:"closed_at"`   // when the alert was fixed or dismissed
+	AgeSeconds int64  `json:"age_seconds"` // how long the alert has been, or was, open
+}
+
+// Import finds all github repositories and writes their code scanning alerts
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "codescanningimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+
+	log.Info("starting ...")
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, err = handler(ctx, clients, in, repoList, time.Now().UTC())
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.", "alerts", len(data))
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository, now time.Time) (data []*Model, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+
+	data = []*Model{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (list []*Model, err error) {
+		var alerts []*github.Alert
+		if alerts, err = repoAlerts(ctx, clients.CodeScanning, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+			return
+		}
+		list = []*Model{}
+		for _, a := range alerts {
+			list = append(list, ToModel(repo.GetFullName(), a, now))
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, list := range found {
+		data = append(data, list...)
+	}
+	return
+}
+
+// repoAlerts fetches all pages of alerts for the repository
+func repoAlerts(ctx context.Context, client codeScanningClient, repo *github.Repository) (alerts []*github.Alert, err error) {
+	var (
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "codescanningimport", "func", "repoAlerts", "repo", repo.GetName())
+		opts              = &github.AlertListOptions{ListOptions: github.ListOptions{PerPage: 100, Page: 1}}
+	)
+	alerts = []*github.Alert{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting code scanning alerts ...")
+	for opts.ListOptions.Page > 0 {
+		var (
+			page     []*github.Alert
+			response *github.Response
+		)
+		err = retry.Do(ctx, func() (e error) {
+			page, response, e = client.ListAlertsForRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opts)
+			return
+		})
+		if err != nil && disabled(response) {
+			log.Warn("code scanning is not enabled, skipping.")
+			err = nil
+			return
+		} else if err != nil {
+			err = errors.Join(ErrFailedGettingAlerts, err)
+			return
+		}
+		alerts = append(alerts, page...)
+		opts.ListOptions.Page = 0
+		if response != nil {
+			opts.ListOptions.Page = response.NextPage
+		}
+	}
+	log.Info("found code scanning alerts ...", "count", len(alerts))
+	return
+}
+
+// disabled returns true when the response shows the alerts are not available for
+// the repository, rather than the call failing
+func disabled(response *github.Response) bool {
+	if response == nil || response.Response == nil {
+		return false
+	}
+	return response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusNotFound
+}
+
+// ToModel converts the github alert into a Model for the codebase; now is used
+// for the age of open alerts
+func ToModel(codebase string, a *github.Alert, now time.Time) (m *Model) {
+	var opened = a.GetCreatedAt().Time
+	var closed = now
+	m = &Model{
+		Codebase: codebase,
+		Number:   a.GetNumber(),
+		State:    a.GetState(),
+		Tool:     a.GetTool().GetName(),
+		Rule:     a.GetRule().GetID(),
+		Severity: a.GetRule().GetSecuritySeverityLevel(),
+		OpenedAt: times.AsString(opened, times.FULL),
+	}
+	if m.Rule == "" {
+		m.Rule = a.GetRuleID()
+	}
+	if m.Severity == "" {
+		m.Severity = a.GetRule().GetSeverity()
+	}
+	switch {
+	case a.FixedAt != nil:
+		closed = a.GetFixedAt().Time
+	case a.DismissedAt != nil:
+		closed = a.GetDismissedAt().Time
+	case a.ClosedAt != nil:
+		closed = a.GetClosedAt().Time
+	}
+	if m.State != StateOpen {
+		m.ClosedAt = times.AsString(closed, times.FULL)
+	}
+	m.AgeSeconds = int64(closed.Sub(opened) / time.Second)
+	return
+}
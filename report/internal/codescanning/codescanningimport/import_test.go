@@ -0,0 +1,99 @@
+package codescanningimport
+
+import (
+	"context"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/times"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockCodeScanning returns a page of alerts per page number; repos named "disabled"
+// have code scanning turned off
+type mockCodeScanning struct {
+	pages map[int][]*github.Alert
+	calls int
+}
+
+func (self *mockCodeScanning) ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error) {
+	self.calls++
+	if repo == "disabled" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "no analysis found"}
+	}
+	resp := &github.Response{}
+	if opts.ListOptions.Page == 1 {
+		resp.NextPage = 2
+	}
+	return self.pages[opts.ListOptions.Page], resp, nil
+}
+
+func alert(number int, state string, security string, opened time.Time, fixed *time.Time) *github.Alert {
+	var a = &github.Alert{
+		Number:    github.Ptr(number),
+		State:     github.Ptr(state),
+		Tool:      &github.Tool{Name: github.Ptr("CodeQL")},
+		Rule:      &github.Rule{ID: github.Ptr("js/sql-injection"), Severity: github.Ptr("error")},
+		CreatedAt: &github.Timestamp{Time: opened},
+	}
+	if security != "" {
+		a.Rule.SecuritySeverityLevel = github.Ptr(security)
+	}
+	if fixed != nil {
+		a.FixedAt = &github.Timestamp{Time: *fixed}
+	}
+	return a
+}
+
+func TestCodeScanningImportHandler(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		opened = times.MustFromString("2025-01-10")
+		fixed  = opened.Add(48 * time.Hour)
+		now    = opened.Add(72 * time.Hour)
+		owner  = &github.User{Login: github.Ptr("org")}
+		client = &mockCodeScanning{pages: map[int][]*github.Alert{
+			1: {alert(1, StateOpen, "high", opened, nil)},
+			2: {alert(2, StateFixed, "critical", opened, &fixed), alert(3, StateOpen, "", opened, nil)},
+		}}
+		repoList = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner},
+			{Name: github.Ptr("disabled"), FullName: github.Ptr("org/disabled"), Owner: owner},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, Archived: github.Ptr(true)},
+		}
+	)
+	alerts, err := handler(ctx, &Clients{CodeScanning: client}, &Args{}, repoList, now)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// both pages of the first repo, disabled and archived are skipped without error
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, found [%d]", len(alerts))
+	}
+	if client.calls != 3 {
+		t.Errorf("expected 3 api calls, found [%d]", client.calls)
+	}
+	for _, a := range alerts {
+		if a.Codebase != "org/repo" || a.Tool != "CodeQL" || a.Rule != "js/sql-injection" {
+			t.Errorf("unexpected alert: %+v", a)
+		}
+		switch a.Number {
+		case 1:
+			if a.Severity != "high" || a.ClosedAt != "" || a.AgeSeconds != int64((72*time.Hour).Seconds()) {
+				t.Errorf("open alert should be aged until now: %+v", a)
+			}
+		case 2:
+			if a.Severity != "critical" || a.ClosedAt == "" || a.AgeSeconds != int64((48*time.Hour).Seconds()) {
+				t.Errorf("fixed alert should be aged until fixed: %+v", a)
+			}
+		case 3:
+			if a.Severity != "error" {
+				t.Errorf("expected rule severity when there is no security severity: %+v", a)
+			}
+		}
+	}
+}
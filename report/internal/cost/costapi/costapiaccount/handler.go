@@ -30,7 +30,7 @@ SELECT
 FROM costs
 LEFT JOIN accounts on accounts.id = costs.account_id
 WHERE
-	costs.service != 'Tax'
+	costs.service NOT IN (:excluded_services)
 	AND costs.month IN (:months)
 GROUP BY
 	costs.month,
@@ -74,8 +74,9 @@ type Response struct {
 // statement.
 // For this endpointm, we only filter by the time period - months
 type Filter struct {
-	Months []string `json:"months"`
-	Team   string   `json:"team"`
+	Months           []string `json:"months"`
+	ExcludedServices []string `json:"excluded_services"` // services to leave out of the totals
+	Team             string   `json:"team"`
 }
 
 // Model is the data struct to use when fetching the select
@@ -123,7 +124,7 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 	}
 	// setup months
 	headings[tabulate.DATA] = months
-	filter = &Filter{Months: months}
+	filter = &Filter{Months: months, ExcludedServices: conf.Excluded()}
 	// look for the optional team
 	if in.Team != "" {
 		log.Info("optional team filter found ...", "team", in.Team)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"opg-reports/report/internal/cost/costapi/costapiaccount"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -80,7 +81,7 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 	var (
 		team         = request.PathValue("team")
 		costEndpoint = costapiaccount.ENDPOINT_BASE
-		billingDay   = 15
+		billingDay   = args.BillingDay
 		dateEnd      = times.ResetMonth(times.Today()) // use this month
 		dateStart    = times.Add(dateEnd, -5, times.MONTH)
 		params       = []*rest.Param{
@@ -103,6 +104,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get homepage costs - trigger the same end date as others
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*costapiaccount.Response](ctx, args.ApiHost, costEndpoint, request, params...)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"opg-reports/report/internal/cost/costapi/costapiteam"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/htmlpage"
@@ -69,7 +70,7 @@ func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (
 // dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
 func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
 	var (
-		billingDay = 15
+		billingDay = args.BillingDay
 		dateEnd    = times.ResetMonth(times.Today()) // use this month
 		dateStart  = times.Add(dateEnd, -5, times.MONTH)
 		params     = []*rest.Param{
@@ -86,6 +87,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get homepage costs - trigger the same end date as others
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*costapiteam.Response](ctx, args.ApiHost, costapiteam.ENDPOINT_BASE, request, params...)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"opg-reports/report/internal/cost/costapi/costapidetailed"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -82,7 +83,7 @@ func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (
 func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
 	var (
 		team         = request.PathValue("team")
-		billingDay   = 15
+		billingDay   = args.BillingDay
 		costEndpoint = costapidetailed.ENDPOINT_BASE
 		dateEnd      = times.ResetMonth(times.Today())
 		dateStart    = times.Add(dateEnd, -5, times.MONTH)
@@ -106,6 +107,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get detailed costs
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*costapidetailed.Response](ctx, args.ApiHost, costEndpoint, request, params...)
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"opg-reports/report/internal/cost/costapi/costapidiff"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -79,7 +80,7 @@ func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (
 func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
 	var (
 		team         = request.PathValue("team")
-		billingDay   = 15
+		billingDay   = args.BillingDay
 		costEndpoint = costapidiff.ENDPOINT_BASE
 		thisMonth    = times.ResetMonth(times.Today())
 		dateB        = times.Add(thisMonth, -1, times.MONTH) // differ should be last month and the month before
@@ -105,6 +106,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get cost differences
 		func(wg *sync.WaitGroup, page *PageContent) {
 			var changes = []string{}
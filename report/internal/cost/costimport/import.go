@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/retry"
 	"opg-reports/report/package/times"
 	"time"
 
@@ -73,7 +74,9 @@ func Import(ctx context.Context, client Client, in *Args) (err error) {
 		times.AsYMDString(in.DateEnd))
 
 	// make the api call
-	result, err = client.GetCostAndUsage(ctx, options)
+	result, err = retry.Get(ctx, func() (*costexplorer.GetCostAndUsageOutput, error) {
+		return client.GetCostAndUsage(ctx, options)
+	})
 	if err != nil {
 		log.Error("error getting cost and usage", "err", err.Error())
 		return
@@ -0,0 +1,181 @@
+package dependabotapiopen
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt counts the open alerts of each codebase by severity, those with the
+// most severe alerts first
+const selectStmt string = `
+SELECT
+	dependabot_alerts.codebase,
+	COALESCE(SUM(dependabot_alerts.severity = 'critical'),0) as critical,
+	COALESCE(SUM(dependabot_alerts.severity = 'high'),0) as high,
+	COALESCE(SUM(dependabot_alerts.severity = 'medium'),0) as medium,
+	COALESCE(SUM(dependabot_alerts.severity = 'low'),0) as low,
+	COUNT(dependabot_alerts.id) as total,
+	COALESCE(MIN(dependabot_alerts.opened_at),'') as oldest
+FROM dependabot_alerts
+LEFT JOIN codebases on codebases.full_name = dependabot_alerts.codebase
+WHERE
+	codebases.archived = 0
+	AND dependabot_alerts.state = 'open'
+GROUP BY
+	dependabot_alerts.codebase
+ORDER BY
+	critical DESC,
+	high DESC,
+	medium DESC,
+	low DESC,
+	dependabot_alerts.codebase ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE dependabot_alerts.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	Team      string `json:"team"`      // optional team filter, rolled up via codebase_owners
+	Org       string `json:"org"`       // optional github org filter (?org=)
+	Ecosystem string `json:"ecosystem"` // optional package ecosystem filter (?ecosystem=npm)
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Model   `json:"summary"` // totals of every codebase
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Team      string `json:"team"`
+	Org       string `json:"org"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	Codebase string `json:"codebase,omitempty"`
+	Critical int    `json:"critical"`
+	High     int    `json:"high"`
+	Medium   int    `json:"medium"`
+	Low      int    `json:"low"`
+	Total    int    `json:"total"`
+	Oldest   string `json:"oldest"` // when the oldest open alert was raised
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Codebase,
+		&self.Critical,
+		&self.High,
+		&self.Medium,
+		&self.Low,
+		&self.Total,
+		&self.Oldest,
+	}
+}
+
+// Add includes the counts of other within this model
+func (self *Model) Add(other *Model) {
+	self.Critical += other.Critical
+	self.High += other.High
+	self.Medium += other.Medium
+	self.Low += other.Low
+	self.Total += other.Total
+	if self.Oldest == "" || (other.Oldest != "" && other.Oldest < self.Oldest) {
+		self.Oldest = other.Oldest
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  *Model                 = &Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "dependabotapiopen", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// look for the optional ecosystem
+	if in.Ecosystem != "" {
+		log.Info("optional ecosystem filter found ...", "ecosystem", in.Ecosystem)
+		filter.Ecosystem = in.Ecosystem
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE dependabot_alerts.ecosystem = :ecosystem AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	for _, r := range all {
+		summary.Add(r)
+	}
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
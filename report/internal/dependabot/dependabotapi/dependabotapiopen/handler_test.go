@@ -0,0 +1,79 @@
+package dependabotapiopen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDependabotOpenAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE, nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 || all.Summary.Total == 0 || all.Summary.Oldest == "" {
+		t.Fatalf("expected open alerts: %+v", all.Summary)
+	}
+	if s := all.Summary; s.Total != s.Critical+s.High+s.Medium+s.Low {
+		t.Errorf("expected total to match severities: %+v", s)
+	}
+	// most critical first
+	for i := 1; i < len(all.Data); i++ {
+		if all.Data[i].Critical > all.Data[i-1].Critical {
+			t.Errorf("expected results to be ordered by critical alerts")
+			break
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(ENDPOINT_TEAM, "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if team.Summary.Total == 0 || team.Summary.Total >= all.Summary.Total {
+		t.Errorf("expected team to have a subset of alerts, got [%d] of [%d]", team.Summary.Total, all.Summary.Total)
+	}
+
+	// unknown ecosystem has no alerts
+	eco := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE+"?ecosystem=not-real", nil))
+	if err = response.As(writer.Result(), &eco); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(eco.Data) != 0 {
+		t.Errorf("expected no results for unknown ecosystem, found [%d]", len(eco.Data))
+	}
+}
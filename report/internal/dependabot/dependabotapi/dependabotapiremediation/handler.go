@@ -0,0 +1,203 @@
+package dependabotapiremediation
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt counts the alerts opened and fixed in each month; an alert is an
+// `opened` event in the month it was raised and a `fixed` event in the month it
+// was fixed. Alerts that are not fixed have an empty fixed month, so are excluded
+// by the month filter.
+const selectStmt string = `
+SELECT
+	events.month,
+	COALESCE(SUM(events.opened),0) as opened,
+	COALESCE(SUM(events.opened AND events.severity IN ('critical','high')),0) as opened_severe,
+	COALESCE(SUM(events.fixed),0) as fixed,
+	COALESCE(SUM(events.fixed AND events.severity IN ('critical','high')),0) as fixed_severe,
+	COALESCE(SUM(events.seconds_to_remediate),0) as seconds_to_remediate
+FROM (
+	SELECT codebase, severity, substr(opened_at,1,7) as month, 1 as opened, 0 as fixed, 0 as seconds_to_remediate FROM dependabot_alerts
+	UNION ALL
+	SELECT codebase, severity, substr(fixed_at,1,7) as month, 0 as opened, 1 as fixed, seconds_to_remediate FROM dependabot_alerts
+) as events
+LEFT JOIN codebases on codebases.full_name = events.codebase
+WHERE
+	codebases.archived = 0
+	AND events.month IN (:months)
+GROUP BY
+	events.month
+ORDER BY
+	events.month DESC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE events.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"` // optional team filter, rolled up via codebase_owners
+	Org       string `json:"org"`  // optional github org filter (?org=)
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Model   `json:"summary"` // totals over the whole period
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+	Org    string   `json:"org"`
+}
+
+// Model is the data struct to use when fetching the select; the counts are
+// selected and the average calculated from them
+type Model struct {
+	Month              string `json:"month,omitempty"` // month as YYYY-MM string
+	Opened             int    `json:"opened"`          // alerts raised in the month
+	OpenedSevere       int    `json:"opened_severe"`   // critical & high alerts raised in the month
+	Fixed              int    `json:"fixed"`           // alerts fixed in the month
+	FixedSevere        int    `json:"fixed_severe"`    // critical & high alerts fixed in the month
+	SecondsToRemediate int64  `json:"seconds_to_remediate"`
+
+	MeanDaysToRemediate float64 `json:"mean_days_to_remediate"` // average days from raised to fixed, of those fixed in the month
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Month,
+		&self.Opened,
+		&self.OpenedSevere,
+		&self.Fixed,
+		&self.FixedSevere,
+		&self.SecondsToRemediate,
+	}
+}
+
+// Add includes the counts of other within this model
+func (self *Model) Add(other *Model) {
+	self.Opened += other.Opened
+	self.OpenedSevere += other.OpenedSevere
+	self.Fixed += other.Fixed
+	self.FixedSevere += other.FixedSevere
+	self.SecondsToRemediate += other.SecondsToRemediate
+}
+
+// Calculate sets the average time to remediate from the totals
+func (self *Model) Calculate() {
+	if self.Fixed > 0 {
+		self.MeanDaysToRemediate = float64(self.SecondsToRemediate) / float64(self.Fixed) / (24 * 3600)
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		months   []string
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  *Model                 = &Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "dependabotapiremediation", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	filter.Months = months
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	for _, r := range all {
+		r.Calculate()
+		summary.Add(r)
+	}
+	summary.Calculate()
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
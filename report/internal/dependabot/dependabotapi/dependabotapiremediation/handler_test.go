@@ -0,0 +1,70 @@
+package dependabotapiremediation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDependabotRemediationAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+		end    = times.ResetMonth(times.Today())
+		start  = times.Add(end, -6, times.MONTH)
+		base   = strings.NewReplacer("{date_start}", times.AsYMDString(start), "{date_end}", times.AsYMDString(end))
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, base.Replace(ENDPOINT_BASE), nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 || len(all.Data) > len(times.Months(start, end)) {
+		t.Fatalf("expected a row per month, found [%d]", len(all.Data))
+	}
+	if all.Summary.Opened == 0 || all.Summary.Fixed == 0 || all.Summary.MeanDaysToRemediate <= 0 {
+		t.Errorf("expected remediation totals: %+v", all.Summary)
+	}
+	for _, m := range all.Data {
+		if m.Month == "" || m.OpenedSevere > m.Opened || m.FixedSevere > m.Fixed {
+			t.Errorf("unexpected month: %+v", m)
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(base.Replace(ENDPOINT_TEAM), "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if team.Summary.Opened == 0 || team.Summary.Opened >= all.Summary.Opened {
+		t.Errorf("expected team to have a subset of alerts, got [%d] of [%d]", team.Summary.Opened, all.Summary.Opened)
+	}
+}
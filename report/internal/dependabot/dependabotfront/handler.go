@@ -0,0 +1,158 @@
+package dependabotfront
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/dependabot/dependabotapi/dependabotapiopen"
+	"opg-reports/report/internal/dependabot/dependabotapi/dependabotapiremediation"
+	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
+	"opg-reports/report/internal/team/teamapi/teamapiall"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/htmlpage"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/rest"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/tmpl"
+	"sync"
+)
+
+type PageContent struct {
+	htmlpage.HTMLPage
+	Team           string
+	DependabotData *frontmodels.DependabotData
+	Dates          *frontmodels.DateRanges
+}
+
+type dataCallerF func(wg *sync.WaitGroup, page *PageContent)
+
+// Handler deals with the dependabot alerts page for all codebases or those of a team
+func Handler(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request, writer http.ResponseWriter) {
+	var (
+		page         *PageContent
+		templateName string
+		team         string         = request.PathValue("team")
+		wg           sync.WaitGroup = sync.WaitGroup{}
+		log          *slog.Logger   = cntxt.GetLogger(ctx).With("package", "dependabotfront", "func", "Handler", "url", request.URL.String())
+	)
+	log.Info("starting ...")
+	page, templateName = getPage(team, args, request)
+	if team != "" {
+		log.Info("found team parameter ... ", "team", team)
+	}
+	// page data fetched from api via blocks
+	for _, blockF := range dataCallers(ctx, args, request) {
+		wg.Add(1)
+		go blockF(&wg, page)
+	}
+	wg.Wait()
+
+	// respond
+	respond.AsHTML(ctx, request, writer, page, &respond.Args{
+		Template:      templateName,
+		TemplateFiles: tmpl.GetTemplateFiles(args.TemplateDir),
+		Funcs:         tmpl.TemplateFunctions(),
+	})
+	log.Info("complete.")
+}
+
+func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (page *PageContent, template string) {
+	var args *htmlpage.Args = &htmlpage.Args{
+		Name:         "OPG Reports",
+		Title:        "OPG Reports - Dependabot Alerts",
+		GovUKVersion: in.GovUKVersion,
+		SemVer:       in.SemVer,
+	}
+	template = "dependabot"
+	if team != "" {
+		args.Title += " - " + cnv.Capitalize(team)
+	}
+	page = &PageContent{
+		HTMLPage:       htmlpage.New(request, args),
+		Team:           team,
+		DependabotData: &frontmodels.DependabotData{Team: team},
+	}
+	return
+}
+
+// dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
+func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
+	var (
+		team                = request.PathValue("team")
+		openEndpoint        = dependabotapiopen.ENDPOINT_BASE
+		remediationEndpoint = dependabotapiremediation.ENDPOINT_BASE
+		dateEnd             = times.ResetMonth(times.Today()) // use this month
+		dateStart           = times.Add(dateEnd, -5, times.MONTH)
+		openParams          = []*rest.Param{
+			// optional filters passed through from the front end request
+			{Type: rest.QUERY, Key: "org"},
+			{Type: rest.QUERY, Key: "ecosystem"},
+		}
+		remediationParams = []*rest.Param{
+			{Type: rest.PATH, Key: "date_end", Value: times.AsYMString(dateEnd)},
+			{Type: rest.PATH, Key: "date_start", Value: times.AsYMString(dateStart)},
+			{Type: rest.QUERY, Key: "org"},
+		}
+	)
+	// add team filter values and url
+	if team != "" {
+		openEndpoint = dependabotapiopen.ENDPOINT_TEAM
+		remediationEndpoint = dependabotapiremediation.ENDPOINT_TEAM
+		openParams = append(openParams, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
+		remediationParams = append(remediationParams, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
+	}
+
+	funcs = []dataCallerF{
+		// get teams
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*teamapiall.Response](ctx, args.ApiHost, teamapiall.ENDPOINT, request)
+			if err == nil {
+				page.Teams = resp.Data
+			}
+			wg.Done()
+		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
+		// get open alerts by severity per codebase
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*dependabotapiopen.Response](ctx, args.ApiHost, openEndpoint, request, openParams...)
+			if err == nil {
+				open := []*frontmodels.DependabotOpen{}
+				summary := &frontmodels.DependabotOpen{}
+				// convert to front end version
+				cnv.Convert(resp.Data, &open)
+				cnv.Convert(resp.Summary, &summary)
+				page.DependabotData.Open = open
+				page.DependabotData.OpenSummary = summary
+			}
+			wg.Done()
+		},
+		// get remediation trend by month
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*dependabotapiremediation.Response](ctx, args.ApiHost, remediationEndpoint, request, remediationParams...)
+			if err == nil {
+				months := []*frontmodels.DependabotRemediation{}
+				summary := &frontmodels.DependabotRemediation{}
+				// convert to front end version
+				cnv.Convert(resp.Data, &months)
+				cnv.Convert(resp.Summary, &summary)
+				page.DependabotData.Remediation = months
+				page.DependabotData.RemediationSummary = summary
+				page.Dates = &frontmodels.DateRanges{
+					DateStart: resp.Request.DateStart,
+					DateEnd:   resp.Request.DateEnd,
+					Months: times.AsYMStrings(
+						times.Months(times.Add(times.Today(), -12, times.MONTH), times.Today()),
+					),
+				}
+			}
+			wg.Done()
+		},
+	}
+	return
+}
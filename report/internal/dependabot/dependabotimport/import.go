@@ -0,0 +1,338 @@
+// Package dependabotimport fetches every dependabot alert, open or closed, of each
+// repository along with the security advisory it was raised for.
+//
+// Alerts are not limited by date, as the open alerts are the current posture of a
+// codebase regardless of when they were raised; each import replaces the state of
+// the alerts already stored.
+//
+// Time to remediate is only set on fixed alerts and runs from the alert being opened
+// to it being fixed. Repositories without dependabot alerts enabled are skipped.
+package dependabotimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertAlertsStatement adds or updates a dependabot alert
+const InsertAlertsStatement string = `
+INSERT INTO dependabot_alerts (
+	codebase,
+	number,
+	ghsa_id,
+	state,
+	severity,
+	ecosystem,
+	package,
+	manifest_path,
+	opened_at,
+	fixed_at,
+	dismissed_at,
+	seconds_to_remediate
+) VALUES (
+	:codebase,
+	:number,
+	:ghsa_id,
+	:state,
+	:severity,
+	:ecosystem,
+	:package,
+	:manifest_path,
+	:opened_at,
+	:fixed_at,
+	:dismissed_at,
+	:seconds_to_remediate
+) ON CONFLICT (codebase,number) DO UPDATE SET
+	ghsa_id=excluded.ghsa_id,
+	state=excluded.state,
+	severity=excluded.severity,
+	ecosystem=excluded.ecosystem,
+	package=excluded.package,
+	manifest_path=excluded.manifest_path,
+	opened_at=excluded.opened_at,
+	fixed_at=excluded.fixed_at,
+	dismissed_at=excluded.dismissed_at,
+	seconds_to_remediate=excluded.seconds_to_remediate
+RETURNING id
+;
+`
+
+// InsertAdvisoriesStatement adds or updates a security advisory
+const InsertAdvisoriesStatement string = `
+INSERT INTO dependabot_advisories (
+	ghsa_id,
+	cve_id,
+	severity,
+	summary,
+	published_at
+) VALUES (
+	:ghsa_id,
+	:cve_id,
+	:severity,
+	:summary,
+	:published_at
+) ON CONFLICT (ghsa_id) DO UPDATE SET
+	cve_id=excluded.cve_id,
+	severity=excluded.severity,
+	summary=excluded.summary,
+	published_at=excluded.published_at
+RETURNING id
+;
+`
+
+// alert states used by github
+const (
+	StateOpen          string = "open"
+	StateFixed         string = "fixed"
+	StateDismissed     string = "dismissed"
+	StateAutoDismissed string = "auto_dismissed"
+)
+
+var ErrFailedGettingAlerts = errors.New("error getting dependabot alerts.")
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// dependabotClient wrapper around *github.DependabotService
+type dependabotClient interface {
+	// api docs - https://docs.github.com/rest/dependabot/alerts#list-dependabot-alerts-for-a-repository
+	ListRepoAlerts(ctx context.Context, owner, repo string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error)
+}
+
+type Args struct {
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams      teamClient       // *github.TeamsService
+	Dependabot dependabotClient // *github.DependabotService
+}
+
+// Alert is a single dependabot alert of a codebase
+type Alert struct {
+	Codebase           string `json:"codebase"`             // full name of codebase
+	Number             int    `json:"number"`               // alert number, unique within the codebase
+	GHSAID             string `json:"ghsa_id"`              // advisory the alert was raised for
+	State              string `json:"state"`                // open, fixed, dismissed or auto_dismissed
+	Severity           string `json:"severity"`             // critical, high, medium or low
+	Ecosystem          string `json:"ecosystem"`            // package ecosystem (npm, pip, gomod etc)
+	Package            string `json:"package"`              // name of the vulnerable package
+	ManifestPath       string `json:"manifest_path"`        // file the dependency was found in
+	OpenedAt           string `json:"opened_at"`            // when the alert was raised
+	FixedAt            string `json:"fixed_at"`             // when the alert was fixed, if it has been
+	DismissedAt        string `json:"dismissed_at"`         // when the alert was dismissed, if it has been
+	SecondsToRemediate int64  `json:"seconds_to_remediate"` // opened to fixed, only set on fixed alerts
+
+	advisory *Advisory // advisory the alert was raised for, written separately
+}
+
+// Advisory is the security advisory an alert was raised for
+type Advisory struct {
+	GHSAID      string `json:"ghsa_id"`
+	CVEID       string `json:"cve_id"`
+	Severity    string `json:"severity"`
+	Summary     string `json:"summary"`
+	PublishedAt string `json:"published_at"`
+}
+
+// Import finds all github repositories and writes their dependabot alerts & advisories
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "dependabotimport", "func", "Import")
+	var repoList []*github.Repository
+	var alerts = []*Alert{}
+	var advisories = []*Advisory{}
+	var dbArgs = &dbx.InsertArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}
+
+	log.Info("starting ...")
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	alerts, advisories, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+	// advisories first, so every alert has one
+	if err = dbx.Insert(ctx, InsertAdvisoriesStatement, advisories, dbArgs); err != nil {
+		log.Error("error writing advisories during import", "err", err.Error())
+		return
+	}
+	if err = dbx.Insert(ctx, InsertAlertsStatement, alerts, dbArgs); err != nil {
+		log.Error("error writing alerts during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.", "alerts", len(alerts), "advisories", len(advisories))
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail. Advisories are de-duplicated as the same
+// one is often raised against several codebases.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (alerts []*Alert, advisories []*Advisory, err error) {
+	var results []*workers.Result[[]*Alert]
+	var found [][]*Alert
+	var seen = map[string]bool{}
+
+	alerts = []*Alert{}
+	advisories = []*Advisory{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (list []*Alert, err error) {
+		var raw []*github.DependabotAlert
+		if raw, err = repoAlerts(ctx, clients.Dependabot, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+			return
+		}
+		list = []*Alert{}
+		for _, a := range raw {
+			list = append(list, ToAlert(repo.GetFullName(), a))
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, list := range found {
+		for _, alert := range list {
+			alerts = append(alerts, alert)
+			if !seen[alert.GHSAID] {
+				seen[alert.GHSAID] = true
+				advisories = append(advisories, alert.advisory)
+			}
+		}
+	}
+	return
+}
+
+// repoAlerts fetches all pages of alerts for the repository; the alerts api uses
+// cursor based pagination
+func repoAlerts(ctx context.Context, client dependabotClient, repo *github.Repository) (alerts []*github.DependabotAlert, err error) {
+	var (
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "dependabotimport", "func", "repoAlerts", "repo", repo.GetName())
+		opts              = &github.ListAlertsOptions{ListCursorOptions: github.ListCursorOptions{PerPage: 100}}
+	)
+	alerts = []*github.DependabotAlert{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting dependabot alerts ...")
+	for {
+		var (
+			page     []*github.DependabotAlert
+			response *github.Response
+		)
+		err = retry.Do(ctx, func() (e error) {
+			page, response, e = client.ListRepoAlerts(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opts)
+			return
+		})
+		if err != nil && disabled(response) {
+			log.Warn("dependabot alerts are not enabled, skipping.")
+			err = nil
+			return
+		} else if err != nil {
+			err = errors.Join(ErrFailedGettingAlerts, err)
+			return
+		}
+		alerts = append(alerts, page...)
+		if response == nil || response.After == "" {
+			break
+		}
+		opts.After = response.After
+	}
+	log.Info("found dependabot alerts ...", "count", len(alerts))
+	return
+}
+
+// disabled returns true when the response shows the alerts are not available for
+// the repository, rather than the call failing
+func disabled(response *github.Response) bool {
+	if response == nil || response.Response == nil {
+		return false
+	}
+	return response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusNotFound
+}
+
+// ToAlert converts the github alert into an Alert for the codebase
+func ToAlert(codebase string, a *github.DependabotAlert) (alert *Alert) {
+	var opened = a.GetCreatedAt().Time
+	alert = &Alert{
+		Codebase:     codebase,
+		Number:       a.GetNumber(),
+		GHSAID:       a.GetSecurityAdvisory().GetGHSAID(),
+		State:        a.GetState(),
+		Severity:     severity(a),
+		Ecosystem:    a.GetDependency().GetPackage().GetEcosystem(),
+		Package:      a.GetDependency().GetPackage().GetName(),
+		ManifestPath: a.GetDependency().GetManifestPath(),
+		OpenedAt:     times.AsString(opened, times.FULL),
+		advisory:     ToAdvisory(a),
+	}
+	if a.FixedAt != nil {
+		alert.FixedAt = times.AsString(a.GetFixedAt().Time, times.FULL)
+		alert.SecondsToRemediate = int64(a.GetFixedAt().Time.Sub(opened) / time.Second)
+	}
+	if a.DismissedAt != nil {
+		alert.DismissedAt = times.AsString(a.GetDismissedAt().Time, times.FULL)
+	} else if a.AutoDismissedAt != nil {
+		alert.DismissedAt = times.AsString(a.GetAutoDismissedAt().Time, times.FULL)
+	}
+	return
+}
+
+// ToAdvisory converts the security advisory of the alert
+func ToAdvisory(a *github.DependabotAlert) (advisory *Advisory) {
+	var adv = a.GetSecurityAdvisory()
+	advisory = &Advisory{
+		GHSAID:   adv.GetGHSAID(),
+		CVEID:    adv.GetCVEID(),
+		Severity: severity(a),
+		Summary:  adv.GetSummary(),
+	}
+	if adv.PublishedAt != nil {
+		advisory.PublishedAt = times.AsString(adv.GetPublishedAt().Time, times.FULL)
+	}
+	return
+}
+
+// severity uses the advisory severity, falling back to that of the vulnerability
+func severity(a *github.DependabotAlert) (s string) {
+	if s = a.GetSecurityAdvisory().GetSeverity(); s == "" {
+		s = a.GetSecurityVulnerability().GetSeverity()
+	}
+	return
+}
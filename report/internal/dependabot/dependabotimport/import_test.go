@@ -0,0 +1,97 @@
+package dependabotimport
+
+import (
+	"context"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/times"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockDependabot returns a page of alerts per cursor; repos named "disabled" have
+// alerts turned off
+type mockDependabot struct {
+	pages map[string][]*github.DependabotAlert
+	calls int
+}
+
+func (self *mockDependabot) ListRepoAlerts(ctx context.Context, owner, repo string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error) {
+	self.calls++
+	if repo == "disabled" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusForbidden}}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Dependabot alerts are disabled for this repository."}
+	}
+	resp := &github.Response{}
+	if opts.After == "" {
+		resp.After = "next"
+	}
+	return self.pages[opts.After], resp, nil
+}
+
+func alert(number int, ghsa string, state string, opened time.Time, fixed *time.Time) *github.DependabotAlert {
+	var a = &github.DependabotAlert{
+		Number: github.Ptr(number),
+		State:  github.Ptr(state),
+		Dependency: &github.Dependency{
+			Package:      &github.VulnerabilityPackage{Ecosystem: github.Ptr("npm"), Name: github.Ptr("left-pad")},
+			ManifestPath: github.Ptr("package-lock.json"),
+		},
+		SecurityAdvisory: &github.DependabotSecurityAdvisory{
+			GHSAID:   github.Ptr(ghsa),
+			CVEID:    github.Ptr("CVE-" + ghsa),
+			Severity: github.Ptr("high"),
+		},
+		CreatedAt: &github.Timestamp{Time: opened},
+	}
+	if fixed != nil {
+		a.FixedAt = &github.Timestamp{Time: *fixed}
+	}
+	return a
+}
+
+func TestDependabotImportHandler(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		opened = times.MustFromString("2025-01-10")
+		fixed  = opened.Add(48 * time.Hour)
+		owner  = &github.User{Login: github.Ptr("org")}
+		client = &mockDependabot{pages: map[string][]*github.DependabotAlert{
+			"":     {alert(1, "GHSA-1", StateOpen, opened, nil)},
+			"next": {alert(2, "GHSA-1", StateFixed, opened, &fixed), alert(3, "GHSA-2", StateOpen, opened, nil)},
+		}}
+		repoList = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner},
+			{Name: github.Ptr("disabled"), FullName: github.Ptr("org/disabled"), Owner: owner},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, Archived: github.Ptr(true)},
+		}
+	)
+	alerts, advisories, err := handler(ctx, &Clients{Dependabot: client}, &Args{}, repoList)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// both pages of the first repo, disabled and archived are skipped without error
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, found [%d]", len(alerts))
+	}
+	if len(advisories) != 2 {
+		t.Errorf("expected advisories to be de-duplicated, found [%d]", len(advisories))
+	}
+	if client.calls != 3 {
+		t.Errorf("expected 3 api calls, found [%d]", client.calls)
+	}
+	for _, a := range alerts {
+		if a.Codebase != "org/repo" || a.Severity != "high" || a.Package != "left-pad" || a.Ecosystem != "npm" {
+			t.Errorf("unexpected alert: %+v", a)
+		}
+		if a.State == StateFixed && a.SecondsToRemediate != int64((48*time.Hour).Seconds()) {
+			t.Errorf("expected fixed alert to have time to remediate: %+v", a)
+		}
+		if a.State == StateOpen && (a.SecondsToRemediate != 0 || a.FixedAt != "") {
+			t.Errorf("open alert should not be remediated: %+v", a)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package doraapi
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt is the sql used to fetch the totals for each month; averages and
+// rates are calculated from these by Model.Calculate
+const selectStmt string = `
+SELECT
+	codebase_dora.month,
+	'' as codebase,
+	COALESCE(SUM(codebase_dora.deployments),0) as deployments,
+	COALESCE(SUM(codebase_dora.failed_deployments),0) as failed_deployments,
+	COALESCE(SUM(codebase_dora.reverts),0) as reverts,
+	COALESCE(SUM(codebase_dora.hotfixes),0) as hotfixes,
+	COALESCE(SUM(codebase_dora.lead_time_seconds),0) as lead_time_seconds,
+	COALESCE(SUM(codebase_dora.lead_time_changes),0) as lead_time_changes,
+	COALESCE(SUM(codebase_dora.restore_seconds),0) as restore_seconds,
+	COALESCE(SUM(codebase_dora.restores),0) as restores
+FROM codebase_dora
+LEFT JOIN codebases on codebases.full_name = codebase_dora.codebase
+WHERE
+	codebases.archived = 0
+	AND codebase_dora.month IN (:months)
+GROUP BY
+	codebase_dora.month
+ORDER BY
+	codebase_dora.month DESC
+;
+`
+
+// selectByCodebaseStmt is the same as selectStmt, but split by codebase as
+// well as month (?split=codebase)
+const selectByCodebaseStmt string = `
+SELECT
+	codebase_dora.month,
+	codebase_dora.codebase,
+	COALESCE(SUM(codebase_dora.deployments),0) as deployments,
+	COALESCE(SUM(codebase_dora.failed_deployments),0) as failed_deployments,
+	COALESCE(SUM(codebase_dora.reverts),0) as reverts,
+	COALESCE(SUM(codebase_dora.hotfixes),0) as hotfixes,
+	COALESCE(SUM(codebase_dora.lead_time_seconds),0) as lead_time_seconds,
+	COALESCE(SUM(codebase_dora.lead_time_changes),0) as lead_time_changes,
+	COALESCE(SUM(codebase_dora.restore_seconds),0) as restore_seconds,
+	COALESCE(SUM(codebase_dora.restores),0) as restores
+FROM codebase_dora
+LEFT JOIN codebases on codebases.full_name = codebase_dora.codebase
+WHERE
+	codebases.archived = 0
+	AND codebase_dora.month IN (:months)
+GROUP BY
+	codebase_dora.month,
+	codebase_dora.codebase
+ORDER BY
+	codebase_dora.month DESC,
+	codebase_dora.codebase ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE codebase_dora.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// splitByCodebase is the `split` query value that returns a row per codebase & month
+const splitByCodebase string = "codebase"
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"`  // optional team filter, rolled up via codebase_owners
+	Org       string `json:"org"`   // optional github org filter (?org=)
+	Split     string `json:"split"` // optional; `codebase` to return a row per codebase & month (?split=codebase)
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Model   `json:"summary"` // metrics over the whole period
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+	Org    string   `json:"org"`
+}
+
+// Model is the data struct to use when fetching the select; the totals are
+// selected and the metrics calculated from them
+type Model struct {
+	Month             string `json:"month,omitempty"`    // month as YYYY-MM string
+	Codebase          string `json:"codebase,omitempty"` // only set when split by codebase
+	Deployments       int    `json:"deployments"`
+	FailedDeployments int    `json:"failed_deployments"`
+	Reverts           int    `json:"reverts"`
+	Hotfixes          int    `json:"hotfixes"`
+	LeadTimeSeconds   int64  `json:"lead_time_seconds"`
+	LeadTimeChanges   int    `json:"lead_time_changes"`
+	RestoreSeconds    int64  `json:"restore_seconds"`
+	Restores          int    `json:"restores"`
+
+	DeploymentFrequency float64 `json:"deployment_frequency"`  // average deployments per month
+	LeadTimeHours       float64 `json:"lead_time_hours"`       // average hours from merge to deployment
+	ChangeFailureRate   float64 `json:"change_failure_rate"`   // percentage of deployments that failed, were reverted or needed a hotfix
+	TimeToRestoreHours  float64 `json:"time_to_restore_hours"` // average hours from a failed deployment to the next successful one
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Month,
+		&self.Codebase,
+		&self.Deployments,
+		&self.FailedDeployments,
+		&self.Reverts,
+		&self.Hotfixes,
+		&self.LeadTimeSeconds,
+		&self.LeadTimeChanges,
+		&self.RestoreSeconds,
+		&self.Restores,
+	}
+}
+
+// Add includes the totals of other within this model
+func (self *Model) Add(other *Model) {
+	self.Deployments += other.Deployments
+	self.FailedDeployments += other.FailedDeployments
+	self.Reverts += other.Reverts
+	self.Hotfixes += other.Hotfixes
+	self.LeadTimeSeconds += other.LeadTimeSeconds
+	self.LeadTimeChanges += other.LeadTimeChanges
+	self.RestoreSeconds += other.RestoreSeconds
+	self.Restores += other.Restores
+}
+
+// Calculate sets the metrics from the totals, which cover the number of months passed
+func (self *Model) Calculate(months int) {
+	var attempts = self.Deployments + self.FailedDeployments
+	if months > 0 {
+		self.DeploymentFrequency = float64(self.Deployments) / float64(months)
+	}
+	if self.LeadTimeChanges > 0 {
+		self.LeadTimeHours = float64(self.LeadTimeSeconds) / float64(self.LeadTimeChanges) / 3600
+	}
+	if attempts > 0 {
+		self.ChangeFailureRate = float64(self.FailedDeployments+self.Reverts+self.Hotfixes) / float64(attempts) * 100
+	}
+	if self.Restores > 0 {
+		self.TimeToRestoreHours = float64(self.RestoreSeconds) / float64(self.Restores) / 3600
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		months   []string
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  *Model                 = &Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "doraapi", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	filter.Months = months
+	if in.Split == splitByCodebase {
+		stmt = selectByCodebaseStmt
+	}
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	// each row covers a single month
+	for _, r := range all {
+		r.Calculate(1)
+		summary.Add(r)
+	}
+	summary.Calculate(len(months))
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
@@ -0,0 +1,79 @@
+package doraapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoraAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+		end    = times.ResetMonth(times.Today())
+		start  = times.Add(end, -3, times.MONTH)
+		base   = strings.NewReplacer("{date_start}", times.AsYMDString(start), "{date_end}", times.AsYMDString(end))
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, base.Replace(ENDPOINT_BASE), nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if months := times.Months(start, end); len(all.Data) != len(months) {
+		t.Errorf("expected a row per month, found [%d] expected [%d]", len(all.Data), len(months))
+	}
+	if all.Summary.Deployments == 0 || all.Summary.LeadTimeHours <= 0 || all.Summary.ChangeFailureRate <= 0 {
+		t.Errorf("expected summary metrics to be calculated: %+v", all.Summary)
+	}
+
+	// team rollup is a subset of all codebases
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(base.Replace(ENDPOINT_TEAM), "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if team.Summary.Deployments == 0 || team.Summary.Deployments >= all.Summary.Deployments {
+		t.Errorf("expected team to have a subset of deployments, got [%d] of [%d]", team.Summary.Deployments, all.Summary.Deployments)
+	}
+
+	// split by codebase
+	split := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, base.Replace(ENDPOINT_BASE)+"?split=codebase", nil))
+	if err = response.As(writer.Result(), &split); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(split.Data) <= len(all.Data) || split.Data[0].Codebase == "" {
+		t.Errorf("expected rows per codebase, found [%d]", len(split.Data))
+	}
+	if split.Summary.Deployments != all.Summary.Deployments {
+		t.Errorf("split summary should match, got [%d] expected [%d]", split.Summary.Deployments, all.Summary.Deployments)
+	}
+}
@@ -0,0 +1,138 @@
+package dorafront
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/dora/doraapi"
+	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
+	"opg-reports/report/internal/team/teamapi/teamapiall"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/htmlpage"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/rest"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/tmpl"
+	"sync"
+)
+
+type PageContent struct {
+	htmlpage.HTMLPage
+	Team     string
+	DoraData *frontmodels.DoraData
+	Dates    *frontmodels.DateRanges
+}
+
+type dataCallerF func(wg *sync.WaitGroup, page *PageContent)
+
+// Handler deals with the dora metrics page for all codebases or those of a team
+func Handler(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request, writer http.ResponseWriter) {
+	var (
+		page         *PageContent
+		templateName string
+		team         string         = request.PathValue("team")
+		wg           sync.WaitGroup = sync.WaitGroup{}
+		log          *slog.Logger   = cntxt.GetLogger(ctx).With("package", "dorafront", "func", "Handler", "url", request.URL.String())
+	)
+	log.Info("starting ...")
+	page, templateName = getPage(team, args, request)
+	if team != "" {
+		log.Info("found team parameter ... ", "team", team)
+	}
+	// page data fetched from api via blocks
+	for _, blockF := range dataCallers(ctx, args, request) {
+		wg.Add(1)
+		go blockF(&wg, page)
+	}
+	wg.Wait()
+
+	// respond
+	respond.AsHTML(ctx, request, writer, page, &respond.Args{
+		Template:      templateName,
+		TemplateFiles: tmpl.GetTemplateFiles(args.TemplateDir),
+		Funcs:         tmpl.TemplateFunctions(),
+	})
+	log.Info("complete.")
+}
+
+func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (page *PageContent, template string) {
+	var args *htmlpage.Args = &htmlpage.Args{
+		Name:         "OPG Reports",
+		Title:        "OPG Reports - DORA Metrics",
+		GovUKVersion: in.GovUKVersion,
+		SemVer:       in.SemVer,
+	}
+	template = "dora"
+	if team != "" {
+		args.Title += " - " + cnv.Capitalize(team)
+	}
+	page = &PageContent{
+		HTMLPage: htmlpage.New(request, args),
+		Team:     team,
+	}
+	return
+}
+
+// dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
+func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
+	var (
+		team         = request.PathValue("team")
+		doraEndpoint = doraapi.ENDPOINT_BASE
+		dateEnd      = times.ResetMonth(times.Today()) // use this month
+		dateStart    = times.Add(dateEnd, -5, times.MONTH)
+		params       = []*rest.Param{
+			{Type: rest.PATH, Key: "date_end", Value: times.AsYMString(dateEnd)},
+			{Type: rest.PATH, Key: "date_start", Value: times.AsYMString(dateStart)},
+			// optional org filter passed through from the front end request
+			{Type: rest.QUERY, Key: "org"},
+		}
+	)
+	// add team filter values and url
+	if team != "" {
+		doraEndpoint = doraapi.ENDPOINT_TEAM
+		params = append(params, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
+	}
+
+	funcs = []dataCallerF{
+		// get teams
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*teamapiall.Response](ctx, args.ApiHost, teamapiall.ENDPOINT, request)
+			if err == nil {
+				page.Teams = resp.Data
+			}
+			wg.Done()
+		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
+		// get dora metrics by month
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*doraapi.Response](ctx, args.ApiHost, doraEndpoint, request, params...)
+			if err == nil {
+				metrics := []*frontmodels.Dora{}
+				summary := &frontmodels.Dora{}
+				// convert to front end version
+				cnv.Convert(resp.Data, &metrics)
+				cnv.Convert(resp.Summary, &summary)
+				page.DoraData = &frontmodels.DoraData{
+					Team:    team,
+					Metrics: metrics,
+					Summary: summary,
+				}
+				page.Dates = &frontmodels.DateRanges{
+					DateStart: resp.Request.DateStart,
+					DateEnd:   resp.Request.DateEnd,
+					Months: times.AsYMStrings(
+						times.Months(times.Add(times.Today(), -12, times.MONTH), times.Today()),
+					),
+				}
+			}
+			wg.Done()
+		},
+	}
+	return
+}
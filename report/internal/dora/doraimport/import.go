@@ -0,0 +1,399 @@
+// Package doraimport calculates the four DORA metrics for each codebase and month.
+//
+// Completed "path to live" workflow runs against the default branch are used as
+// deployments; merged pull requests provide the start of the lead time and identify
+// hotfixes:
+//
+//   - deployments: successful path to live runs
+//   - lead time: time from a pull request being merged to the completion of the
+//     first successful path to live run containing it (matched on the merge commit,
+//     or the next run after the merge)
+//   - change failure rate: failed path to live runs, reverts (successful runs whose
+//     head commit is a `Revert "..."`) and hotfix pull requests
+//   - time to restore: time from the first failed path to live run in a streak to the
+//     completion of the next successful one
+//
+// Only totals are stored (see `codebase_dora`) so the api can roll months, codebases
+// and teams up without averaging averages.
+//
+// Runs and pull requests are fetched from a month before the start date so changes
+// merged just before the period, but deployed within it, still have a lead time while
+// those already deployed before it are matched to that earlier run. Only months within
+// the period are returned.
+package doraimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement replaces the totals for the codebase & month
+const InsertStatement string = `
+INSERT INTO codebase_dora (
+	codebase,
+	month,
+	deployments,
+	failed_deployments,
+	reverts,
+	hotfixes,
+	lead_time_seconds,
+	lead_time_changes,
+	restore_seconds,
+	restores
+) VALUES (
+	:codebase,
+	:month,
+	:deployments,
+	:failed_deployments,
+	:reverts,
+	:hotfixes,
+	:lead_time_seconds,
+	:lead_time_changes,
+	:restore_seconds,
+	:restores
+) ON CONFLICT (codebase,month) DO UPDATE SET
+	deployments=excluded.deployments,
+	failed_deployments=excluded.failed_deployments,
+	reverts=excluded.reverts,
+	hotfixes=excluded.hotfixes,
+	lead_time_seconds=excluded.lead_time_seconds,
+	lead_time_changes=excluded.lead_time_changes,
+	restore_seconds=excluded.restore_seconds,
+	restores=excluded.restores
+RETURNING id
+;
+`
+
+// deleteRangeStmt removes the totals of a codebase for the months between the
+// first and last month (inclusive)
+const deleteRangeStmt string = `DELETE FROM codebase_dora WHERE codebase = ? AND month >= ? AND month <= ?;`
+
+// PathToLive is the (lowercase) name workflows must contain to count as a deployment
+const PathToLive string = "path to live"
+
+// conclusions of path to live runs that are used; others (cancelled, skipped) are ignored
+const (
+	conclusionSuccess string = "success"
+	conclusionFailure string = "failure"
+)
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// actionClient wrapper for *github.ActionsService
+type actionClient interface {
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error)
+}
+
+// prClient is a wrapper for *github.PullRequestsService
+type prClient interface {
+	List(ctx context.Context, owner string, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+}
+
+type Args struct {
+	DB           string    `json:"db"`             // database path
+	Driver       string    `json:"driver"`         // database driver
+	Params       string    `json:"params"`         // database connection params
+	OrgSlug      string    `json:"org_slug"`       // github org name
+	ParentSlug   string    `json:"parent_slug"`    // parent slug
+	Sources      []string  `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool      `json:"recursive"`      // include repositories of all child teams
+	FilterByName string    `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	DateStart    time.Time `json:"date_start"`     // start date
+	DateEnd      time.Time `json:"date_end"`       // end date
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams   teamClient   // *github.TeamsService
+	Actions actionClient // *github.ActionsService
+	PR      prClient     // *github.PullRequestService
+}
+
+// Model is the totals for a codebase & month
+type Model struct {
+	Codebase          string `json:"codebase"`           // full name of codebase
+	Month             string `json:"month"`              // month as YYYY-MM string
+	Deployments       int    `json:"deployments"`        // successful path to live runs
+	FailedDeployments int    `json:"failed_deployments"` // failed path to live runs
+	Reverts           int    `json:"reverts"`            // successful path to live runs of a revert commit
+	Hotfixes          int    `json:"hotfixes"`           // merged hotfix pull requests
+	LeadTimeSeconds   int64  `json:"lead_time_seconds"`  // total lead time of changes deployed this month
+	LeadTimeChanges   int    `json:"lead_time_changes"`  // number of changes included in the lead time
+	RestoreSeconds    int64  `json:"restore_seconds"`    // total time to restore of failures starting this month
+	Restores          int    `json:"restores"`           // number of restores included in the restore time
+}
+
+// Import finds all github repositories and writes the dora totals for each month,
+// replacing those already stored for the months within the date range
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "doraimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+	var done = []string{}
+
+	log.Info("starting ...", "date_start", times.AsYMDString(in.DateStart), "date_end", times.AsYMDString(in.DateEnd))
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, done, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	if err = removeExisting(ctx, in, done); err != nil {
+		log.Error("error removing existing totals", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.")
+	return
+}
+
+// removeExisting deletes the stored totals of each processed repository (done)
+// for the months within the date range, so a month that no longer has any
+// activity does not keep its old totals. A repository that failed keeps its
+// previous totals.
+func removeExisting(ctx context.Context, in *Args, done []string) (err error) {
+	var (
+		args  = &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}
+		first = times.AsYMString(in.DateStart)
+		last  = times.AsYMString(in.DateEnd.Add(-time.Second)) // end is exclusive
+	)
+	for _, codebase := range done {
+		if err = dbx.Exec(ctx, deleteRangeStmt, args, codebase, first, last); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail. done is the full name of each repository
+// that was processed.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*Model, done []string, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+
+	data = []*Model{}
+	done = []string{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (metrics []*Model, err error) {
+		if metrics, err = repoMetrics(ctx, clients, in, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, repo := range workers.Succeeded(repoList, results) {
+		done = append(done, repo.GetFullName())
+	}
+	for _, metrics := range found {
+		data = append(data, metrics...)
+	}
+	return
+}
+
+// repoMetrics fetches the path to live runs and merged pull requests of the
+// repository and calculates the totals from them
+func repoMetrics(ctx context.Context, clients *Clients, in *Args, repo *github.Repository) (metrics []*Model, err error) {
+	var (
+		log      *slog.Logger = cntxt.GetLogger(ctx).With("package", "doraimport", "func", "repoMetrics", "repo", repo.GetName())
+		runs     []*github.WorkflowRun
+		prs      []*github.PullRequest
+		lookback time.Time = times.Add(in.DateStart, -1, times.MONTH)
+	)
+	metrics = []*Model{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting path to live runs ...")
+	runs, err = repos.GetWorkflowRuns(ctx, clients.Actions, repo, &repos.Args{
+		DateStart:    lookback,
+		DateEnd:      in.DateEnd,
+		FilterByName: PathToLive,
+		Status:       "completed",
+	}, true)
+	if err != nil {
+		return
+	}
+	// nothing is deployed via a workflow, so there is nothing to measure
+	if len(runs) == 0 {
+		log.Info("no path to live runs found.")
+		return
+	}
+	log.Info("getting merged pull requests ...")
+	prs, err = repos.GetMergedPRs(ctx, clients.PR, repo, &repos.Args{
+		DateStart: lookback,
+		DateEnd:   in.DateEnd,
+	})
+	if err != nil {
+		return
+	}
+	metrics = Calculate(repo.GetFullName(), runs, prs, in.DateStart, in.DateEnd)
+	log.Info("found metrics ...", "months", len(metrics))
+	return
+}
+
+// Calculate returns the totals for each month of the codebase from its completed path
+// to live runs and merged pull requests. Runs and pull requests outside of the date
+// range are only used to work out what was deployed when; totals are only kept for
+// months within it.
+func Calculate(codebase string, runs []*github.WorkflowRun, prs []*github.PullRequest, start time.Time, end time.Time) (metrics []*Model) {
+	var (
+		byMonth   = map[string]*Model{}
+		successes = []*github.WorkflowRun{}
+		failedAt  *time.Time
+	)
+	// totals for times outside of the range are discarded
+	var month = func(t time.Time) *Model {
+		if t.Before(start) || !t.Before(end) {
+			return &Model{}
+		}
+		var m = times.AsYMString(t)
+		if _, ok := byMonth[m]; !ok {
+			byMonth[m] = &Model{Codebase: codebase, Month: m}
+		}
+		return byMonth[m]
+	}
+	metrics = []*Model{}
+
+	runs = slices.Clone(runs)
+	slices.SortFunc(runs, func(a, b *github.WorkflowRun) int {
+		return a.GetCreatedAt().Time.Compare(b.GetCreatedAt().Time)
+	})
+	// deployments, failures and time to restore
+	for _, run := range runs {
+		var created = run.GetCreatedAt().Time
+		switch run.GetConclusion() {
+		case conclusionSuccess:
+			var m = month(created)
+			successes = append(successes, run)
+			m.Deployments += 1
+			if isRevert(run) {
+				m.Reverts += 1
+			}
+			if failedAt != nil {
+				var r = month(*failedAt)
+				r.Restores += 1
+				r.RestoreSeconds += int64(completedAt(run).Sub(*failedAt).Seconds())
+				failedAt = nil
+			}
+		case conclusionFailure:
+			month(created).FailedDeployments += 1
+			// only the first failure of a streak starts the clock
+			if failedAt == nil {
+				failedAt = &created
+			}
+		}
+	}
+	// lead time and hotfixes
+	for _, pr := range prs {
+		var merged time.Time
+		var deploy *github.WorkflowRun
+		if pr.MergedAt == nil {
+			continue
+		}
+		merged = pr.GetMergedAt().Time
+		if isHotfix(pr) {
+			month(merged).Hotfixes += 1
+		}
+		if deploy = deployedBy(pr, successes); deploy != nil {
+			var m = month(deploy.GetCreatedAt().Time)
+			m.LeadTimeChanges += 1
+			m.LeadTimeSeconds += int64(completedAt(deploy).Sub(merged).Seconds())
+		}
+	}
+
+	for _, m := range byMonth {
+		metrics = append(metrics, m)
+	}
+	slices.SortFunc(metrics, func(a, b *Model) int {
+		return strings.Compare(a.Month, b.Month)
+	})
+	return
+}
+
+// deployedBy returns the first successful run that included the pull request; the
+// run of the merge commit when there is one, otherwise the first run after the merge
+func deployedBy(pr *github.PullRequest, successes []*github.WorkflowRun) (deploy *github.WorkflowRun) {
+	var merged = pr.GetMergedAt().Time
+	for _, run := range successes {
+		if pr.GetMergeCommitSHA() != "" && run.GetHeadSHA() == pr.GetMergeCommitSHA() {
+			return run
+		}
+	}
+	for _, run := range successes {
+		if !run.GetCreatedAt().Time.Before(merged) {
+			return run
+		}
+	}
+	return
+}
+
+// completedAt returns when the run finished; runs are not updated once completed
+func completedAt(run *github.WorkflowRun) time.Time {
+	if run.UpdatedAt != nil {
+		return run.GetUpdatedAt().Time
+	}
+	return run.GetCreatedAt().Time
+}
+
+// isRevert returns true when the run deployed a revert commit
+func isRevert(run *github.WorkflowRun) bool {
+	if run.HeadCommit == nil {
+		return false
+	}
+	return strings.HasPrefix(run.HeadCommit.GetMessage(), `Revert "`)
+}
+
+// isHotfix returns true when the title or branch name mentions a hotfix
+func isHotfix(pr *github.PullRequest) bool {
+	var title = strings.ToLower(pr.GetTitle())
+	var branch string
+	if pr.Head != nil {
+		branch = strings.ToLower(pr.Head.GetRef())
+	}
+	return strings.Contains(title, "hotfix") || strings.Contains(branch, "hotfix")
+}
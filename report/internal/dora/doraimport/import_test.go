@@ -0,0 +1,162 @@
+package doraimport
+
+import (
+	"database/sql"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+func run(sha string, conclusion string, created time.Time, took time.Duration, message string) *github.WorkflowRun {
+	return &github.WorkflowRun{
+		HeadSHA:    github.Ptr(sha),
+		Conclusion: github.Ptr(conclusion),
+		CreatedAt:  &github.Timestamp{Time: created},
+		UpdatedAt:  &github.Timestamp{Time: created.Add(took)},
+		HeadCommit: &github.HeadCommit{Message: github.Ptr(message)},
+	}
+}
+
+func pr(sha string, title string, merged time.Time) *github.PullRequest {
+	return &github.PullRequest{
+		Title:          github.Ptr(title),
+		MergeCommitSHA: github.Ptr(sha),
+		MergedAt:       &github.Timestamp{Time: merged},
+		Head:           &github.PullRequestBranch{Ref: github.Ptr("feature")},
+	}
+}
+
+func TestDoraImportCalculate(t *testing.T) {
+	var (
+		start = times.MustFromString("2025-01-01")
+		end   = times.MustFromString("2025-03-01")
+		jan   = start.Add(10 * 24 * time.Hour)
+		feb   = times.MustFromString("2025-02-10")
+	)
+	var runs = []*github.WorkflowRun{
+		run("a", "success", jan, 10*time.Minute, "change a"),
+		// failure streak in january, restored in february
+		run("b", "failure", jan.Add(2*time.Hour), 5*time.Minute, "change b"),
+		run("c", "failure", jan.Add(3*time.Hour), 5*time.Minute, "change c"),
+		run("d", "success", feb, time.Hour, `Revert "change b"`),
+		run("e", "cancelled", feb.Add(time.Hour), time.Minute, "ignored"),
+	}
+	var prs = []*github.PullRequest{
+		// merged in december, deployed in january
+		pr("a", "change a", jan.Add(-24*time.Hour)),
+		// no run for its commit, so the next successful run after the merge
+		pr("x", "hotfix: something", jan.Add(4*time.Hour)),
+	}
+
+	metrics := Calculate("org/repo", runs, prs, start, end)
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 months, found [%d]", len(metrics))
+	}
+	j, f := metrics[0], metrics[1]
+	if j.Month != "2025-01" || f.Month != "2025-02" {
+		t.Fatalf("unexpected months: [%s] [%s]", j.Month, f.Month)
+	}
+	if j.Deployments != 1 || j.FailedDeployments != 2 || f.Deployments != 1 || f.Reverts != 1 {
+		t.Errorf("unexpected deployment counts: %+v %+v", j, f)
+	}
+	if j.Hotfixes != 1 || f.Hotfixes != 0 {
+		t.Errorf("expected hotfix in january: %+v %+v", j, f)
+	}
+	// lead time of a: 24h + 10m
+	if j.LeadTimeChanges != 1 || j.LeadTimeSeconds != int64((24*time.Hour+10*time.Minute).Seconds()) {
+		t.Errorf("unexpected january lead time: %+v", j)
+	}
+	// hotfix deployed by d: completes at feb + 1h
+	if want := int64(feb.Add(time.Hour).Sub(jan.Add(4 * time.Hour)).Seconds()); f.LeadTimeChanges != 1 || f.LeadTimeSeconds != want {
+		t.Errorf("unexpected february lead time: %+v, expected [%d]", f, want)
+	}
+	// restore is from the first failure to the revert completing, counted in january
+	if want := int64(feb.Add(time.Hour).Sub(jan.Add(2 * time.Hour)).Seconds()); j.Restores != 1 || j.RestoreSeconds != want {
+		t.Errorf("unexpected restore: %+v, expected [%d]", j, want)
+	}
+}
+
+func TestDoraImportCalculateLookback(t *testing.T) {
+	var (
+		start = times.MustFromString("2025-01-01")
+		end   = times.MustFromString("2025-02-01")
+		dec   = times.MustFromString("2024-12-10")
+		jan   = times.MustFromString("2025-01-10")
+	)
+	var runs = []*github.WorkflowRun{
+		// lookback runs: a deployed & a failure restored in january
+		run("a", "success", dec, 10*time.Minute, "change a"),
+		run("f", "failure", dec.Add(24*time.Hour), 5*time.Minute, "change f"),
+		run("b", "success", jan, 10*time.Minute, "change b"),
+	}
+	var prs = []*github.PullRequest{
+		// merged & deployed before the period
+		pr("a", "change a", dec.Add(-time.Hour)),
+		// merged before the period without its own run, so deployed by the next run in december
+		pr("x", "hotfix: x", dec.Add(-2*time.Hour)),
+		// merged before the period, deployed within it
+		pr("b", "change b", jan.Add(-48*time.Hour)),
+	}
+
+	metrics := Calculate("org/repo", runs, prs, start, end)
+	if len(metrics) != 1 || metrics[0].Month != "2025-01" {
+		t.Fatalf("expected only january, found %+v", metrics)
+	}
+	j := metrics[0]
+	if j.Deployments != 1 || j.FailedDeployments != 0 || j.Hotfixes != 0 || j.Restores != 0 {
+		t.Errorf("unexpected counts: %+v", j)
+	}
+	// only b has a lead time in january
+	if want := int64((48*time.Hour + 10*time.Minute).Seconds()); j.LeadTimeChanges != 1 || j.LeadTimeSeconds != want {
+		t.Errorf("unexpected lead time: %+v, expected [%d]", j, want)
+	}
+}
+
+// TestDoraImportRemoveExisting checks only the months within the date range of
+// the processed codebases are removed
+func TestDoraImportRemoveExisting(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-dora.db")
+		in     = &Args{DB: dbpath, Driver: "sqlite3", DateStart: times.MustFromString("2025-01-01"), DateEnd: times.MustFromString("2025-03-01")}
+		data   = []*Model{
+			{Codebase: "org/a", Month: "2024-12", Deployments: 1},
+			{Codebase: "org/a", Month: "2025-01", Deployments: 1},
+			{Codebase: "org/a", Month: "2025-02", Deployments: 1},
+			{Codebase: "org/a", Month: "2025-03", Deployments: 1},
+			{Codebase: "org/b", Month: "2025-01", Deployments: 1},
+		}
+		found = []string{}
+	)
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// org/b failed, so keeps its totals
+	if err := removeExisting(ctx, in, []string{"org/a"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	dbx.Select(ctx, `SELECT codebase, month FROM codebase_dora ORDER BY codebase, month ASC;`, &dbx.SelectArgs{
+		DB: in.DB, Driver: in.Driver,
+		ScanF: func(rows *sql.Rows) (err error) {
+			var c, m string
+			if err = rows.Scan(&c, &m); err == nil {
+				found = append(found, c+"@"+m)
+			}
+			return
+		},
+	})
+	if got := strings.Join(found, ","); got != "org/a@2024-12,org/a@2025-03,org/b@2025-01" {
+		t.Errorf("unexpected remaining totals: [%s]", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"opg-reports/report/internal/codeowners/codeownersapi"
 	"opg-reports/report/internal/global/frontmodels"
 	"opg-reports/report/internal/headline/headlineapi/headlineapi"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/cnv"
@@ -110,6 +111,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get code ownership items
 		func(wg *sync.WaitGroup, page *PageContent) {
 			// if there is now team, then push a team of `none` to find those without owners
@@ -139,6 +145,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 					CodebasePassed:      resp.Data.CodebasePassed,
 					Releases:            resp.Data.Releases,
 					ReleasesSecurityish: resp.Data.ReleasesSecurityish,
+					// security alerts
+					CodeScanningOpen:         resp.Data.CodeScanningOpen,
+					CodeScanningOpenSevere:   resp.Data.CodeScanningOpenSevere,
+					SecretScanningOpen:       resp.Data.SecretScanningOpen,
+					SecretScanningOpenSevere: resp.Data.SecretScanningOpenSevere,
 				}
 				// also set date values
 				page.Dates = &frontmodels.DateRanges{
@@ -7,6 +7,7 @@ import (
 	"opg-reports/report/internal/codebasereleases/codebasereleasesapi"
 	"opg-reports/report/internal/cost/costapi/costapiteam"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/internal/uptime/uptimeapi/uptimeapiteam"
 	"opg-reports/report/package/cntxt"
@@ -64,7 +65,7 @@ func Handler(ctx context.Context, args *frontmodels.RegisterArgs, request *http.
 // dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
 func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) []dataCallerF {
 	var (
-		billingDay = 15
+		billingDay = args.BillingDay
 		dateEnd    = times.Add(times.ResetMonth(times.Today()), -1, times.MONTH) // home page uses last complete month
 		dateStart  = times.Add(dateEnd, -5, times.MONTH)                         // show 6 months
 		params     = []*rest.Param{
@@ -82,6 +83,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get release stats grouped by team
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*codebasereleasesapi.Response](ctx, args.ApiHost, codebasereleasesapi.ENDPOINT_BASE, request, params...)
@@ -1,5 +1,8 @@
 package apimodels
 
+// DefaultExcludedServices are left out of cost totals when none are configured
+var DefaultExcludedServices = []string{"Tax"}
+
 // Config contains required values for DB and others to generate a response
 type Args struct {
 	DB      string `json:"db"`
@@ -7,4 +10,15 @@ type Args struct {
 	Params  string `json:"params"`
 	Version string `json:"version"`
 	SHA     string `json:"sha"`
+
+	ExcludedServices []string `json:"excluded_services"` // cost services to leave out of totals
+}
+
+// Excluded returns the configured excluded services, or the defaults if
+// none are set
+func (self *Args) Excluded() []string {
+	if len(self.ExcludedServices) == 0 {
+		return DefaultExcludedServices
+	}
+	return self.ExcludedServices
 }
@@ -0,0 +1,226 @@
+// Package backfill splits a long date range into month sized chunks and calls
+// an import function for each one, recording completed chunks in the `backfills`
+// table so an interrupted backfill can resume where it stopped.
+//
+// The current month is never recorded as complete, as more data will arrive
+// for it, so it is always re-imported.
+package backfill
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/times"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const InsertStatement string = `
+INSERT INTO backfills (
+	dataset,
+	month
+) VALUES (
+	:dataset,
+	:month
+) ON CONFLICT (dataset,month) DO NOTHING
+RETURNING id
+;
+`
+
+const selectStmt string = `
+SELECT
+	month
+FROM backfills
+WHERE dataset = :dataset
+;
+`
+
+const deleteStmt string = `DELETE FROM backfills WHERE dataset = ?;`
+
+var (
+	ErrInvalidRange    = errors.New("backfill start date must be before the end date.")
+	ErrFailedChunk     = errors.New("backfill chunk failed with error.")
+	ErrFailedRecording = errors.New("failed to record backfill progress with error.")
+)
+
+// Model represents a completed chunk in the backfills table
+type Model struct {
+	Dataset string `json:"dataset"`
+	Month   string `json:"month"`
+}
+
+// Chunk is a single month of the backfill; End is exclusive
+type Chunk struct {
+	Month   string    `json:"month"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Partial bool      `json:"partial"` // true when the chunk has been cut short by the current date
+}
+
+// ChunkF imports the data for a single chunk
+type ChunkF func(ctx context.Context, chunk *Chunk) (err error)
+
+// WaitF is called before each chunk and should block until it is ok to continue,
+// used to respect api rate limits
+type WaitF func(ctx context.Context) (err error)
+
+type Args struct {
+	DB     string `json:"db"`     // database path
+	Driver string `json:"driver"` // database driver
+	Params string `json:"params"` // database connection params
+
+	Dataset string        `json:"dataset"` // name of the dataset being backfilled, used to track progress
+	From    time.Time     `json:"from"`    // start of the range, reset to start of the month
+	To      time.Time     `json:"to"`      // end of the range (exclusive)
+	Restart bool          `json:"restart"` // ignore previously completed chunks and start again
+	Pause   time.Duration `json:"pause"`   // fixed pause between chunks
+	Wait    WaitF         `json:"-"`       // optional, called before each chunk
+}
+
+// Run calls importer for each chunk between the dates that has not already been
+// completed. Stops at the first failed chunk so it can be resumed later.
+func Run(ctx context.Context, importer ChunkF, in *Args) (err error) {
+	var (
+		chunks    []*Chunk
+		completed map[string]bool
+		count     int          = 0
+		log       *slog.Logger = cntxt.GetLogger(ctx).With("package", "backfill", "func", "Run", "dataset", in.Dataset)
+	)
+	log.With("options", in).Info("starting ...")
+
+	chunks, err = Chunks(in.From, in.To, time.Now().UTC())
+	if err != nil {
+		return
+	}
+	if in.Restart {
+		log.Info("restarting backfill, clearing previous progress ...")
+		if err = dbx.Exec(ctx, deleteStmt, &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}, in.Dataset); err != nil {
+			err = errors.Join(ErrFailedRecording, err)
+			return
+		}
+	}
+	completed, err = Completed(ctx, in)
+	if err != nil {
+		return
+	}
+
+	for i, chunk := range chunks {
+		var lg = log.With("month", chunk.Month, "chunk", i+1, "of", len(chunks))
+
+		if completed[chunk.Month] {
+			lg.Info("chunk already completed, skipping ...")
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		// wait for any rate limits / pause between each chunk
+		if count > 0 && in.Pause > 0 {
+			if err = sleep(ctx, in.Pause); err != nil {
+				return
+			}
+		}
+		if in.Wait != nil {
+			if err = in.Wait(ctx); err != nil {
+				return
+			}
+		}
+		count++
+
+		lg.Info("importing chunk ...")
+		if err = importer(ctx, chunk); err != nil {
+			lg.Error("chunk failed, stopping backfill", "err", err.Error())
+			err = errors.Join(ErrFailedChunk, err)
+			return
+		}
+		if chunk.Partial {
+			lg.Info("chunk is partial, not recording as complete.")
+			continue
+		}
+		err = dbx.Insert(ctx, InsertStatement, []*Model{{Dataset: in.Dataset, Month: chunk.Month}}, &dbx.InsertArgs{
+			DB:     in.DB,
+			Driver: in.Driver,
+			Params: in.Params,
+		})
+		if err != nil {
+			lg.Error("failed to record chunk", "err", err.Error())
+			err = errors.Join(ErrFailedRecording, err)
+			return
+		}
+	}
+	log.With("imported", count).Info("complete.")
+	return
+}
+
+// Chunks splits the range into months, with the end of each being the start of
+// the next month. Chunks are cut short (and marked as partial) at now.
+func Chunks(from time.Time, to time.Time, now time.Time) (chunks []*Chunk, err error) {
+	from = times.ResetMonth(from)
+	if !from.Before(to) {
+		err = ErrInvalidRange
+		return
+	}
+	chunks = []*Chunk{}
+	for start := from; start.Before(to) && start.Before(now); start = times.Add(start, 1, times.MONTH) {
+		var chunk = &Chunk{
+			Month: times.AsYMString(start),
+			Start: start,
+			End:   times.Add(start, 1, times.MONTH),
+		}
+		if to.Before(chunk.End) {
+			chunk.End = to
+			chunk.Partial = true
+		}
+		if now.Before(chunk.End) {
+			chunk.End = times.ResetDay(now)
+			chunk.Partial = true
+		}
+		// nothing to import yet (first day of the month)
+		if !chunk.End.After(chunk.Start) {
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+	return
+}
+
+// Completed returns the months that have already been completed for the dataset
+func Completed(ctx context.Context, in *Args) (months map[string]bool, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "backfill", "func", "Completed")
+
+	months = map[string]bool{}
+	err = dbx.Select(ctx, selectStmt, &dbx.SelectArgs{
+		DB:      in.DB,
+		Driver:  in.Driver,
+		Params:  in.Params,
+		BindMap: map[string]interface{}{"dataset": in.Dataset},
+		ScanF: func(rows *sql.Rows) (e error) {
+			var month string
+			if e = rows.Scan(&month); e == nil {
+				months[month] = true
+			}
+			return
+		},
+	})
+	if err != nil {
+		log.Error("failed to get completed chunks", "err", err.Error())
+		err = errors.Join(ErrFailedRecording, err)
+	}
+	return
+}
+
+// sleep pauses for the duration or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) (err error) {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+	}
+	return
+}
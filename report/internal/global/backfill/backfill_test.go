@@ -0,0 +1,97 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackfillChunks(t *testing.T) {
+	var (
+		from = time.Date(2025, 11, 15, 0, 0, 0, 0, time.UTC)
+		to   = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		now  = time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	)
+	chunks, err := Chunks(from, to, now)
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	expected := []string{"2025-11", "2025-12", "2026-01", "2026-02"}
+	if len(chunks) != len(expected) {
+		t.Errorf("expected [%d] chunks, got [%d]", len(expected), len(chunks))
+		t.FailNow()
+	}
+	for i, c := range chunks {
+		if c.Month != expected[i] {
+			t.Errorf("expected month [%s] got [%s]", expected[i], c.Month)
+		}
+	}
+	if chunks[0].Start.Day() != 1 || chunks[0].End.Month() != time.December {
+		t.Errorf("first chunk should cover the whole of november")
+	}
+	last := chunks[len(chunks)-1]
+	if !last.Partial || last.End.Day() != 10 {
+		t.Errorf("last chunk should be partial and end today: %+v", last)
+	}
+
+	if _, err = Chunks(to, from, now); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected invalid range error")
+	}
+}
+
+func TestBackfillRunResumes(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-backfill.db")
+		calls  = []string{}
+		failOn = "2025-03"
+		in     = &Args{
+			DB:      dbpath,
+			Driver:  driver,
+			Dataset: "costs",
+			From:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:      time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+		}
+		importer = func(ctx context.Context, chunk *Chunk) error {
+			calls = append(calls, chunk.Month)
+			if chunk.Month == failOn {
+				return errors.New("interrupted")
+			}
+			return nil
+		}
+	)
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: dbpath, Driver: driver}); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	// first run fails part way through
+	if err := Run(ctx, importer, in); !errors.Is(err, ErrFailedChunk) {
+		t.Errorf("expected chunk failure, got [%v]", err)
+	}
+	// second run should pick up from the failed chunk
+	failOn = ""
+	calls = []string{}
+	if err := Run(ctx, importer, in); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	if len(calls) != 2 || calls[0] != "2025-03" || calls[1] != "2025-04" {
+		t.Errorf("expected resume from 2025-03, got %v", calls)
+	}
+	// restart runs everything again
+	calls = []string{}
+	in.Restart = true
+	if err := Run(ctx, importer, in); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	if len(calls) != 4 {
+		t.Errorf("expected all chunks on restart, got %v", calls)
+	}
+}
@@ -0,0 +1,415 @@
+// Package config loads the shared yaml / json configuration file used by the
+// import, api, front and migrate commands.
+//
+// Values are resolved in order of precedence: flags > env > file > defaults.
+// Each command sets its defaults on its flag struct, cobra parses the flags
+// and then Resolve applies the file, env values and finally re-applies any
+// flags that were explicitly passed.
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/env"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	ErrReadingConfig = errors.New("failed to read config file.")
+	ErrParsingConfig = errors.New("failed to parse config file.")
+	ErrInvalidConfig = errors.New("config file is invalid.")
+)
+
+// supported database drivers
+var drivers = []string{"sqlite3"}
+
+// githubAPIs are the supported values for github.api
+var githubAPIs = []string{"rest", "graphql"}
+
+// Config is the content of the config file; every value is optional
+type Config struct {
+	present map[string]bool // dot separated keys found in the file (eg github.recursive), see Set
+
+	Database   Database   `json:"database"`
+	GitHub     GitHub     `json:"github"`
+	AWS        AWS        `json:"aws"`
+	Dates      Dates      `json:"dates"`
+	Costs      Costs      `json:"costs"`
+	Compliance Compliance `json:"compliance"`
+	Branches   Branches   `json:"branch_protection"`
+	Standards  []Standard `json:"standards"`
+	API        API        `json:"api"`
+	Front      Front      `json:"front"`
+	Retry      Retry      `json:"retry"`
+}
+
+// Database connection settings
+type Database struct {
+	Driver string `json:"driver"`
+	DB     string `json:"db"`
+	Params string `json:"params"`
+}
+
+// GitHub settings used by the importers
+type GitHub struct {
+	Org         string            `json:"org"`           // organisation slug
+	Parent      string            `json:"parent"`        // parent team slug
+	Sources     []string          `json:"sources"`       // org/team slugs to list repositories from instead of org & parent
+	Recursive   bool              `json:"recursive"`     // include repositories from child teams
+	Concurrency int               `json:"concurrency"`   // number of repositories processed at once
+	HTTPCache   string            `json:"http_cache"`    // directory for cached api responses
+	API         string            `json:"api"`           // rest or graphql, used to fetch repository metadata
+	OwnerToTeam map[string]string `json:"owner_to_team"` // codeowner (org/team) to service team name
+}
+
+// AWS settings used by the importers
+type AWS struct {
+	Region string `json:"region"`
+}
+
+// Dates are the default date ranges for the importers
+type Dates struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	StartCosts string `json:"start_costs"`
+}
+
+// Costs settings for the api & front end
+type Costs struct {
+	ExcludedServices []string `json:"excluded_services"` // services left out of all cost totals
+	BillingDay       int      `json:"billing_day"`       // day of the month the previous months costs become stable
+}
+
+// Compliance settings for the codebase stats import
+type Compliance struct {
+	BaseURL string `json:"base_url"` // repository standards site used for badges & reports
+}
+
+// Branches is the policy the default branch of each codebase is checked against by
+// the branch protection import
+type Branches struct {
+	Checks     []string `json:"checks"`      // checks that have to pass (protected, reviews, signed_commits etc)
+	MinReviews int      `json:"min_reviews"` // approvals needed to pass the reviews check
+}
+
+// Standard is a single rule of the repository standards import; which of the other
+// values are used depends on the type
+type Standard struct {
+	Name        string `json:"name"`        // unique name of the rule
+	Description string `json:"description"` // shown on the front end
+	Type        string `json:"type"`        // file_exists, workflow_uses, setting_equals or codeowners
+	Path        string `json:"path"`        // file_exists; path or glob pattern of the file
+	Action      string `json:"action"`      // workflow_uses; action without a version (owner/name)
+	Setting     string `json:"setting"`     // setting_equals; repository setting, dot separated for nested values
+	Value       string `json:"value"`       // setting_equals; value the setting has to equal
+}
+
+// API server settings
+type API struct {
+	Host string `json:"host"` // address to run the api on
+}
+
+// Front server settings
+type Front struct {
+	Host    string `json:"host"`     // address to run the front on
+	API     string `json:"api"`      // address of the api to call
+	RootDir string `json:"root_dir"` // root directory of templates & assets
+}
+
+// Retry settings for calls to external apis (aws, github etc)
+type Retry struct {
+	Attempts  int    `json:"attempts"`   // attempts for each call, including the first
+	BaseDelay string `json:"base_delay"` // delay before the first retry, doubled for each after (eg 500ms)
+	MaxDelay  string `json:"max_delay"`  // longest delay between retries (eg 30s)
+}
+
+// Load reads and validates the config file; yaml is used unless the file has
+// a .json extension. Unknown keys are treated as errors to catch typos.
+func Load(ctx context.Context, file string) (cfg *Config, err error) {
+	var (
+		content []byte
+		log     *slog.Logger = cntxt.GetLogger(ctx).With("package", "config", "func", "Load", "file", file)
+	)
+	log.Debug("starting ...")
+	cfg = &Config{}
+	if content, err = os.ReadFile(file); err != nil {
+		err = errors.Join(ErrReadingConfig, err)
+		return
+	}
+	if err = parse(content, filepath.Ext(file) == ".json", cfg); err != nil {
+		err = errors.Join(ErrParsingConfig, fmt.Errorf("file [%s]", file), err)
+		return
+	}
+	if err = cfg.Validate(); err != nil {
+		err = errors.Join(ErrInvalidConfig, fmt.Errorf("file [%s]", file), err)
+		return
+	}
+	log.Debug("complete.")
+	return
+}
+
+// parse decodes the content into cfg; yaml is converted to json first so the
+// json tags are used for both
+func parse(content []byte, isJSON bool, cfg *Config) (err error) {
+	var dec *json.Decoder
+	if !isJSON {
+		var data interface{}
+		var node yaml.Node
+		if err = yaml.Unmarshal(content, &node); err != nil {
+			return
+		}
+		// empty file
+		if len(node.Content) == 0 {
+			return
+		}
+		asStrings(&node)
+		if err = node.Decode(&data); err != nil {
+			return
+		}
+		if content, err = json.Marshal(data); err != nil {
+			return
+		}
+	}
+	dec = json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cfg); err != nil {
+		return
+	}
+	// note which keys were present so zero values in the file are still used
+	var raw map[string]interface{}
+	if err = json.Unmarshal(content, &raw); err != nil {
+		return
+	}
+	cfg.present = map[string]bool{}
+	presentKeys(raw, "", cfg.present)
+	return
+}
+
+// presentKeys adds the dot separated key of every value within data, including
+// those of nested objects
+func presentKeys(data map[string]interface{}, prefix string, present map[string]bool) {
+	for name, value := range data {
+		var key = prefix + name
+		present[key] = true
+		if nested, ok := value.(map[string]interface{}); ok {
+			presentKeys(nested, key+".", present)
+		}
+	}
+}
+
+// has returns true when the file contained the value of field, which must be a
+// pointer to a field of the config (eg &cfg.GitHub.Recursive)
+func (self *Config) has(field any) (found bool) {
+	var (
+		target = reflect.ValueOf(field)
+		walk   func(v reflect.Value, prefix string) bool
+	)
+	// match on the address & type, as a struct shares its address with its first field
+	walk = func(v reflect.Value, prefix string) bool {
+		for i := 0; i < v.NumField(); i++ {
+			var f = v.Field(i)
+			var name, _, _ = strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if addr := f.Addr(); addr.Pointer() == target.Pointer() && addr.Type() == target.Type() {
+				found = self.present[prefix+name]
+				return true
+			}
+			if f.Kind() == reflect.Struct && walk(f, prefix+name+".") {
+				return true
+			}
+		}
+		return false
+	}
+	walk(reflect.ValueOf(self).Elem(), "")
+	return
+}
+
+// asStrings marks unquoted dates as strings so they are kept as written
+// (YYYY-MM-DD) rather than being converted to timestamps
+func asStrings(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		asStrings(child)
+	}
+}
+
+// Validate checks the values set are usable, returning all problems found
+func (self *Config) Validate() (err error) {
+	var errs = []error{}
+	var invalid = func(key string, msg string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(msg, args...)))
+	}
+
+	if d := self.Database.Driver; d != "" && !slices.Contains(drivers, d) {
+		invalid("database.driver", "unsupported driver [%s], expected one of [%s]", d, strings.Join(drivers, ", "))
+	}
+	for _, kv := range [][]string{{"dates.start", self.Dates.Start}, {"dates.end", self.Dates.End}, {"dates.start_costs", self.Dates.StartCosts}} {
+		if _, e := times.FromString(kv[1]); kv[1] != "" && e != nil {
+			invalid(kv[0], "unable to parse date [%s], expected YYYY-MM-DD", kv[1])
+		}
+	}
+	for _, source := range self.GitHub.Sources {
+		if parts := strings.Split(source, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			invalid("github.sources", "source [%s] should be in the form org/team", source)
+		}
+	}
+	if a := self.GitHub.API; a != "" && !slices.Contains(githubAPIs, a) {
+		invalid("github.api", "unsupported api [%s], expected one of [%s]", a, strings.Join(githubAPIs, ", "))
+	}
+	for _, owner := range slices.Sorted(maps.Keys(self.GitHub.OwnerToTeam)) {
+		var team = self.GitHub.OwnerToTeam[owner]
+		if parts := strings.Split(owner, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			invalid("github.owner_to_team", "owner [%s] should be in the form org/team", owner)
+		}
+		if team == "" {
+			invalid("github.owner_to_team", "owner [%s] has no team", owner)
+		}
+	}
+	if d := self.Costs.BillingDay; d != 0 && (d < 1 || d > 28) {
+		invalid("costs.billing_day", "must be between 1 and 28, got [%d]", d)
+	}
+	for _, svc := range self.Costs.ExcludedServices {
+		if strings.TrimSpace(svc) == "" {
+			invalid("costs.excluded_services", "contains an empty service name")
+		}
+	}
+	if u := self.Compliance.BaseURL; u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		invalid("compliance.base_url", "must start with http:// or https://, got [%s]", u)
+	}
+	for _, check := range self.Branches.Checks {
+		if strings.TrimSpace(check) == "" {
+			invalid("branch_protection.checks", "contains an empty check name")
+		}
+	}
+	if r := self.Branches.MinReviews; r < 0 || r > 6 {
+		invalid("branch_protection.min_reviews", "must be between 0 and 6, got [%d]", r)
+	}
+	var names = map[string]bool{}
+	for i, std := range self.Standards {
+		if std.Name == "" {
+			invalid("standards", "rule [%d] has no name", i)
+		} else if names[std.Name] {
+			invalid("standards", "rule name [%s] is used more than once", std.Name)
+		}
+		names[std.Name] = true
+		if std.Type == "" {
+			invalid("standards", "rule [%s] has no type", std.Name)
+		}
+	}
+	for _, kv := range [][]string{{"api.host", self.API.Host}, {"front.host", self.Front.Host}, {"front.api", self.Front.API}} {
+		if kv[1] != "" && !strings.Contains(kv[1], ":") {
+			invalid(kv[0], "must include a port (eg :8080), got [%s]", kv[1])
+		}
+	}
+	// attempts include the first call, so 0 would never make one
+	if self.has(&self.Retry.Attempts) && self.Retry.Attempts < 1 {
+		invalid("retry.attempts", "must be at least 1, got [%d]", self.Retry.Attempts)
+	}
+	for _, kv := range [][]string{{"retry.base_delay", self.Retry.BaseDelay}, {"retry.max_delay", self.Retry.MaxDelay}} {
+		if _, e := time.ParseDuration(kv[1]); kv[1] != "" && e != nil {
+			invalid(kv[0], "unable to parse duration [%s], expected a value like 500ms or 30s", kv[1])
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// RetryPolicy creates a retry policy from the resolved settings, using the
+// retry.Default values for either delay when it is empty
+func RetryPolicy(attempts int, baseDelay string, maxDelay string) (policy *retry.Policy, err error) {
+	var p = *retry.Default
+	policy = &p
+	policy.Attempts = attempts
+	if baseDelay != "" {
+		if policy.BaseDelay, err = time.ParseDuration(baseDelay); err != nil {
+			err = errors.Join(retry.ErrInvalidPolicy, err)
+			return
+		}
+	}
+	if maxDelay != "" {
+		if policy.MaxDelay, err = time.ParseDuration(maxDelay); err != nil {
+			err = errors.Join(retry.ErrInvalidPolicy, err)
+			return
+		}
+	}
+	err = policy.Validate()
+	return
+}
+
+// ApplyF copies values from the config into the command's settings
+type ApplyF func(cfg *Config)
+
+// Resolve applies the config file, env values and explicitly passed flags to
+// the settings (in that order), so flags > env > file > defaults. The defaults
+// are whatever the settings contained before the flags were parsed.
+//
+// The file is optional; when empty the CONFIG env value is used instead and
+// if that is also empty, no file is loaded.
+func Resolve[T any](cmd *cobra.Command, file string, settings T, apply ApplyF) (cfg *Config, err error) {
+	var changed = map[string]string{}
+	var lists = map[string][]string{}
+	if file == "" {
+		file = env.Get("CONFIG", "")
+	}
+	// note which flags were passed so they can be re-applied last; slice flags
+	// keep a copy of their values as a list, as their String() is `[a,b]` and
+	// calling Set on an already changed slice flag appends rather than replaces
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			lists[f.Name] = slices.Clone(sv.GetSlice())
+		} else {
+			changed[f.Name] = f.Value.String()
+		}
+	})
+	cfg = &Config{}
+	if file != "" {
+		if cfg, err = Load(cmd.Context(), file); err != nil {
+			return
+		}
+	}
+	apply(cfg)
+	if err = env.OverwriteStruct(settings); err != nil {
+		return
+	}
+	for name, value := range changed {
+		if err = cmd.Flags().Set(name, value); err != nil {
+			return
+		}
+	}
+	for name, values := range lists {
+		if err = cmd.Flags().Lookup(name).Value.(pflag.SliceValue).Replace(values); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Set updates dest with value, a pointer to a field of cfg, when that key was
+// present in the file; used in ApplyF functions so only values from the file
+// are used, including zero values such as false, 0 or an empty list
+func Set[T any](cfg *Config, dest *T, value *T) {
+	if cfg.has(value) {
+		*dest = *value
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/retry"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const testYAML string = `
+database:
+  db: ./from-file.db
+dates:
+  start: 2025-01-01
+github:
+  org: file-org
+  owner_to_team:
+    ministryofjustice/opg-sirius: Sirius
+costs:
+  excluded_services: [Tax, Support]
+  billing_day: 12
+`
+
+const testJSON string = `{"database": {"db": "./from-json.db"}, "costs": {"billing_day": 3}}`
+
+func writeFile(t *testing.T, name string, content string) (file string) {
+	file = filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	return
+}
+
+func TestConfigLoad(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+
+	cfg, err := Load(ctx, writeFile(t, "config.yaml", testYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if cfg.Database.DB != "./from-file.db" || cfg.GitHub.Org != "file-org" || cfg.Costs.BillingDay != 12 {
+		t.Errorf("yaml values not loaded: %+v", cfg)
+	}
+	if cfg.Dates.Start != "2025-01-01" {
+		t.Errorf("yaml date should be kept as written, got [%s]", cfg.Dates.Start)
+	}
+	if len(cfg.Costs.ExcludedServices) != 2 || cfg.GitHub.OwnerToTeam["ministryofjustice/opg-sirius"] != "Sirius" {
+		t.Errorf("yaml list / map values not loaded: %+v", cfg)
+	}
+
+	cfg, err = Load(ctx, writeFile(t, "config.json", testJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if cfg.Database.DB != "./from-json.db" || cfg.Costs.BillingDay != 3 {
+		t.Errorf("json values not loaded: %+v", cfg)
+	}
+
+	// unknown keys should fail
+	_, err = Load(ctx, writeFile(t, "typo.yaml", "databse:\n  db: ./a.db\n"))
+	if !errors.Is(err, ErrParsingConfig) {
+		t.Errorf("expected parsing error for unknown key, got [%v]", err)
+	}
+	// missing file
+	_, err = Load(ctx, filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, ErrReadingConfig) {
+		t.Errorf("expected reading error for missing file, got [%v]", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+	var content = `
+database:
+  driver: postgres
+dates:
+  start: 2025-13-01
+github:
+  api: soap
+  owner_to_team:
+    opg-sirius: Sirius
+costs:
+  billing_day: 31
+branch_protection:
+  min_reviews: 10
+standards:
+  - name: has-readme
+    type: file_exists
+  - name: has-readme
+api:
+  host: localhost
+retry:
+  attempts: 0
+  base_delay: fast
+`
+	_, err := Load(ctx, writeFile(t, "invalid.yaml", content))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected invalid config error, got [%v]", err)
+	}
+	for _, key := range []string{"database.driver", "dates.start", "github.api", "github.owner_to_team", "costs.billing_day", "branch_protection.min_reviews", "standards", "api.host", "retry.attempts", "retry.base_delay"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error to mention [%s]: [%s]", key, err.Error())
+		}
+	}
+}
+
+type testSettings struct {
+	DB     string `json:"test_config_db"`
+	Org    string `json:"test_config_org"`
+	Region string `json:"test_config_region"`
+	Day    int    `json:"test_config_day"`
+}
+
+func TestConfigResolvePrecedence(t *testing.T) {
+	var (
+		file     = writeFile(t, "config.yaml", "database:\n  db: file.db\ngithub:\n  org: file-org\naws:\n  region: file-region\n")
+		settings = &testSettings{DB: "default.db", Org: "default-org", Region: "default-region", Day: 15}
+		cmd      = &cobra.Command{Use: "test"}
+	)
+	cmd.Flags().StringVar(&settings.DB, "db", settings.DB, "")
+	cmd.Flags().StringVar(&settings.Org, "org", settings.Org, "")
+	cmd.SetContext(cntxt.AddLogger(t.Context(), logger.New("error")))
+	if err := cmd.ParseFlags([]string{"--db", "flag.db"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	t.Setenv("TEST_CONFIG_DB", "env.db")
+	t.Setenv("TEST_CONFIG_ORG", "env-org")
+
+	_, err := Resolve(cmd, file, &settings, func(cfg *Config) {
+		Set(cfg, &settings.DB, &cfg.Database.DB)
+		Set(cfg, &settings.Org, &cfg.GitHub.Org)
+		Set(cfg, &settings.Region, &cfg.AWS.Region)
+		Set(cfg, &settings.Day, &cfg.Costs.BillingDay)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// flag > env > file > default
+	if settings.DB != "flag.db" {
+		t.Errorf("expected flag value, got [%s]", settings.DB)
+	}
+	if settings.Org != "env-org" {
+		t.Errorf("expected env value, got [%s]", settings.Org)
+	}
+	if settings.Region != "file-region" {
+		t.Errorf("expected file value, got [%s]", settings.Region)
+	}
+	if settings.Day != 15 {
+		t.Errorf("expected default value, got [%d]", settings.Day)
+	}
+}
+
+type testSliceSettings struct {
+	Sources []string `json:"test_config_sources"`
+}
+
+func TestConfigResolveSliceFlag(t *testing.T) {
+	var (
+		settings = &testSliceSettings{}
+		cmd      = &cobra.Command{Use: "test"}
+	)
+	cmd.Flags().StringSliceVar(&settings.Sources, "sources", settings.Sources, "")
+	cmd.SetContext(cntxt.AddLogger(t.Context(), logger.New("error")))
+	if err := cmd.ParseFlags([]string{"--sources", "org/a,org/b"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	t.Setenv("TEST_CONFIG_SOURCES", "env/a")
+
+	if _, err := Resolve(cmd, "", &settings, func(cfg *Config) {}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if strings.Join(settings.Sources, "|") != "org/a|org/b" {
+		t.Errorf("expected flag values to be kept as passed, got [%v]", settings.Sources)
+	}
+}
+
+type testZeroSettings struct {
+	Recursive        bool     `json:"test_config_recursive"`
+	MinReviews       int      `json:"test_config_min_reviews"`
+	ExcludedServices []string `json:"test_config_excluded_services"`
+	Region           string   `json:"test_config_region"`
+}
+
+func TestConfigResolveZeroValues(t *testing.T) {
+	var (
+		file     = writeFile(t, "config.yaml", "github:\n  recursive: false\nbranch_protection:\n  min_reviews: 0\ncosts:\n  excluded_services: []\n")
+		settings = &testZeroSettings{Recursive: true, MinReviews: 1, ExcludedServices: []string{"Tax"}, Region: "default-region"}
+		cmd      = &cobra.Command{Use: "test"}
+	)
+	cmd.SetContext(cntxt.AddLogger(t.Context(), logger.New("error")))
+
+	_, err := Resolve(cmd, file, &settings, func(cfg *Config) {
+		Set(cfg, &settings.Recursive, &cfg.GitHub.Recursive)
+		Set(cfg, &settings.MinReviews, &cfg.Branches.MinReviews)
+		Set(cfg, &settings.ExcludedServices, &cfg.Costs.ExcludedServices)
+		Set(cfg, &settings.Region, &cfg.AWS.Region)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// zero values in the file replace the defaults, missing keys do not
+	if settings.Recursive || settings.MinReviews != 0 || len(settings.ExcludedServices) != 0 {
+		t.Errorf("expected zero values from the file, got %+v", settings)
+	}
+	if settings.Region != "default-region" {
+		t.Errorf("expected default value, got [%s]", settings.Region)
+	}
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	policy, err := RetryPolicy(2, "", "5s")
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if policy.Attempts != 2 || policy.BaseDelay != retry.Default.BaseDelay || policy.MaxDelay != 5*time.Second {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	if _, err = RetryPolicy(0, "soon", ""); !errors.Is(err, retry.ErrInvalidPolicy) {
+		t.Errorf("expected invalid policy error, got [%v]", err)
+	}
+	if _, err = RetryPolicy(0, "", ""); !errors.Is(err, retry.ErrInvalidPolicy) {
+		t.Errorf("expected invalid policy error for 0 attempts, got [%v]", err)
+	}
+}
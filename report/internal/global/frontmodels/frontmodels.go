@@ -6,6 +6,7 @@ type RegisterArgs struct {
 	SemVer       string `json:"semver"`
 	RootDir      string `json:"root_dir"`
 	TemplateDir  string `json:"template_dir"`
+	BillingDay   int    `json:"billing_day"` // day of the month the previous months costs become stable
 }
 
 // HeadlineData
@@ -24,6 +25,11 @@ type HeadlineData struct {
 	// Releases
 	Releases            int `json:"releases"`             // count releases
 	ReleasesSecurityish int `json:"releases_securityish"` // count of releases if they likely sec
+	// security alerts
+	CodeScanningOpen         int `json:"code_scanning_open"`          // count of open code scanning alerts
+	CodeScanningOpenSevere   int `json:"code_scanning_open_severe"`   // count of open critical & high code scanning alerts
+	SecretScanningOpen       int `json:"secret_scanning_open"`        // count of open secret scanning alerts
+	SecretScanningOpenSevere int `json:"secret_scanning_open_severe"` // count of open critical secret scanning alerts
 }
 
 // TableHeaders
@@ -70,10 +76,18 @@ type Codebase struct {
 	ComplianceBadge     string `json:"compliance_badge,omitempty"`      // compliance badge url
 	ComplianceGrade     int    `json:"compliance_grade,omitempty"`
 
-	TrivyUsage     int    `json:"trivy_usage"`      // boolean flag to show if the codebase is using trivy in workflows
-	TrivySBOMUsage int    `json:"trivy_sbom_usage"` // boolean flag to show if trivy is being used to generate sboms
-	TrivyLocations string `json:"trivy_locations"`  // tracks files where trivy has been utilised
+	SecurityTools         string `json:"security_tools"`          // comma separated list of the security tools used in workflows
+	SecurityToolLocations string `json:"security_tool_locations"` // comma separated list of `tool: file` where each tool was found
+	SBOMUsage             int    `json:"sbom_usage"`              // boolean flag to show if any tool is being used to generate sboms
+	WorkflowErrors        string `json:"workflow_errors"`         // comma separated list of workflow / action files that could not be parsed
 
+	Branch               string `json:"branch"`                 // default branch checked by the branch protection import
+	BranchProtected      int    `json:"branch_protected"`       // boolean flag for classic protection or rulesets on the default branch
+	RequiredReviews      int    `json:"required_reviews"`       // approvals needed to merge to the default branch
+	SignedCommits        int    `json:"signed_commits"`         // boolean flag for signed commits on the default branch
+	StatusChecks         int    `json:"status_checks"`          // count of required status checks on the default branch
+	BranchPolicyPassed   int    `json:"branch_policy_passed"`   // boolean flag for passing the branch protection policy
+	BranchPolicyFailures string `json:"branch_policy_failures"` // comma separated list of the policy checks that failed
 }
 
 // Codeowner
@@ -90,11 +104,23 @@ type Owner struct {
 	TeamName string `json:"team_name"`
 }
 
+// CodeownersIssue is a codebase whose CODEOWNERS file has errors or no catch-all rule
+type CodeownersIssue struct {
+	Name     string `json:"name,omitempty"`      // short name of codebase (without owner)
+	FullName string `json:"full_name,omitempty"` // full name including the owner
+	Url      string `json:"url,omitempty"`       // url to access the codebase
+	Location string `json:"location"`            // path of the CODEOWNERS file, empty when there is not one
+	Rules    int    `json:"rules"`               // count of rules within the file
+	CatchAll int    `json:"catch_all"`           // boolean flag for a rule that owns every path
+	Errors   string `json:"errors"`              // comma separated list of errors, with line numbers
+}
+
 // CodebaseData
 type CodebaseData struct {
-	Team       string
-	Codebases  []*Codebase
-	CodeOwners []*Codeowner
+	Team             string
+	Codebases        []*Codebase
+	CodeOwners       []*Codeowner
+	CodeownersIssues []*CodeownersIssue
 }
 
 type ReleaseData struct {
@@ -107,3 +133,87 @@ type Release struct {
 	Releases            int    `json:"releases"`             // count of releases for this month
 	ReleasesSecurityish int    `json:"releases_securityish"` // count of releases for this month that seem to be security related
 }
+
+type DoraData struct {
+	Team    string
+	Metrics []*Dora
+	Summary *Dora
+}
+type Dora struct {
+	Month               string  `json:"month"`                 // month as YYYY-MM string
+	Deployments         int     `json:"deployments"`           // successful deployments
+	FailedDeployments   int     `json:"failed_deployments"`    // failed deployments
+	Reverts             int     `json:"reverts"`               // deployments of a revert
+	Hotfixes            int     `json:"hotfixes"`              // hotfix pull requests
+	DeploymentFrequency float64 `json:"deployment_frequency"`  // average deployments per month
+	LeadTimeHours       float64 `json:"lead_time_hours"`       // average hours from merge to deployment
+	ChangeFailureRate   float64 `json:"change_failure_rate"`   // percentage of deployments that failed
+	TimeToRestoreHours  float64 `json:"time_to_restore_hours"` // average hours to recover from a failure
+}
+
+type WorkflowReliabilityData struct {
+	Team      string
+	Workflows []*WorkflowReliability // worst offenders only
+	Total     int                    // number of workflows before being limited
+	Summary   *WorkflowReliability
+}
+type WorkflowReliability struct {
+	Codebase           string  `json:"codebase"`               // full name of codebase
+	Workflow           string  `json:"workflow"`               // name of the workflow
+	Runs               int     `json:"runs"`                   // completed runs
+	Failures           int     `json:"failures"`               // runs that failed
+	Reruns             int     `json:"reruns"`                 // runs that were re-run
+	PassedOnRerun      int     `json:"passed_on_rerun"`        // runs that passed after a re-run
+	FailureRate        float64 `json:"failure_rate"`           // percentage of runs that failed
+	RerunRate          float64 `json:"rerun_rate"`             // percentage of runs that were re-run
+	MeanTimeToGreenHrs float64 `json:"mean_time_to_green_hrs"` // average hours from red to green
+}
+
+type DependabotData struct {
+	Team               string
+	Open               []*DependabotOpen
+	OpenSummary        *DependabotOpen
+	Remediation        []*DependabotRemediation
+	RemediationSummary *DependabotRemediation
+}
+type DependabotOpen struct {
+	Codebase string `json:"codebase"` // full name of codebase
+	Critical int    `json:"critical"` // open critical alerts
+	High     int    `json:"high"`     // open high alerts
+	Medium   int    `json:"medium"`   // open medium alerts
+	Low      int    `json:"low"`      // open low alerts
+	Total    int    `json:"total"`    // all open alerts
+	Oldest   string `json:"oldest"`   // when the oldest open alert was raised
+}
+type StandardsData struct {
+	Team      string
+	Rules     []*StandardsRule
+	Codebases []*StandardsCodebase
+}
+type StandardsRule struct {
+	Rule        string `json:"rule"`        // name of the rule
+	Description string `json:"description"` // description of the rule
+	Passed      int    `json:"passed"`      // codebases passing the rule
+	Total       int    `json:"total"`       // codebases checked
+}
+type StandardsResult struct {
+	Codebase string `json:"codebase"` // full name of codebase
+	Rule     string `json:"rule"`     // name of the rule
+	Passed   int    `json:"passed"`   // 1 when the codebase meets the rule
+	Detail   string `json:"detail"`   // what was found, such as the matching file
+}
+
+// StandardsCodebase is a row of the rule x codebase matrix
+type StandardsCodebase struct {
+	Codebase string                      // full name of codebase
+	Passed   int                         // rules passed
+	Results  map[string]*StandardsResult // result by rule name
+}
+type DependabotRemediation struct {
+	Month               string  `json:"month"`                  // month as YYYY-MM string
+	Opened              int     `json:"opened"`                 // alerts raised
+	OpenedSevere        int     `json:"opened_severe"`          // critical & high alerts raised
+	Fixed               int     `json:"fixed"`                  // alerts fixed
+	FixedSevere         int     `json:"fixed_severe"`           // critical & high alerts fixed
+	MeanDaysToRemediate float64 `json:"mean_days_to_remediate"` // average days from raised to fixed
+}
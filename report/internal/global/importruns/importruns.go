@@ -0,0 +1,161 @@
+// Package importruns records an audit log of each import in the `import_runs`
+// table; the command, its arguments, when it ran, the outcome and the number
+// of rows written.
+//
+// This is used to report on how fresh each dataset is.
+package importruns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"slices"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const InsertStatement string = `
+INSERT INTO import_runs (
+	command,
+	arguments,
+	started_at,
+	ended_at,
+	status,
+	error,
+	rows
+) VALUES (
+	:command,
+	:arguments,
+	:started_at,
+	:ended_at,
+	:status,
+	:error,
+	:rows
+) RETURNING id
+;
+`
+
+// Status values stored against each run
+const (
+	StatusSuccess string = "success"
+	StatusFailed  string = "failed"
+	StatusPartial string = "partial" // completed, but some items (like repositories) failed
+)
+
+// Commands is the name of every import command that records its runs, so
+// anything reporting on them (like freshness) can cover each one
+var Commands = []string{
+	"teams",
+	"accounts",
+	"costs",
+	"uptime",
+	"uptime-canaries",
+	"alarms",
+	"codebases",
+	"codeowners",
+	"codebase-stats",
+	"codebase-releases",
+	"dora",
+	"workflow-usage",
+	"workflow-reliability",
+	"dependabot",
+	"code-scanning",
+	"secret-scanning",
+	"branch-protection",
+	"standards",
+}
+
+var ErrFailedRecording = errors.New("failed to record import run with error.")
+
+// Model represents a simple, joinless, db row in the import_runs table
+type Model struct {
+	Command   string `json:"command"`
+	Arguments string `json:"arguments"`
+	StartedAt string `json:"started_at"`
+	EndedAt   string `json:"ended_at"`
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	Rows      int64  `json:"rows"`
+}
+
+// RunF is the import function to record
+type RunF func(ctx context.Context) (err error)
+
+type Args struct {
+	DB     string `json:"db"`     // database path
+	Driver string `json:"driver"` // database driver
+	Params string `json:"params"` // database connection params
+
+	Command   string      `json:"command"`   // name of the import command (costs, uptime etc)
+	Arguments interface{} `json:"arguments"` // arguments used for the import, stored as json
+}
+
+// Record calls run and writes the outcome to the import_runs table. Rows written
+// via dbx.Insert during the run are counted.
+//
+// When the run succeeds but items processed via workers.Map failed the status
+// is StatusPartial and their errors are stored.
+//
+// The error from run is always returned; failing to record the run is logged and
+// joined to that error.
+func Record(ctx context.Context, run RunF, in *Args) (err error) {
+	var (
+		tally     *dbx.Tally
+		failures  *workers.Failures
+		arguments []byte
+		started   time.Time    = time.Now().UTC()
+		log       *slog.Logger = cntxt.GetLogger(ctx).With("package", "importruns", "func", "Record", "command", in.Command)
+		model     *Model       = &Model{Command: in.Command, Status: StatusSuccess, Arguments: "{}"}
+	)
+	if !slices.Contains(Commands, in.Command) {
+		log.Warn("command is not listed in importruns.Commands")
+	}
+	ctx, tally = dbx.WithTally(ctx)
+	ctx, failures = workers.WithFailures(ctx)
+	err = run(ctx)
+
+	model.StartedAt = times.AsString(started, times.FULL)
+	model.EndedAt = times.AsString(time.Now().UTC(), times.FULL)
+	model.Rows = tally.Rows()
+	if err != nil {
+		model.Status = StatusFailed
+		model.Error = err.Error()
+	} else if e := failures.Err(); e != nil {
+		model.Status = StatusPartial
+		model.Error = e.Error()
+	}
+	if arguments, _ = json.Marshal(in.Arguments); arguments != nil {
+		model.Arguments = string(arguments)
+	}
+
+	// use exec rather than insert so this row is not included in any tally
+	if e := write(context.WithoutCancel(ctx), model, in); e != nil {
+		log.Error("failed to record import run", "err", e.Error())
+		err = errors.Join(err, ErrFailedRecording, e)
+	}
+	return
+}
+
+// write binds the model to the insert statement and executes it
+func write(ctx context.Context, model *Model, in *Args) (err error) {
+	var (
+		stmt string
+		args []interface{}
+		row  map[string]interface{} = map[string]interface{}{}
+	)
+	if err = cnv.Convert(model, &row); err != nil {
+		return
+	}
+	if stmt, args, err = dbx.Bind(ctx, InsertStatement, row); err != nil {
+		return
+	}
+	err = dbx.Exec(ctx, stmt, &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}, args...)
+	return
+}
@@ -0,0 +1,81 @@
+package importruns
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/internal/team/teamimport"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/workers"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportRunsRecord(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-import-runs.db")
+		found  = []*Model{}
+		in     = &Args{DB: dbpath, Driver: driver, Command: "teams", Arguments: map[string]string{"src": "teams.json"}}
+	)
+	if err = migrations.Migrate(ctx, &migrations.Args{DB: dbpath, Driver: driver}); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	// successful run that writes 2 rows
+	err = Record(ctx, func(ctx context.Context) error {
+		return dbx.Insert(ctx, teamimport.InsertStatement, []*teamimport.Model{{Name: "a"}, {Name: "b"}}, &dbx.InsertArgs{DB: dbpath, Driver: driver})
+	}, in)
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	// failed run, error should be returned
+	err = Record(ctx, func(ctx context.Context) error { return errors.New("boom") }, in)
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expected run error to be returned, got [%v]", err)
+	}
+
+	// partial run, one item failed but the run itself succeeds
+	err = Record(ctx, func(ctx context.Context) error {
+		workers.Map(ctx, []string{"a", "b"}, func(ctx context.Context, item string) (string, error) {
+			if item == "b" {
+				return "", errors.New("item failed")
+			}
+			return item, nil
+		}, nil)
+		return nil
+	}, in)
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	dbx.Select(ctx, `SELECT command, arguments, status, error, rows FROM import_runs ORDER BY id ASC;`, &dbx.SelectArgs{
+		DB:     dbpath,
+		Driver: driver,
+		ScanF: func(rows *sql.Rows) (e error) {
+			var m = &Model{}
+			if e = rows.Scan(&m.Command, &m.Arguments, &m.Status, &m.Error, &m.Rows); e == nil {
+				found = append(found, m)
+			}
+			return
+		},
+	})
+	if len(found) != 3 {
+		t.Errorf("expected 3 runs, found [%d]", len(found))
+		t.FailNow()
+	}
+	if found[0].Status != StatusSuccess || found[0].Rows != 2 || found[0].Arguments != `{"src":"teams.json"}` {
+		t.Errorf("unexpected first run: %+v", found[0])
+	}
+	if found[1].Status != StatusFailed || found[1].Error != "boom" || found[1].Rows != 0 {
+		t.Errorf("unexpected second run: %+v", found[1])
+	}
+	if found[2].Status != StatusPartial || found[2].Error != "item failed" {
+		t.Errorf("unexpected third run: %+v", found[2])
+	}
+}
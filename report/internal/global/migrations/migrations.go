@@ -16,11 +16,27 @@ type Args struct {
 	Params string `json:"params"` // --params
 }
 
+// Migration is a sql statement to run against the database.
+//
+// Most statements are safe to run repeatedly (`IF NOT EXISTS` etc), but those that
+// are not (such as table rebuilds) should set `Once` so they are tracked in the
+// `migrations` table and only ever run a single time.
 type Migration struct {
 	Key  string
 	Stmt string
+	Once bool
 }
 
+// create_migrations tracks the `Once` migrations that have been run
+const create_migrations string = `
+CREATE TABLE IF NOT EXISTS migrations (
+	key TEXT PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') )
+) STRICT;
+`
+const select_migration string = `SELECT count(*) FROM migrations WHERE key = ?;`
+const insert_migration string = `INSERT INTO migrations (key) VALUES (?);`
+
 var migrations = []*Migration{
 	{Key: "create_teams", Stmt: create_teams},
 	{Key: "create_accounts", Stmt: create_accounts},
@@ -30,6 +46,25 @@ var migrations = []*Migration{
 	{Key: "create_codebase_stats", Stmt: create_codebase_stats},
 	{Key: "create_codeowner", Stmt: create_codeowner},
 	{Key: "create_codebase_metrics", Stmt: create_codebase_metrics},
+	{Key: "alter_uptime_source", Stmt: alter_uptime_source, Once: true},
+	{Key: "create_alarms", Stmt: create_alarms},
+	{Key: "create_backfills", Stmt: create_backfills},
+	{Key: "create_import_runs", Stmt: create_import_runs},
+	{Key: "alter_codebases_source", Stmt: alter_codebases_source, Once: true},
+	{Key: "create_codebase_dora", Stmt: create_codebase_dora},
+	{Key: "create_codebase_workflow_usage", Stmt: create_codebase_workflow_usage},
+	{Key: "create_workflow_runs", Stmt: create_workflow_runs},
+	{Key: "create_dependabot_advisories", Stmt: create_dependabot_advisories},
+	{Key: "create_dependabot_alerts", Stmt: create_dependabot_alerts},
+	{Key: "create_code_scanning_alerts", Stmt: create_code_scanning_alerts},
+	{Key: "create_secret_scanning_alerts", Stmt: create_secret_scanning_alerts},
+	{Key: "create_codebase_branch_protection", Stmt: create_codebase_branch_protection},
+	{Key: "create_codebase_standards", Stmt: create_codebase_standards},
+	{Key: "create_codebase_security_tools", Stmt: create_codebase_security_tools},
+	{Key: "alter_codebase_stats_drop_trivy", Stmt: alter_codebase_stats_drop_trivy, Once: true},
+	{Key: "alter_codebase_stats_workflow_errors", Stmt: alter_codebase_stats_workflow_errors, Once: true},
+	{Key: "create_codebase_codeowners_files", Stmt: create_codebase_codeowners_files},
+	{Key: "create_codebase_codeowners_rules", Stmt: create_codebase_codeowners_rules},
 
 	// {Key: "alter_codebase_metrics", Stmt: alter_codebase_metrics},
 	{Key: "lowercase_team_name", Stmt: lowercase_team_name},
@@ -56,9 +91,22 @@ func runMigrations(ctx context.Context, opts *Args, migrations []*Migration) (er
 	}
 	// close at the end & write migrations
 	defer db.Close()
+	// make sure the tracking table exists
+	if _, err = db.ExecContext(ctx, create_migrations); err != nil {
+		log.Error("error creating migrations table", "err", err.Error())
+		return
+	}
 
 	// now process all migrations, skipping those we've excluded from the migration file
 	for _, migration := range migrations {
+		// one off migrations are handled seperately
+		if migration.Once {
+			if err = runOnce(ctx, db, migration); err != nil {
+				log.Error("error with migration", "key", migration.Key, "err", err.Error())
+				return
+			}
+			continue
+		}
 		// run the migration, if theres a error, fail
 		if _, err = db.ExecContext(ctx, migration.Stmt); err != nil {
 			log.Error("error with migration", "key", migration.Key, "err", err.Error())
@@ -69,3 +117,30 @@ func runMigrations(ctx context.Context, opts *Args, migrations []*Migration) (er
 	log.Info("complete.")
 	return
 }
+
+// runOnce checks the migrations table for this key and, if its not been run before,
+// runs the migration and records the key within a single transaction
+func runOnce(ctx context.Context, db *sql.DB, migration *Migration) (err error) {
+	var (
+		tx    *sql.Tx
+		count int
+		log   *slog.Logger = cntxt.GetLogger(ctx).With("package", "global", "func", "runOnce", "key", migration.Key)
+	)
+	if err = db.QueryRowContext(ctx, select_migration, migration.Key).Scan(&count); err != nil || count > 0 {
+		return
+	}
+	log.Info("running one off migration ...")
+	if tx, err = db.BeginTx(ctx, nil); err != nil {
+		return
+	}
+	if _, err = tx.ExecContext(ctx, migration.Stmt); err != nil {
+		tx.Rollback()
+		return
+	}
+	if _, err = tx.ExecContext(ctx, insert_migration, migration.Key); err != nil {
+		tx.Rollback()
+		return
+	}
+	err = tx.Commit()
+	return
+}
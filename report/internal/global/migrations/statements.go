@@ -62,6 +62,32 @@ CREATE INDEX IF NOT EXISTS idx_uptime_month ON uptime(month);
 CREATE INDEX IF NOT EXISTS idx_uptime_account_month ON uptime(account_id,month);
 `
 
+// alter_uptime_source rebuilds the uptime table to include the source of the
+// data (route53 health check, synthetics canary etc) as part of the unique key.
+// Existing data is all from route53 health checks.
+const alter_uptime_source string = `
+CREATE TABLE uptime_sourced (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	vendor TEXT NOT NULL DEFAULT 'aws',
+	month TEXT NOT NULL,
+	account_id TEXT,
+	source TEXT NOT NULL DEFAULT 'route53',
+	average TEXT NOT NULL,
+	granularity TEXT NOT NULL,
+	UNIQUE (account_id,month,source)
+) STRICT;
+INSERT INTO uptime_sourced (id, created_at, vendor, month, account_id, average, granularity)
+	SELECT id, created_at, vendor, month, account_id, average, granularity FROM uptime;
+DROP INDEX IF EXISTS idx_uptime_month;
+DROP INDEX IF EXISTS idx_uptime_account_month;
+DROP TABLE uptime;
+ALTER TABLE uptime_sourced RENAME TO uptime;
+CREATE INDEX IF NOT EXISTS idx_uptime_month ON uptime(month);
+CREATE INDEX IF NOT EXISTS idx_uptime_account_month ON uptime(account_id,month);
+CREATE INDEX IF NOT EXISTS idx_uptime_source ON uptime(source);
+`
+
 const create_codebases string = `
 CREATE TABLE IF NOT EXISTS codebases (
 	id INTEGER PRIMARY KEY,
@@ -120,6 +146,319 @@ CREATE INDEX IF NOT EXISTS idx_codebase_metrics_month ON codebase_metrics(codeba
 CREATE INDEX IF NOT EXISTS idx_codebase_metrics ON codebase_metrics(codebase);
 `
 
+// create_alarms stores cloudwatch alarm state transitions; `seconds_in_alarm` is
+// only set on transitions into the ALARM state
+const create_alarms string = `
+CREATE TABLE IF NOT EXISTS alarms (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	vendor TEXT NOT NULL DEFAULT 'aws',
+	account_id TEXT NOT NULL,
+	alarm_name TEXT NOT NULL,
+	month TEXT NOT NULL,
+	transitioned_at TEXT NOT NULL,
+	state_from TEXT NOT NULL,
+	state_to TEXT NOT NULL,
+	seconds_in_alarm INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (account_id,alarm_name,transitioned_at)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_alarms_month ON alarms(month);
+CREATE INDEX IF NOT EXISTS idx_alarms_account_month ON alarms(account_id,month);
+`
+
+// create_backfills records which month chunks of a backfill have completed
+const create_backfills string = `
+CREATE TABLE IF NOT EXISTS backfills (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	dataset TEXT NOT NULL,
+	month TEXT NOT NULL,
+	UNIQUE (dataset,month)
+) STRICT;
+`
+
+// create_import_runs is an audit log of each import that has been run
+const create_import_runs string = `
+CREATE TABLE IF NOT EXISTS import_runs (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	command TEXT NOT NULL,
+	arguments TEXT NOT NULL DEFAULT '{}',
+	started_at TEXT NOT NULL,
+	ended_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	rows INTEGER NOT NULL DEFAULT 0
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_import_runs_command ON import_runs(command, ended_at);
+`
+
+// create_codebase_dora stores the raw totals behind the DORA metrics for each
+// codebase & month; averages and rates are calculated by the api so months and
+// codebases can be rolled up correctly.
+//
+// `lead_time_seconds` & `restore_seconds` are sums, divided by `lead_time_changes`
+// & `restores` for the average.
+const create_codebase_dora string = `
+CREATE TABLE IF NOT EXISTS codebase_dora (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	month TEXT NOT NULL,
+	deployments INTEGER NOT NULL DEFAULT 0,
+	failed_deployments INTEGER NOT NULL DEFAULT 0,
+	reverts INTEGER NOT NULL DEFAULT 0,
+	hotfixes INTEGER NOT NULL DEFAULT 0,
+	lead_time_seconds INTEGER NOT NULL DEFAULT 0,
+	lead_time_changes INTEGER NOT NULL DEFAULT 0,
+	restore_seconds INTEGER NOT NULL DEFAULT 0,
+	restores INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,month)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_codebase_dora_month ON codebase_dora(month);
+CREATE INDEX IF NOT EXISTS idx_codebase_dora ON codebase_dora(codebase);
+`
+
+// create_codebase_workflow_usage stores the run duration and billable time, by runner
+// os, of each workflow per codebase & month; all times are totals in milliseconds
+const create_codebase_workflow_usage string = `
+CREATE TABLE IF NOT EXISTS codebase_workflow_usage (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	workflow TEXT NOT NULL,
+	month TEXT NOT NULL,
+	runs INTEGER NOT NULL DEFAULT 0,
+	duration_ms INTEGER NOT NULL DEFAULT 0,
+	ubuntu_ms INTEGER NOT NULL DEFAULT 0,
+	macos_ms INTEGER NOT NULL DEFAULT 0,
+	windows_ms INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,workflow,month)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_codebase_workflow_usage_month ON codebase_workflow_usage(month);
+CREATE INDEX IF NOT EXISTS idx_codebase_workflow_usage ON codebase_workflow_usage(codebase);
+`
+
+// create_workflow_runs stores each completed workflow run, using the latest attempt,
+// for reliability reporting; `seconds_to_green` is only set on runs that started a
+// failure that has since been fixed
+const create_workflow_runs string = `
+CREATE TABLE IF NOT EXISTS workflow_runs (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	run_id INTEGER NOT NULL,
+	workflow TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	event TEXT NOT NULL,
+	month TEXT NOT NULL,
+	started_at TEXT NOT NULL,
+	completed_at TEXT NOT NULL,
+	conclusion TEXT NOT NULL,
+	attempt INTEGER NOT NULL DEFAULT 1,
+	seconds_to_green INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,run_id)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_month ON workflow_runs(month);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_codebase ON workflow_runs(codebase,workflow);
+`
+
+// create_dependabot_advisories stores the security advisories referenced by the
+// dependabot alerts; each advisory can be raised against many codebases
+const create_dependabot_advisories string = `
+CREATE TABLE IF NOT EXISTS dependabot_advisories (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	ghsa_id TEXT NOT NULL,
+	cve_id TEXT NOT NULL DEFAULT '',
+	severity TEXT NOT NULL,
+	summary TEXT NOT NULL DEFAULT '',
+	published_at TEXT NOT NULL DEFAULT '',
+	UNIQUE (ghsa_id)
+) STRICT;
+`
+
+// create_dependabot_alerts stores every dependabot alert of each codebase, open or
+// closed; `seconds_to_remediate` is only set on fixed alerts
+const create_dependabot_alerts string = `
+CREATE TABLE IF NOT EXISTS dependabot_alerts (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	number INTEGER NOT NULL,
+	ghsa_id TEXT NOT NULL,
+	state TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	ecosystem TEXT NOT NULL,
+	package TEXT NOT NULL,
+	manifest_path TEXT NOT NULL DEFAULT '',
+	opened_at TEXT NOT NULL,
+	fixed_at TEXT NOT NULL DEFAULT '',
+	dismissed_at TEXT NOT NULL DEFAULT '',
+	seconds_to_remediate INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,number)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_dependabot_alerts_state ON dependabot_alerts(state,severity);
+CREATE INDEX IF NOT EXISTS idx_dependabot_alerts_ghsa ON dependabot_alerts(ghsa_id);
+`
+
+// create_code_scanning_alerts stores the code scanning (codeql & third party sarif)
+// alerts of each codebase; `age_seconds` is how long the alert has been, or was, open
+// at the time of import
+const create_code_scanning_alerts string = `
+CREATE TABLE IF NOT EXISTS code_scanning_alerts (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	number INTEGER NOT NULL,
+	state TEXT NOT NULL,
+	tool TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	opened_at TEXT NOT NULL,
+	closed_at TEXT NOT NULL DEFAULT '',
+	age_seconds INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,number)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_code_scanning_alerts_state ON code_scanning_alerts(state,severity);
+`
+
+// create_secret_scanning_alerts stores the secret scanning alerts of each codebase;
+// `age_seconds` is how long the alert has been, or was, open at the time of import
+const create_secret_scanning_alerts string = `
+CREATE TABLE IF NOT EXISTS secret_scanning_alerts (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	number INTEGER NOT NULL,
+	state TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	resolution TEXT NOT NULL DEFAULT '',
+	opened_at TEXT NOT NULL,
+	closed_at TEXT NOT NULL DEFAULT '',
+	age_seconds INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,number)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_secret_scanning_alerts_state ON secret_scanning_alerts(state);
+`
+
+// create_codebase_branch_protection stores the branch protection and ruleset settings
+// of the default branch of each codebase, combined, and the result of checking them
+// against the policy at the time of import; `policy_failures` is a comma separated
+// list of the checks that failed
+const create_codebase_branch_protection string = `
+CREATE TABLE IF NOT EXISTS codebase_branch_protection (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	source TEXT NOT NULL,
+	protected INTEGER NOT NULL DEFAULT 0,
+	required_reviews INTEGER NOT NULL DEFAULT 0,
+	code_owner_reviews INTEGER NOT NULL DEFAULT 0,
+	dismiss_stale_reviews INTEGER NOT NULL DEFAULT 0,
+	signed_commits INTEGER NOT NULL DEFAULT 0,
+	status_checks INTEGER NOT NULL DEFAULT 0,
+	enforce_admins INTEGER NOT NULL DEFAULT 0,
+	force_push_blocked INTEGER NOT NULL DEFAULT 0,
+	deletion_blocked INTEGER NOT NULL DEFAULT 0,
+	linear_history INTEGER NOT NULL DEFAULT 0,
+	policy_passed INTEGER NOT NULL DEFAULT 0,
+	policy_failures TEXT NOT NULL DEFAULT '',
+	UNIQUE (codebase)
+) STRICT;
+`
+
+// create_codebase_standards stores the result of checking each codebase against
+// each configured repository standard (rule) at the time of import; `detail` is
+// what was found, such as the matching file or the value of a setting
+const create_codebase_standards string = `
+CREATE TABLE IF NOT EXISTS codebase_standards (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	passed INTEGER NOT NULL DEFAULT 0,
+	detail TEXT NOT NULL DEFAULT '',
+	UNIQUE (codebase,rule)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_codebase_standards_rule ON codebase_standards(rule);
+`
+
+// create_codebase_security_tools stores each security tool (trivy, codeql etc) found
+// within the workflow & composite action files of a codebase; a row per tool and
+// file, replacing the trivy columns of codebase_stats
+const create_codebase_security_tools string = `
+CREATE TABLE IF NOT EXISTS codebase_security_tools (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	tool TEXT NOT NULL,
+	location TEXT NOT NULL,
+	action INTEGER NOT NULL DEFAULT 0,
+	cli INTEGER NOT NULL DEFAULT 0,
+	sbom INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (codebase,tool,location)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_codebase_security_tools_tool ON codebase_security_tools(tool);
+`
+
+// alter_codebase_stats_drop_trivy removes the trivy columns now that tool usage is
+// stored in codebase_security_tools; the next codebase-stats import fills that table
+const alter_codebase_stats_drop_trivy string = `
+ALTER TABLE codebase_stats DROP COLUMN trivy_usage;
+ALTER TABLE codebase_stats DROP COLUMN trivy_sbom_usage;
+ALTER TABLE codebase_stats DROP COLUMN trivy_locations;
+`
+
+// alter_codebase_stats_workflow_errors tracks the workflow & action files of a codebase
+// that could not be parsed, so they are reported rather than silently skipped
+const alter_codebase_stats_workflow_errors string = `
+ALTER TABLE codebase_stats ADD COLUMN workflow_errors TEXT NOT NULL DEFAULT '';
+`
+
+// create_codebase_codeowners_files stores the CODEOWNERS file used by each codebase;
+// location is empty when there is no file, catch_all flags a rule that owns every
+// path and errors are the syntax & owner problems found, by line
+const create_codebase_codeowners_files string = `
+CREATE TABLE IF NOT EXISTS codebase_codeowners_files (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	location TEXT NOT NULL DEFAULT '',
+	rules INTEGER NOT NULL DEFAULT 0,
+	catch_all INTEGER NOT NULL DEFAULT 0,
+	errors TEXT NOT NULL DEFAULT '',
+	UNIQUE (codebase)
+) STRICT;
+`
+
+// create_codebase_codeowners_rules stores each rule (pattern & owners) of the
+// CODEOWNERS file of a codebase in file order, so ownership of a path can be found
+const create_codebase_codeowners_rules string = `
+CREATE TABLE IF NOT EXISTS codebase_codeowners_rules (
+	id INTEGER PRIMARY KEY,
+	created_at TEXT NOT NULL DEFAULT (strftime('%FT%TZ', 'now') ),
+	codebase TEXT NOT NULL,
+	location TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	pattern TEXT NOT NULL,
+	owners TEXT NOT NULL DEFAULT '',
+	UNIQUE (codebase,line)
+) STRICT;
+CREATE INDEX IF NOT EXISTS idx_codebase_codeowners_rules ON codebase_codeowners_rules(codebase,line);
+`
+
+// alter_codebases_source adds the github org & team each codebase was found
+// in; existing rows are left empty until the next codebases import.
+const alter_codebases_source string = `
+ALTER TABLE codebases ADD COLUMN org TEXT NOT NULL DEFAULT '';
+ALTER TABLE codebases ADD COLUMN team TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_codebases_org ON codebases(org);
+`
+
 // const alter_codebase_metrics string = `
 // ALTER TABLE codebase_metrics DROP COLUMN IF EXISTS releases_average_time;
 // ALTER TABLE codebase_metrics DROP COLUMN IF EXISTS pr_count;
@@ -1,15 +1,38 @@
 package global
 
+import "opg-reports/report/internal/global/config"
+
 type ImportArgs struct {
-	DB             string `json:"db"`               // DB related (--db)
-	Driver         string `json:"driver"`           // DB related (--driver)
-	Params         string `json:"params"`           // DB related (--params)
-	Region         string `json:"region"`           // AWS related (--region)
-	DateStart      string `json:"date_start"`       // Date ranges (--start)
-	DateStartCosts string `json:"date_start_costs"` // Date ranges (--start-costs)
-	DateEnd        string `json:"date_end"`         // Date ranges (--end)
-	SrcFile        string `json:"src-file"`         // File based import (--src-file)
-	OrgSlug        string `json:"org"`              // github org (--org)
-	ParentSlug     string `json:"parent"`           // github parent team (--parent)
-	Filter         string `json:"filter"`           // --filter
+	DB             string   `json:"db"`               // DB related (--db)
+	Driver         string   `json:"driver"`           // DB related (--driver)
+	Params         string   `json:"params"`           // DB related (--params)
+	Region         string   `json:"region"`           // AWS related (--region)
+	DateStart      string   `json:"date_start"`       // Date ranges (--start)
+	DateStartCosts string   `json:"date_start_costs"` // Date ranges (--start-costs)
+	DateEnd        string   `json:"date_end"`         // Date ranges (--end)
+	SrcFile        string   `json:"src-file"`         // File based import (--src-file)
+	TeamsFile      string   `json:"teams_file"`       // File based import for teams; used by import groups (--teams-file)
+	AccountsFile   string   `json:"accounts_file"`    // File based import for accounts; used by import groups (--accounts-file)
+	OrgSlug        string   `json:"org"`              // github org (--org)
+	ParentSlug     string   `json:"parent"`           // github parent team (--parent)
+	Sources        []string `json:"sources"`          // github org/team slugs to list repositories from instead of org & parent (--sources)
+	Recursive      bool     `json:"recursive"`        // include repositories from child teams (--recursive)
+	Concurrency    int      `json:"concurrency"`      // number of github repositories processed at once (--concurrency)
+	HTTPCache      string   `json:"http_cache"`       // directory for cached github responses, empty to disable (--http-cache)
+	GitHubAPI      string   `json:"github_api"`       // rest or graphql; api used for repository metadata (--github-api)
+	Filter         string   `json:"filter"`           // --filter
+	UptimeMapping  string   `json:"uptime_mapping"`   // health check to account mapping file for uptime (--uptime-mapping)
+	DryRun         bool     `json:"dry_run"`          // fetch data but do not write to the database (--dry-run)
+	DryRunFormat   string   `json:"dry_run_format"`   // output format for dry run changes; table or json (--dry-run-format)
+	Record         string   `json:"record"`           // directory to save api responses to (--record)
+	Replay         string   `json:"replay"`           // directory to serve api responses from instead of calling the apis (--replay)
+	RetryAttempts  int      `json:"retry_attempts"`   // attempts for each api call (--retry-attempts)
+	RetryBaseDelay string   `json:"retry_base_delay"` // delay before the first retry, doubled for each after (--retry-base-delay)
+	RetryMaxDelay  string   `json:"retry_max_delay"`  // longest delay between retries (--retry-max-delay)
+	// config file only
+	OwnerToTeam       map[string]string `json:"owner_to_team"`       // codeowner to service team mapping for the codeowners import
+	ComplianceBaseURL string            `json:"compliance_base_url"` // repository standards site for the codebase stats import
+	BranchChecks      []string          `json:"branch_checks"`       // checks of the branch protection policy
+	BranchMinReviews  int               `json:"branch_min_reviews"`  // approvals needed by the branch protection policy
+	Standards         []config.Standard `json:"standards"`           // rules of the repository standards import
 }
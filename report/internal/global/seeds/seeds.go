@@ -5,13 +5,27 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"opg-reports/report/internal/account/accountimport"
+	"opg-reports/report/internal/alarms/alarmsimport"
+	"opg-reports/report/internal/branchprotection/branchprotectionimport"
 	"opg-reports/report/internal/codebases/codebasesimport"
+	"opg-reports/report/internal/codebasestats/codebasestatsimport"
+	"opg-reports/report/internal/codeowners/codeownersimport"
+	"opg-reports/report/internal/codescanning/codescanningimport"
 	"opg-reports/report/internal/cost/costimport"
+	"opg-reports/report/internal/dependabot/dependabotimport"
+	"opg-reports/report/internal/dora/doraimport"
+	"opg-reports/report/internal/global/importruns"
 	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/internal/secretscanning/secretscanningimport"
+	"opg-reports/report/internal/standards/standardsimport"
 	"opg-reports/report/internal/team/teamimport"
 	"opg-reports/report/internal/uptime/uptimeimport"
+	"opg-reports/report/internal/workflowreliability/workflowreliabilityimport"
+	"opg-reports/report/internal/workflowusage/workflowusageimport"
 	"opg-reports/report/package/dbx"
 	"opg-reports/report/package/times"
+	"strings"
+	"time"
 )
 
 var teamList []string = []string{
@@ -64,11 +78,27 @@ var codebasestats []string = []string{
 // Results contains all the seed data that was inserted
 // including any that may have failed
 type Results struct {
-	Teams     []*teamimport.Model         `json:"teams"`
-	Accounts  []*accountimport.Model      `json:"accounts"`
-	Costs     []*costimport.Model         `json:"costs"`
-	Uptime    []*uptimeimport.Model       `json:"uptime"`
-	Codebases []*codebasesimport.Codebase `json:"codebases"`
+	Teams      []*teamimport.Model                  `json:"teams"`
+	Accounts   []*accountimport.Model               `json:"accounts"`
+	Costs      []*costimport.Model                  `json:"costs"`
+	Uptime     []*uptimeimport.Model                `json:"uptime"`
+	Alarms     []*alarmsimport.Model                `json:"alarms"`
+	Runs       []*importruns.Model                  `json:"import_runs"`
+	Codebases  []*codebasesimport.Codebase          `json:"codebases"`
+	Owners     []*codeownersimport.CodebaseOwner    `json:"codebase_owners"`
+	CodeFiles  []*codeownersimport.CodeownersFile   `json:"codebase_codeowners_files"`
+	CodeRules  []*codeownersimport.CodeownersRule   `json:"codebase_codeowners_rules"`
+	Dora       []*doraimport.Model                  `json:"codebase_dora"`
+	Usage      []*workflowusageimport.Model         `json:"codebase_workflow_usage"`
+	Workflows  []*workflowreliabilityimport.Model   `json:"workflow_runs"`
+	Advisories []*dependabotimport.Advisory         `json:"dependabot_advisories"`
+	Alerts     []*dependabotimport.Alert            `json:"dependabot_alerts"`
+	CodeScans  []*codescanningimport.Model          `json:"code_scanning_alerts"`
+	Secrets    []*secretscanningimport.Model        `json:"secret_scanning_alerts"`
+	Branches   []*branchprotectionimport.Model      `json:"codebase_branch_protection"`
+	Standards  []*standardsimport.Model             `json:"codebase_standards"`
+	Stats      []*codebasestatsimport.CodebaseStats `json:"codebase_stats"`
+	Tools      []*codebasestatsimport.ToolUsage     `json:"codebase_security_tools"`
 }
 
 // Args
@@ -81,10 +111,16 @@ type Args struct {
 // SeedAll
 func SeedAll(ctx context.Context, in *Args) (results *Results, err error) {
 	var (
-		numAccounts  = 25
-		numCosts     = 13000
-		numUptime    = 1200
-		numCodebases = 50
+		numAccounts     = 25
+		numCosts        = 13000
+		numUptime       = 1200
+		numAlarms       = 600
+		numCodebases    = 50
+		numWorkflowRuns = 2000
+		numAdvisories   = 40
+		numAlerts       = 500
+		numCodeScans    = 300
+		numSecrets      = 60
 	)
 
 	var args = &dbx.InsertArgs{
@@ -122,25 +158,100 @@ func SeedAll(ctx context.Context, in *Args) (results *Results, err error) {
 	if err != nil {
 		return
 	}
+	// seed alarms
+	results.Alarms, err = seedAlarms(ctx, args, numAlarms, results.Accounts)
+	if err != nil {
+		return
+	}
+	// seed import runs
+	results.Runs, err = seedImportRuns(ctx, args)
+	if err != nil {
+		return
+	}
 	// seed codebases
 	results.Codebases, err = seedCodebases(ctx, args, numCodebases)
 	if err != nil {
 		return
 	}
+	// seed codebase owners
+	results.Owners, err = seedCodebaseOwners(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed CODEOWNERS files and their rules
+	results.CodeFiles, results.CodeRules, err = seedCodeowners(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed dora metrics
+	results.Dora, err = seedDora(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed workflow usage
+	results.Usage, err = seedWorkflowUsage(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed workflow runs
+	results.Workflows, err = seedWorkflowRuns(ctx, args, numWorkflowRuns, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed dependabot advisories & alerts
+	results.Advisories, err = seedDependabotAdvisories(ctx, args, numAdvisories)
+	if err != nil {
+		return
+	}
+	results.Alerts, err = seedDependabotAlerts(ctx, args, numAlerts, results.Codebases, results.Advisories)
+	if err != nil {
+		return
+	}
+	// seed code scanning & secret scanning alerts
+	results.CodeScans, err = seedCodeScanningAlerts(ctx, args, numCodeScans, results.Codebases)
+	if err != nil {
+		return
+	}
+	results.Secrets, err = seedSecretScanningAlerts(ctx, args, numSecrets, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed branch protection
+	results.Branches, err = seedBranchProtection(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed codebase stats & the security tools they use
+	results.Stats, results.Tools, err = seedCodebaseStats(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
+	// seed repository standards
+	results.Standards, err = seedStandards(ctx, args, results.Codebases)
+	if err != nil {
+		return
+	}
 
 	return
 }
 
 func seedCodebases(ctx context.Context, in *dbx.InsertArgs, n int) (insert []*codebasesimport.Codebase, err error) {
-	var githubOrg = "mock-org"
+	var githubOrgs = []string{"mock-org", "mock-org-other"}
 	insert = []*codebasesimport.Codebase{}
 	for i := 0; i < n; i++ {
 		var name = fmt.Sprintf("codebase-%02d", i+1)
+		// most codebases come from the main org
+		var githubOrg = githubOrgs[0]
+		if i%5 == 4 {
+			githubOrg = githubOrgs[1]
+		}
 		// var compI = rand.IntN(len(codebasestats))
 		insert = append(insert, &codebasesimport.Codebase{
 			Name:     name,
 			FullName: fmt.Sprintf("%s/%s", githubOrg, name),
 			Url:      fmt.Sprintf("https://mock-github.local/%s/%s", githubOrg, name),
+			Org:      githubOrg,
+			Team:     "mock-team",
 			// ComplianceReportUrl: fmt.Sprintf("https://mock-compliance-report.local/%s", name),
 			// ComplianceBadge:     fmt.Sprintf("https://mock-compliance-report.local/%s/badge", name),
 			// ComplianceLevel:     codebasestats[compI],
@@ -150,13 +261,419 @@ func seedCodebases(ctx context.Context, in *dbx.InsertArgs, n int) (insert []*co
 	return
 }
 
-// seedUptime generates and inserts uptime data
-func seedUptime(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*accountimport.Model) (insert []*uptimeimport.Model, err error) {
+// seedCodebaseOwners assigns each codebase to one of the teams
+func seedCodebaseOwners(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*codeownersimport.CodebaseOwner, err error) {
+	insert = []*codeownersimport.CodebaseOwner{}
+	for i, codebase := range codebases {
+		var team = teamList[i%len(teamList)]
+		insert = append(insert, &codeownersimport.CodebaseOwner{
+			Owner:    fmt.Sprintf("@%s/%s", codebase.Org, team),
+			Codebase: codebase.FullName,
+			TeamName: team,
+		})
+	}
+	err = dbx.Insert(ctx, codeownersimport.InsertOwnersStatement, insert, in)
+	return
+}
+
+// seedCodeowners generates a CODEOWNERS file for most codebases, rotating through
+// those without a file, without a catch-all rule and with errors so each shows
+// within the reports
+func seedCodeowners(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (files []*codeownersimport.CodeownersFile, rules []*codeownersimport.CodeownersRule, err error) {
+	files = []*codeownersimport.CodeownersFile{}
+	rules = []*codeownersimport.CodeownersRule{}
+	for i, codebase := range codebases {
+		var team = fmt.Sprintf("@%s/%s,", codebase.Org, teamList[i%len(teamList)])
+		var file = &codeownersimport.CodeownersFile{Codebase: codebase.FullName}
+		var patterns = []string{"*", "/docs/", "*.tf"}
+		var owners = []string{team, team, fmt.Sprintf("@%s/webops,", codebase.Org)}
+
+		files = append(files, file)
+		// no file
+		if i%5 == 0 {
+			continue
+		}
+		file.Location = ".github/CODEOWNERS"
+		file.CatchAll = 1
+		switch i % 5 {
+		case 1:
+			patterns[0] = "/src/"
+			file.CatchAll = 0
+		case 2:
+			file.Errors = fmt.Sprintf("line 3: unknown team [@%s/webops],", codebase.Org)
+		}
+		for l, pattern := range patterns {
+			rules = append(rules, &codeownersimport.CodeownersRule{
+				Codebase: codebase.FullName,
+				Location: file.Location,
+				Line:     l + 1,
+				Pattern:  pattern,
+				Owners:   owners[l],
+			})
+		}
+		file.Rules = len(patterns)
+	}
+	if err = dbx.Insert(ctx, codeownersimport.InsertFilesStatement, files, in); err != nil {
+		return
+	}
+	err = dbx.Insert(ctx, codeownersimport.InsertRulesStatement, rules, in)
+	return
+}
+
+// seedDora generates dora totals for each codebase over the last year
+func seedDora(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*doraimport.Model, err error) {
 	var (
 		end    = times.ResetMonth(times.Today())
-		start  = times.ResetMonth(times.Add(end, -3, times.YEAR))
+		start  = times.ResetMonth(times.Add(end, -1, times.YEAR))
 		months = times.Months(start, end)
 	)
+	insert = []*doraimport.Model{}
+	for _, codebase := range codebases {
+		for _, month := range months {
+			var deployments = rand.IntN(20)
+			var failed = rand.IntN(3)
+			var changes = deployments + rand.IntN(10)
+			insert = append(insert, &doraimport.Model{
+				Codebase:          codebase.FullName,
+				Month:             times.AsYMString(month),
+				Deployments:       deployments,
+				FailedDeployments: failed,
+				Reverts:           rand.IntN(2),
+				Hotfixes:          rand.IntN(2),
+				LeadTimeSeconds:   int64(changes * (3600 + rand.IntN(3*24*3600))), // 1h - 3d each
+				LeadTimeChanges:   changes,
+				RestoreSeconds:    int64(failed * (600 + rand.IntN(8*3600))), // 10m - 8h each
+				Restores:          failed,
+			})
+		}
+	}
+	err = dbx.Insert(ctx, doraimport.InsertStatement, insert, in)
+	return
+}
+
+// seedWorkflowUsage generates usage for a few workflows of each codebase over the last year
+func seedWorkflowUsage(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*workflowusageimport.Model, err error) {
+	var (
+		end       = times.ResetMonth(times.Today())
+		start     = times.ResetMonth(times.Add(end, -1, times.YEAR))
+		months    = times.Months(start, end)
+		workflows = []string{"[Workflow] Path to live", "[Workflow] PR", "[Scheduled] Nightly"}
+	)
+	insert = []*workflowusageimport.Model{}
+	for _, codebase := range codebases {
+		for _, month := range months {
+			for i, workflow := range workflows {
+				var runs = 1 + rand.IntN(40)
+				var duration = int64(runs * (60000 + rand.IntN(30*60000))) // 1m - 30m each
+				var m = &workflowusageimport.Model{
+					Codebase:   codebase.FullName,
+					Workflow:   workflow,
+					Month:      times.AsYMString(month),
+					Runs:       runs,
+					DurationMS: duration,
+					UbuntuMS:   duration * int64(1+rand.IntN(3)), // jobs run in parallel
+				}
+				// a few workflows use other runners
+				if i == 2 && rand.IntN(5) == 0 {
+					m.MacOSMS = duration
+				}
+				insert = append(insert, m)
+			}
+		}
+	}
+	err = dbx.Insert(ctx, workflowusageimport.InsertStatement, insert, in)
+	return
+}
+
+// seedWorkflowRuns generates runs of a few workflows with a mix of failures and re-runs
+func seedWorkflowRuns(ctx context.Context, in *dbx.InsertArgs, n int, codebases []*codebasesimport.Codebase) (insert []*workflowreliabilityimport.Model, err error) {
+	var (
+		end         = times.ResetMonth(times.Today())
+		start       = times.ResetMonth(times.Add(end, -1, times.YEAR))
+		months      = times.Months(start, end)
+		workflows   = []string{"[Workflow] Path to live", "[Workflow] PR", "[Scheduled] Nightly"}
+		conclusions = []string{"success", "success", "success", "success", "failure", "cancelled"}
+	)
+	insert = []*workflowreliabilityimport.Model{}
+	for i := 0; i < n; i++ {
+		var ts = times.Add(months[rand.IntN(len(months))], rand.IntN(27*24), times.HOUR)
+		var m = &workflowreliabilityimport.Model{
+			Codebase:    codebases[rand.IntN(len(codebases))].FullName,
+			RunID:       int64(i + 1),
+			Workflow:    workflows[rand.IntN(len(workflows))],
+			Branch:      "main",
+			Event:       "push",
+			Month:       times.AsYMString(ts),
+			StartedAt:   times.AsString(ts, times.FULL),
+			CompletedAt: times.AsString(ts.Add(time.Duration(1+rand.IntN(30))*time.Minute), times.FULL),
+			Conclusion:  conclusions[rand.IntN(len(conclusions))],
+			Attempt:     1,
+		}
+		// some runs pass or fail after a re-run
+		if rand.IntN(8) == 0 {
+			m.Attempt = 2 + rand.IntN(2)
+		}
+		if m.Conclusion == "failure" || (m.Conclusion == "success" && m.Attempt > 1) {
+			m.SecondsToGreen = int64(600 + rand.IntN(24*3600)) // 10m - 1d
+		}
+		insert = append(insert, m)
+	}
+	err = dbx.Insert(ctx, workflowreliabilityimport.InsertStatement, insert, in)
+	return
+}
+
+// seedDependabotAdvisories generates advisories of each severity
+func seedDependabotAdvisories(ctx context.Context, in *dbx.InsertArgs, n int) (insert []*dependabotimport.Advisory, err error) {
+	var severities = []string{"critical", "high", "high", "medium", "medium", "medium", "low", "low"}
+	insert = []*dependabotimport.Advisory{}
+	for i := 0; i < n; i++ {
+		insert = append(insert, &dependabotimport.Advisory{
+			GHSAID:      fmt.Sprintf("GHSA-mock-%04d", i+1),
+			CVEID:       fmt.Sprintf("CVE-2025-%04d", i+1),
+			Severity:    severities[rand.IntN(len(severities))],
+			Summary:     fmt.Sprintf("Mock vulnerability %d", i+1),
+			PublishedAt: times.AsString(times.Add(times.Today(), -rand.IntN(700), times.DAY), times.FULL),
+		})
+	}
+	err = dbx.Insert(ctx, dependabotimport.InsertAdvisoriesStatement, insert, in)
+	return
+}
+
+// seedDependabotAlerts generates alerts over the last year, most of which have been fixed
+func seedDependabotAlerts(ctx context.Context, in *dbx.InsertArgs, n int, codebases []*codebasesimport.Codebase, advisories []*dependabotimport.Advisory) (insert []*dependabotimport.Alert, err error) {
+	var (
+		states     = []string{dependabotimport.StateFixed, dependabotimport.StateFixed, dependabotimport.StateFixed, dependabotimport.StateOpen, dependabotimport.StateOpen, dependabotimport.StateDismissed}
+		ecosystems = []string{"npm", "pip", "go", "composer", "actions"}
+		end        = times.Today()
+	)
+	insert = []*dependabotimport.Alert{}
+	for i := 0; i < n; i++ {
+		var (
+			advisory = advisories[rand.IntN(len(advisories))]
+			opened   = times.Add(end, -rand.IntN(365), times.DAY)
+			a        = &dependabotimport.Alert{
+				Codebase:     codebases[rand.IntN(len(codebases))].FullName,
+				Number:       i + 1,
+				GHSAID:       advisory.GHSAID,
+				State:        states[rand.IntN(len(states))],
+				Severity:     advisory.Severity,
+				Ecosystem:    ecosystems[rand.IntN(len(ecosystems))],
+				Package:      fmt.Sprintf("package-%02d", rand.IntN(30)),
+				ManifestPath: "package-lock.json",
+				OpenedAt:     times.AsString(opened, times.FULL),
+			}
+		)
+		switch a.State {
+		case dependabotimport.StateFixed:
+			var fixed = opened.Add(time.Duration(1+rand.IntN(60*24)) * time.Hour) // 1h - 60d
+			if fixed.After(end) {
+				fixed = end
+			}
+			a.FixedAt = times.AsString(fixed, times.FULL)
+			a.SecondsToRemediate = int64(fixed.Sub(opened).Seconds())
+		case dependabotimport.StateDismissed:
+			a.DismissedAt = times.AsString(opened.Add(24*time.Hour), times.FULL)
+		}
+		insert = append(insert, a)
+	}
+	err = dbx.Insert(ctx, dependabotimport.InsertAlertsStatement, insert, in)
+	return
+}
+
+// seedCodeScanningAlerts generates alerts from a few tools over the last year, about half of which are closed
+func seedCodeScanningAlerts(ctx context.Context, in *dbx.InsertArgs, n int, codebases []*codebasesimport.Codebase) (insert []*codescanningimport.Model, err error) {
+	var (
+		states     = []string{codescanningimport.StateFixed, codescanningimport.StateFixed, codescanningimport.StateOpen, codescanningimport.StateOpen, codescanningimport.StateDismissed}
+		tools      = []string{"CodeQL", "CodeQL", "trivy", "tfsec"}
+		severities = []string{"critical", "high", "high", "medium", "medium", "low", "warning", "note"}
+		end        = times.Today()
+	)
+	insert = []*codescanningimport.Model{}
+	for i := 0; i < n; i++ {
+		var (
+			opened = times.Add(end, -rand.IntN(365), times.DAY)
+			closed = end
+			a      = &codescanningimport.Model{
+				Codebase: codebases[rand.IntN(len(codebases))].FullName,
+				Number:   i + 1,
+				State:    states[rand.IntN(len(states))],
+				Tool:     tools[rand.IntN(len(tools))],
+				Rule:     fmt.Sprintf("rule-%02d", rand.IntN(20)),
+				Severity: severities[rand.IntN(len(severities))],
+				OpenedAt: times.AsString(opened, times.FULL),
+			}
+		)
+		if a.State != codescanningimport.StateOpen {
+			closed = opened.Add(time.Duration(1+rand.IntN(90*24)) * time.Hour) // 1h - 90d
+			if closed.After(end) {
+				closed = end
+			}
+			a.ClosedAt = times.AsString(closed, times.FULL)
+		}
+		a.AgeSeconds = int64(closed.Sub(opened).Seconds())
+		insert = append(insert, a)
+	}
+	err = dbx.Insert(ctx, codescanningimport.InsertStatement, insert, in)
+	return
+}
+
+// seedSecretScanningAlerts generates alerts over the last year, most of which have been resolved
+func seedSecretScanningAlerts(ctx context.Context, in *dbx.InsertArgs, n int, codebases []*codebasesimport.Codebase) (insert []*secretscanningimport.Model, err error) {
+	var (
+		states      = []string{secretscanningimport.StateResolved, secretscanningimport.StateResolved, secretscanningimport.StateOpen}
+		severities  = []string{secretscanningimport.SeverityCritical, secretscanningimport.SeverityHigh, secretscanningimport.SeverityHigh}
+		rules       = []string{"github_personal_access_token", "aws_access_key_id", "slack_incoming_webhook_url", "notify_api_key"}
+		resolutions = []string{"revoked", "false_positive", "used_in_tests"}
+		end         = times.Today()
+	)
+	insert = []*secretscanningimport.Model{}
+	for i := 0; i < n; i++ {
+		var (
+			opened = times.Add(end, -rand.IntN(365), times.DAY)
+			closed = end
+			a      = &secretscanningimport.Model{
+				Codebase: codebases[rand.IntN(len(codebases))].FullName,
+				Number:   i + 1,
+				State:    states[rand.IntN(len(states))],
+				Rule:     rules[rand.IntN(len(rules))],
+				Severity: severities[rand.IntN(len(severities))],
+				OpenedAt: times.AsString(opened, times.FULL),
+			}
+		)
+		if a.State == secretscanningimport.StateResolved {
+			closed = opened.Add(time.Duration(1+rand.IntN(14*24)) * time.Hour) // 1h - 14d
+			if closed.After(end) {
+				closed = end
+			}
+			a.Resolution = resolutions[rand.IntN(len(resolutions))]
+			a.ClosedAt = times.AsString(closed, times.FULL)
+		}
+		a.AgeSeconds = int64(closed.Sub(opened).Seconds())
+		insert = append(insert, a)
+	}
+	err = dbx.Insert(ctx, secretscanningimport.InsertStatement, insert, in)
+	return
+}
+
+// seedBranchProtection generates the default branch protection of each codebase, a mix
+// of classic, ruleset and unprotected, evaluated against the default policy
+func seedBranchProtection(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*branchprotectionimport.Model, err error) {
+	var sources = []string{branchprotectionimport.SourceClassic, branchprotectionimport.SourceClassic, branchprotectionimport.SourceRuleset, branchprotectionimport.SourceBoth, branchprotectionimport.SourceNone}
+	insert = []*branchprotectionimport.Model{}
+	for _, cb := range codebases {
+		var m = &branchprotectionimport.Model{
+			Codebase: cb.FullName,
+			Branch:   "main",
+			Source:   sources[rand.IntN(len(sources))],
+		}
+		if m.Source != branchprotectionimport.SourceNone {
+			m.Protected = 1
+			m.RequiredReviews = rand.IntN(3)
+			m.CodeOwnerReviews = rand.IntN(2)
+			m.DismissStaleReviews = rand.IntN(2)
+			m.SignedCommits = rand.IntN(2)
+			m.StatusChecks = rand.IntN(5)
+			m.ForcePushBlocked = 1
+			m.DeletionBlocked = rand.IntN(2)
+			m.LinearHistory = rand.IntN(2)
+		}
+		if failures := branchprotectionimport.DefaultPolicy.Evaluate(m); len(failures) == 0 {
+			m.PolicyPassed = 1
+		} else {
+			m.PolicyFailures = strings.Join(failures, ",") + ","
+		}
+		insert = append(insert, m)
+	}
+	err = dbx.Insert(ctx, branchprotectionimport.InsertStatement, insert, in)
+	return
+}
+
+// seedCodebaseStats generates compliance stats for each codebase along with a few of
+// the registered security tools found in their workflows
+func seedCodebaseStats(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*codebasestatsimport.CodebaseStats, tools []*codebasestatsimport.ToolUsage, err error) {
+	var (
+		levels       = []string{"not_found", "baseline", "standard", "exemplar"}
+		visibilities = []string{"public", "public", "private", "internal"}
+		grades       = map[string]int{"not_found": 10, "baseline": 20, "standard": 30, "exemplar": 40}
+		locations    = []string{".github/workflows/ci.yml", ".github/workflows/path-to-live.yml", ".github/actions/scan/action.yml"}
+	)
+	insert = []*codebasestatsimport.CodebaseStats{}
+	tools = []*codebasestatsimport.ToolUsage{}
+	for _, cb := range codebases {
+		var level = levels[rand.IntN(len(levels))]
+		var errs = ""
+		if rand.IntN(5) == 0 {
+			errs = fmt.Sprintf("%s,", locations[rand.IntN(len(locations))])
+		}
+		insert = append(insert, &codebasestatsimport.CodebaseStats{
+			Codebase:            cb.FullName,
+			Visibility:          visibilities[rand.IntN(len(visibilities))],
+			ComplianceLevel:     level,
+			ComplianceGrade:     grades[level],
+			ComplianceReportUrl: fmt.Sprintf("https://mock-compliance-report.local/%s", cb.Name),
+			ComplianceBadge:     fmt.Sprintf("https://mock-compliance-report.local/%s/badge", cb.Name),
+			WorkflowErrors:      errs,
+		})
+		for _, d := range codebasestatsimport.Detectors {
+			if rand.IntN(3) > 0 {
+				continue
+			}
+			var tool = &codebasestatsimport.ToolUsage{
+				Codebase: cb.FullName,
+				Tool:     d.Tool,
+				Location: locations[rand.IntN(len(locations))],
+				Action:   rand.IntN(2),
+			}
+			tool.CLI = 1 - tool.Action
+			if len(d.SBOM) > 0 {
+				tool.SBOM = rand.IntN(2)
+			}
+			tools = append(tools, tool)
+		}
+	}
+	if err = dbx.Insert(ctx, codebasestatsimport.InsertStatsStatement, insert, in); err != nil {
+		return
+	}
+	err = dbx.Insert(ctx, codebasestatsimport.InsertToolsStatement, tools, in)
+	return
+}
+
+// seedStandards generates a result for each of the default rules for every codebase,
+// with a detail that looks like what the import finds for those that pass
+func seedStandards(ctx context.Context, in *dbx.InsertArgs, codebases []*codebasesimport.Codebase) (insert []*standardsimport.Model, err error) {
+	var details = map[string]string{
+		standardsimport.TypeFileExists:    "README.md",
+		standardsimport.TypeWorkflowUses:  ".github/workflows/ci.yml",
+		standardsimport.TypeSettingEquals: "main",
+		standardsimport.TypeCodeowners:    ".github/CODEOWNERS",
+	}
+	insert = []*standardsimport.Model{}
+	for _, cb := range codebases {
+		for _, rule := range standardsimport.DefaultRules {
+			var m = &standardsimport.Model{
+				Codebase:    cb.FullName,
+				Rule:        rule.Name,
+				Description: rule.Description,
+				Passed:      rand.IntN(2),
+			}
+			if m.Passed == 1 {
+				m.Detail = details[rule.Type]
+			}
+			insert = append(insert, m)
+		}
+	}
+	err = dbx.Insert(ctx, standardsimport.InsertStatement, insert, in)
+	return
+}
+
+// seedUptime generates and inserts uptime data
+func seedUptime(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*accountimport.Model) (insert []*uptimeimport.Model, err error) {
+	var (
+		end     = times.ResetMonth(times.Today())
+		start   = times.ResetMonth(times.Add(end, -3, times.YEAR))
+		months  = times.Months(start, end)
+		sources = []string{uptimeimport.SourceHealthCheck, uptimeimport.SourceCanary}
+	)
 	insert = []*uptimeimport.Model{}
 
 	for i := 0; i < n; i++ {
@@ -169,6 +686,7 @@ func seedUptime(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*acco
 			AccountID:   accounts[accountI].ID,
 			Granularity: "3600",
 			Average:     fmt.Sprintf("%g", avg),
+			Source:      sources[rand.IntN(len(sources))],
 		})
 	}
 	err = dbx.Insert(ctx, uptimeimport.InsertStatement, insert, in)
@@ -176,6 +694,83 @@ func seedUptime(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*acco
 	return
 }
 
+// seedAlarms generates and inserts alarm transitions into and out of ALARM
+func seedAlarms(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*accountimport.Model) (insert []*alarmsimport.Model, err error) {
+	var (
+		end    = times.ResetMonth(times.Today())
+		start  = times.ResetMonth(times.Add(end, -3, times.YEAR))
+		months = times.Months(start, end)
+	)
+	insert = []*alarmsimport.Model{}
+
+	for i := 0; i < n; i++ {
+		var accountI = rand.IntN(len(accounts))
+		var monthI = rand.IntN(len(months))
+		var ts = times.Add(months[monthI], rand.IntN(27*24), times.HOUR)
+		var seconds = int64(60 + rand.IntN(4*60*60)) // 1m - 4h
+		var name = fmt.Sprintf("alarm-%02d", rand.IntN(20)+1)
+
+		insert = append(insert,
+			&alarmsimport.Model{
+				AccountID:      accounts[accountI].ID,
+				AlarmName:      name,
+				Month:          times.AsYMString(ts),
+				TransitionedAt: times.AsString(ts, times.FULL),
+				StateFrom:      "OK",
+				StateTo:        alarmsimport.StateAlarm,
+				SecondsInAlarm: seconds,
+			},
+			&alarmsimport.Model{
+				AccountID:      accounts[accountI].ID,
+				AlarmName:      name,
+				Month:          times.AsYMString(ts),
+				TransitionedAt: times.AsString(ts.Add(time.Duration(seconds)*time.Second), times.FULL),
+				StateFrom:      alarmsimport.StateAlarm,
+				StateTo:        "OK",
+			},
+		)
+	}
+	err = dbx.Insert(ctx, alarmsimport.InsertStatement, insert, in)
+
+	return
+}
+
+// seedImportRuns generates an import run for each of the main datasets
+// with a mix of fresh, stale and failed runs
+func seedImportRuns(ctx context.Context, in *dbx.InsertArgs) (insert []*importruns.Model, err error) {
+	var now = time.Now().UTC()
+	var runs = []struct {
+		command string
+		ago     time.Duration
+		status  string
+	}{
+		{"teams", 30 * 24 * time.Hour, importruns.StatusSuccess},
+		{"accounts", 30 * 24 * time.Hour, importruns.StatusSuccess},
+		{"costs", 2 * time.Hour, importruns.StatusSuccess},
+		{"uptime", 21 * 24 * time.Hour, importruns.StatusSuccess},
+		{"codebases", 26 * time.Hour, importruns.StatusSuccess},
+		{"codebases", 2 * time.Hour, importruns.StatusFailed},
+	}
+	insert = []*importruns.Model{}
+	for _, r := range runs {
+		var m = &importruns.Model{
+			Command:   r.command,
+			Arguments: "{}",
+			StartedAt: times.AsString(now.Add(-r.ago-time.Minute), times.FULL),
+			EndedAt:   times.AsString(now.Add(-r.ago), times.FULL),
+			Status:    r.status,
+			Rows:      int64(rand.IntN(1000)),
+		}
+		if r.status == importruns.StatusFailed {
+			m.Error = "seeded failure"
+			m.Rows = 0
+		}
+		insert = append(insert, m)
+	}
+	err = dbx.Insert(ctx, importruns.InsertStatement, insert, in)
+	return
+}
+
 // seedCosts generates and inserts cost data similar to real life values
 func seedCosts(ctx context.Context, in *dbx.InsertArgs, n int, accounts []*accountimport.Model) (insert []*costimport.Model, err error) {
 	var (
@@ -214,11 +809,12 @@ func seedAccounts(ctx context.Context, in *dbx.InsertArgs, n int, teams []*teami
 		var teamI = rand.IntN(len(teams))
 		var id = fmt.Sprintf("%04d", i+1)
 		insert = append(insert, &accountimport.Model{
-			ID:          id,
-			Name:        fmt.Sprintf("Account %s", id),
-			Label:       fmt.Sprintf("%d", i+1),
-			Environment: environmentList[envI],
-			TeamName:    teams[teamI].Name,
+			ID:             id,
+			Name:           fmt.Sprintf("Account %s", id),
+			Label:          fmt.Sprintf("%d", i+1),
+			Environment:    environmentList[envI],
+			UptimeTracking: true,
+			TeamName:       teams[teamI].Name,
 		})
 	}
 
@@ -2,6 +2,7 @@ package seeds
 
 import (
 	"context"
+	"opg-reports/report/internal/standards/standardsimport"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/logger"
 	"path/filepath"
@@ -36,9 +37,45 @@ func TestSeedsSeedAll(t *testing.T) {
 	if len(res.Uptime) < 100 {
 		t.Errorf("not enough uptime records generated")
 	}
+	if len(res.Alarms) < 100 {
+		t.Errorf("not enough alarm records generated")
+	}
+	if len(res.Runs) == 0 {
+		t.Errorf("no import runs generated")
+	}
 	if len(res.Codebases) < 10 {
 		t.Errorf("not enough codebase records generated")
 	}
+	if len(res.Owners) != len(res.Codebases) {
+		t.Errorf("expected an owner for each codebase")
+	}
+	if len(res.CodeFiles) != len(res.Codebases) || len(res.CodeRules) == 0 {
+		t.Errorf("expected a codeowners file row for every codebase with some rules")
+	}
+	if len(res.Dora) < 100 {
+		t.Errorf("not enough dora records generated")
+	}
+	if len(res.Usage) < 100 {
+		t.Errorf("not enough workflow usage records generated")
+	}
+	if len(res.Workflows) < 100 {
+		t.Errorf("not enough workflow run records generated")
+	}
+	if len(res.Advisories) == 0 || len(res.Alerts) < 100 {
+		t.Errorf("not enough dependabot records generated")
+	}
+	if len(res.CodeScans) < 100 || len(res.Secrets) == 0 {
+		t.Errorf("not enough code or secret scanning records generated")
+	}
+	if len(res.Branches) != len(res.Codebases) {
+		t.Errorf("expected branch protection for every codebase")
+	}
+	if len(res.Stats) != len(res.Codebases) || len(res.Tools) == 0 {
+		t.Errorf("expected codebase stats for every codebase with some security tools")
+	}
+	if len(res.Standards) != len(res.Codebases)*len(standardsimport.DefaultRules) {
+		t.Errorf("expected every default standard for every codebase")
+	}
 
 	// dump.Now(res)
 
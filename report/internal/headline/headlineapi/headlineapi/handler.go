@@ -26,22 +26,27 @@ SELECT
 FROM costs
 LEFT JOIN accounts on accounts.id = costs.account_id
 WHERE
-	costs.service != 'Tax'
+	costs.service NOT IN (:excluded_services)
 	AND costs.month IN (:months)
 ;
 `
 
-// average uptime between all services
+// average uptime between all services of a single uptime source
 const uptimeSelect string = `
 SELECT
 	CAST(COALESCE(AVG(uptime.average), 0) as REAL) as uptime
 FROM uptime
 LEFT JOIN accounts on accounts.id = uptime.account_id
 WHERE
-	uptime.month IN (:months)
+	uptime.source = :source
+	AND uptime.month IN (:months)
 ;
 `
 
+// defaultUptimeSource is the uptime source used when the request does not
+// set one; route53 health checks are the source used for the headline figure
+const defaultUptimeSource string = "route53"
+
 // codebase standards
 const codebaseSelect string = `
 SELECT
@@ -80,11 +85,38 @@ WHERE
 ;
 `
 
+// open code scanning alerts of active codebases, with those of critical or high severity
+const codeScanningSelect string = `
+SELECT
+	COUNT(code_scanning_alerts.id) as open_alerts,
+	COALESCE(SUM(code_scanning_alerts.severity IN ('critical','high')),0) as open_severe
+FROM code_scanning_alerts
+LEFT JOIN codebases on codebases.full_name = code_scanning_alerts.codebase
+WHERE
+	codebases.archived = 0
+	AND code_scanning_alerts.state = 'open'
+;
+`
+
+// open secret scanning alerts of active codebases, with those that are critical
+const secretScanningSelect string = `
+SELECT
+	COUNT(secret_scanning_alerts.id) as open_alerts,
+	COALESCE(SUM(secret_scanning_alerts.severity = 'critical'),0) as open_severe
+FROM secret_scanning_alerts
+LEFT JOIN codebases on codebases.full_name = secret_scanning_alerts.codebase
+WHERE
+	codebases.archived = 0
+	AND secret_scanning_alerts.state = 'open'
+;
+`
+
 // Request contains url path / query values
 type Request struct {
 	DateStart string `json:"date_start"`
 	DateEnd   string `json:"date_end"`
 	Team      string `json:"team"`
+	Source    string `json:"source"` // optional query string; uptime source (route53 / synthetics) to average, defaults to route53
 }
 
 func (self *Request) Start() (t time.Time) {
@@ -108,8 +140,10 @@ type Response struct {
 // Filter is with the sql to replace the `:name` named parameters within the
 // statement.
 type Filter struct {
-	Months []string `json:"months"`
-	Team   string   `json:"team"`
+	Months           []string `json:"months"`
+	ExcludedServices []string `json:"excluded_services"` // services to leave out of the totals
+	Team             string   `json:"team"`
+	Source           string   `json:"source"` // uptime source to average
 }
 
 type Result struct {
@@ -124,7 +158,11 @@ type Result struct {
 	// Releases
 	Releases            int `json:"releases"`             // count releases
 	ReleasesSecurityish int `json:"releases_securityish"` // count of releases if they likely sec
-
+	// Security alerts
+	CodeScanningOpen         int `json:"code_scanning_open"`          // count of open code scanning alerts
+	CodeScanningOpenSevere   int `json:"code_scanning_open_severe"`   // count of open critical & high code scanning alerts
+	SecretScanningOpen       int `json:"secret_scanning_open"`        // count of open secret scanning alerts
+	SecretScanningOpenSevere int `json:"secret_scanning_open_severe"` // count of open critical secret scanning alerts
 }
 
 // Responder process the incoming request, queries the database and returns the result as json data.
@@ -149,11 +187,15 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 		return
 	}
 	// setup month filter
-	filter = &Filter{Months: months}
+	filter = &Filter{Months: months, ExcludedServices: conf.Excluded(), Source: defaultUptimeSource}
 	if in.Team != "" {
 		log.Info("optional team filter found ...", "team", in.Team)
 		filter.Team = in.Team
 	}
+	if in.Source != "" {
+		log.Info("optional source filter found ...", "source", in.Source)
+		filter.Source = in.Source
+	}
 	// now convert to a map for use in bound statements
 	err = cnv.Convert(filter, &bindMap)
 	if err != nil {
@@ -168,6 +210,8 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 	codebasesSelectRun(ctx, conf, filter, bindMap, res)
 	// release info
 	releasesSelectRun(ctx, conf, filter, bindMap, res)
+	// security alerts
+	alertsSelectRun(ctx, conf, filter, bindMap, res)
 
 	response = &Response{
 		Version: conf.Version,
@@ -306,3 +350,34 @@ func releasesSelectRun(ctx context.Context, conf *apimodels.Args, filter *Filter
 
 	return res
 }
+
+// alertsSelectRun runs the code scanning and secret scanning selects and fetches the
+// open alert counts
+func alertsSelectRun(ctx context.Context, conf *apimodels.Args, filter *Filter, bindMap map[string]interface{}, res *Result) *Result {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "headlineapi", "func", "alertsSelectRun")
+	var selects = map[string][]any{
+		codeScanningSelect:   {&res.CodeScanningOpen, &res.CodeScanningOpenSevere},
+		secretScanningSelect: {&res.SecretScanningOpen, &res.SecretScanningOpenSevere},
+	}
+
+	for stmt, scan := range selects {
+		if filter.Team != "" {
+			log.Info("optional team filter found ...", "team", filter.Team)
+			stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.full_name IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND")
+		}
+		dbx.Select(ctx, stmt, &dbx.SelectArgs{
+			DB:      conf.DB,
+			Driver:  conf.Driver,
+			Params:  conf.Params,
+			BindMap: bindMap,
+			ScanF: func(rows *sql.Rows) error {
+				var err error
+				if err = rows.Scan(scan...); err != nil {
+					log.Error("row scan failed", "err", err.Error())
+				}
+				return err
+			},
+		})
+	}
+	return res
+}
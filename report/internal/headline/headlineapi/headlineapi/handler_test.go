@@ -0,0 +1,60 @@
+package headlineapi
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"testing"
+)
+
+func TestHeadlineAPIUptimeSource(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		driver = "sqlite3"
+		dbpath = filepath.Join(t.TempDir(), "test-handler.db")
+		end    = times.AsYMString(times.Today())
+		start  = times.AsYMString(times.Add(times.Today(), -3, times.YEAR))
+		months = times.AsYMStrings(times.Months(times.MustFromString(start), times.MustFromString(end)))
+		// average uptime of a single source calculated directly
+		expected = func(source string) (avg float64) {
+			dbx.Select(ctx, `SELECT CAST(COALESCE(AVG(average), 0) as REAL) FROM uptime WHERE source = :source AND month IN (:months);`, &dbx.SelectArgs{
+				DB: dbpath, Driver: driver,
+				BindMap: map[string]interface{}{"source": source, "months": months},
+				ScanF:   func(rows *sql.Rows) error { return rows.Scan(&avg) },
+			})
+			return
+		}
+		// overall uptime from the handler
+		get = func(query string) float64 {
+			var rec = &Response{}
+			var mux = http.NewServeMux()
+			var writer = httptest.NewRecorder()
+			Register(ctx, mux, &apimodels.Args{Driver: driver, DB: dbpath})
+			mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/v1/headlines/"+start+"/"+end+"/"+query, nil))
+			if err := response.As(writer.Result(), &rec); err != nil {
+				t.Fatalf("error converting ...")
+			}
+			return rec.Data.OverallUptime
+		}
+	)
+	if _, err = seeds.SeedAll(ctx, &seeds.Args{Driver: driver, DB: dbpath}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// route53 is used by default rather than an average of every source
+	if got, exp := get(""), expected("route53"); math.Abs(got-exp) > 0.0001 {
+		t.Errorf("expected route53 uptime by default, expected [%f] got [%f]", exp, got)
+	}
+	if got, exp := get("?source=synthetics"), expected("synthetics"); math.Abs(got-exp) > 0.0001 {
+		t.Errorf("expected synthetics uptime, expected [%f] got [%f]", exp, got)
+	}
+}
@@ -0,0 +1,263 @@
+// Package secretscanningimport fetches every secret scanning alert, open or resolved,
+// of each repository.
+//
+// The rule of an alert is the type of secret found. Github does not give secret alerts
+// a severity, so one is set from what is known about the secret: `critical` when it has
+// been publicly leaked or is still valid (`active`), otherwise `high`. Age is how long
+// the alert has been open at the time of import, or was open for once resolved.
+//
+// Repositories without secret scanning enabled are skipped.
+package secretscanningimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement adds or updates a secret scanning alert
+const InsertStatement string = `
+INSERT INTO secret_scanning_alerts (
+	codebase,
+	number,
+	state,
+	rule,
+	severity,
+	resolution,
+	opened_at,
+	closed_at,
+	age_seconds
+) VALUES (
+	:codebase,
+	:number,
+	:state,
+	:rule,
+	:severity,
+	:resolution,
+	:opened_at,
+	:closed_at,
+	:age_seconds
+) ON CONFLICT (codebase,number) DO UPDATE SET
+	state=excluded.state,
+	rule=excluded.rule,
+	severity=excluded.severity,
+	resolution=excluded.resolution,
+	opened_at=excluded.opened_at,
+	closed_at=excluded.closed_at,
+	age_seconds=excluded.age_seconds
+RETURNING id
+;
+`
+
+// alert states used by github
+const (
+	StateOpen     string = "open"
+	StateResolved string = "resolved"
+)
+
+// severities set on the alerts, see package docs
+const (
+	SeverityCritical string = "critical"
+	SeverityHigh     string = "high"
+)
+
+// validityActive is the validity of a secret that still works
+const validityActive string = "active"
+
+var ErrFailedGettingAlerts = errors.New("error getting secret scanning alerts.")
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// secretScanningClient wrapper around *github.SecretScanningService
+type secretScanningClient interface {
+	// api docs - https://docs.github.com/rest/secret-scanning/secret-scanning#list-secret-scanning-alerts-for-a-repository
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error)
+}
+
+type Args struct {
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams          teamClient           // *github.TeamsService
+	SecretScanning secretScanningClient // *github.SecretScanningService
+}
+
+// Model is a single secret scanning alert of a codebase
+type Model struct {
+	Codebase   string `json:"codebase"`    // full name of codebase
+	Number     int    `json:"number"`      // alert number, unique within the codebase
+	State      string `json:"state"`       // open or resolved
+	Rule       string `json:"rule"`        // type of secret found
+	Severity   string `json:"severity"`    // see package docs
+	Resolution string `json:"resolution"`  // why the alert was resolved (revoked, false_positive etc)
+	OpenedAt   string `json:"opened_at"`   // when the alert was raised
+	ClosedAt   string `json:"closed_at"`   // when the alert was resolved
+	AgeSeconds int64  `json:"age_seconds"` // how long the alert has been, or was, open
+}
+
+// Import finds all github repositories and writes their secret scanning alerts
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "secretscanningimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+
+	log.Info("starting ...")
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, err = handler(ctx, clients, in, repoList, time.Now().UTC())
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.", "alerts", len(data))
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository, now time.Time) (data []*Model, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+
+	data = []*Model{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (list []*Model, err error) {
+		var alerts []*github.SecretScanningAlert
+		if alerts, err = repoAlerts(ctx, clients.SecretScanning, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+			return
+		}
+		list = []*Model{}
+		for _, a := range alerts {
+			list = append(list, ToModel(repo.GetFullName(), a, now))
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, list := range found {
+		data = append(data, list...)
+	}
+	return
+}
+
+// repoAlerts fetches all pages of alerts for the repository
+func repoAlerts(ctx context.Context, client secretScanningClient, repo *github.Repository) (alerts []*github.SecretScanningAlert, err error) {
+	var (
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "secretscanningimport", "func", "repoAlerts", "repo", repo.GetName())
+		opts              = &github.SecretScanningAlertListOptions{ListOptions: github.ListOptions{PerPage: 100, Page: 1}}
+	)
+	alerts = []*github.SecretScanningAlert{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting secret scanning alerts ...")
+	for opts.ListOptions.Page > 0 {
+		var (
+			page     []*github.SecretScanningAlert
+			response *github.Response
+		)
+		err = retry.Do(ctx, func() (e error) {
+			page, response, e = client.ListAlertsForRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opts)
+			return
+		})
+		if err != nil && disabled(response) {
+			log.Warn("secret scanning is not enabled, skipping.")
+			err = nil
+			return
+		} else if err != nil {
+			err = errors.Join(ErrFailedGettingAlerts, err)
+			return
+		}
+		alerts = append(alerts, page...)
+		opts.ListOptions.Page = 0
+		if response != nil {
+			opts.ListOptions.Page = response.NextPage
+		}
+	}
+	log.Info("found secret scanning alerts ...", "count", len(alerts))
+	return
+}
+
+// disabled returns true when the response shows the alerts are not available for
+// the repository, rather than the call failing
+func disabled(response *github.Response) bool {
+	if response == nil || response.Response == nil {
+		return false
+	}
+	return response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusNotFound
+}
+
+// ToModel converts the github alert into a Model for the codebase; now is used
+// for the age of open alerts
+func ToModel(codebase string, a *github.SecretScanningAlert, now time.Time) (m *Model) {
+	var opened = a.GetCreatedAt().Time
+	var closed = now
+	m = &Model{
+		Codebase:   codebase,
+		Number:     a.GetNumber(),
+		State:      a.GetState(),
+		Rule:       a.GetSecretType(),
+		Severity:   SeverityHigh,
+		Resolution: a.GetResolution(),
+		OpenedAt:   times.AsString(opened, times.FULL),
+	}
+	if a.GetPubliclyLeaked() || a.GetValidity() == validityActive {
+		m.Severity = SeverityCritical
+	}
+	if a.ResolvedAt != nil {
+		closed = a.GetResolvedAt().Time
+		m.ClosedAt = times.AsString(closed, times.FULL)
+	}
+	m.AgeSeconds = int64(closed.Sub(opened) / time.Second)
+	return
+}
@@ -0,0 +1,98 @@
+package secretscanningimport
+
+import (
+	"context"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/times"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockSecretScanning returns a page of alerts per page number; repos named "disabled"
+// have secret scanning turned off
+type mockSecretScanning struct {
+	pages map[int][]*github.SecretScanningAlert
+	calls int
+}
+
+func (self *mockSecretScanning) ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error) {
+	self.calls++
+	if repo == "disabled" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Secret scanning is disabled on this repository."}
+	}
+	resp := &github.Response{}
+	if opts.ListOptions.Page == 1 {
+		resp.NextPage = 2
+	}
+	return self.pages[opts.ListOptions.Page], resp, nil
+}
+
+func alert(number int, validity string, opened time.Time, resolved *time.Time) *github.SecretScanningAlert {
+	var a = &github.SecretScanningAlert{
+		Number:     github.Ptr(number),
+		State:      github.Ptr(StateOpen),
+		SecretType: github.Ptr("github_personal_access_token"),
+		Validity:   github.Ptr(validity),
+		CreatedAt:  &github.Timestamp{Time: opened},
+	}
+	if resolved != nil {
+		a.State = github.Ptr(StateResolved)
+		a.Resolution = github.Ptr("revoked")
+		a.ResolvedAt = &github.Timestamp{Time: *resolved}
+	}
+	return a
+}
+
+func TestSecretScanningImportHandler(t *testing.T) {
+	var (
+		ctx      = cntxt.AddLogger(t.Context(), logger.New("error"))
+		opened   = times.MustFromString("2025-01-10")
+		resolved = opened.Add(48 * time.Hour)
+		now      = opened.Add(72 * time.Hour)
+		owner    = &github.User{Login: github.Ptr("org")}
+		client   = &mockSecretScanning{pages: map[int][]*github.SecretScanningAlert{
+			1: {alert(1, "active", opened, nil)},
+			2: {alert(2, "inactive", opened, &resolved), alert(3, "unknown", opened, nil)},
+		}}
+		repoList = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner},
+			{Name: github.Ptr("disabled"), FullName: github.Ptr("org/disabled"), Owner: owner},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, Archived: github.Ptr(true)},
+		}
+	)
+	alerts, err := handler(ctx, &Clients{SecretScanning: client}, &Args{}, repoList, now)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// both pages of the first repo, disabled and archived are skipped without error
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, found [%d]", len(alerts))
+	}
+	if client.calls != 3 {
+		t.Errorf("expected 3 api calls, found [%d]", client.calls)
+	}
+	for _, a := range alerts {
+		if a.Codebase != "org/repo" || a.Rule != "github_personal_access_token" {
+			t.Errorf("unexpected alert: %+v", a)
+		}
+		switch a.Number {
+		case 1:
+			if a.Severity != SeverityCritical || a.ClosedAt != "" || a.AgeSeconds != int64((72*time.Hour).Seconds()) {
+				t.Errorf("active secret should be critical and aged until now: %+v", a)
+			}
+		case 2:
+			if a.Severity != SeverityHigh || a.Resolution != "revoked" || a.AgeSeconds != int64((48*time.Hour).Seconds()) {
+				t.Errorf("resolved secret should be aged until resolved: %+v", a)
+			}
+		case 3:
+			if a.Severity != SeverityHigh {
+				t.Errorf("secret of unknown validity should be high: %+v", a)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package standardsapi
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"slices"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt returns the result of every rule for each active codebase
+const selectStmt string = `
+SELECT
+	codebase_standards.codebase,
+	codebase_standards.rule,
+	codebase_standards.description,
+	codebase_standards.passed,
+	codebase_standards.detail
+FROM codebase_standards
+LEFT JOIN codebases on codebases.full_name = codebase_standards.codebase
+WHERE
+	codebases.archived = 0
+ORDER BY
+	codebase_standards.codebase ASC,
+	codebase_standards.rule ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE codebase_standards.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	Team string `json:"team"` // optional team filter, rolled up via codebase_owners
+	Org  string `json:"org"`  // optional github org filter (?org=)
+	Rule string `json:"rule"` // optional rule name filter (?rule=has-codeowners)
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string      `json:"version"`
+	SHA     string      `json:"sha"`
+	Request *Request    `json:"request"`
+	Data    []*Model    `json:"data"`    // the actual data results
+	Summary []*RuleStat `json:"summary"` // pass counts of each rule, in rule name order
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Team string `json:"team"`
+	Org  string `json:"org"`
+	Rule string `json:"rule"`
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	Codebase    string `json:"codebase"`
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Passed      int    `json:"passed"`
+	Detail      string `json:"detail"` // what was found, such as the matching file
+}
+
+// RuleStat is how many codebases pass a rule
+type RuleStat struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Passed      int    `json:"passed"`
+	Total       int    `json:"total"`
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Codebase,
+		&self.Rule,
+		&self.Description,
+		&self.Passed,
+		&self.Detail,
+	}
+}
+
+// Summarise counts the codebases passing each rule, in rule name order
+func Summarise(all []*Model) (stats []*RuleStat) {
+	var byRule = map[string]*RuleStat{}
+	stats = []*RuleStat{}
+	for _, m := range all {
+		stat, ok := byRule[m.Rule]
+		if !ok {
+			stat = &RuleStat{Rule: m.Rule, Description: m.Description}
+			byRule[m.Rule] = stat
+			stats = append(stats, stat)
+		}
+		stat.Passed += m.Passed
+		stat.Total++
+	}
+	slices.SortFunc(stats, func(a, b *RuleStat) int { return strings.Compare(a.Rule, b.Rule) })
+	return
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  []*RuleStat            = []*RuleStat{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "standardsapi", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// look for the optional rule
+	if in.Rule != "" {
+		log.Info("optional rule filter found ...", "rule", in.Rule)
+		filter.Rule = in.Rule
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebase_standards.rule = :rule AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	summary = Summarise(all)
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
@@ -0,0 +1,78 @@
+package standardsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/internal/standards/standardsimport"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStandardsAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE, nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 {
+		t.Fatalf("expected results")
+	}
+	if len(all.Summary) != len(standardsimport.DefaultRules) {
+		t.Errorf("expected a summary for each rule, found [%d]", len(all.Summary))
+	}
+	for _, s := range all.Summary {
+		if s.Total == 0 || s.Passed > s.Total {
+			t.Errorf("unexpected rule summary: %+v", s)
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(ENDPOINT_TEAM, "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(team.Data) == 0 || len(team.Data) >= len(all.Data) {
+		t.Errorf("expected team to have a subset of results, got [%d] of [%d]", len(team.Data), len(all.Data))
+	}
+
+	// rule filter only returns that rule
+	rule := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, ENDPOINT_BASE+"?rule=has-codeowners", nil))
+	if err = response.As(writer.Result(), &rule); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(rule.Summary) != 1 || rule.Summary[0].Rule != "has-codeowners" {
+		t.Errorf("expected only the filtered rule: %+v", rule.Summary)
+	}
+}
@@ -0,0 +1,141 @@
+package standardsfront
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/standards/standardsapi"
+	"opg-reports/report/internal/status/statusfront"
+	"opg-reports/report/internal/team/teamapi/teamapiall"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/htmlpage"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/rest"
+	"opg-reports/report/package/tmpl"
+	"sync"
+)
+
+type PageContent struct {
+	htmlpage.HTMLPage
+	Team          string
+	StandardsData *frontmodels.StandardsData
+}
+
+type dataCallerF func(wg *sync.WaitGroup, page *PageContent)
+
+// Handler deals with the repository standards matrix for all codebases or those of a team
+func Handler(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request, writer http.ResponseWriter) {
+	var (
+		page         *PageContent
+		templateName string
+		team         string         = request.PathValue("team")
+		wg           sync.WaitGroup = sync.WaitGroup{}
+		log          *slog.Logger   = cntxt.GetLogger(ctx).With("package", "standardsfront", "func", "Handler", "url", request.URL.String())
+	)
+	log.Info("starting ...")
+	page, templateName = getPage(team, args, request)
+	if team != "" {
+		log.Info("found team parameter ... ", "team", team)
+	}
+	// page data fetched from api via blocks
+	for _, blockF := range dataCallers(ctx, args, request) {
+		wg.Add(1)
+		go blockF(&wg, page)
+	}
+	wg.Wait()
+
+	// respond
+	respond.AsHTML(ctx, request, writer, page, &respond.Args{
+		Template:      templateName,
+		TemplateFiles: tmpl.GetTemplateFiles(args.TemplateDir),
+		Funcs:         tmpl.TemplateFunctions(),
+	})
+	log.Info("complete.")
+}
+
+func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (page *PageContent, template string) {
+	var args *htmlpage.Args = &htmlpage.Args{
+		Name:         "OPG Reports",
+		Title:        "OPG Reports - Repository Standards",
+		GovUKVersion: in.GovUKVersion,
+		SemVer:       in.SemVer,
+	}
+	template = "standards"
+	if team != "" {
+		args.Title += " - " + cnv.Capitalize(team)
+	}
+	page = &PageContent{
+		HTMLPage:      htmlpage.New(request, args),
+		Team:          team,
+		StandardsData: &frontmodels.StandardsData{Team: team},
+	}
+	return
+}
+
+// dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
+func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
+	var (
+		team     = request.PathValue("team")
+		endpoint = standardsapi.ENDPOINT_BASE
+		params   = []*rest.Param{
+			// optional filters passed through from the front end request
+			{Type: rest.QUERY, Key: "org"},
+			{Type: rest.QUERY, Key: "rule"},
+		}
+	)
+	// add team filter values and url
+	if team != "" {
+		endpoint = standardsapi.ENDPOINT_TEAM
+		params = append(params, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
+	}
+
+	funcs = []dataCallerF{
+		// get teams
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*teamapiall.Response](ctx, args.ApiHost, teamapiall.ENDPOINT, request)
+			if err == nil {
+				page.Teams = resp.Data
+			}
+			wg.Done()
+		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
+		// get the result of each rule for each codebase
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*standardsapi.Response](ctx, args.ApiHost, endpoint, request, params...)
+			if err == nil {
+				rules := []*frontmodels.StandardsRule{}
+				results := []*frontmodels.StandardsResult{}
+				// convert to front end version
+				cnv.Convert(resp.Summary, &rules)
+				cnv.Convert(resp.Data, &results)
+				page.StandardsData.Rules = rules
+				page.StandardsData.Codebases = matrix(results)
+			}
+			wg.Done()
+		},
+	}
+	return
+}
+
+// matrix groups the results into a row per codebase, keeping the api order
+func matrix(results []*frontmodels.StandardsResult) (rows []*frontmodels.StandardsCodebase) {
+	var byCodebase = map[string]*frontmodels.StandardsCodebase{}
+	rows = []*frontmodels.StandardsCodebase{}
+	for _, r := range results {
+		row, ok := byCodebase[r.Codebase]
+		if !ok {
+			row = &frontmodels.StandardsCodebase{Codebase: r.Codebase, Results: map[string]*frontmodels.StandardsResult{}}
+			byCodebase[r.Codebase] = row
+			rows = append(rows, row)
+		}
+		row.Results[r.Rule] = r
+		row.Passed += r.Passed
+	}
+	return
+}
@@ -0,0 +1,387 @@
+// Package standardsimport checks each repository against the repository standards
+// (rules) declared in config and stores a pass / fail result per rule.
+//
+// Everything a rule can look at is fetched once per repository into a Snapshot:
+//   - the repository metadata from the listing, flattened to dot separated settings
+//     (`default_branch`, `security_and_analysis.secret_scanning.status` etc)
+//   - every file path on the default branch (a single recursive tree call)
+//   - the parsed workflow & composite action files (see ghworkflows), only when a
+//     rule needs them; files that fail to parse are tracked rather than skipped
+//
+// The table is a snapshot of the current state, so it is replaced on each import;
+// when some repositories fail, only the results of the others are replaced.
+// Archived and empty repositories are skipped.
+package standardsimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/ghworkflows"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/workers"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement adds or updates the result of a rule for a codebase
+const InsertStatement string = `
+INSERT INTO codebase_standards (
+	codebase,
+	rule,
+	description,
+	passed,
+	detail
+) VALUES (
+	:codebase,
+	:rule,
+	:description,
+	:passed,
+	:detail
+) ON CONFLICT (codebase,rule) DO UPDATE SET
+	description=excluded.description,
+	passed=excluded.passed,
+	detail=excluded.detail
+RETURNING id
+;
+`
+const truncateStmt string = `DELETE FROM codebase_standards;`
+
+// deleteCodebaseStmt removes the results of a single codebase
+const deleteCodebaseStmt string = `DELETE FROM codebase_standards WHERE codebase = ?;`
+
+// deleteRemovedStmt removes the results of codebases no longer in the repository list
+const deleteRemovedStmt string = `DELETE FROM codebase_standards WHERE codebase NOT IN (:codebases);`
+
+var (
+	ErrFailedGettingTree    = errors.New("error getting repository file tree.")
+	ErrFailedGettingContent = errors.New("error getting workflow file content.")
+)
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// repoClient wrapper around *github.RepositoriesService
+type repoClient interface {
+	DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error)
+}
+
+// gitClient wrapper around *github.GitService
+type gitClient interface {
+	// api docs - https://docs.github.com/rest/git/trees#get-a-tree
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error)
+}
+
+type Args struct {
+	DB           string   `json:"db"`             // database path
+	Driver       string   `json:"driver"`         // database driver
+	Params       string   `json:"params"`         // database connection params
+	OrgSlug      string   `json:"org_slug"`       // github org name
+	ParentSlug   string   `json:"parent_slug"`    // parent slug
+	Sources      []string `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool     `json:"recursive"`      // include repositories of all child teams
+	FilterByName string   `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+
+	Rules []*Rule `json:"rules"` // optional; standards to check, uses DefaultRules when empty
+}
+
+type Clients struct {
+	Teams teamClient // *github.TeamsService
+	Repos repoClient // *github.RepositoriesService
+	Git   gitClient  // *github.GitService
+}
+
+// Model is the result of a single rule for a codebase
+type Model struct {
+	Codebase    string `json:"codebase"`    // full name of codebase
+	Rule        string `json:"rule"`        // name of the rule
+	Description string `json:"description"` // description of the rule
+	Passed      int    `json:"passed"`      // 1 when the codebase meets the rule
+	Detail      string `json:"detail"`      // what was found, see Rule.Evaluate
+}
+
+// Import finds all github repositories, checks them against the rules and
+// replaces the stored results
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "standardsimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+	var done = []string{}
+
+	if len(in.Rules) == 0 {
+		in.Rules = DefaultRules
+	}
+	if err = Validate(in.Rules); err != nil {
+		return
+	}
+
+	log.Info("starting ...", "rules", len(in.Rules))
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, done, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	if err = removeExisting(ctx, in, done, repoList); err != nil {
+		log.Error("error removing existing results", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.", "results", len(data))
+	return
+}
+
+// removeExisting deletes the stored results ahead of writing the new ones. The
+// table is emptied when every repository was processed, otherwise the results
+// of the processed repositories (done) and of any codebase no longer in the
+// repository list (such as archived or removed repositories) are removed, so
+// a repository that failed keeps its previous results.
+func removeExisting(ctx context.Context, in *Args, done []string, repositories []*github.Repository) (err error) {
+	var (
+		args  = &dbx.ExecArgs{DB: in.DB, Driver: in.Driver, Params: in.Params}
+		names = []string{}
+		stmt  string
+		binds []interface{}
+	)
+	if len(done) == len(repositories) {
+		return dbx.Exec(ctx, truncateStmt, args)
+	}
+	for _, repo := range repositories {
+		names = append(names, repo.GetFullName())
+	}
+	if stmt, binds, err = dbx.Bind(ctx, deleteRemovedStmt, map[string]interface{}{"codebases": names}); err != nil {
+		return
+	}
+	if err = dbx.Exec(ctx, stmt, args, binds...); err != nil {
+		return
+	}
+	for _, codebase := range done {
+		if err = dbx.Exec(ctx, deleteCodebaseStmt, args, codebase); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail. done is the full name of each repository
+// that was processed.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*Model, done []string, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+	var withWorkflows = NeedsWorkflows(in.Rules)
+
+	data = []*Model{}
+	done = []string{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (list []*Model, err error) {
+		var snap *Snapshot
+		if snap, err = GetSnapshot(ctx, clients, repo, withWorkflows); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+			return
+		}
+		list = []*Model{}
+		if snap == nil {
+			return
+		}
+		for _, rule := range in.Rules {
+			list = append(list, ToModel(repo.GetFullName(), rule, snap))
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, repo := range workers.Succeeded(repoList, results) {
+		done = append(done, repo.GetFullName())
+	}
+	for _, list := range found {
+		data = append(data, list...)
+	}
+	return
+}
+
+// ToModel evaluates the rule against the snapshot for the codebase
+func ToModel(codebase string, rule *Rule, snap *Snapshot) (m *Model) {
+	var passed, detail = rule.Evaluate(snap)
+	m = &Model{
+		Codebase:    codebase,
+		Rule:        rule.Name,
+		Description: rule.Description,
+		Detail:      detail,
+	}
+	if passed {
+		m.Passed = 1
+	}
+	return
+}
+
+// GetSnapshot fetches everything the rules need for the repository; returns nil
+// for archived and empty repositories
+func GetSnapshot(ctx context.Context, clients *Clients, repo *github.Repository, withWorkflows bool) (snap *Snapshot, err error) {
+	var (
+		log      *slog.Logger = cntxt.GetLogger(ctx).With("package", "standardsimport", "func", "GetSnapshot", "repo", repo.GetName())
+		owner                 = repo.GetOwner().GetLogin()
+		tree     *github.Tree
+		response *github.Response
+	)
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting repository snapshot ...")
+	err = retry.Do(ctx, func() (e error) {
+		tree, response, e = clients.Git.GetTree(ctx, owner, repo.GetName(), repo.GetDefaultBranch(), true)
+		return
+	})
+	if err != nil && empty(response) {
+		log.Warn("repository is empty, skipping.")
+		err = nil
+		return
+	} else if err != nil {
+		err = errors.Join(ErrFailedGettingTree, err)
+		return
+	}
+	if tree.GetTruncated() {
+		log.Warn("file tree is truncated, file rules may fail.")
+	}
+
+	snap = &Snapshot{
+		Settings:       Settings(repo),
+		Files:          []string{},
+		Workflows:      map[string]*ghworkflows.File{},
+		WorkflowErrors: []string{},
+	}
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			snap.Files = append(snap.Files, entry.GetPath())
+		}
+	}
+	if !withWorkflows {
+		return
+	}
+	for _, file := range snap.Files {
+		var content []byte
+		var parsed *ghworkflows.File
+		if !ghworkflows.IsWorkflowFile(file) {
+			continue
+		}
+		err = retry.Do(ctx, func() (e error) {
+			var rc io.ReadCloser
+			rc, _, e = clients.Repos.DownloadContents(ctx, owner, repo.GetName(), file, &github.RepositoryContentGetOptions{Ref: repo.GetDefaultBranch()})
+			if e != nil {
+				return
+			}
+			defer rc.Close()
+			content, e = io.ReadAll(rc)
+			return
+		})
+		if err != nil {
+			err = errors.Join(ErrFailedGettingContent, fmt.Errorf("file [%s]", file), err)
+			return
+		}
+		if parsed, err = ghworkflows.Parse(file, content); err != nil {
+			log.Warn("workflow file could not be parsed", "file", file, "err", err.Error())
+			snap.WorkflowErrors = append(snap.WorkflowErrors, file)
+			err = nil
+			continue
+		}
+		snap.Workflows[file] = parsed
+	}
+	return
+}
+
+// empty returns true when the response shows the repository has no commits
+// (or the branch is gone), rather than the call failing
+func empty(response *github.Response) bool {
+	if response == nil || response.Response == nil {
+		return false
+	}
+	return response.StatusCode == http.StatusConflict || response.StatusCode == http.StatusNotFound
+}
+
+// Settings flattens the repository metadata into dot separated keys with string
+// values; lists of values are comma separated and objects are walked into
+func Settings(repo *github.Repository) (settings map[string]string) {
+	var raw = map[string]any{}
+	var bytes, _ = json.Marshal(repo)
+
+	settings = map[string]string{}
+	json.Unmarshal(bytes, &raw)
+	flatten("", raw, settings)
+	return
+}
+
+// flatten walks the value, adding each scalar to settings under its dot path
+func flatten(prefix string, value any, settings map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, sub := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, sub, settings)
+		}
+	case []any:
+		var values = []string{}
+		for _, sub := range v {
+			if s := scalar(sub); s != "" {
+				values = append(values, s)
+			}
+		}
+		settings[prefix] = strings.Join(values, ",")
+	default:
+		settings[prefix] = scalar(v)
+	}
+}
+
+// scalar converts json scalar values to a string; objects become empty
+func scalar(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
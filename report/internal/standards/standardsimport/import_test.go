@@ -0,0 +1,232 @@
+package standardsimport
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockGit returns the same tree for every repo; repos named "empty" have no commits
+type mockGit struct {
+	paths []string
+	calls int
+}
+
+func (self *mockGit) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	self.calls++
+	if repo == "empty" {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusConflict}}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Git Repository is empty."}
+	}
+	tree := &github.Tree{Entries: []*github.TreeEntry{{Path: github.Ptr(".github"), Type: github.Ptr("tree")}}}
+	for _, p := range self.paths {
+		tree.Entries = append(tree.Entries, &github.TreeEntry{Path: github.Ptr(p), Type: github.Ptr("blob")})
+	}
+	return tree, &github.Response{}, nil
+}
+
+// mockRepos returns file content by path
+type mockRepos struct {
+	files map[string]string
+	calls int
+}
+
+func (self *mockRepos) DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error) {
+	self.calls++
+	if content, ok := self.files[filepath]; ok {
+		return io.NopCloser(bytes.NewBufferString(content)), &github.Response{}, nil
+	}
+	return nil, &github.Response{}, errors.New("not found")
+}
+
+func TestStandardsImportHandler(t *testing.T) {
+	var (
+		ctx   = cntxt.AddLogger(t.Context(), logger.New("error"))
+		owner = &github.User{Login: github.Ptr("org")}
+		git   = &mockGit{paths: []string{"README.md", ".github/CODEOWNERS", ".github/workflows/ci.yml", ".github/workflows/broken.yml", "src/main.go"}}
+		rp    = &mockRepos{files: map[string]string{
+			".github/workflows/ci.yml":     "jobs:\n  scan:\n    steps:\n      # - uses: aquasecurity/trivy-action@master\n      - uses: 'github/codeql-action/init@v3'\n",
+			".github/workflows/broken.yml": "jobs:\n  scan:\n    steps:\n  - uses: [aquasecurity/trivy-action@master\n",
+		}}
+		rules = []*Rule{
+			{Name: "readme", Type: TypeFileExists, Path: "README*"},
+			{Name: "licence", Type: TypeFileExists, Path: "LICENSE"},
+			{Name: "codeowners", Type: TypeCodeowners},
+			{Name: "codeql", Type: TypeWorkflowUses, Action: "github/codeql-action"},
+			{Name: "trivy", Type: TypeWorkflowUses, Action: "aquasecurity/trivy-action"},
+			{Name: "main", Type: TypeSettingEquals, Setting: "default_branch", Value: "main"},
+			{Name: "secrets", Type: TypeSettingEquals, Setting: "security_and_analysis.secret_scanning.status", Value: "enabled"},
+		}
+		repoList = []*github.Repository{
+			{Name: github.Ptr("repo"), FullName: github.Ptr("org/repo"), Owner: owner, DefaultBranch: github.Ptr("main"),
+				SecurityAndAnalysis: &github.SecurityAndAnalysis{SecretScanning: &github.SecretScanning{Status: github.Ptr("enabled")}}},
+			{Name: github.Ptr("empty"), FullName: github.Ptr("org/empty"), Owner: owner, DefaultBranch: github.Ptr("main")},
+			{Name: github.Ptr("old"), FullName: github.Ptr("org/old"), Owner: owner, Archived: github.Ptr(true)},
+		}
+		expected = map[string]*Model{
+			"readme":     {Passed: 1, Detail: "README.md"},
+			"licence":    {Passed: 0, Detail: ""},
+			"codeowners": {Passed: 1, Detail: ".github/CODEOWNERS"},
+			"codeql":     {Passed: 1, Detail: ".github/workflows/ci.yml"},
+			"trivy":      {Passed: 0, Detail: "unparsed: .github/workflows/broken.yml"},
+			"main":       {Passed: 1, Detail: "main"},
+			"secrets":    {Passed: 1, Detail: "enabled"},
+		}
+	)
+	results, done, err := handler(ctx, &Clients{Git: git, Repos: rp}, &Args{Rules: rules}, repoList)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if len(done) != len(repoList) {
+		t.Errorf("expected every repo to be processed, found [%v]", done)
+	}
+	// empty and archived repos are skipped without error
+	if len(results) != len(rules) {
+		t.Fatalf("expected [%d] results, found [%d]", len(rules), len(results))
+	}
+	if git.calls != 2 || rp.calls != 2 {
+		t.Errorf("expected 2 tree and 2 content calls, found [%d] [%d]", git.calls, rp.calls)
+	}
+	for _, r := range results {
+		exp := expected[r.Rule]
+		if r.Codebase != "org/repo" || r.Passed != exp.Passed || r.Detail != exp.Detail {
+			t.Errorf("unexpected result for [%s]: %+v", r.Rule, r)
+		}
+	}
+
+	// content is not fetched when no rule needs it
+	rp.calls = 0
+	if _, _, err = handler(ctx, &Clients{Git: git, Repos: rp}, &Args{Rules: rules[:3]}, repoList); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if rp.calls != 0 {
+		t.Errorf("expected no content calls, found [%d]", rp.calls)
+	}
+}
+
+func TestStandardsImportRemoveExisting(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-standards.db")
+		in     = &Args{DB: dbpath, Driver: "sqlite3"}
+		data   = []*Model{
+			{Codebase: "org/a", Rule: "readme", Passed: 1},
+			{Codebase: "org/b", Rule: "readme", Passed: 0},
+			{Codebase: "org/archived", Rule: "readme", Passed: 1},
+		}
+		repoList = []*github.Repository{
+			{FullName: github.Ptr("org/a")},
+			{FullName: github.Ptr("org/b")},
+			{FullName: github.Ptr("org/c")},
+		}
+		count = func() (codebases []string) {
+			codebases = []string{}
+			dbx.Select(ctx, `SELECT codebase FROM codebase_standards ORDER BY codebase ASC;`, &dbx.SelectArgs{
+				DB: in.DB, Driver: in.Driver,
+				ScanF: func(rows *sql.Rows) (err error) {
+					var c string
+					if err = rows.Scan(&c); err == nil {
+						codebases = append(codebases, c)
+					}
+					return
+				},
+			})
+			return
+		}
+	)
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// org/b failed, so the results of org/a are removed along with those of
+	// org/archived as it is no longer in the repository list
+	if err := removeExisting(ctx, in, []string{"org/a", "org/c"}, repoList); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if found := count(); len(found) != 1 || found[0] != "org/b" {
+		t.Errorf("expected only the failed codebase to keep its results, found [%v]", found)
+	}
+	// every repo processed, so the table is emptied
+	if err := removeExisting(ctx, in, []string{"org/c"}, repoList[2:]); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if found := count(); len(found) != 0 {
+		t.Errorf("expected the table to be emptied, found [%v]", found)
+	}
+}
+
+// TestStandardsImportDryRunPartial checks a partial import through a dry run
+// reports the rows the per codebase delete would remove
+func TestStandardsImportDryRunPartial(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-standards-dryrun.db")
+		in     = &Args{DB: dbpath, Driver: "sqlite3"}
+		data   = []*Model{
+			{Codebase: "org/a", Rule: "readme", Passed: 1},
+			{Codebase: "org/a", Rule: "license", Passed: 1},
+			{Codebase: "org/b", Rule: "readme", Passed: 0},
+		}
+	)
+	if err := migrations.Migrate(ctx, &migrations.Args{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// org/b failed, org/a now fails the readme rule and no longer has a license result
+	dryCtx, dr := dbx.WithDryRun(ctx)
+	if err := removeExisting(dryCtx, in, []string{"org/a"}, []*github.Repository{{FullName: github.Ptr("org/a")}, {FullName: github.Ptr("org/b")}}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if err := dbx.Insert(dryCtx, InsertStatement, []*Model{{Codebase: "org/a", Rule: "readme", Passed: 0}}, &dbx.InsertArgs{DB: in.DB, Driver: in.Driver}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	diff, err := dr.Diff(ctx, &dbx.DiffArgs{DB: in.DB, Driver: in.Driver})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if s := diff.Summary[0]; s.Inserts != 0 || s.Updates != 1 || s.Deletes != 1 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	for _, c := range diff.Changes {
+		if c.Key["codebase"] != "org/a" {
+			t.Errorf("expected only org/a to change: %+v", c)
+		}
+	}
+}
+
+func TestStandardsValidate(t *testing.T) {
+	if err := Validate(DefaultRules); err != nil {
+		t.Errorf("default rules should be valid: [%s]", err.Error())
+	}
+	var invalid = []*Rule{
+		{Name: "a", Type: TypeFileExists},
+		{Name: "a", Type: TypeWorkflowUses},
+		{Name: "b", Type: TypeSettingEquals},
+		{Name: "c", Type: "unknown"},
+		{Name: "d", Type: TypeFileExists, Path: "[a"},
+	}
+	err := Validate(invalid)
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("expected invalid rules error, found [%v]", err)
+	}
+	// one line per problem after the wrapping error
+	if n := strings.Count(err.Error(), "\n"); n != 6 {
+		t.Errorf("expected 6 problems, found [%d]: %s", n, err.Error())
+	}
+}
@@ -0,0 +1,154 @@
+package standardsimport
+
+import (
+	"errors"
+	"fmt"
+	"opg-reports/report/package/ghcodeowners"
+	"opg-reports/report/package/ghworkflows"
+	"path"
+	"slices"
+	"strings"
+)
+
+// rule types
+const (
+	TypeFileExists    string = "file_exists"    // a file matching Path exists on the default branch
+	TypeWorkflowUses  string = "workflow_uses"  // a workflow or composite action uses Action
+	TypeSettingEquals string = "setting_equals" // repository Setting equals Value
+	TypeCodeowners    string = "codeowners"     // a CODEOWNERS file exists in one of the locations github reads
+)
+
+// Types is every rule type
+var Types = []string{
+	TypeFileExists,
+	TypeWorkflowUses,
+	TypeSettingEquals,
+	TypeCodeowners,
+}
+
+// DefaultRules are used when no standards are configured
+var DefaultRules = []*Rule{
+	{Name: "has-codeowners", Description: "CODEOWNERS present", Type: TypeCodeowners},
+	{Name: "has-readme", Description: "README present", Type: TypeFileExists, Path: "README*"},
+	{Name: "has-dependabot", Description: "Dependabot configured", Type: TypeFileExists, Path: ".github/dependabot.y*ml"},
+	{Name: "uses-trivy", Description: "Trivy action in workflows", Type: TypeWorkflowUses, Action: "aquasecurity/trivy-action"},
+	{Name: "default-branch-main", Description: "Default branch is main", Type: TypeSettingEquals, Setting: "default_branch", Value: "main"},
+}
+
+var ErrInvalidRules = errors.New("repository standards are invalid.")
+
+// Rule is a single standard a repository is checked against; which values are
+// used depends on the Type
+type Rule struct {
+	Name        string `json:"name"`        // unique name of the rule
+	Description string `json:"description"` // shown on the front end
+	Type        string `json:"type"`        // see Types
+	Path        string `json:"path"`        // file_exists; path or glob pattern (path.Match syntax) of the file
+	Action      string `json:"action"`      // workflow_uses; action without a version (owner/name)
+	Setting     string `json:"setting"`     // setting_equals; repository setting, dot separated for nested values
+	Value       string `json:"value"`       // setting_equals; value the setting has to equal
+}
+
+// Snapshot is everything known about a repository, fetched once and used by every rule
+type Snapshot struct {
+	Settings       map[string]string            `json:"settings"`        // flattened repository metadata, see settings
+	Files          []string                     `json:"files"`           // every file path on the default branch
+	Workflows      map[string]*ghworkflows.File `json:"workflows"`       // parsed workflow & action files by path
+	WorkflowErrors []string                     `json:"workflow_errors"` // workflow & action files that could not be parsed
+}
+
+// Validate checks the rule has a known type and the values that type needs
+func (self *Rule) Validate() (err error) {
+	var missing = func(field string) error {
+		return fmt.Errorf("rule [%s] of type [%s] needs a %s", self.Name, self.Type, field)
+	}
+	switch self.Type {
+	case TypeFileExists:
+		if self.Path == "" {
+			return missing("path")
+		}
+		if _, e := path.Match(self.Path, ""); e != nil {
+			return fmt.Errorf("rule [%s] has an invalid path pattern [%s]", self.Name, self.Path)
+		}
+	case TypeWorkflowUses:
+		if self.Action == "" {
+			return missing("action")
+		}
+	case TypeSettingEquals:
+		if self.Setting == "" {
+			return missing("setting")
+		}
+	case TypeCodeowners:
+	default:
+		return fmt.Errorf("rule [%s] has unknown type [%s], expected one of [%s]", self.Name, self.Type, strings.Join(Types, ", "))
+	}
+	return
+}
+
+// Validate checks every rule and that the names are unique
+func Validate(rules []*Rule) (err error) {
+	var errs = []error{}
+	var names = map[string]bool{}
+	for _, rule := range rules {
+		if rule.Name == "" || names[rule.Name] {
+			errs = append(errs, fmt.Errorf("rule name [%s] is empty or used more than once", rule.Name))
+		}
+		names[rule.Name] = true
+		if e := rule.Validate(); e != nil {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		err = errors.Join(ErrInvalidRules, errors.Join(errs...))
+	}
+	return
+}
+
+// NeedsWorkflows returns true when any rule reads workflow file content
+func NeedsWorkflows(rules []*Rule) bool {
+	return slices.ContainsFunc(rules, func(r *Rule) bool { return r.Type == TypeWorkflowUses })
+}
+
+// Evaluate checks the snapshot against the rule; detail is what was found (the
+// matching file, the setting value etc) to explain the result
+func (self *Rule) Evaluate(snap *Snapshot) (passed bool, detail string) {
+	switch self.Type {
+	case TypeFileExists:
+		for _, file := range snap.Files {
+			if ok, _ := path.Match(self.Path, file); ok {
+				return true, file
+			}
+		}
+	case TypeCodeowners:
+		for _, loc := range ghcodeowners.Locations {
+			if slices.Contains(snap.Files, loc) {
+				return true, loc
+			}
+		}
+	case TypeWorkflowUses:
+		for _, file := range slices.Sorted(mapKeys(snap.Workflows)) {
+			if len(snap.Workflows[file].Uses(self.Action)) > 0 {
+				return true, file
+			}
+		}
+		// the action may be within a file that could not be parsed
+		if len(snap.WorkflowErrors) > 0 {
+			detail = fmt.Sprintf("unparsed: %s", strings.Join(snap.WorkflowErrors, ", "))
+		}
+	case TypeSettingEquals:
+		detail = snap.Settings[self.Setting]
+		passed = strings.EqualFold(detail, self.Value)
+	}
+	return
+}
+
+// mapKeys returns the keys of m
+func mapKeys[V any](m map[string]V) func(func(string) bool) {
+	return func(yield func(string) bool) {
+		for k := range m {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package statusapifreshness
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt fetches the latest run details for each import command
+const selectStmt string = `
+SELECT
+	runs.command as dataset,
+	MAX(runs.ended_at) as last_run,
+	COALESCE(MAX(CASE WHEN runs.status IN ('success','partial') THEN runs.ended_at END), '') as last_success,
+	(
+		SELECT latest.status FROM import_runs as latest
+		WHERE latest.command = runs.command
+		ORDER BY latest.ended_at DESC LIMIT 1
+	) as last_status,
+	COALESCE((
+		SELECT latest.rows FROM import_runs as latest
+		WHERE latest.command = runs.command AND latest.status IN ('success','partial')
+		ORDER BY latest.ended_at DESC LIMIT 1
+	), 0) as rows
+FROM import_runs as runs
+GROUP BY
+	runs.command
+ORDER BY
+	runs.command ASC
+;
+`
+
+// defaultStaleAfter is used for any dataset not in staleAfter; most imports
+// run daily
+const defaultStaleAfter time.Duration = 48 * time.Hour
+
+// staleAfter is how long after the last successful import a dataset is
+// considered stale; zero means it is never stale (manually imported). Every
+// importruns.Commands entry should be listed.
+var staleAfter = map[string]time.Duration{
+	"teams":                0,
+	"accounts":             0,
+	"costs":                defaultStaleAfter,
+	"uptime":               defaultStaleAfter,
+	"uptime-canaries":      defaultStaleAfter,
+	"codebases":            defaultStaleAfter,
+	"codeowners":           defaultStaleAfter,
+	"codebase-stats":       96 * time.Hour, // runs 3 times a week
+	"codebase-releases":    96 * time.Hour, // runs 3 times a week
+	"dora":                 96 * time.Hour, // runs with codebase-releases
+	"workflow-usage":       96 * time.Hour, // runs with codebase-releases
+	"workflow-reliability": 96 * time.Hour, // runs with codebase-releases
+	"dependabot":           96 * time.Hour, // runs with codebase-stats
+	"code-scanning":        96 * time.Hour, // runs with codebase-stats
+	"secret-scanning":      96 * time.Hour, // runs with codebase-stats
+	"branch-protection":    96 * time.Hour, // runs with codebase-stats
+	"standards":            96 * time.Hour, // runs with codebase-stats
+	"alarms":               7 * 24 * time.Hour,
+}
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct{}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"` // the actual data results
+}
+
+// Model is the data struct to use when fetching the select
+type Model struct {
+	Dataset         string  `json:"dataset"`           // import command name
+	LastRun         string  `json:"last_run"`          // when the import last finished
+	LastSuccess     string  `json:"last_success"`      // when the import last finished without error
+	LastStatus      string  `json:"last_status"`       // status of the latest run
+	Rows            int64   `json:"rows"`              // rows written by the last successful run
+	Stale           bool    `json:"stale"`             // true if the last success is older than StaleAfterHours
+	StaleAfterHours float64 `json:"stale_after_hours"` // zero if the dataset is never stale
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Dataset,
+		&self.LastRun,
+		&self.LastSuccess,
+		&self.LastStatus,
+		&self.Rows,
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		in       *Request     = &Request{}
+		all      []*Model     = []*Model{}
+		now      time.Time    = time.Now().UTC()
+		log      *slog.Logger = cntxt.GetLogger(ctx).With("package", "statusapifreshness", "func", "Responder")
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// make the db call via the Select helper that handles row scanning.
+	// No return value as local values are updates within ScanF lambda
+	dbx.Select(ctx, selectStmt, &dbx.SelectArgs{
+		DB:     conf.DB,
+		Driver: conf.Driver,
+		Params: conf.Params,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	for _, m := range all {
+		setStale(m, now)
+	}
+
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
+
+// setStale works out if the dataset is stale based on the last successful import
+func setStale(m *Model, now time.Time) {
+	var after, ok = staleAfter[m.Dataset]
+	if !ok {
+		after = defaultStaleAfter
+	}
+	m.StaleAfterHours = after.Hours()
+	if after == 0 {
+		return
+	}
+	if m.LastSuccess == "" {
+		m.Stale = true
+		return
+	}
+	if last, err := times.FromString(m.LastSuccess); err != nil || now.Sub(last) > after {
+		m.Stale = true
+	}
+}
@@ -0,0 +1,78 @@
+package statusapifreshness
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/importruns"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusAPIFreshnessHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+	)
+	// run seeds
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, ENDPOINT+"/", nil)
+	writer := httptest.NewRecorder()
+
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	mux.ServeHTTP(writer, req)
+
+	rec := &Response{}
+	err = response.As(writer.Result(), &rec)
+	if err != nil {
+		t.Errorf("error converting ...")
+	}
+	found := map[string]*Model{}
+	for _, m := range rec.Data {
+		found[m.Dataset] = m
+	}
+	// costs was imported recently
+	if found["costs"] == nil || found["costs"].Stale {
+		t.Errorf("costs should be fresh: %+v", found["costs"])
+	}
+	// uptime is weeks old
+	if found["uptime"] == nil || !found["uptime"].Stale {
+		t.Errorf("uptime should be stale: %+v", found["uptime"])
+	}
+	// teams are never stale
+	if found["teams"] == nil || found["teams"].Stale {
+		t.Errorf("teams should never be stale: %+v", found["teams"])
+	}
+	// codebases latest run failed, but last success is recent
+	if cb := found["codebases"]; cb == nil || cb.LastStatus != "failed" || cb.LastSuccess == "" || cb.Stale {
+		t.Errorf("unexpected codebases status: %+v", cb)
+	}
+}
+
+// TestStatusAPIFreshnessCoversCommands checks every import command has its own
+// staleness entry rather than falling back to the default
+func TestStatusAPIFreshnessCoversCommands(t *testing.T) {
+	for _, command := range importruns.Commands {
+		if _, ok := staleAfter[command]; !ok {
+			t.Errorf("no staleAfter entry for import command [%s]", command)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package statusfront fetches the status of the datasets for use in the
+// front end pages
+package statusfront
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/status/statusapi/statusapifreshness"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/htmlpage"
+	"opg-reports/report/package/rest"
+)
+
+// Freshness fetches when each dataset was last imported from the api. An
+// empty list is returned if the api call fails so pages still render.
+func Freshness(ctx context.Context, apiHost string, request *http.Request) (freshness []*htmlpage.Freshness) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "statusfront", "func", "Freshness")
+
+	freshness = []*htmlpage.Freshness{}
+	resp, err := rest.FromApi[*statusapifreshness.Response](ctx, apiHost, statusapifreshness.ENDPOINT, request)
+	if err != nil {
+		log.Error("failed to fetch data freshness", "err", err.Error())
+		return
+	}
+	cnv.Convert(resp.Data, &freshness)
+	return
+}
@@ -21,30 +21,39 @@ import (
 
 // selectStmt is the sql used to fetch data including
 // and params (`:name`) that will be replaced by values
-// from `Request` (by configuring `Filter`)
+// from `Request` (by configuring `Filter`).
+//
+// `{SOURCE}` and `{GROUP_SOURCE}` are swapped out depending
+// on if the results are split by uptime source or not
 const selectStmt string = `
 SELECT
 	uptime.month as month,
 	CAST(COALESCE(AVG(uptime.average), 0) as REAL) as average,
-	IIF(accounts.team_name != "", accounts.team_name, "")  as team
+	IIF(accounts.team_name != "", accounts.team_name, "")  as team,
+	{SOURCE} as source
 FROM uptime
 LEFT JOIN accounts on accounts.id = uptime.account_id
 WHERE
 	uptime.month IN (:months)
 GROUP BY
 	uptime.month,
-	accounts.team_name
+	accounts.team_name{GROUP_SOURCE}
 ORDER BY
 	accounts.team_name ASC
 ;
 `
 
+// splitBySource is the `split` query value that groups results by uptime source
+const splitBySource string = "source"
+
 // Request contains the url path / query string values that we will use
 // in this handler
 type Request struct {
 	DateStart string `json:"date_start"`
 	DateEnd   string `json:"date_end"`
 	Team      string `json:"team"`
+	Source    string `json:"source"` // optional query string filter to a single uptime source (route53 / synthetics)
+	Split     string `json:"split"`  // optional query string; when set to "source" each source has its own row
 }
 
 func (self *Request) Start() (t time.Time) {
@@ -73,6 +82,7 @@ type Response struct {
 type Filter struct {
 	Months []string `json:"months"`
 	Team   string   `json:"team"`
+	Source string   `json:"source"`
 }
 
 // Model is the data struct to use when fetching the select
@@ -80,12 +90,13 @@ type Model struct {
 	Month   string  `json:"month"`
 	Average float64 `json:"average"`
 	Team    string  `json:"team"`
+	Source  string  `json:"source"`
 }
 
 // Sequence is used to return the columns in the order they are selected
 func (self *Model) Sequence() []any {
 	return []any{
-		&self.Month, &self.Average, &self.Team,
+		&self.Month, &self.Average, &self.Team, &self.Source,
 	}
 }
 
@@ -127,6 +138,22 @@ func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request,
 		filter.Team = in.Team
 		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE accounts.team_name = :team AND")
 	}
+	// look for the optional source filter
+	if in.Source != "" {
+		log.Info("optional source filter found ...", "source", in.Source)
+		filter.Source = in.Source
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE uptime.source = :source AND")
+	}
+	// when splitting by source, group on the source and add it as a key column
+	if in.Split == splitBySource {
+		log.Info("splitting by source ...")
+		headings[tabulate.KEY] = append(headings[tabulate.KEY], "source")
+		stmt = strings.ReplaceAll(stmt, "{SOURCE}", "uptime.source")
+		stmt = strings.ReplaceAll(stmt, "{GROUP_SOURCE}", ", uptime.source")
+	} else {
+		stmt = strings.ReplaceAll(stmt, "{SOURCE}", `""`)
+		stmt = strings.ReplaceAll(stmt, "{GROUP_SOURCE}", "")
+	}
 	// now convert to a map for use in bound statements
 	err = cnv.Convert(filter, &bindMap)
 	if err != nil {
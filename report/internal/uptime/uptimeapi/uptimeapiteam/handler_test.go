@@ -67,3 +67,66 @@ func TestUptimeAPITeamHandler(t *testing.T) {
 		t.Error("incorrect number of labels returned")
 	}
 }
+
+func TestUptimeAPITeamHandlerSplitBySource(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler-split.db")
+		end    = times.AsYMString(times.Today())
+		start  = times.AsYMString(times.Add(times.Today(), -3, times.YEAR))
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	// split by source should add the source column and contain both sources
+	req := httptest.NewRequest(http.MethodGet, "/v1/uptime/between/"+start+"/"+end+"/?split=source", nil)
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, req)
+
+	rec := &Response{}
+	if err = response.As(writer.Result(), &rec); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(rec.Headers["labels"]) != 2 {
+		t.Errorf("expected team & source labels, got [%v]", rec.Headers["labels"])
+	}
+	found := map[string]bool{}
+	for _, row := range rec.Data {
+		found[row["source"].(string)] = true
+	}
+	if len(found) != 2 {
+		t.Errorf("expected both sources in split data, got [%v]", found)
+	}
+
+	// filtering by a single source should only return that source
+	req = httptest.NewRequest(http.MethodGet, "/v1/uptime/between/"+start+"/"+end+"/?split=source&source=synthetics", nil)
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, req)
+
+	rec = &Response{}
+	if err = response.As(writer.Result(), &rec); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(rec.Data) < 1 {
+		t.Errorf("expected synthetics rows; might be due to seed data using random values")
+	}
+	for _, row := range rec.Data {
+		if row["source"] != "synthetics" {
+			t.Errorf("unexpected source returned: [%v]", row["source"])
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
 	"opg-reports/report/internal/team/teamapi/teamapiall"
 	"opg-reports/report/internal/uptime/uptimeapi/uptimeapiteam"
 	"opg-reports/report/package/cntxt"
@@ -85,6 +86,9 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 		params         = []*rest.Param{
 			{Type: rest.PATH, Key: "date_end", Value: times.AsYMString(dateEnd)},
 			{Type: rest.PATH, Key: "date_start", Value: times.AsYMString(dateStart)},
+			// optional source filter / split values passed through from the front end request
+			{Type: rest.QUERY, Key: "source"},
+			{Type: rest.QUERY, Key: "split"},
 		}
 	)
 	// add team filter values and url
@@ -102,6 +106,11 @@ func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *h
 			}
 			wg.Done()
 		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
 		// get team uptime breakdown
 		func(wg *sync.WaitGroup, page *PageContent) {
 			resp, err := rest.FromApi[*uptimeapiteam.Response](ctx, args.ApiHost, uptimeEndpoint, request, params...)
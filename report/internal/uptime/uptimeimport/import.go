@@ -1,19 +1,42 @@
+// Package uptimeimport fetches uptime percentages from cloudwatch and stores the monthly
+// average per account and source.
+//
+// Two sources are supported:
+//   - route53 health checks (`SourceHealthCheck`) which must be fetched via us-east-1
+//   - cloudwatch synthetics canaries (`SourceCanary`) which are fetched from the region
+//     the canaries run in
+//
+// Health checks are attributed to an account by (in order of precedence):
+//   - an entry in the optional mapping file (`MappingFile`)
+//   - a tag (`MappingTagKey`) on the route53 health check itself
+//   - the account the import is running as (`AccountID`)
+//
+// Canaries are always attributed to the account the import is running as.
+//
+// This allows health checks that are hosted centrally to be reported against
+// the account of the service they monitor. Accounts that have `uptime_tracking`
+// disabled within the `accounts` table are skipped.
 package uptimeimport
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"opg-reports/report/package/cntxt"
 	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/files"
 	"opg-reports/report/package/ptr"
+	"opg-reports/report/package/retry"
 	"opg-reports/report/package/times"
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
-
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -22,31 +45,77 @@ INSERT INTO uptime (
 	month,
 	average,
 	granularity,
-	account_id
+	account_id,
+	source
 ) VALUES (
 	:month,
 	:average,
 	:granularity,
-	:account_id
-) ON CONFLICT (account_id,month)
+	:account_id,
+	:source
+) ON CONFLICT (account_id,month,source)
  	DO UPDATE SET average=excluded.average, granularity=excluded.granularity
 RETURNING id
 ;
 `
 
+// trackingStmt fetches the uptime tracking flag for all known accounts
+const trackingStmt string = `
+SELECT
+	id,
+	uptime_tracking
+FROM accounts
+;
+`
+
+// MappingTagKey is the route53 health check tag used to attribute a health check
+// to an account
+const MappingTagKey string = "account-id"
+
+// Uptime sources, stored in the `source` column
+const (
+	SourceHealthCheck string = "route53"
+	SourceCanary      string = "synthetics"
+)
+
 // these are fixed values used for the api calls
 const (
 	metricRegion     string             = "us-east-1"
-	metricsNamespace string             = "AWS/Route53"
-	metricsName      string             = "HealthCheckPercentageHealthy"
+	metricsDimension string             = "HealthCheckId"
 	metricsStatistic types.Statistic    = types.StatisticAverage
 	metricsUnit      types.StandardUnit = types.StandardUnitPercent
+	tagsBatchSize    int                = 10 // max number of health checks per ListTagsForResources call
 )
 
+// source contains the metric details that differ between each uptime source
+type source struct {
+	Namespace string // cloudwatch namespace
+	Metric    string // metric name
+	Region    string // region the client must use, empty for any
+	Tagged    bool   // if health check tags & mapping file are used to attribute the metric to an account
+}
+
+var sources = map[string]*source{
+	SourceHealthCheck: {
+		Namespace: "AWS/Route53",
+		Metric:    "HealthCheckPercentageHealthy",
+		Region:    metricRegion,
+		Tagged:    true,
+	},
+	SourceCanary: {
+		Namespace: "CloudWatchSynthetics",
+		Metric:    "SuccessPercent",
+	},
+}
+
 var (
 	ErrIncorrectRegion          = errors.New("metrics must be fetched via us-east-1.")
+	ErrUnknownSource            = errors.New("unknown uptime source.")
 	ErrFailedGettingMetricsList = errors.New("failed to get metrics list with error.")
 	ErrFailedGettingMetricStats = errors.New("failed to get metric statistics with error.")
+	ErrFailedGettingTags        = errors.New("failed to get health check tags with error.")
+	ErrFailedReadingMapping     = errors.New("failed to read health check mapping file with error.")
+	ErrFailedGettingTracking    = errors.New("failed to get account uptime tracking with error.")
 )
 
 // Model represents a simple, joinless, db row in the cost table; used by imports and seeding commands
@@ -55,6 +124,14 @@ type Model struct {
 	Average     string `json:"average,omitempty"`
 	Granularity string `json:"granularity,omityempty"`
 	AccountID   string `json:"account_id,omityempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// Mapping links a route53 health check to the account its uptime should be
+// recorded against; used as the structure of the mapping file
+type Mapping struct {
+	HealthCheckID string `json:"health_check_id"`
+	AccountID     string `json:"account_id"`
 }
 
 // Client is used to allow mocking and is a proxy for *cloudwatch.Client
@@ -65,52 +142,110 @@ type Client interface {
 	Options() cloudwatch.Options
 }
 
+// TagClient is used to allow mocking and is a proxy for *route53.Client
+type TagClient interface {
+	ListTagsForResources(ctx context.Context, params *route53.ListTagsForResourcesInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourcesOutput, error)
+}
+
+type Clients struct {
+	Metrics Client    // used to fetch health check metrics - *cloudwatch.Client
+	Tags    TagClient // optional; used to fetch health check tags for account mapping - *route53.Client
+}
+
 type Args struct {
 	DB     string `json:"db"`     // database path
 	Driver string `json:"driver"` // database driver
 	Params string `json:"params"` // database connection params
 
-	DateStart time.Time `json:"date_start"` // start date, this will be reset to start of the month (and expanded to capture historical data)
-	DateEnd   time.Time `json:"date_end"`   // end date
-	AccountID string    `json:"account_id"` // AccountID provided by awsid.AccountID
+	DateStart   time.Time `json:"date_start"`   // start date, this will be reset to start of the month (and expanded to capture historical data)
+	DateEnd     time.Time `json:"date_end"`     // end date
+	AccountID   string    `json:"account_id"`   // AccountID provided by awsid.AccountID; used for any health check without a mapping
+	MappingFile string    `json:"mapping_file"` // optional json file containing a list of health check to account mappings
+	Source      string    `json:"source"`       // uptime source to import (SourceHealthCheck or SourceCanary); defaults to SourceHealthCheck
 }
 
-func Import(ctx context.Context, client Client, in *Args) (err error) {
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
 	var (
+		src       *source
+		ok        bool
 		list      *cloudwatch.ListMetricsOutput
-		statsOpts *cloudwatch.GetMetricStatisticsInput
-		stats     *cloudwatch.GetMetricStatisticsOutput
-		data      []*Model
-		setRegion string       = client.Options().Region
-		log       *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "Import")
+		tracking  map[string]bool
+		grouped   map[string][]types.Metric
+		skipped   map[string][]string
+		accounts  []string
+		mapping   map[string]string = map[string]string{}
+		data      []*Model          = []*Model{}
+		setRegion string            = clients.Metrics.Options().Region
+		log       *slog.Logger      = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "Import")
 	)
 
 	log.With("options", in).Info("starting ...")
+	if in.Source == "" {
+		in.Source = SourceHealthCheck
+	}
+	if src, ok = sources[in.Source]; !ok {
+		err = ErrUnknownSource
+		log.Error("unknown uptime source", "source", in.Source)
+		return
+	}
 	// check region
-	if setRegion != metricRegion {
+	if src.Region != "" && setRegion != src.Region {
 		err = ErrIncorrectRegion
-		log.Error("incorrect region used in client - requires us-east-1", "region", setRegion)
+		log.Error("incorrect region used in client", "region", setRegion, "required", src.Region)
 		return
 	}
 
 	// fetch the list of all metrics from the api
 	log.Debug("getting lisst of metrics ...")
-	list, err = getHealthCheckMetrics(ctx, client)
+	list, err = getMetrics(ctx, clients.Metrics, src)
 	if err != nil {
 		return
 	}
-
-	// get all the datapoints for each of the metrics
-	log.Debug("getting metric stats ...")
-	stats, statsOpts, err = getHealthCheckStatistics(ctx, client, list, in)
+	// work out which account each health check belongs to
+	if src.Tagged {
+		log.Debug("getting health check mapping ...")
+		mapping, err = getMapping(ctx, clients.Tags, list.Metrics, in)
+		if err != nil {
+			return
+		}
+	}
+	// find which accounts have tracking enabled
+	log.Debug("getting account tracking ...")
+	tracking, err = getTracking(ctx, in)
 	if err != nil {
 		return
 	}
+	grouped, skipped = groupByAccount(ctx, list.Metrics, mapping, tracking, in.AccountID)
+	for account, ids := range skipped {
+		log.Warn("uptime tracking disabled for account, health checks not imported", "account", account, "health_checks", ids)
+	}
 
-	log.Debug("coverting to models ...")
-	data, err = toModels(ctx, in.AccountID, *statsOpts.Period, stats)
-	if err != nil {
-		return
+	accounts = []string{}
+	for account := range grouped {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+
+	// get all the datapoints for each of the accounts metrics
+	for _, account := range accounts {
+		var (
+			stats  *cloudwatch.GetMetricStatisticsOutput
+			models []*Model
+		)
+		log.Debug("getting metric stats ...", "account", account)
+		stats, err = getStatistics(ctx, clients.Metrics, src, grouped[account], in)
+		if err != nil {
+			return
+		}
+		log.Debug("coverting to models ...", "account", account)
+		models, err = toModels(ctx, account, getPeriod(in.DateStart), stats)
+		if err != nil {
+			return
+		}
+		for _, m := range models {
+			m.Source = in.Source
+		}
+		data = append(data, models...)
 	}
 
 	// now write to db
@@ -124,11 +259,158 @@ func Import(ctx context.Context, client Client, in *Args) (err error) {
 		return
 	}
 
-	log.With("count", len(data)).Info("complete.")
+	log.With("count", len(data), "accounts", len(accounts)).Info("complete.")
 	return
 
 }
 
+// groupByAccount splits the metrics into sets for each account using the mapping
+// and removes any accounts that have tracking disabled, returning the health
+// check ids removed for each of those accounts as skipped.
+//
+// Health checks without a mapping use the fallback account. Accounts that are not
+// present in tracking (so not imported yet) are still included.
+func groupByAccount(ctx context.Context, metrics []types.Metric, mapping map[string]string, tracking map[string]bool, fallback string) (grouped map[string][]types.Metric, skipped map[string][]string) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "groupByAccount")
+
+	grouped = map[string][]types.Metric{}
+	skipped = map[string][]string{}
+	for _, metric := range metrics {
+		var account = fallback
+		var id = healthCheckID(metric)
+
+		if mapped, ok := mapping[id]; ok && mapped != "" {
+			account = mapped
+		}
+		if enabled, ok := tracking[account]; ok && !enabled {
+			log.Info("uptime tracking disabled for account, skipping health check", "account", account, "health_check", id)
+			skipped[account] = append(skipped[account], id)
+			continue
+		}
+		if _, ok := tracking[account]; !ok {
+			log.Warn("account not found, including health check", "account", account, "health_check", id)
+		}
+		grouped[account] = append(grouped[account], metric)
+	}
+	return
+}
+
+// getMapping returns a map of health check id to account id. Tags on the health
+// checks are used first and then any entries in the mapping file are merged over
+// the top.
+func getMapping(ctx context.Context, client TagClient, metrics []types.Metric, in *Args) (mapping map[string]string, err error) {
+	var (
+		fromFile []*Mapping   = []*Mapping{}
+		log      *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getMapping")
+	)
+	mapping = map[string]string{}
+
+	if client != nil {
+		mapping, err = getTagMapping(ctx, client, metrics)
+		if err != nil {
+			return
+		}
+	}
+
+	if in.MappingFile != "" {
+		log.Debug("reading mapping file ...", "file", in.MappingFile)
+		if err = files.ReadJSON(ctx, in.MappingFile, &fromFile); err != nil {
+			log.Error("error reading mapping file", "err", err.Error())
+			err = errors.Join(ErrFailedReadingMapping, err)
+			return
+		}
+		for _, m := range fromFile {
+			mapping[m.HealthCheckID] = m.AccountID
+		}
+	}
+	log.With("count", len(mapping)).Debug("complete.")
+	return
+}
+
+// getTagMapping looks for the `MappingTagKey` on each of the health checks and
+// returns a map of health check id to the tag value.
+//
+// ListTagsForResources is limited to 10 ids per call, so these are batched
+func getTagMapping(ctx context.Context, client TagClient, metrics []types.Metric) (mapping map[string]string, err error) {
+	var (
+		ids []string     = []string{}
+		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getTagMapping")
+	)
+	mapping = map[string]string{}
+
+	for _, metric := range metrics {
+		if id := healthCheckID(metric); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	for batch := range slices.Chunk(ids, tagsBatchSize) {
+		var out *route53.ListTagsForResourcesOutput
+
+		log.Debug("getting tags for health checks ...", "count", len(batch))
+		out, err = retry.Get(ctx, func() (*route53.ListTagsForResourcesOutput, error) {
+			return client.ListTagsForResources(ctx, &route53.ListTagsForResourcesInput{
+				ResourceType: r53types.TagResourceTypeHealthcheck,
+				ResourceIds:  batch,
+			})
+		})
+		if err != nil {
+			log.Error("error getting health check tags", "err", err.Error())
+			err = errors.Join(ErrFailedGettingTags, err)
+			return
+		}
+		for _, set := range out.ResourceTagSets {
+			for _, tag := range set.Tags {
+				if tag.Key != nil && tag.Value != nil && *tag.Key == MappingTagKey {
+					mapping[*set.ResourceId] = *tag.Value
+				}
+			}
+		}
+	}
+	log.With("count", len(mapping)).Debug("complete.")
+	return
+}
+
+// getTracking returns a map of account id to the uptime tracking flag for all
+// accounts currently in the database
+func getTracking(ctx context.Context, in *Args) (tracking map[string]bool, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getTracking")
+
+	tracking = map[string]bool{}
+	err = dbx.Select(ctx, trackingStmt, &dbx.SelectArgs{
+		DB:      in.DB,
+		Driver:  in.Driver,
+		Params:  in.Params,
+		BindMap: map[string]interface{}{},
+		ScanF: func(rows *sql.Rows) (e error) {
+			var id, enabled string
+			if e = rows.Scan(&id, &enabled); e == nil {
+				tracking[id] = (enabled == "true")
+			}
+			return
+		},
+	})
+	if err != nil {
+		log.Error("error getting account tracking", "err", err.Error())
+		err = errors.Join(ErrFailedGettingTracking, err)
+		return
+	}
+	log.With("count", len(tracking)).Debug("complete.")
+	return
+}
+
+// healthCheckID returns the health check id from the metric dimensions
+func healthCheckID(metric types.Metric) (id string) {
+	for _, dim := range metric.Dimensions {
+		if dim.Name != nil && dim.Value != nil && *dim.Name == metricsDimension {
+			id = *dim.Value
+		}
+	}
+	return
+}
+
 // toModels converts the raw data into a list of models ready to write to the database
 func toModels(ctx context.Context, account string, period int32, result *cloudwatch.GetMetricStatisticsOutput) (data []*Model, err error) {
 	var (
@@ -174,42 +456,57 @@ func toModels(ctx context.Context, account string, period int32, result *cloudwa
 	return
 }
 
-// getHealthCheckStatistics uses the list of metrics from getHealthCheckMetrics to fetch the uptime datapoints
-// from the api and return the api call values
+// getStatistics fetches the datapoints for each of the metrics in turn and merges
+// them into a single result, so toModels can average them for each month.
+//
+// CloudWatch treats each combination of dimensions as a separate metric, so a
+// single call using the dimensions of every health check would not match any of
+// them; each metric is requested with only its own dimensions instead.
 //
 // T is *cloudwatch.Client
-func getHealthCheckStatistics[T Client](ctx context.Context, client T, list *cloudwatch.ListMetricsOutput, options *Args) (stats *cloudwatch.GetMetricStatisticsOutput, statsInput *cloudwatch.GetMetricStatisticsInput, err error) {
-	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getHealthCheckStatistics")
+func getStatistics[T Client](ctx context.Context, client T, src *source, metrics []types.Metric, options *Args) (stats *cloudwatch.GetMetricStatisticsOutput, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getStatistics")
 
-	statsInput = getHeathCheckMetricStatsOptions(list, options)
 	log.Debug("starting ...")
-	log.With("period", *statsInput.Period).Debug("getting metrics statistics ...")
-	// try and get the stats
-	stats, err = client.GetMetricStatistics(ctx, statsInput)
-	if err != nil {
-		log.Error("error getting metric statistics.", "err", err.Error())
-		err = errors.Join(ErrFailedGettingMetricStats, err)
-		return
+	stats = &cloudwatch.GetMetricStatisticsOutput{Datapoints: []types.Datapoint{}}
+
+	for _, metric := range metrics {
+		var out *cloudwatch.GetMetricStatisticsOutput
+		var statsInput = getMetricStatsOptions(src, metric, options)
+
+		log.With("period", *statsInput.Period).Debug("getting metrics statistics ...")
+		// try and get the stats
+		out, err = retry.Get(ctx, func() (*cloudwatch.GetMetricStatisticsOutput, error) {
+			return client.GetMetricStatistics(ctx, statsInput)
+		})
+		if err != nil {
+			log.Error("error getting metric statistics.", "err", err.Error())
+			err = errors.Join(ErrFailedGettingMetricStats, err)
+			return
+		}
+		stats.Datapoints = append(stats.Datapoints, out.Datapoints...)
 	}
 	log.With("count", len(stats.Datapoints)).Debug("complete.")
 	return
 }
 
-// getHealthCheckMetrics returns the list of metrics to use for uptime data. Limit to recently active metrics so
-// we aren't picking up aged / dead health checks.
+// getMetrics returns the list of metrics to use for uptime data. Limit to recently active metrics so
+// we aren't picking up aged / dead health checks or canaries.
 //
 // Client is *cloudwatch.Client
-func getHealthCheckMetrics(ctx context.Context, client Client) (list *cloudwatch.ListMetricsOutput, err error) {
-	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getHealthCheckMetrics")
+func getMetrics(ctx context.Context, client Client, src *source) (list *cloudwatch.ListMetricsOutput, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "uptimeimport", "func", "getMetrics")
 	var listOptions *cloudwatch.ListMetricsInput = &cloudwatch.ListMetricsInput{
-		Namespace:      ptr.Ptr(metricsNamespace),
-		MetricName:     ptr.Ptr(metricsName),
+		Namespace:      ptr.Ptr(src.Namespace),
+		MetricName:     ptr.Ptr(src.Metric),
 		RecentlyActive: types.RecentlyActivePt3h,
 	}
 
 	log.Debug("starting ...")
-	log.Debug("fetching metric data for account ...")
-	list, err = client.ListMetrics(ctx, listOptions)
+	log.Debug("fetching metric data for account ...", "namespace", src.Namespace)
+	list, err = retry.Get(ctx, func() (*cloudwatch.ListMetricsOutput, error) {
+		return client.ListMetrics(ctx, listOptions)
+	})
 	if err != nil {
 		log.Error("error getting list of metrics", "err", err.Error())
 		err = errors.Join(ErrFailedGettingMetricsList, err)
@@ -220,29 +517,22 @@ func getHealthCheckMetrics(ctx context.Context, client Client) (list *cloudwatch
 	return
 }
 
-// getHeathCheckMetricStatsOptions is used to generate a suitable GetMetricStatisticsInput struct
-// that contains all of the metric dimensions and uses the correct period and units that we need
-// to fetch uptime data
-func getHeathCheckMetricStatsOptions(list *cloudwatch.ListMetricsOutput, options *Args) (opts *cloudwatch.GetMetricStatisticsInput) {
-	var (
-		period     int32             = getPeriod(options.DateStart)
-		dimensions []types.Dimension = []types.Dimension{}
-	)
+// getMetricStatsOptions is used to generate a suitable GetMetricStatisticsInput struct
+// for a single metric that uses the correct period and units that we need to fetch
+// uptime data
+func getMetricStatsOptions(src *source, metric types.Metric, options *Args) (opts *cloudwatch.GetMetricStatisticsInput) {
+	var period int32 = getPeriod(options.DateStart)
 
-	// merge all of the metric dimensions together
-	for _, metric := range list.Metrics {
-		dimensions = append(dimensions, metric.Dimensions...)
-	}
 	// generate the input struct
 	opts = &cloudwatch.GetMetricStatisticsInput{
-		Namespace:  ptr.Ptr(metricsNamespace),
-		MetricName: ptr.Ptr(metricsName),
+		Namespace:  ptr.Ptr(src.Namespace),
+		MetricName: ptr.Ptr(src.Metric),
 		StartTime:  ptr.Ptr(options.DateStart),
 		EndTime:    ptr.Ptr(options.DateEnd),
 		Period:     ptr.Ptr(period),
 		Unit:       metricsUnit,
 		Statistics: []types.Statistic{metricsStatistic},
-		Dimensions: dimensions,
+		Dimensions: metric.Dimensions,
 	}
 
 	return
@@ -45,7 +45,7 @@ func TestUptimeImportWithoutMock(t *testing.T) {
 	})
 
 	accountId = awsid.AccountID(ctx, "us-east-1")
-	err = Import(ctx, client, &Args{
+	err = Import(ctx, &Clients{Metrics: client}, &Args{
 		DB:        dbpath,
 		Driver:    "sqlite3",
 		DateStart: start,
@@ -0,0 +1,187 @@
+package uptimeimport
+
+import (
+	"context"
+	"database/sql"
+	"opg-reports/report/internal/account/accountimport"
+	"opg-reports/report/internal/global/migrations"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/files"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/ptr"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// mockMetrics returns a fixed set of health checks with a single datapoint,
+// recording the dimensions of each statistics call
+type mockMetrics struct {
+	healthChecks []string
+	calls        [][]types.Dimension
+}
+
+func (self *mockMetrics) ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error) {
+	var out = &cloudwatch.ListMetricsOutput{}
+	for _, id := range self.healthChecks {
+		out.Metrics = append(out.Metrics, types.Metric{
+			Dimensions: []types.Dimension{{Name: ptr.Ptr(metricsDimension), Value: ptr.Ptr(id)}},
+		})
+	}
+	return out, nil
+}
+
+func (self *mockMetrics) GetMetricStatistics(ctx context.Context, params *cloudwatch.GetMetricStatisticsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	self.calls = append(self.calls, params.Dimensions)
+	return &cloudwatch.GetMetricStatisticsOutput{
+		Datapoints: []types.Datapoint{
+			{Timestamp: ptr.Ptr(time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)), Average: ptr.Ptr(99.5)},
+		},
+	}, nil
+}
+
+func (self *mockMetrics) Options() cloudwatch.Options {
+	return cloudwatch.Options{Region: metricRegion}
+}
+
+// mockTags returns the tags map for each health check requested
+type mockTags struct {
+	tags map[string]string
+}
+
+func (self *mockTags) ListTagsForResources(ctx context.Context, params *route53.ListTagsForResourcesInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourcesOutput, error) {
+	var out = &route53.ListTagsForResourcesOutput{}
+	for _, id := range params.ResourceIds {
+		if v, ok := self.tags[id]; ok {
+			out.ResourceTagSets = append(out.ResourceTagSets, r53types.ResourceTagSet{
+				ResourceId: ptr.Ptr(id),
+				Tags:       []r53types.Tag{{Key: ptr.Ptr(MappingTagKey), Value: ptr.Ptr(v)}},
+			})
+		}
+	}
+	return out, nil
+}
+
+// TestUptimeImportMappingAndTracking checks that health checks are attributed
+// to accounts via tags & mapping file and that accounts with tracking disabled
+// are skipped
+func TestUptimeImportMappingAndTracking(t *testing.T) {
+	var (
+		err     error
+		ctx     context.Context = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir     string          = t.TempDir()
+		dbpath  string          = filepath.Join(dir, "test-import.db")
+		mapfile string          = filepath.Join(dir, "mapping.json")
+		found   map[string]bool = map[string]bool{}
+	)
+	err = migrations.Migrate(ctx, &migrations.Args{DB: dbpath, Driver: "sqlite3"})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	// A001 & A002 are tracked, A003 is not
+	err = dbx.Insert(ctx, accountimport.InsertStatement, []*accountimport.Model{
+		{ID: "A001", Name: "A001", Label: "a", Environment: "production", UptimeTracking: true, TeamName: "team-a"},
+		{ID: "A002", Name: "A002", Label: "b", Environment: "production", UptimeTracking: true, TeamName: "team-b"},
+		{ID: "A003", Name: "A003", Label: "c", Environment: "production", UptimeTracking: false, TeamName: "team-c"},
+	}, &dbx.InsertArgs{DB: dbpath, Driver: "sqlite3"})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	// hc-2 is mapped by tag to A003, but the file overwrites that to A002
+	files.WriteAsJSON(ctx, mapfile, []*Mapping{{HealthCheckID: "hc-2", AccountID: "A002"}})
+
+	err = Import(ctx, &Clients{
+		Metrics: &mockMetrics{healthChecks: []string{"hc-1", "hc-2", "hc-3"}},
+		Tags:    &mockTags{tags: map[string]string{"hc-2": "A003", "hc-3": "A003"}},
+	}, &Args{
+		DB:          dbpath,
+		Driver:      "sqlite3",
+		DateStart:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateEnd:     time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		AccountID:   "A001",
+		MappingFile: mapfile,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+
+	dbx.Select(ctx, `SELECT account_id FROM uptime;`, &dbx.SelectArgs{
+		DB:      dbpath,
+		Driver:  "sqlite3",
+		BindMap: map[string]interface{}{},
+		ScanF: func(rows *sql.Rows) (e error) {
+			var id string
+			if e = rows.Scan(&id); e == nil {
+				found[id] = true
+			}
+			return
+		},
+	})
+
+	if len(found) != 2 || !found["A001"] || !found["A002"] {
+		t.Errorf("unexpected accounts in uptime table: %v", found)
+	}
+	if found["A003"] {
+		t.Errorf("account with uptime tracking disabled was imported")
+	}
+}
+
+// TestUptimeImportGroupByAccountSkipped checks a health check tagged with an
+// account that has tracking disabled is reported as skipped
+func TestUptimeImportGroupByAccountSkipped(t *testing.T) {
+	var (
+		ctx     context.Context = cntxt.AddLogger(t.Context(), logger.New("error"))
+		metrics []types.Metric  = []types.Metric{}
+	)
+	for _, id := range []string{"hc-1", "hc-2", "hc-3"} {
+		metrics = append(metrics, types.Metric{
+			Dimensions: []types.Dimension{{Name: ptr.Ptr(metricsDimension), Value: ptr.Ptr(id)}},
+		})
+	}
+	// hc-2 is tagged with untracked A003, hc-3 with A004 which is not known yet
+	grouped, skipped := groupByAccount(ctx, metrics,
+		map[string]string{"hc-2": "A003", "hc-3": "A004"},
+		map[string]bool{"A001": true, "A003": false},
+		"A001",
+	)
+	if len(grouped["A001"]) != 1 || len(grouped["A004"]) != 1 || len(grouped["A003"]) != 0 {
+		t.Errorf("unexpected grouping: %v", grouped)
+	}
+	if len(skipped) != 1 || len(skipped["A003"]) != 1 || skipped["A003"][0] != "hc-2" {
+		t.Errorf("expected hc-2 to be skipped for A003, got %v", skipped)
+	}
+}
+
+// TestUptimeImportStatisticsPerMetric checks each metric is requested with only
+// its own dimensions and the datapoints are merged
+func TestUptimeImportStatisticsPerMetric(t *testing.T) {
+	var (
+		ctx     = cntxt.AddLogger(t.Context(), logger.New("error"))
+		client  = &mockMetrics{healthChecks: []string{"hc-1", "hc-2"}}
+		in      = &Args{DateStart: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), DateEnd: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}
+		list, _ = client.ListMetrics(ctx, nil)
+	)
+	stats, err := getStatistics(ctx, client, sources[SourceHealthCheck], list.Metrics, in)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if len(client.calls) != 2 {
+		t.Fatalf("expected a call per metric, found [%d]", len(client.calls))
+	}
+	for i, dims := range client.calls {
+		if len(dims) != 1 || *dims[0].Value != client.healthChecks[i] {
+			t.Errorf("expected only the dimensions of [%s], found %v", client.healthChecks[i], dims)
+		}
+	}
+	if len(stats.Datapoints) != 2 {
+		t.Errorf("expected the datapoints of each metric, found [%d]", len(stats.Datapoints))
+	}
+}
@@ -0,0 +1,223 @@
+package workflowreliabilityapi
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt counts the runs, failures and re-runs of each workflow over the
+// date range; the workflows that fail the most are first
+const selectStmt string = `
+SELECT
+	workflow_runs.codebase,
+	workflow_runs.workflow,
+	COUNT(workflow_runs.id) as runs,
+	COALESCE(SUM(workflow_runs.conclusion = 'failure'),0) as failures,
+	COALESCE(SUM(workflow_runs.attempt > 1),0) as reruns,
+	COALESCE(SUM(workflow_runs.attempt > 1 AND workflow_runs.conclusion = 'success'),0) as passed_on_rerun,
+	COALESCE(SUM(workflow_runs.seconds_to_green),0) as seconds_to_green,
+	COALESCE(SUM(workflow_runs.seconds_to_green > 0),0) as recoveries
+FROM workflow_runs
+LEFT JOIN codebases on codebases.full_name = workflow_runs.codebase
+WHERE
+	codebases.archived = 0
+	AND workflow_runs.month IN (:months)
+GROUP BY
+	workflow_runs.codebase,
+	workflow_runs.workflow
+ORDER BY
+	failures DESC,
+	passed_on_rerun DESC,
+	runs DESC,
+	workflow_runs.codebase ASC,
+	workflow_runs.workflow ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE workflow_runs.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"`   // optional team filter, rolled up via codebase_owners
+	Org       string `json:"org"`    // optional github org filter (?org=)
+	Branch    string `json:"branch"` // optional branch filter (?branch=main)
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Model   `json:"summary"` // totals of every workflow
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+	Org    string   `json:"org"`
+	Branch string   `json:"branch"`
+}
+
+// Model is the data struct to use when fetching the select; the counts are
+// selected and the rates calculated from them
+type Model struct {
+	Codebase       string `json:"codebase,omitempty"`
+	Workflow       string `json:"workflow,omitempty"`
+	Runs           int    `json:"runs"`
+	Failures       int    `json:"failures"`        // runs that failed on their last attempt
+	Reruns         int    `json:"reruns"`          // runs with more than one attempt
+	PassedOnRerun  int    `json:"passed_on_rerun"` // runs that were re-run and then passed, likely flaky
+	SecondsToGreen int64  `json:"seconds_to_green"`
+	Recoveries     int    `json:"recoveries"` // number of times the workflow went from red to green
+
+	FailureRate        float64 `json:"failure_rate"`           // percentage of runs that failed
+	RerunRate          float64 `json:"rerun_rate"`             // percentage of runs that were re-run
+	MeanTimeToGreenHrs float64 `json:"mean_time_to_green_hrs"` // average hours from red to green
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Codebase,
+		&self.Workflow,
+		&self.Runs,
+		&self.Failures,
+		&self.Reruns,
+		&self.PassedOnRerun,
+		&self.SecondsToGreen,
+		&self.Recoveries,
+	}
+}
+
+// Add includes the counts of other within this model
+func (self *Model) Add(other *Model) {
+	self.Runs += other.Runs
+	self.Failures += other.Failures
+	self.Reruns += other.Reruns
+	self.PassedOnRerun += other.PassedOnRerun
+	self.SecondsToGreen += other.SecondsToGreen
+	self.Recoveries += other.Recoveries
+}
+
+// Calculate sets the rates from the counts
+func (self *Model) Calculate() {
+	if self.Runs > 0 {
+		self.FailureRate = float64(self.Failures) / float64(self.Runs) * 100
+		self.RerunRate = float64(self.Reruns) / float64(self.Runs) * 100
+	}
+	if self.Recoveries > 0 {
+		self.MeanTimeToGreenHrs = float64(self.SecondsToGreen) / float64(self.Recoveries) / 3600
+	}
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		months   []string
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  *Model                 = &Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "workflowreliabilityapi", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	filter.Months = months
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// look for the optional branch
+	if in.Branch != "" {
+		log.Info("optional branch filter found ...", "branch", in.Branch)
+		filter.Branch = in.Branch
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE workflow_runs.branch = :branch AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	for _, r := range all {
+		r.Calculate()
+		summary.Add(r)
+	}
+	summary.Calculate()
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
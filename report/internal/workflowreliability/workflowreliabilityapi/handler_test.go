@@ -0,0 +1,80 @@
+package workflowreliabilityapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/internal/global/seeds"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/response"
+	"opg-reports/report/package/times"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkflowReliabilityAPIHandler(t *testing.T) {
+	var (
+		err    error
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		driver = "sqlite3"
+		dbpath = filepath.Join(dir, "test-handler.db")
+		end    = times.ResetMonth(times.Today())
+		start  = times.Add(end, -6, times.MONTH)
+		base   = strings.NewReplacer("{date_start}", times.AsYMDString(start), "{date_end}", times.AsYMDString(end))
+	)
+	_, err = seeds.SeedAll(ctx, &seeds.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	mux := http.NewServeMux()
+	Register(ctx, mux, &apimodels.Args{
+		Driver: driver,
+		DB:     dbpath,
+	})
+
+	all := &Response{}
+	writer := httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, base.Replace(ENDPOINT_BASE), nil))
+	if err = response.As(writer.Result(), &all); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(all.Data) == 0 || all.Summary.Failures == 0 || all.Summary.FailureRate <= 0 || all.Summary.MeanTimeToGreenHrs <= 0 {
+		t.Fatalf("expected rates to be calculated: %+v", all.Summary)
+	}
+	// most failures first
+	for i := 1; i < len(all.Data); i++ {
+		if all.Data[i].Failures > all.Data[i-1].Failures {
+			t.Errorf("expected results to be ordered by failures")
+			break
+		}
+	}
+
+	// team rollup is a subset
+	team := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(base.Replace(ENDPOINT_TEAM), "{team}", "team-a"), nil))
+	if err = response.As(writer.Result(), &team); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if team.Summary.Runs == 0 || team.Summary.Runs >= all.Summary.Runs {
+		t.Errorf("expected team to have a subset of runs, got [%d] of [%d]", team.Summary.Runs, all.Summary.Runs)
+	}
+
+	// no runs on other branches
+	branch := &Response{}
+	writer = httptest.NewRecorder()
+	mux.ServeHTTP(writer, httptest.NewRequest(http.MethodGet, base.Replace(ENDPOINT_BASE)+"?branch=not-a-branch", nil))
+	if err = response.As(writer.Result(), &branch); err != nil {
+		t.Errorf("error converting ...")
+	}
+	if len(branch.Data) != 0 {
+		t.Errorf("expected no results for unknown branch, found [%d]", len(branch.Data))
+	}
+}
@@ -0,0 +1,144 @@
+package workflowreliabilityfront
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/frontmodels"
+	"opg-reports/report/internal/status/statusfront"
+	"opg-reports/report/internal/team/teamapi/teamapiall"
+	"opg-reports/report/internal/workflowreliability/workflowreliabilityapi"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/htmlpage"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/rest"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/tmpl"
+	"sync"
+)
+
+// worstOffenders is the number of workflows shown on the page; the api
+// returns them with the most failures first
+const worstOffenders int = 20
+
+type PageContent struct {
+	htmlpage.HTMLPage
+	Team                    string
+	WorkflowReliabilityData *frontmodels.WorkflowReliabilityData
+	Dates                   *frontmodels.DateRanges
+}
+
+type dataCallerF func(wg *sync.WaitGroup, page *PageContent)
+
+// Handler deals with the workflow reliability page for all codebases or those of a team
+func Handler(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request, writer http.ResponseWriter) {
+	var (
+		page         *PageContent
+		templateName string
+		team         string         = request.PathValue("team")
+		wg           sync.WaitGroup = sync.WaitGroup{}
+		log          *slog.Logger   = cntxt.GetLogger(ctx).With("package", "workflowreliabilityfront", "func", "Handler", "url", request.URL.String())
+	)
+	log.Info("starting ...")
+	page, templateName = getPage(team, args, request)
+	if team != "" {
+		log.Info("found team parameter ... ", "team", team)
+	}
+	// page data fetched from api via blocks
+	for _, blockF := range dataCallers(ctx, args, request) {
+		wg.Add(1)
+		go blockF(&wg, page)
+	}
+	wg.Wait()
+
+	// respond
+	respond.AsHTML(ctx, request, writer, page, &respond.Args{
+		Template:      templateName,
+		TemplateFiles: tmpl.GetTemplateFiles(args.TemplateDir),
+		Funcs:         tmpl.TemplateFunctions(),
+	})
+	log.Info("complete.")
+}
+
+func getPage(team string, in *frontmodels.RegisterArgs, request *http.Request) (page *PageContent, template string) {
+	var args *htmlpage.Args = &htmlpage.Args{
+		Name:         "OPG Reports",
+		Title:        "OPG Reports - Workflow Reliability",
+		GovUKVersion: in.GovUKVersion,
+		SemVer:       in.SemVer,
+	}
+	template = "workflow-reliability"
+	if team != "" {
+		args.Title += " - " + cnv.Capitalize(team)
+	}
+	page = &PageContent{
+		HTMLPage: htmlpage.New(request, args),
+		Team:     team,
+	}
+	return
+}
+
+// dataCallers provides all the aync / concurrent api calls to fetch and attach data to this page
+func dataCallers(ctx context.Context, args *frontmodels.RegisterArgs, request *http.Request) (funcs []dataCallerF) {
+	var (
+		team        = request.PathValue("team")
+		apiEndpoint = workflowreliabilityapi.ENDPOINT_BASE
+		dateEnd     = times.ResetMonth(times.Today()) // use this month
+		dateStart   = times.Add(dateEnd, -5, times.MONTH)
+		params      = []*rest.Param{
+			{Type: rest.PATH, Key: "date_end", Value: times.AsYMString(dateEnd)},
+			{Type: rest.PATH, Key: "date_start", Value: times.AsYMString(dateStart)},
+			// optional filters passed through from the front end request
+			{Type: rest.QUERY, Key: "org"},
+			{Type: rest.QUERY, Key: "branch"},
+		}
+	)
+	// add team filter values and url
+	if team != "" {
+		apiEndpoint = workflowreliabilityapi.ENDPOINT_TEAM
+		params = append(params, &rest.Param{Type: rest.PATH, Key: "team", Value: team})
+	}
+
+	funcs = []dataCallerF{
+		// get teams
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*teamapiall.Response](ctx, args.ApiHost, teamapiall.ENDPOINT, request)
+			if err == nil {
+				page.Teams = resp.Data
+			}
+			wg.Done()
+		},
+		// get data freshness for the banner
+		func(wg *sync.WaitGroup, page *PageContent) {
+			page.Freshness = statusfront.Freshness(ctx, args.ApiHost, request)
+			wg.Done()
+		},
+		// get the workflows that fail the most
+		func(wg *sync.WaitGroup, page *PageContent) {
+			resp, err := rest.FromApi[*workflowreliabilityapi.Response](ctx, args.ApiHost, apiEndpoint, request, params...)
+			if err == nil {
+				workflows := []*frontmodels.WorkflowReliability{}
+				summary := &frontmodels.WorkflowReliability{}
+				// convert to front end version
+				cnv.Convert(resp.Data, &workflows)
+				cnv.Convert(resp.Summary, &summary)
+				page.WorkflowReliabilityData = &frontmodels.WorkflowReliabilityData{
+					Team:      team,
+					Workflows: workflows[:min(len(workflows), worstOffenders)],
+					Total:     len(workflows),
+					Summary:   summary,
+				}
+				page.Dates = &frontmodels.DateRanges{
+					DateStart: resp.Request.DateStart,
+					DateEnd:   resp.Request.DateEnd,
+					Months: times.AsYMStrings(
+						times.Months(times.Add(times.Today(), -12, times.MONTH), times.Today()),
+					),
+				}
+			}
+			wg.Done()
+		},
+	}
+	return
+}
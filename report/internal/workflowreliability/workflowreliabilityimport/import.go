@@ -0,0 +1,271 @@
+// Package workflowreliabilityimport records every completed workflow run so the
+// failure rate, re-run rate and mean time to green of each workflow can be reported.
+//
+// Runs of all events and branches are fetched via `repos.GetWorkflowRuns`; the list
+// api returns the latest attempt of each run, so `attempt` greater than 1 means the
+// run was re-run and its conclusion is that of the final attempt.
+//
+// Time to green is set on the run that turned a workflow red on a branch:
+//
+//   - a run that passed on a re-run: from the run being created to its last attempt completing
+//   - the first failed run of a streak: from it being created to the next successful run
+//     of the same workflow and branch completing (zero if there is none yet)
+package workflowreliabilityimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"slices"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement adds or updates a workflow run
+const InsertStatement string = `
+INSERT INTO workflow_runs (
+	codebase,
+	run_id,
+	workflow,
+	branch,
+	event,
+	month,
+	started_at,
+	completed_at,
+	conclusion,
+	attempt,
+	seconds_to_green
+) VALUES (
+	:codebase,
+	:run_id,
+	:workflow,
+	:branch,
+	:event,
+	:month,
+	:started_at,
+	:completed_at,
+	:conclusion,
+	:attempt,
+	:seconds_to_green
+) ON CONFLICT (codebase,run_id) DO UPDATE SET
+	workflow=excluded.workflow,
+	branch=excluded.branch,
+	event=excluded.event,
+	month=excluded.month,
+	started_at=excluded.started_at,
+	completed_at=excluded.completed_at,
+	conclusion=excluded.conclusion,
+	attempt=excluded.attempt,
+	seconds_to_green=excluded.seconds_to_green
+RETURNING id
+;
+`
+
+// conclusions used to work out when a workflow is red or green; others
+// (cancelled, skipped etc) leave it as it was
+const (
+	ConclusionSuccess string = "success"
+	ConclusionFailure string = "failure"
+)
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// actionClient wrapper for *github.ActionsService
+type actionClient interface {
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error)
+}
+
+type Args struct {
+	DB           string    `json:"db"`             // database path
+	Driver       string    `json:"driver"`         // database driver
+	Params       string    `json:"params"`         // database connection params
+	OrgSlug      string    `json:"org_slug"`       // github org name
+	ParentSlug   string    `json:"parent_slug"`    // parent slug
+	Sources      []string  `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool      `json:"recursive"`      // include repositories of all child teams
+	FilterByName string    `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	DateStart    time.Time `json:"date_start"`     // start date
+	DateEnd      time.Time `json:"date_end"`       // end date
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams   teamClient   // *github.TeamsService
+	Actions actionClient // *github.ActionsService
+}
+
+// Model is a single completed workflow run
+type Model struct {
+	Codebase       string `json:"codebase"`         // full name of codebase
+	RunID          int64  `json:"run_id"`           // github id of the run
+	Workflow       string `json:"workflow"`         // name of the workflow
+	Branch         string `json:"branch"`           // head branch of the run
+	Event          string `json:"event"`            // event that triggered the run
+	Month          string `json:"month"`            // month as YYYY-MM string, from the created date
+	StartedAt      string `json:"started_at"`       // when the run was created
+	CompletedAt    string `json:"completed_at"`     // when the last attempt completed
+	Conclusion     string `json:"conclusion"`       // conclusion of the last attempt
+	Attempt        int    `json:"attempt"`          // attempt number of the last attempt
+	SecondsToGreen int64  `json:"seconds_to_green"` // time taken to go from red to green, see package docs
+}
+
+// Import finds all github repositories and writes their completed workflow runs
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "workflowreliabilityimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+
+	log.Info("starting ...", "date_start", times.AsYMDString(in.DateStart), "date_end", times.AsYMDString(in.DateEnd))
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.")
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*Model, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+
+	data = []*Model{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (runs []*Model, err error) {
+		if runs, err = repoRuns(ctx, clients.Actions, in, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, runs := range found {
+		data = append(data, runs...)
+	}
+	return
+}
+
+// repoRuns fetches the completed runs of the repository and converts them
+func repoRuns(ctx context.Context, client actionClient, in *Args, repo *github.Repository) (found []*Model, err error) {
+	var (
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "workflowreliabilityimport", "func", "repoRuns", "repo", repo.GetName())
+		runs []*github.WorkflowRun
+	)
+	found = []*Model{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting workflow runs ...")
+	runs, err = repos.GetWorkflowRuns(ctx, client, repo, &repos.Args{
+		DateStart: in.DateStart,
+		DateEnd:   in.DateEnd,
+		Event:     repos.AllEvents,
+		Status:    "completed",
+	}, false)
+	if err != nil {
+		return
+	}
+	found = Convert(repo.GetFullName(), runs)
+	log.Info("found workflow runs ...", "count", len(found))
+	return
+}
+
+// Convert returns a model for each run, in the order they were created, with the
+// time to green calculated for each workflow & branch
+func Convert(codebase string, runs []*github.WorkflowRun) (found []*Model) {
+	var redSince = map[string]*Model{} // workflow & branch to the first failed run of the streak
+	var redAt = map[string]time.Time{} // workflow & branch to when the streak started
+
+	found = []*Model{}
+	runs = slices.Clone(runs)
+	slices.SortFunc(runs, func(a, b *github.WorkflowRun) int {
+		return a.GetCreatedAt().Time.Compare(b.GetCreatedAt().Time)
+	})
+	for _, run := range runs {
+		var (
+			created   = run.GetCreatedAt().Time
+			completed = completedAt(run)
+			key       = run.GetName() + "/" + run.GetHeadBranch()
+			m         = &Model{
+				Codebase:    codebase,
+				RunID:       run.GetID(),
+				Workflow:    run.GetName(),
+				Branch:      run.GetHeadBranch(),
+				Event:       run.GetEvent(),
+				Month:       times.AsYMString(created),
+				StartedAt:   times.AsString(created, times.FULL),
+				CompletedAt: times.AsString(completed, times.FULL),
+				Conclusion:  run.GetConclusion(),
+				Attempt:     max(run.GetRunAttempt(), 1),
+			}
+		)
+		found = append(found, m)
+
+		switch m.Conclusion {
+		case ConclusionSuccess:
+			if red, ok := redSince[key]; ok {
+				red.SecondsToGreen = int64(completed.Sub(redAt[key]).Seconds())
+				delete(redSince, key)
+			} else if m.Attempt > 1 {
+				// went red and was fixed by a re-run
+				m.SecondsToGreen = int64(completed.Sub(created).Seconds())
+			}
+		case ConclusionFailure:
+			if _, ok := redSince[key]; !ok {
+				redSince[key] = m
+				redAt[key] = created
+			}
+		}
+	}
+	return
+}
+
+// completedAt returns when the last attempt of the run finished; runs are not
+// updated once completed
+func completedAt(run *github.WorkflowRun) time.Time {
+	if run.UpdatedAt != nil {
+		return run.GetUpdatedAt().Time
+	}
+	return run.GetCreatedAt().Time
+}
@@ -0,0 +1,59 @@
+package workflowreliabilityimport
+
+import (
+	"opg-reports/report/package/times"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+func run(id int64, name string, branch string, conclusion string, attempt int, created time.Time, took time.Duration) *github.WorkflowRun {
+	return &github.WorkflowRun{
+		ID:         github.Ptr(id),
+		Name:       github.Ptr(name),
+		HeadBranch: github.Ptr(branch),
+		Event:      github.Ptr("push"),
+		Conclusion: github.Ptr(conclusion),
+		RunAttempt: github.Ptr(attempt),
+		CreatedAt:  &github.Timestamp{Time: created},
+		UpdatedAt:  &github.Timestamp{Time: created.Add(took)},
+	}
+}
+
+func TestWorkflowReliabilityImportConvert(t *testing.T) {
+	var start = times.MustFromString("2025-01-10")
+	var runs = []*github.WorkflowRun{
+		// red streak on main, fixed by run 4
+		run(3, "build", "main", "failure", 1, start.Add(time.Hour), time.Minute),
+		run(1, "build", "main", "success", 1, start, time.Minute),
+		run(2, "build", "main", "failure", 1, start.Add(30*time.Minute), time.Minute),
+		// another branch is tracked separately and never goes green
+		run(5, "build", "feature", "failure", 1, start.Add(45*time.Minute), time.Minute),
+		run(4, "build", "main", "success", 1, start.Add(2*time.Hour), 10*time.Minute),
+		// passed on a re-run
+		run(6, "test", "main", "success", 3, start, 90*time.Minute),
+		run(7, "test", "main", "cancelled", 1, start.Add(time.Hour), time.Minute),
+	}
+	found := Convert("org/repo", runs)
+	if len(found) != len(runs) {
+		t.Fatalf("expected a model per run, found [%d]", len(found))
+	}
+	byID := map[int64]*Model{}
+	for _, m := range found {
+		byID[m.RunID] = m
+	}
+	// first failure of the streak until run 4 completes
+	if want := int64((90*time.Minute + 10*time.Minute).Seconds()); byID[2].SecondsToGreen != want {
+		t.Errorf("expected [%d] seconds to green, found [%d]", want, byID[2].SecondsToGreen)
+	}
+	if byID[3].SecondsToGreen != 0 || byID[5].SecondsToGreen != 0 || byID[4].SecondsToGreen != 0 {
+		t.Errorf("only the start of a recovered streak should have a time to green: %+v %+v %+v", byID[3], byID[4], byID[5])
+	}
+	if byID[6].SecondsToGreen != int64((90*time.Minute).Seconds()) || byID[6].Attempt != 3 {
+		t.Errorf("re-run pass should have a time to green: %+v", byID[6])
+	}
+	if byID[1].Month != "2025-01" || byID[1].Branch != "main" || byID[1].Event != "push" {
+		t.Errorf("unexpected run details: %+v", byID[1])
+	}
+}
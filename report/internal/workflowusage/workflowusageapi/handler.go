@@ -0,0 +1,261 @@
+package workflowusageapi
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/internal/global/apimodels"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/requested"
+	"opg-reports/report/package/respond"
+	"opg-reports/report/package/times"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// selectStmt fetches the usage totals of each workflow over the date range,
+// slowest (by average run duration) first
+const selectStmt string = `
+SELECT
+	'' as month,
+	codebase_workflow_usage.codebase,
+	codebase_workflow_usage.workflow,
+	COALESCE(SUM(codebase_workflow_usage.runs),0) as runs,
+	COALESCE(SUM(codebase_workflow_usage.duration_ms),0) as duration_ms,
+	COALESCE(SUM(codebase_workflow_usage.ubuntu_ms),0) as ubuntu_ms,
+	COALESCE(SUM(codebase_workflow_usage.macos_ms),0) as macos_ms,
+	COALESCE(SUM(codebase_workflow_usage.windows_ms),0) as windows_ms
+FROM codebase_workflow_usage
+LEFT JOIN codebases on codebases.full_name = codebase_workflow_usage.codebase
+WHERE
+	codebases.archived = 0
+	AND codebase_workflow_usage.month IN (:months)
+GROUP BY
+	codebase_workflow_usage.codebase,
+	codebase_workflow_usage.workflow
+ORDER BY
+	(SUM(codebase_workflow_usage.duration_ms) / MAX(SUM(codebase_workflow_usage.runs),1)) DESC,
+	codebase_workflow_usage.codebase ASC,
+	codebase_workflow_usage.workflow ASC
+;
+`
+
+// selectByMonthStmt is the same as selectStmt, but split by month as well (?split=month)
+const selectByMonthStmt string = `
+SELECT
+	codebase_workflow_usage.month,
+	codebase_workflow_usage.codebase,
+	codebase_workflow_usage.workflow,
+	COALESCE(SUM(codebase_workflow_usage.runs),0) as runs,
+	COALESCE(SUM(codebase_workflow_usage.duration_ms),0) as duration_ms,
+	COALESCE(SUM(codebase_workflow_usage.ubuntu_ms),0) as ubuntu_ms,
+	COALESCE(SUM(codebase_workflow_usage.macos_ms),0) as macos_ms,
+	COALESCE(SUM(codebase_workflow_usage.windows_ms),0) as windows_ms
+FROM codebase_workflow_usage
+LEFT JOIN codebases on codebases.full_name = codebase_workflow_usage.codebase
+WHERE
+	codebases.archived = 0
+	AND codebase_workflow_usage.month IN (:months)
+GROUP BY
+	codebase_workflow_usage.month,
+	codebase_workflow_usage.codebase,
+	codebase_workflow_usage.workflow
+ORDER BY
+	codebase_workflow_usage.month DESC,
+	codebase_workflow_usage.codebase ASC,
+	codebase_workflow_usage.workflow ASC
+;
+`
+
+// teamFilter limits the codebases to those owned by the team; a sub query is
+// used as a codebase can have several owners within the same team
+const teamFilter string = `WHERE codebase_workflow_usage.codebase IN (SELECT codebase_owners.codebase FROM codebase_owners WHERE codebase_owners.team_name = :team) AND`
+
+// splitByMonth is the `split` query value that returns a row per month as well
+const splitByMonth string = "month"
+
+// per minute list prices (USD) of the standard github hosted runners, used
+// to estimate the cost of the billable time
+const (
+	ubuntuPerMinute  float64 = 0.008
+	windowsPerMinute float64 = 0.016
+	macosPerMinute   float64 = 0.08
+)
+
+// Request contains the url path / query string values that we will use
+// in this handler
+type Request struct {
+	DateStart string `json:"date_start"`
+	DateEnd   string `json:"date_end"`
+	Team      string `json:"team"`  // optional team filter, rolled up via codebase_owners
+	Org       string `json:"org"`   // optional github org filter (?org=)
+	Split     string `json:"split"` // optional; `month` to return a row per month as well (?split=month)
+}
+
+func (self *Request) Start() (t time.Time) {
+	t = times.MustFromString(self.DateStart)
+	return
+}
+func (self *Request) End() (t time.Time) {
+	t = times.MustFromString(self.DateEnd)
+	return
+}
+
+// Response is the end result thats sent back from the handler via the writter
+type Response struct {
+	Version string   `json:"version"`
+	SHA     string   `json:"sha"`
+	Request *Request `json:"request"`
+	Data    []*Model `json:"data"`    // the actual data results
+	Summary *Model   `json:"summary"` // totals of every workflow
+}
+
+// Filter is with the sql to replace the named parameters
+// within the statement.
+type Filter struct {
+	Months []string `json:"months"`
+	Team   string   `json:"team"`
+	Org    string   `json:"org"`
+}
+
+// Model is the data struct to use when fetching the select; the totals are
+// selected and the minutes calculated from them
+type Model struct {
+	Month      string `json:"month,omitempty"` // only set when split by month
+	Codebase   string `json:"codebase,omitempty"`
+	Workflow   string `json:"workflow,omitempty"`
+	Runs       int    `json:"runs"`
+	DurationMS int64  `json:"duration_ms"`
+	UbuntuMS   int64  `json:"ubuntu_ms"`
+	MacOSMS    int64  `json:"macos_ms"`
+	WindowsMS  int64  `json:"windows_ms"`
+
+	AverageMinutes  float64 `json:"average_minutes"`  // average wall clock minutes per run
+	UbuntuMinutes   float64 `json:"ubuntu_minutes"`   // billable minutes on linux runners
+	MacOSMinutes    float64 `json:"macos_minutes"`    // billable minutes on macos runners
+	WindowsMinutes  float64 `json:"windows_minutes"`  // billable minutes on windows runners
+	BillableMinutes float64 `json:"billable_minutes"` // billable minutes on all runners
+	EstimatedCost   float64 `json:"estimated_cost"`   // billable minutes at list price (USD)
+}
+
+// Sequence is used to return the columns in the order they are selected
+func (self *Model) Sequence() []any {
+	return []any{
+		&self.Month,
+		&self.Codebase,
+		&self.Workflow,
+		&self.Runs,
+		&self.DurationMS,
+		&self.UbuntuMS,
+		&self.MacOSMS,
+		&self.WindowsMS,
+	}
+}
+
+// Add includes the totals of other within this model
+func (self *Model) Add(other *Model) {
+	self.Runs += other.Runs
+	self.DurationMS += other.DurationMS
+	self.UbuntuMS += other.UbuntuMS
+	self.MacOSMS += other.MacOSMS
+	self.WindowsMS += other.WindowsMS
+}
+
+// Calculate sets the minutes and estimated cost from the totals
+func (self *Model) Calculate() {
+	var minute float64 = float64(time.Minute.Milliseconds())
+	if self.Runs > 0 {
+		self.AverageMinutes = float64(self.DurationMS) / float64(self.Runs) / minute
+	}
+	self.UbuntuMinutes = float64(self.UbuntuMS) / minute
+	self.MacOSMinutes = float64(self.MacOSMS) / minute
+	self.WindowsMinutes = float64(self.WindowsMS) / minute
+	self.BillableMinutes = self.UbuntuMinutes + self.MacOSMinutes + self.WindowsMinutes
+	self.EstimatedCost = (self.UbuntuMinutes * ubuntuPerMinute) +
+		(self.MacOSMinutes * macosPerMinute) +
+		(self.WindowsMinutes * windowsPerMinute)
+}
+
+// Responder process the incoming request, queries the database and returns the result as json data.
+func Responder(ctx context.Context, conf *apimodels.Args, request *http.Request, writer http.ResponseWriter) {
+	var (
+		err      error
+		response *Response
+		months   []string
+		filter   *Filter                = &Filter{}
+		in       *Request               = &Request{}
+		bindMap  map[string]interface{} = map[string]interface{}{}
+		all      []*Model               = []*Model{}
+		summary  *Model                 = &Model{}
+		log      *slog.Logger           = cntxt.GetLogger(ctx).With("package", "workflowusageapi", "func", "Responder")
+		stmt     string                 = selectStmt // localised constant
+	)
+	log.Info("running http handler ...")
+	// convert the http request into Request struct
+	requested.Parse(ctx, request, &in)
+	// get months between dates
+	months = times.AsYMStrings(times.Months(in.Start(), in.End()))
+	if len(months) <= 0 {
+		log.Error("no months found with date range provided")
+		return
+	}
+	filter.Months = months
+	if in.Split == splitByMonth {
+		stmt = selectByMonthStmt
+	}
+	// look for the optional team
+	if in.Team != "" {
+		log.Info("optional team filter found ...", "team", in.Team)
+		filter.Team = in.Team
+		stmt = strings.ReplaceAll(stmt, "WHERE", teamFilter)
+	}
+	// look for the optional org
+	if in.Org != "" {
+		log.Info("optional org filter found ...", "org", in.Org)
+		filter.Org = in.Org
+		stmt = strings.ReplaceAll(stmt, "WHERE", "WHERE codebases.org = :org AND")
+	}
+	// now convert to a map for use in bound statements
+	err = cnv.Convert(filter, &bindMap)
+	if err != nil {
+		log.Error("failed to convert filter into map for binding", "err", err.Error())
+		return
+	}
+	dbx.Select(ctx, stmt, &dbx.SelectArgs{
+		DB:      conf.DB,
+		Driver:  conf.Driver,
+		Params:  conf.Params,
+		BindMap: bindMap,
+		ScanF: func(rows *sql.Rows) error {
+			var r = &Model{}
+			var seq = r.Sequence()
+			if err = rows.Scan(seq...); err == nil {
+				all = append(all, r)
+			} else {
+				log.Error("row scan failed", "err", err.Error())
+			}
+			return err
+		},
+	})
+	for _, r := range all {
+		r.Calculate()
+		summary.Add(r)
+	}
+	summary.Calculate()
+
+	// setup response object
+	response = &Response{
+		Version: conf.Version,
+		SHA:     conf.SHA,
+		Request: in,
+		Data:    all,
+		Summary: summary,
+	}
+	log.Info("complete.")
+	respond.AsJSON(ctx, request, writer, response)
+}
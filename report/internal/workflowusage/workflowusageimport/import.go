@@ -0,0 +1,245 @@
+// Package workflowusageimport fetches the duration and billable time of every completed
+// workflow run and totals them per codebase, workflow and month.
+//
+// Runs of all events and branches are included, with the month based on the created
+// date of the run. The usage of each run is fetched separately, so this makes one api
+// call per run on top of listing them.
+//
+// Billable time is split by runner os (`UBUNTU`, `MACOS` & `WINDOWS`) as each is charged
+// at a different rate; github only reports billable time for private repositories.
+package workflowusageimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/repos"
+	"opg-reports/report/package/retry"
+	"opg-reports/report/package/times"
+	"opg-reports/report/package/workers"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// InsertStatement replaces the totals for the codebase, workflow & month
+const InsertStatement string = `
+INSERT INTO codebase_workflow_usage (
+	codebase,
+	workflow,
+	month,
+	runs,
+	duration_ms,
+	ubuntu_ms,
+	macos_ms,
+	windows_ms
+) VALUES (
+	:codebase,
+	:workflow,
+	:month,
+	:runs,
+	:duration_ms,
+	:ubuntu_ms,
+	:macos_ms,
+	:windows_ms
+) ON CONFLICT (codebase,workflow,month) DO UPDATE SET
+	runs=excluded.runs,
+	duration_ms=excluded.duration_ms,
+	ubuntu_ms=excluded.ubuntu_ms,
+	macos_ms=excluded.macos_ms,
+	windows_ms=excluded.windows_ms
+RETURNING id
+;
+`
+
+// runner os keys used in the billable usage
+const (
+	RunnerUbuntu  string = "UBUNTU"
+	RunnerMacOS   string = "MACOS"
+	RunnerWindows string = "WINDOWS"
+)
+
+var ErrFailedGettingUsage = errors.New("error getting workflow run usage.")
+
+// teamClient wrapper around *github.TeamsService
+type teamClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// actionClient wrapper for *github.ActionsService
+type actionClient interface {
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error)
+}
+
+type Args struct {
+	DB           string    `json:"db"`             // database path
+	Driver       string    `json:"driver"`         // database driver
+	Params       string    `json:"params"`         // database connection params
+	OrgSlug      string    `json:"org_slug"`       // github org name
+	ParentSlug   string    `json:"parent_slug"`    // parent slug
+	Sources      []string  `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool      `json:"recursive"`      // include repositories of all child teams
+	FilterByName string    `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
+	DateStart    time.Time `json:"date_start"`     // start date
+	DateEnd      time.Time `json:"date_end"`       // end date
+
+	Concurrency int           `json:"concurrency"` // number of repositories processed at once
+	Wait        workers.WaitF `json:"-"`           // optional; called before each repository, used to respect rate limits
+}
+
+type Clients struct {
+	Teams   teamClient   // *github.TeamsService
+	Actions actionClient // *github.ActionsService
+}
+
+// Model is the usage totals of a workflow for a month
+type Model struct {
+	Codebase   string `json:"codebase"`    // full name of codebase
+	Workflow   string `json:"workflow"`    // name of the workflow
+	Month      string `json:"month"`       // month as YYYY-MM string
+	Runs       int    `json:"runs"`        // number of completed runs
+	DurationMS int64  `json:"duration_ms"` // total wall clock duration of the runs
+	UbuntuMS   int64  `json:"ubuntu_ms"`   // total billable time on linux runners
+	MacOSMS    int64  `json:"macos_ms"`    // total billable time on macos runners
+	WindowsMS  int64  `json:"windows_ms"`  // total billable time on windows runners
+}
+
+// Import finds all github repositories and writes the workflow usage for each month
+func Import(ctx context.Context, clients *Clients, in *Args) (err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "workflowusageimport", "func", "Import")
+	var repoList []*github.Repository
+	var data = []*Model{}
+
+	log.Info("starting ...", "date_start", times.AsYMDString(in.DateStart), "date_end", times.AsYMDString(in.DateEnd))
+	log.Debug("getting repository list ...")
+	repoList, err = repos.GetList(ctx, clients.Teams, &repos.Args{
+		OrgSlug:      in.OrgSlug,
+		ParentSlug:   in.ParentSlug,
+		Sources:      in.Sources,
+		Recursive:    in.Recursive,
+		FilterByName: in.FilterByName,
+	})
+	if err != nil {
+		return
+	}
+
+	data, err = handler(ctx, clients, in, repoList)
+	if err != nil {
+		log.Error("error processing repos", "err", err.Error())
+		return
+	}
+
+	err = dbx.Insert(ctx, InsertStatement, data, &dbx.InsertArgs{
+		DB:     in.DB,
+		Driver: in.Driver,
+		Params: in.Params,
+	})
+	if err != nil {
+		log.Error("error write data during import", "err", err.Error())
+		return
+	}
+
+	log.Info("complete.")
+	return
+}
+
+// handler processes each repository concurrently; failed repositories are logged
+// and skipped unless all of them fail.
+func handler(ctx context.Context, clients *Clients, in *Args, repoList []*github.Repository) (data []*Model, err error) {
+	var results []*workers.Result[[]*Model]
+	var found [][]*Model
+
+	data = []*Model{}
+	results = workers.Map(ctx, repoList, func(ctx context.Context, repo *github.Repository) (usage []*Model, err error) {
+		if usage, err = repoUsage(ctx, clients.Actions, in, repo); err != nil {
+			err = errors.Join(fmt.Errorf("repository [%s]", repo.GetFullName()), err)
+		}
+		return
+	}, &workers.Args{Concurrency: in.Concurrency, Wait: in.Wait})
+
+	if found, err = workers.Collect(ctx, results); err != nil {
+		return
+	}
+	for _, usage := range found {
+		data = append(data, usage...)
+	}
+	return
+}
+
+// repoUsage fetches the completed runs of the repository along with the usage
+// of each and totals them by workflow & month
+func repoUsage(ctx context.Context, client actionClient, in *Args, repo *github.Repository) (usage []*Model, err error) {
+	var (
+		log     *slog.Logger = cntxt.GetLogger(ctx).With("package", "workflowusageimport", "func", "repoUsage", "repo", repo.GetName())
+		runs    []*github.WorkflowRun
+		byGroup map[string]*Model = map[string]*Model{}
+	)
+	usage = []*Model{}
+	if repo.GetArchived() {
+		log.Warn("repository is archived, skipping.")
+		return
+	}
+	log.Info("getting workflow runs ...")
+	runs, err = repos.GetWorkflowRuns(ctx, client, repo, &repos.Args{
+		DateStart: in.DateStart,
+		DateEnd:   in.DateEnd,
+		Event:     repos.AllEvents,
+		Status:    "completed",
+	}, false)
+	if err != nil {
+		return
+	}
+	log.Info("getting usage of workflow runs ...", "count", len(runs))
+	for _, run := range runs {
+		var u *github.WorkflowRunUsage
+		var m *Model
+		var key = run.GetName() + "/" + times.AsYMString(run.GetCreatedAt().Time)
+
+		err = retry.Do(ctx, func() (e error) {
+			u, _, e = client.GetWorkflowRunUsageByID(ctx, repo.GetOwner().GetLogin(), repo.GetName(), run.GetID())
+			return
+		})
+		if err != nil {
+			err = errors.Join(ErrFailedGettingUsage, fmt.Errorf("run [%d]", run.GetID()), err)
+			return
+		}
+		if m = byGroup[key]; m == nil {
+			m = &Model{Codebase: repo.GetFullName(), Workflow: run.GetName(), Month: times.AsYMString(run.GetCreatedAt().Time)}
+			byGroup[key] = m
+		}
+		m.Add(u)
+	}
+	for _, m := range byGroup {
+		usage = append(usage, m)
+	}
+	slices.SortFunc(usage, func(a, b *Model) int {
+		return strings.Compare(a.Month+a.Workflow, b.Month+b.Workflow)
+	})
+	return
+}
+
+// Add includes the run usage in the totals
+func (self *Model) Add(usage *github.WorkflowRunUsage) {
+	self.Runs += 1
+	self.DurationMS += usage.GetRunDurationMS()
+	if usage.Billable == nil {
+		return
+	}
+	for runner, bill := range *usage.Billable {
+		switch strings.ToUpper(runner) {
+		case RunnerUbuntu:
+			self.UbuntuMS += bill.GetTotalMS()
+		case RunnerMacOS:
+			self.MacOSMS += bill.GetTotalMS()
+		case RunnerWindows:
+			self.WindowsMS += bill.GetTotalMS()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package workflowusageimport
+
+import (
+	"context"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/times"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockActions returns the same runs for every call, with usage for each run id
+type mockActions struct {
+	runs  []*github.WorkflowRun
+	usage map[int64]*github.WorkflowRunUsage
+	opts  *github.ListWorkflowRunsOptions
+}
+
+func (self *mockActions) ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	self.opts = opts
+	return &github.WorkflowRuns{WorkflowRuns: self.runs}, &github.Response{}, nil
+}
+
+func (self *mockActions) GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error) {
+	return self.usage[runID], &github.Response{}, nil
+}
+
+func usage(duration int64, bills map[string]int64) *github.WorkflowRunUsage {
+	var billable = github.WorkflowRunBillMap{}
+	for runner, ms := range bills {
+		billable[runner] = &github.WorkflowRunBill{TotalMS: github.Ptr(ms)}
+	}
+	return &github.WorkflowRunUsage{RunDurationMS: github.Ptr(duration), Billable: &billable}
+}
+
+func TestWorkflowUsageImportRepoUsage(t *testing.T) {
+	var (
+		ctx     = cntxt.AddLogger(t.Context(), logger.New("error"))
+		created = &github.Timestamp{Time: times.MustFromString("2025-01-10")}
+		repo    = &github.Repository{
+			Name:     github.Ptr("repo"),
+			FullName: github.Ptr("org/repo"),
+			Owner:    &github.User{Login: github.Ptr("org")},
+		}
+		client = &mockActions{
+			runs: []*github.WorkflowRun{
+				{ID: github.Ptr(int64(1)), Name: github.Ptr("build"), CreatedAt: created},
+				{ID: github.Ptr(int64(2)), Name: github.Ptr("build"), CreatedAt: created},
+				{ID: github.Ptr(int64(3)), Name: github.Ptr("deploy"), CreatedAt: created},
+			},
+			usage: map[int64]*github.WorkflowRunUsage{
+				1: usage(60000, map[string]int64{RunnerUbuntu: 120000}),
+				2: usage(30000, map[string]int64{RunnerUbuntu: 60000, RunnerMacOS: 10000}),
+				3: usage(5000, map[string]int64{RunnerWindows: 5000}),
+			},
+		}
+	)
+	found, err := repoUsage(ctx, client, &Args{
+		DateStart: times.MustFromString("2025-01-01"),
+		DateEnd:   times.MustFromString("2025-02-01"),
+	}, repo)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	// runs of all events should be requested
+	if client.opts.Event != "" || client.opts.Status != "completed" {
+		t.Errorf("unexpected list options: event [%s] status [%s]", client.opts.Event, client.opts.Status)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 workflows, found [%d]", len(found))
+	}
+	build, deploy := found[0], found[1]
+	if build.Workflow != "build" || build.Runs != 2 || build.DurationMS != 90000 || build.UbuntuMS != 180000 || build.MacOSMS != 10000 {
+		t.Errorf("unexpected build usage: %+v", build)
+	}
+	if deploy.Month != "2025-01" || deploy.Runs != 1 || deploy.WindowsMS != 5000 {
+		t.Errorf("unexpected deploy usage: %+v", deploy)
+	}
+}
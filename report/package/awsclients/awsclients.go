@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
@@ -20,12 +21,12 @@ var (
 
 // SupportedClients is a type constraint on creatign clients
 type SupportedClients interface {
-	*s3.Client | *sts.Client | *costexplorer.Client | *cloudwatch.Client
+	*s3.Client | *sts.Client | *costexplorer.Client | *cloudwatch.Client | *route53.Client
 }
 
 // New fetches a aws-sdk-v2 version of the appropriate client for T
 //
-// Supports: *s3.Client | *sts.Client | *costexplorer.Client | *cloudwatch.Client
+// Supports: *s3.Client | *sts.Client | *costexplorer.Client | *cloudwatch.Client | *route53.Client
 func New[T SupportedClients](ctx context.Context, region string) (T, error) {
 	var (
 		err    error
@@ -52,6 +53,8 @@ func New[T SupportedClients](ctx context.Context, region string) (T, error) {
 		})
 	case *cloudwatch.Client:
 		c = cloudwatch.NewFromConfig(awscfg)
+	case *route53.Client:
+		c = route53.NewFromConfig(awscfg)
 	default:
 		err = errors.Join(ErrUnsupportedType, fmt.Errorf("client type [%T] is not supported.", t))
 		return nil, err
@@ -3,6 +3,7 @@ package awsid
 import (
 	"context"
 	"opg-reports/report/package/awsclients"
+	"opg-reports/report/package/retry"
 
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
@@ -17,7 +18,9 @@ func Identity(ctx context.Context, region string) (id *sts.GetCallerIdentityOutp
 		return
 	}
 
-	id, err = client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	id, err = retry.Get(ctx, func() (*sts.GetCallerIdentityOutput, error) {
+		return client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	})
 	if err != nil {
 		return
 	}
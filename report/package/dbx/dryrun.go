@@ -0,0 +1,521 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/cnv"
+	"opg-reports/report/package/conn"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+const dryRunKey string = "dbx-dry-run"
+
+// Change actions
+const (
+	ActionInsert string = "insert"
+	ActionUpdate string = "update"
+	ActionDelete string = "delete"
+)
+
+var ErrDryRunStatement = errors.New("unable to parse insert statement for dry run.")
+
+var (
+	insertRe   *regexp.Regexp = regexp.MustCompile(`(?is)INSERT\s+INTO\s+(\w+)\s*\(([^)]*)\)\s*VALUES\s*\(`)
+	conflictRe *regexp.Regexp = regexp.MustCompile(`(?is)ON\s+CONFLICT\s*\(([^)]*)\)\s*DO\s+(NOTHING|UPDATE\s+SET\s+(.*?))\s*(RETURNING|;|$)`)
+	deleteRe   *regexp.Regexp = regexp.MustCompile(`(?is)^\s*DELETE\s+FROM\s+(\w+)(?:\s+WHERE\s+(.*?))?\s*;?\s*$`)
+)
+
+// DryRun collects the writes that Insert and Exec would have made when using a
+// context created by WithDryRun, without changing the database. Diff then
+// compares those writes against the current database content.
+//
+// Only inserts (including upserts) and deletes (`DELETE FROM x;` or
+// `DELETE FROM x WHERE ...;`) are tracked; any other Exec statement is skipped
+// and logged. Deletes are assumed to run before the inserts into the same table,
+// which is how the importers use them.
+type DryRun struct {
+	mu     sync.Mutex
+	tables []string               // tables in the order they were first written to
+	writes map[string]*tableWrite // pending writes by table name
+}
+
+// tableWrite is the pending set of writes for a single table
+type tableWrite struct {
+	table     string
+	columns   []string
+	conflict  []string // columns in the on conflict clause
+	update    []string // columns updated on conflict
+	truncated bool     // table would have been emptied first
+	deletes   []*deleteWhere
+	rows      []map[string]string
+}
+
+// deleteWhere is the where clause and bind values of a delete statement
+type deleteWhere struct {
+	where string
+	args  []any
+}
+
+// Change is a single row that would be inserted, updated or deleted
+type Change struct {
+	Table    string            `json:"table"`
+	Action   string            `json:"action"`
+	Key      map[string]string `json:"key"`                // values used to match existing rows
+	Values   map[string]string `json:"values,omitempty"`   // new values (all columns for insert, changed columns for update)
+	Previous map[string]string `json:"previous,omitempty"` // current values (changed columns for update, all columns for delete)
+}
+
+// Summary is the count of changes for a table
+type Summary struct {
+	Table     string `json:"table"`
+	Inserts   int    `json:"inserts"`
+	Updates   int    `json:"updates"`
+	Deletes   int    `json:"deletes"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// Diff is the full set of changes a dry run would have made
+type Diff struct {
+	Summary []*Summary `json:"summary"`
+	Changes []*Change  `json:"changes"`
+}
+
+type DiffArgs struct {
+	DB     string `json:"db"`     // database path
+	Driver string `json:"driver"` // database driver
+	Params string `json:"params"` // database connection params
+}
+
+// WithDryRun returns a new context that stops Insert and Exec from writing to
+// the database, along with the DryRun that records what would have been written
+func WithDryRun(ctx context.Context) (context.Context, *DryRun) {
+	var dr = &DryRun{tables: []string{}, writes: map[string]*tableWrite{}}
+	return context.WithValue(ctx, dryRunKey, dr), dr
+}
+
+// getDryRun returns the dry run attached to the context, if there is one
+func getDryRun(ctx context.Context) (dr *DryRun, ok bool) {
+	dr, ok = ctx.Value(dryRunKey).(*DryRun)
+	return
+}
+
+// table returns the pending writes for the named table, creating it if needed
+func (self *DryRun) table(name string) (w *tableWrite) {
+	var ok bool
+	if w, ok = self.writes[name]; !ok {
+		w = &tableWrite{table: name, rows: []map[string]string{}}
+		self.writes[name] = w
+		self.tables = append(self.tables, name)
+	}
+	return
+}
+
+// exec records a delete, other statements are ignored
+func (self *DryRun) exec(ctx context.Context, stmt string, args ...any) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "dbx", "func", "DryRun.exec")
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if m := deleteRe.FindStringSubmatch(stmt); m != nil {
+		var w = self.table(m[1])
+		if m[2] != "" {
+			w.deletes = append(w.deletes, &deleteWhere{where: m[2], args: args})
+			return
+		}
+		w.truncated = true
+		w.deletes = nil
+		w.rows = []map[string]string{}
+		return
+	}
+	log.Debug("dry run, skipping statement", "sql", stmt)
+}
+
+// insert evaluates the values that would be inserted for each record and
+// records them against the table
+func (self *DryRun) insert(ctx context.Context, stmt string, records []map[string]interface{}, in *InsertArgs) (err error) {
+	var (
+		db     *sql.DB
+		parsed *tableWrite
+		values string
+		rows   = []map[string]string{}
+	)
+	if parsed, values, err = parseInsert(stmt); err != nil {
+		return
+	}
+	db, err = sql.Open(in.Driver, conn.SqlitePath(in.DB, in.Params))
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	// let the database evaluate the values so any expressions within
+	// the insert are accounted for
+	for _, record := range records {
+		var row map[string]string
+		bound, args, e := Bind(ctx, "SELECT "+values+";", record)
+		if e != nil {
+			return e
+		}
+		if row, err = queryRow(ctx, db, bound, parsed.columns, args...); err != nil {
+			return
+		}
+		rows = append(rows, row)
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	var w = self.table(parsed.table)
+	w.columns = parsed.columns
+	w.conflict = parsed.conflict
+	w.update = parsed.update
+	w.rows = append(w.rows, rows...)
+	return
+}
+
+// Diff compares the recorded writes against the current database content
+func (self *DryRun) Diff(ctx context.Context, in *DiffArgs) (diff *Diff, err error) {
+	var db *sql.DB
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	diff = &Diff{Summary: []*Summary{}, Changes: []*Change{}}
+	db, err = sql.Open(in.Driver, conn.SqlitePath(in.DB, in.Params))
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	for _, table := range self.tables {
+		var (
+			changes []*Change
+			summary *Summary
+		)
+		if changes, summary, err = self.writes[table].diff(ctx, db); err != nil {
+			return
+		}
+		diff.Changes = append(diff.Changes, changes...)
+		diff.Summary = append(diff.Summary, summary)
+	}
+	return
+}
+
+// diff works out the changes for this table compared to the existing rows
+func (self *tableWrite) diff(ctx context.Context, db *sql.DB) (changes []*Change, summary *Summary, err error) {
+	var (
+		existing []map[string]string
+		columns  []string
+		keyCols  []string
+		compare  []string            = self.update
+		current  map[string]int      = map[string]int{}
+		proposed []map[string]string = []map[string]string{}
+		seen     map[string]int      = map[string]int{}
+		removed  map[string]bool     = map[string]bool{}
+	)
+	changes = []*Change{}
+	summary = &Summary{Table: self.table}
+
+	if existing, columns, err = self.existing(ctx, db, nil); err != nil {
+		return
+	}
+	keyCols = self.conflict
+	if len(keyCols) == 0 {
+		keyCols = columns
+	}
+	for i, row := range existing {
+		var key = rowKey(row, keyCols)
+		current[key] = i
+		if self.truncated {
+			removed[key] = true
+		}
+	}
+	// find the rows the partial deletes would remove
+	for _, del := range self.deletes {
+		var matched []map[string]string
+		if matched, _, err = self.existing(ctx, db, del); err != nil {
+			return
+		}
+		for _, row := range matched {
+			removed[rowKey(row, keyCols)] = true
+		}
+	}
+	// merge rows that would conflict with each other
+	for _, row := range self.rows {
+		var key = rowKey(row, keyCols)
+		if i, ok := seen[key]; ok {
+			for _, col := range self.update {
+				proposed[i][col] = row[col]
+			}
+			continue
+		}
+		seen[key] = len(proposed)
+		proposed = append(proposed, row)
+	}
+
+	for _, row := range proposed {
+		var i, ok = current[rowKey(row, keyCols)]
+		if !ok {
+			summary.Inserts++
+			changes = append(changes, &Change{Table: self.table, Action: ActionInsert, Key: subset(row, keyCols), Values: row})
+			continue
+		}
+		// deleted rows that are inserted again have every column replaced
+		var cols = compare
+		if removed[rowKey(row, keyCols)] {
+			cols = columns
+		}
+		var before, after = map[string]string{}, map[string]string{}
+		for _, col := range cols {
+			if existing[i][col] != row[col] {
+				before[col] = existing[i][col]
+				after[col] = row[col]
+			}
+		}
+		if len(after) == 0 {
+			summary.Unchanged++
+			continue
+		}
+		summary.Updates++
+		changes = append(changes, &Change{Table: self.table, Action: ActionUpdate, Key: subset(row, keyCols), Values: after, Previous: before})
+	}
+
+	for _, row := range existing {
+		var key = rowKey(row, keyCols)
+		if _, ok := seen[key]; removed[key] && !ok {
+			summary.Deletes++
+			changes = append(changes, &Change{Table: self.table, Action: ActionDelete, Key: subset(row, keyCols), Previous: row})
+		}
+	}
+	return
+}
+
+// existing returns the current rows of the table, limited to those matching the
+// delete when one is passed; when no insert has been recorded all columns are used
+func (self *tableWrite) existing(ctx context.Context, db *sql.DB, del *deleteWhere) (found []map[string]string, columns []string, err error) {
+	var (
+		rows   *sql.Rows
+		fields string = "*"
+		stmt   string
+		args   []any
+	)
+	found = []map[string]string{}
+	columns = self.columns
+	if len(columns) > 0 {
+		fields = strings.Join(columns, ", ")
+	}
+	stmt = fmt.Sprintf("SELECT %s FROM %s;", fields, self.table)
+	if del != nil {
+		stmt = fmt.Sprintf("SELECT %s FROM %s WHERE %s;", fields, self.table, del.where)
+		args = del.args
+	}
+	rows, err = db.QueryContext(ctx, stmt, args...)
+	// a table that does not exist yet has no rows
+	if err != nil && strings.Contains(err.Error(), "no such table") {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	if columns, err = rows.Columns(); err != nil {
+		return
+	}
+	for rows.Next() {
+		var row map[string]string
+		if row, err = scanRow(rows, columns); err != nil {
+			return
+		}
+		found = append(found, row)
+	}
+	err = rows.Err()
+	return
+}
+
+// WriteDiff writes a summary of each table followed by each change as a
+// tab aligned table
+func WriteDiff(w io.Writer, diff *Diff) {
+	var tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TABLE\tINSERTS\tUPDATES\tDELETES\tUNCHANGED")
+	for _, s := range diff.Summary {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", s.Table, s.Inserts, s.Updates, s.Deletes, s.Unchanged)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "ACTION\tTABLE\tKEY\tCHANGES")
+	for _, c := range diff.Changes {
+		var detail string
+		switch c.Action {
+		case ActionInsert:
+			detail = asPairs(c.Values, nil)
+		case ActionUpdate:
+			detail = asPairs(c.Values, c.Previous)
+		case ActionDelete:
+			detail = asPairs(c.Previous, nil)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Action, c.Table, asPairs(c.Key, nil), detail)
+	}
+	tw.Flush()
+}
+
+// parseInsert finds the table, columns, values and conflict handling
+// from an insert statement
+func parseInsert(stmt string) (w *tableWrite, values string, err error) {
+	var (
+		loc   []int = insertRe.FindStringSubmatchIndex(stmt)
+		depth int   = 1
+		end   int   = -1
+	)
+	if loc == nil {
+		err = ErrDryRunStatement
+		return
+	}
+	// find the closing bracket of the values, allowing for functions
+	for i := loc[1]; i < len(stmt) && end < 0; i++ {
+		switch stmt[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		err = ErrDryRunStatement
+		return
+	}
+	w = &tableWrite{
+		table:   stmt[loc[2]:loc[3]],
+		columns: splitList(stmt[loc[4]:loc[5]]),
+	}
+	values = stmt[loc[1]:end]
+	if m := conflictRe.FindStringSubmatch(stmt[end:]); m != nil {
+		w.conflict = splitList(m[1])
+		for _, set := range splitList(m[3]) {
+			w.update = append(w.update, strings.TrimSpace(strings.SplitN(set, "=", 2)[0]))
+		}
+	}
+	return
+}
+
+// queryRow runs the statement and returns the first row as strings
+func queryRow(ctx context.Context, db *sql.DB, stmt string, columns []string, args ...interface{}) (row map[string]string, err error) {
+	var rows *sql.Rows
+	if rows, err = db.QueryContext(ctx, stmt, args...); err != nil {
+		return
+	}
+	defer rows.Close()
+	row = map[string]string{}
+	if rows.Next() {
+		row, err = scanRow(rows, columns)
+	}
+	return
+}
+
+// scanRow reads the current row into a map of column name to value
+func scanRow(rows *sql.Rows, columns []string) (row map[string]string, err error) {
+	var values = make([]interface{}, len(columns))
+	var ptrs = make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err = rows.Scan(ptrs...); err != nil {
+		return
+	}
+	row = map[string]string{}
+	for i, col := range columns {
+		row[col] = asString(values[i])
+	}
+	return
+}
+
+// asString normalises a database value so they can be compared
+func asString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// rowKey generates a single string from the key columns to match rows on
+func rowKey(row map[string]string, keys []string) string {
+	var parts = []string{}
+	for _, k := range keys {
+		parts = append(parts, row[k])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// subset returns only the named columns of the row
+func subset(row map[string]string, keys []string) (sub map[string]string) {
+	sub = map[string]string{}
+	for _, k := range keys {
+		sub[k] = row[k]
+	}
+	return
+}
+
+// asPairs formats the map as sorted `key=value` pairs, showing the previous
+// value when one is passed
+func asPairs(values map[string]string, previous map[string]string) string {
+	var keys = []string{}
+	var pairs = []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if old, ok := previous[k]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%s->%s", k, old, values[k]))
+		} else {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", k, values[k]))
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// splitList splits a comma separated sql list, trimming each item
+func splitList(s string) (list []string) {
+	list = []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return
+}
+
+// asMaps converts the records into maps for binding
+func asMaps[T any](records []T) (maps []map[string]interface{}, err error) {
+	maps = []map[string]interface{}{}
+	for _, model := range records {
+		row := map[string]interface{}{}
+		if err = cnv.Convert(model, &row); err != nil {
+			return
+		}
+		maps = append(maps, row)
+	}
+	return
+}
@@ -0,0 +1,115 @@
+package dbx
+
+import (
+	"database/sql"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const testCreate string = `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, size INTEGER, active TEXT);`
+const testInsert string = `
+INSERT INTO items (
+	name,
+	size,
+	active
+) VALUES (
+	:name,
+	:size,
+	IIF(:active, 'true', 'false')
+) ON CONFLICT (name) DO UPDATE SET
+	size=excluded.size
+RETURNING id
+;
+`
+
+type testItem struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Active bool   `json:"active"`
+}
+
+func TestDryRunDiff(t *testing.T) {
+	var (
+		err    error
+		diff   *Diff
+		count  int
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dbpath = filepath.Join(t.TempDir(), "test-dryrun.db")
+		args   = &InsertArgs{DB: dbpath, Driver: "sqlite3"}
+	)
+	if err = Exec(ctx, testCreate, &ExecArgs{DB: dbpath, Driver: "sqlite3"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	err = Insert(ctx, testInsert, []*testItem{{Name: "a", Size: 1}, {Name: "b", Size: 2}, {Name: "c", Size: 3}}, args)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+
+	// upsert only: a is unchanged, b is updated, d is new
+	dryCtx, dr := WithDryRun(ctx)
+	err = Insert(dryCtx, testInsert, []*testItem{{Name: "a", Size: 1}, {Name: "b", Size: 5}, {Name: "d", Size: 4, Active: true}}, args)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if diff, err = dr.Diff(ctx, &DiffArgs{DB: dbpath, Driver: "sqlite3"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	s := diff.Summary[0]
+	if s.Inserts != 1 || s.Updates != 1 || s.Deletes != 0 || s.Unchanged != 1 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	for _, c := range diff.Changes {
+		if c.Action == ActionInsert && c.Values["active"] != "true" {
+			t.Errorf("expected insert expression to be evaluated: %+v", c.Values)
+		}
+		if c.Action == ActionUpdate && (c.Previous["size"] != "2" || c.Values["size"] != "5") {
+			t.Errorf("unexpected update: %+v", c)
+		}
+	}
+
+	// truncate first: c is no longer present so is deleted
+	dryCtx, dr = WithDryRun(ctx)
+	Exec(dryCtx, `DELETE FROM items;`, &ExecArgs{DB: dbpath, Driver: "sqlite3"})
+	Insert(dryCtx, testInsert, []*testItem{{Name: "a", Size: 1}, {Name: "b", Size: 2}}, args)
+	if diff, err = dr.Diff(ctx, &DiffArgs{DB: dbpath, Driver: "sqlite3"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	s = diff.Summary[0]
+	if s.Inserts != 0 || s.Updates != 0 || s.Deletes != 1 || s.Unchanged != 2 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+
+	// partial delete: only the rows matching the where clause are replaced, so
+	// b is re-inserted, c is deleted and a is left alone
+	dryCtx, dr = WithDryRun(ctx)
+	Exec(dryCtx, `DELETE FROM items WHERE name = ?;`, &ExecArgs{DB: dbpath, Driver: "sqlite3"}, "b")
+	Exec(dryCtx, `DELETE FROM items WHERE name = ?;`, &ExecArgs{DB: dbpath, Driver: "sqlite3"}, "c")
+	Insert(dryCtx, testInsert, []*testItem{{Name: "b", Size: 2, Active: true}}, args)
+	if diff, err = dr.Diff(ctx, &DiffArgs{DB: dbpath, Driver: "sqlite3"}); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	s = diff.Summary[0]
+	if s.Inserts != 0 || s.Updates != 1 || s.Deletes != 1 || s.Unchanged != 0 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	for _, c := range diff.Changes {
+		if c.Action == ActionDelete && c.Key["name"] != "c" {
+			t.Errorf("unexpected delete: %+v", c)
+		}
+		if c.Action == ActionUpdate && c.Values["active"] != "true" {
+			t.Errorf("expected all columns of a deleted row to be compared: %+v", c)
+		}
+	}
+
+	// nothing should have been written
+	Select(ctx, `SELECT count(*) FROM items;`, &SelectArgs{DB: dbpath, Driver: "sqlite3", ScanF: func(rows *sql.Rows) error {
+		return rows.Scan(&count)
+	}})
+	if count != 3 {
+		t.Errorf("dry run changed the database, found [%d] rows", count)
+	}
+}
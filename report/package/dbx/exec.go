@@ -19,6 +19,17 @@ func Exec(ctx context.Context, stmt string, in *ExecArgs, args ...any) (err erro
 		db  *sql.DB
 		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "dbx", "func", "Insert")
 	)
+	// dry runs only record deletes
+	if dr, ok := getDryRun(ctx); ok {
+		dr.exec(ctx, stmt, args...)
+		return
+	}
+	// one writer per file at a time, held until the connection is closed as
+	// that can also write (wal checkpoint)
+	mu := writeLock(in.DB)
+	mu.Lock()
+	defer mu.Unlock()
+
 	db, err = sql.Open(in.Driver, conn.SqlitePath(in.DB, in.Params))
 	if err != nil {
 		log.Error("error connecting to database", "err", err.Error())
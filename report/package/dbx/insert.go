@@ -18,8 +18,26 @@ type InsertArgs struct {
 func Insert[T any](ctx context.Context, stmt string, records []T, in *InsertArgs) (err error) {
 	var (
 		db  *sql.DB
+		tx  *sql.Tx
 		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "dbx", "func", "Insert")
 	)
+	// dry runs only record what would be inserted
+	if dr, ok := getDryRun(ctx); ok {
+		var rows []map[string]interface{}
+		if rows, err = asMaps(records); err != nil {
+			return
+		}
+		if err = dr.insert(ctx, stmt, rows, in); err == nil {
+			addToTally(ctx, int64(len(rows)))
+		}
+		return
+	}
+	// one writer per file at a time, held until the connection is closed as
+	// that can also write (wal checkpoint), with all rows in a single transaction
+	mu := writeLock(in.DB)
+	mu.Lock()
+	defer mu.Unlock()
+
 	db, err = sql.Open(in.Driver, conn.SqlitePath(in.DB, in.Params))
 	if err != nil {
 		log.Error("error connecting to database", "err", err.Error())
@@ -27,6 +45,13 @@ func Insert[T any](ctx context.Context, stmt string, records []T, in *InsertArgs
 	}
 	defer db.Close()
 
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("error starting transaction", "err", err.Error())
+		return
+	}
+	defer tx.Rollback()
+
 	for _, model := range records {
 		// convert to map
 		row := map[string]interface{}{}
@@ -38,12 +63,16 @@ func Insert[T any](ctx context.Context, stmt string, records []T, in *InsertArgs
 		if e != nil {
 			return e
 		}
-		_, err = db.ExecContext(ctx, bound, args...)
+		_, err = tx.ExecContext(ctx, bound, args...)
 		if err != nil {
 			return
 		}
-
 	}
+	if err = tx.Commit(); err != nil {
+		log.Error("error committing transaction", "err", err.Error())
+		return
+	}
+	addToTally(ctx, int64(len(records)))
 
 	return
 }
@@ -0,0 +1,17 @@
+package dbx
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// writers has a mutex for each database file. sqlite only allows one writer at
+// a time and importers run concurrently, so Insert & Exec hold the lock for
+// their file while writing rather than failing with "database is locked".
+var writers sync.Map
+
+// writeLock returns the mutex for the database file
+func writeLock(db string) *sync.Mutex {
+	var mu, _ = writers.LoadOrStore(filepath.Clean(db), &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
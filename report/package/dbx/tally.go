@@ -0,0 +1,45 @@
+package dbx
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+const tallyKey string = "dbx-tally"
+
+// Tally counts the number of rows written by Insert calls using a context
+// created by WithTally. Tallies can be nested, with rows being added to
+// every tally in the chain.
+type Tally struct {
+	rows   atomic.Int64
+	parent *Tally
+}
+
+// Rows returns the number of rows written so far
+func (self *Tally) Rows() int64 {
+	return self.rows.Load()
+}
+
+// add increments this and all parent tallies
+func (self *Tally) add(n int64) {
+	for t := self; t != nil; t = t.parent {
+		t.rows.Add(n)
+	}
+}
+
+// WithTally returns a new context that will count the rows written by Insert
+// along with the Tally to read the count from
+func WithTally(ctx context.Context) (context.Context, *Tally) {
+	var tally = &Tally{}
+	if parent, ok := ctx.Value(tallyKey).(*Tally); ok {
+		tally.parent = parent
+	}
+	return context.WithValue(ctx, tallyKey, tally), tally
+}
+
+// addToTally increments the tally attached to the context, if there is one
+func addToTally(ctx context.Context, n int64) {
+	if v, ok := ctx.Value(tallyKey).(*Tally); ok {
+		v.add(n)
+	}
+}
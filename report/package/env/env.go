@@ -3,6 +3,8 @@ package env
 import (
 	"opg-reports/report/package/cnv"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -20,8 +22,13 @@ func Get(key string, def string) (value string) {
 //
 // Uses the uppercase version of the key name (so `id` => `ID`) and and
 // hyphens become underscores.
+//
+// Number and bool fields are parsed from the env value and slice fields are
+// split on commas; slices are found from the struct fields rather than the
+// current value, so nil slices (which are `null` once converted) are still split.
 func OverwriteStruct[T any](data T) (err error) {
 	var json = map[string]interface{}{}
+	var slices = sliceKeys(data)
 	// convert data to a map for checking
 	err = cnv.Convert(data, &json)
 	if err != nil {
@@ -30,11 +37,64 @@ func OverwriteStruct[T any](data T) (err error) {
 	// check for each uppercase version of the key name
 	for key, _ := range json {
 		osKey := strings.ReplaceAll(strings.ToUpper(key), "-", "_")
-		if v := os.Getenv(osKey); v != "" {
-			json[key] = v
+		if v := os.Getenv(osKey); v != "" && slices[key] {
+			json[key] = split(v)
+		} else if v != "" {
+			json[key] = asType(json[key], v)
 		}
 	}
 	// convert back
 	err = cnv.Convert(json, &data)
 	return
 }
+
+// asType converts the env value to match the type of the current value
+func asType(current interface{}, v string) (value interface{}) {
+	value = v
+	switch current.(type) {
+	case float64:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			value = f
+		}
+	case bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			value = b
+		}
+	case []interface{}:
+		value = split(v)
+	}
+	return
+}
+
+// split converts the comma separated env value into a list
+func split(v string) (list []interface{}) {
+	list = []interface{}{}
+	for _, item := range strings.Split(v, ",") {
+		list = append(list, strings.TrimSpace(item))
+	}
+	return
+}
+
+// sliceKeys returns the json keys of the fields of the struct (data, or what it
+// points to) that are slices of simple values, such as []string
+func sliceKeys(data any) (keys map[string]bool) {
+	var t = reflect.TypeOf(data)
+	keys = map[string]bool{}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		var f = t.Field(i)
+		var name, _, _ = strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() <= reflect.String && f.Type.Elem().Kind() != reflect.Invalid {
+			keys[name] = true
+		}
+	}
+	return
+}
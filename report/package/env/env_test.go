@@ -37,3 +37,42 @@ func TestUtilsEnvOverwriteStruct(t *testing.T) {
 	}
 
 }
+
+type mockTyped struct {
+	Count    int      `json:"test_count"`
+	Enabled  bool     `json:"test_enabled"`
+	Services []string `json:"test_services"`
+}
+
+func TestUtilsEnvOverwriteStructTypes(t *testing.T) {
+	t.Setenv("TEST_COUNT", "20")
+	t.Setenv("TEST_ENABLED", "true")
+	t.Setenv("TEST_SERVICES", "Tax, Support")
+
+	m := &mockTyped{Count: 1, Services: []string{"a"}}
+	if err := OverwriteStruct(&m); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	if m.Count != 20 || !m.Enabled || len(m.Services) != 2 || m.Services[1] != "Support" {
+		t.Errorf("incorrect values")
+		fmt.Println(dump.Any(m))
+	}
+}
+
+type mockNilSlice struct {
+	Name    string   `json:"test_name"`
+	Sources []string `json:"test_sources"`
+}
+
+func TestUtilsEnvOverwriteStructNilSlice(t *testing.T) {
+	t.Setenv("TEST_SOURCES", "org/a, org/b")
+
+	m := &mockNilSlice{}
+	if err := OverwriteStruct(&m); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	if len(m.Sources) != 2 || m.Sources[0] != "org/a" || m.Sources[1] != "org/b" {
+		t.Errorf("expected nil slice to be split")
+		fmt.Println(dump.Any(m))
+	}
+}
@@ -0,0 +1,285 @@
+package ghclients
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/httpcache"
+	"opg-reports/report/package/retry"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofri/go-github-ratelimit/v2/github_ratelimit"
+	"github.com/google/go-github/v84/github"
+)
+
+var (
+	ErrInvalidApp           = errors.New("invalid github app configuration.")
+	ErrInvalidPrivateKey    = errors.New("invalid github app private key.")
+	ErrFailedGettingToken   = errors.New("failed to get github app installation token with error.")
+	ErrFailedFindingInstall = errors.New("failed to find github app installation with error.")
+)
+
+const (
+	// jwtLifetime is how long each app jwt is valid for; github allows up to 10 minutes
+	jwtLifetime time.Duration = 9 * time.Minute
+	// jwtClockDrift backdates the issued time to allow for clock differences with github
+	jwtClockDrift time.Duration = 60 * time.Second
+	// refreshBefore is how long before expiry an installation token is replaced
+	refreshBefore time.Duration = 5 * time.Minute
+)
+
+// App is the configuration for authenticating as a github app installation
+type App struct {
+	ID             int64  `json:"id"`              // app id (or client id as ClientID)
+	ClientID       string `json:"client_id"`       // optional; used as the jwt issuer instead of ID when set
+	InstallationID int64  `json:"installation_id"` // optional; found from Org when not set
+	Org            string `json:"org"`             // org the app is installed on, used to find the installation
+	PrivateKey     []byte `json:"-"`               // pem encoded private key of the app
+	BaseURL        string `json:"base_url"`        // optional; api url, used for testing & enterprise servers
+}
+
+// issuer returns the jwt issuer for the app
+func (self *App) issuer() string {
+	if self.ClientID != "" {
+		return self.ClientID
+	}
+	return fmt.Sprintf("%d", self.ID)
+}
+
+// Validate checks the required values are present
+func (self *App) Validate() (err error) {
+	switch {
+	case self.ID == 0 && self.ClientID == "":
+		err = fmt.Errorf("app id is required")
+	case len(self.PrivateKey) == 0:
+		err = fmt.Errorf("private key is required")
+	case self.InstallationID == 0 && self.Org == "":
+		err = fmt.Errorf("installation id or org is required")
+	}
+	if err != nil {
+		err = errors.Join(ErrInvalidApp, err)
+	}
+	return
+}
+
+// NewApp returns a client that authenticates as a github app installation.
+//
+// A short lived jwt, signed with the app private key, is exchanged for an
+// installation token; that token is replaced automatically before it expires
+// so long running imports are not interrupted. The client is the same shape
+// as New returns, so importers do not need to know how it authenticates.
+//
+// When a cache is attached to the context it is used below the token, as it
+// is for New, so the token is part of each cache key and responses are never
+// shared between credentials; entries saved with a replaced token are not reused.
+func NewApp(ctx context.Context, app *App) (client *github.Client, err error) {
+	var (
+		key     *rsa.PrivateKey
+		limited *http.Client
+		api     http.RoundTripper
+		tokens  *installationTokens
+		log     *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghclients", "func", "NewApp")
+	)
+	log.Debug("starting ...")
+	if err = app.Validate(); err != nil {
+		log.Error("error creating github app client", "err", err.Error())
+		return
+	}
+	if key, err = parsePrivateKey(app.PrivateKey); err != nil {
+		log.Error("error creating github app client", "err", err.Error())
+		return
+	}
+	limited = github_ratelimit.NewClient(nil)
+	tokens = &installationTokens{app: app, key: key, base: limited.Transport}
+	// fetch the first token now so bad credentials fail straight away
+	if _, err = tokens.Token(ctx); err != nil {
+		return
+	}
+	api = limited.Transport
+	if cache := httpcache.GetCache(ctx); cache != nil {
+		log.Debug("using http cache", "dir", cache.Dir)
+		api = cache.Transport(api)
+	}
+	limited.Transport = &tokenTransport{tokens: tokens, base: api}
+	client = github.NewClient(limited)
+	if err = setBaseURL(client, app.BaseURL); err != nil {
+		return
+	}
+	log.Debug("complete.")
+	return
+}
+
+// installationTokens creates and refreshes installation tokens for the app
+type installationTokens struct {
+	app  *App
+	key  *rsa.PrivateKey
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns the current installation token, replacing it first when it
+// is missing or close to expiring
+func (self *installationTokens) Token(ctx context.Context) (token string, err error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.token != "" && time.Until(self.expires) > refreshBefore {
+		return self.token, nil
+	}
+	if err = self.refresh(ctx); err != nil {
+		return
+	}
+	token = self.token
+	return
+}
+
+// refresh exchanges a new jwt for an installation token, finding the
+// installation from the org when there is no id
+func (self *installationTokens) refresh(ctx context.Context) (err error) {
+	var (
+		client  *github.Client
+		install *github.Installation
+		token   *github.InstallationToken
+		log     *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghclients", "func", "refresh")
+	)
+	log.Debug("getting installation token ...")
+	client = github.NewClient(&http.Client{Transport: &jwtTransport{tokens: self, base: self.base}})
+	if err = setBaseURL(client, self.app.BaseURL); err != nil {
+		return
+	}
+	if self.app.InstallationID == 0 {
+		install, err = retry.Get(ctx, func() (i *github.Installation, e error) {
+			i, _, e = client.Apps.FindOrganizationInstallation(ctx, self.app.Org)
+			return
+		})
+		if err != nil {
+			log.Error("error finding installation", "org", self.app.Org, "err", err.Error())
+			err = errors.Join(ErrFailedFindingInstall, fmt.Errorf("org [%s]", self.app.Org), err)
+			return
+		}
+		self.app.InstallationID = install.GetID()
+	}
+
+	token, err = retry.Get(ctx, func() (t *github.InstallationToken, e error) {
+		t, _, e = client.Apps.CreateInstallationToken(ctx, self.app.InstallationID, nil)
+		return
+	})
+	if err != nil {
+		log.Error("error getting installation token", "installation", self.app.InstallationID, "err", err.Error())
+		err = errors.Join(ErrFailedGettingToken, err)
+		return
+	}
+	self.token = token.GetToken()
+	self.expires = token.GetExpiresAt().Time
+	log.Info("installation token refreshed", "installation", self.app.InstallationID, "expires", self.expires.Format(time.RFC3339))
+	return
+}
+
+// jwt returns a signed RS256 jwt for the app
+func (self *installationTokens) jwt() (signed string, err error) {
+	var (
+		header, claims []byte
+		sig            []byte
+		now            time.Time = time.Now()
+		enc                      = base64.RawURLEncoding
+	)
+	header, _ = json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ = json.Marshal(map[string]interface{}{
+		"iat": now.Add(-jwtClockDrift).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": self.app.issuer(),
+	})
+	signed = enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signed))
+	if sig, err = rsa.SignPKCS1v15(rand.Reader, self.key, crypto.SHA256, hash[:]); err != nil {
+		return
+	}
+	signed += "." + enc.EncodeToString(sig)
+	return
+}
+
+// jwtTransport authenticates as the app itself; only used to get tokens
+type jwtTransport struct {
+	tokens *installationTokens
+	base   http.RoundTripper
+}
+
+func (self *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var signed, err = self.tokens.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+signed)
+	return self.base.RoundTrip(req)
+}
+
+// tokenTransport authenticates as the installation
+type tokenTransport struct {
+	tokens *installationTokens
+	base   http.RoundTripper
+}
+
+func (self *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var token, err = self.tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return self.base.RoundTrip(req)
+}
+
+// parsePrivateKey reads a pem encoded rsa key in either PKCS1 (as downloaded
+// from github) or PKCS8 format
+func parsePrivateKey(content []byte) (key *rsa.PrivateKey, err error) {
+	var (
+		ok     bool
+		parsed interface{}
+		block  *pem.Block
+	)
+	if block, _ = pem.Decode(content); block == nil {
+		err = errors.Join(ErrInvalidPrivateKey, fmt.Errorf("no pem data found"))
+		return
+	}
+	if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return
+	}
+	if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		err = errors.Join(ErrInvalidPrivateKey, err)
+		return
+	}
+	if key, ok = parsed.(*rsa.PrivateKey); !ok {
+		err = errors.Join(ErrInvalidPrivateKey, fmt.Errorf("not an rsa key"))
+	}
+	return
+}
+
+// setBaseURL changes the api url of the client when one is set
+func setBaseURL(client *github.Client, base string) (err error) {
+	if base == "" {
+		return
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	client.BaseURL, err = url.Parse(base)
+	return
+}
@@ -0,0 +1,183 @@
+package ghclients
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/httpcache"
+	"opg-reports/report/package/logger"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockAppServer acts as the github api for app authentication; tokens are
+// numbered and expire after the lifetime given. Other responses have an etag of
+// the token used and conditional counts the requests that sent one back.
+func mockAppServer(t *testing.T, key *rsa.PrivateKey, lifetime time.Duration) (server *httptest.Server, issued *atomic.Int32, conditional *atomic.Int32) {
+	issued = &atomic.Int32{}
+	conditional = &atomic.Int32{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var auth = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch {
+		case r.URL.Path == "/orgs/mock-org/installation":
+			if err := verifyJWT(&key.PublicKey, auth); err != nil {
+				t.Errorf("invalid jwt: [%s]", err.Error())
+			}
+			fmt.Fprint(w, `{"id": 10}`)
+		case r.URL.Path == "/app/installations/10/access_tokens" && r.Method == http.MethodPost:
+			if err := verifyJWT(&key.PublicKey, auth); err != nil {
+				t.Errorf("invalid jwt: [%s]", err.Error())
+			}
+			n := issued.Add(1)
+			json.NewEncoder(w).Encode(map[string]string{
+				"token":      fmt.Sprintf("token-%d", n),
+				"expires_at": time.Now().Add(lifetime).UTC().Format(time.RFC3339),
+			})
+		default:
+			if !strings.HasPrefix(auth, "token-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var etag = `"` + auth + `"`
+			if match := r.Header.Get("If-None-Match"); match != "" {
+				conditional.Add(1)
+				if match == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+			w.Header().Set("ETag", etag)
+			fmt.Fprintf(w, `{"login": "%s"}`, auth)
+		}
+	}))
+	return
+}
+
+// verifyJWT checks the signature and claims of the jwt
+func verifyJWT(pub *rsa.PublicKey, token string) (err error) {
+	var parts = strings.Split(token, ".")
+	var claims = map[string]interface{}{}
+	if len(parts) != 3 {
+		return errors.New("expected 3 parts")
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+		return
+	}
+	body, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(body, &claims)
+	if claims["iss"] != "123" {
+		err = fmt.Errorf("unexpected issuer [%v]", claims["iss"])
+	}
+	return
+}
+
+func TestGHClientsNewApp(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	// long lived token, reused for each call
+	server, issued, _ := mockAppServer(t, key, time.Hour)
+	defer server.Close()
+
+	client, err := NewApp(ctx, &App{ID: 123, Org: "mock-org", PrivateKey: pemKey, BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	for range 3 {
+		user, _, err := client.Users.Get(ctx, "")
+		if err != nil || user.GetLogin() != "token-1" {
+			t.Errorf("expected first token to be used, got [%s] [%v]", user.GetLogin(), err)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Errorf("expected a single token, found [%d]", issued.Load())
+	}
+
+	// token that is close to expiring is replaced before use
+	server, issued, _ = mockAppServer(t, key, time.Minute)
+	defer server.Close()
+
+	client, _ = NewApp(ctx, &App{ID: 123, InstallationID: 10, PrivateKey: pemKey, BaseURL: server.URL})
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil || user.GetLogin() != "token-2" {
+		t.Errorf("expected refreshed token to be used, got [%s] [%v]", user.GetLogin(), err)
+	}
+}
+
+func TestGHClientsNewAppInvalid(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+
+	if _, err := NewApp(ctx, &App{ID: 123, Org: "mock-org"}); !errors.Is(err, ErrInvalidApp) {
+		t.Errorf("expected invalid app error, got [%v]", err)
+	}
+	if _, err := NewApp(ctx, &App{ID: 123, Org: "mock-org", PrivateKey: []byte("not a key")}); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Errorf("expected invalid key error, got [%v]", err)
+	}
+}
+
+// TestGHClientsCacheKey checks the http cache is below the token for both
+// client types, so cached responses are never shared between credentials
+func TestGHClientsCacheKey(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+
+	cache, err := httpcache.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	ctx = httpcache.WithCache(ctx, cache)
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	server, _, conditional := mockAppServer(t, key, time.Hour)
+	defer server.Close()
+
+	var get = func(client *github.Client) {
+		if _, _, err := client.Users.Get(ctx, ""); err != nil {
+			t.Errorf("unexpected error: [%s]", err.Error())
+		}
+	}
+	// a personal access token reuses its own entry but not that of another token
+	tokenA, _ := New(ctx, "token-a")
+	tokenB, _ := New(ctx, "token-b")
+	setBaseURL(tokenA, server.URL)
+	setBaseURL(tokenB, server.URL)
+	get(tokenA)
+	get(tokenA)
+	get(tokenB)
+	if conditional.Load() != 1 {
+		t.Errorf("expected only the repeated token request to be conditional, found [%d]", conditional.Load())
+	}
+	// the app token is included in the key in the same way; each app client is
+	// given its own token
+	first, err := NewApp(ctx, &App{ID: 123, Org: "mock-org", PrivateKey: pemKey, BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	second, _ := NewApp(ctx, &App{ID: 123, Org: "mock-org", PrivateKey: pemKey, BaseURL: server.URL})
+	get(first)
+	get(first)
+	get(second)
+	if conditional.Load() != 2 {
+		t.Errorf("expected only the repeated app request to be conditional, found [%d]", conditional.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 2 {
+		t.Errorf("expected 2 cache hits, found %+v", stats)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/httpcache"
 
 	"github.com/gofri/go-github-ratelimit/v2/github_ratelimit"
 	"github.com/google/go-github/v84/github"
@@ -13,7 +14,11 @@ import (
 
 var ErrNoToken = errors.New("missing required token.")
 
-// New returns a token based client for github usage
+// New returns a token based client for github usage.
+//
+// When a cache is attached to the context (httpcache.WithCache) GET requests
+// are sent as conditional requests and unchanged responses are served from it.
+// The cache sits below the token so the token is part of each cache key.
 func New(ctx context.Context, token string) (client *github.Client, err error) {
 	var limited *http.Client
 	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghclients", "func", "New")
@@ -26,6 +31,10 @@ func New(ctx context.Context, token string) (client *github.Client, err error) {
 	}
 
 	limited = github_ratelimit.NewClient(nil)
+	if cache := httpcache.GetCache(ctx); cache != nil {
+		log.Debug("using http cache", "dir", cache.Dir)
+		limited.Transport = cache.Transport(limited.Transport)
+	}
 	client = github.NewClient(limited).WithAuthToken(token)
 	log.Debug("complete.")
 	return
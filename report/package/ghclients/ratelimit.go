@@ -0,0 +1,77 @@
+package ghclients
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+var ErrFailedGettingRateLimit = errors.New("failed to get github rate limit with error.")
+
+// RateLimitClient is a proxy for *github.RateLimitService (client.RateLimit)
+type RateLimitClient interface {
+	Get(ctx context.Context) (*github.RateLimits, *github.Response, error)
+}
+
+// WaitForRateLimit checks the core rate limit and, when fewer than minRemaining
+// requests are left, blocks until the limit resets (or the context is cancelled).
+//
+// The secondary rate limits are handled by the transport in New; this is for
+// long running processes (like backfills) that would otherwise exhaust the
+// hourly limit part way through.
+func WaitForRateLimit[T RateLimitClient](ctx context.Context, client T, minRemaining int) (err error) {
+	var (
+		limits *github.RateLimits
+		wait   time.Duration
+		log    *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghclients", "func", "WaitForRateLimit")
+	)
+	limits, _, err = client.Get(ctx)
+	if err != nil {
+		log.Error("error getting rate limit", "err", err.Error())
+		err = errors.Join(ErrFailedGettingRateLimit, err)
+		return
+	}
+	if limits.Core == nil || limits.Core.Remaining >= minRemaining {
+		return
+	}
+	wait = time.Until(limits.Core.Reset.Time)
+	if wait <= 0 {
+		return
+	}
+	log.Warn("github rate limit low, waiting for reset ...", "remaining", limits.Core.Remaining, "wait", wait.String())
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+	}
+	return
+}
+
+// RateLimitWaiter returns a function that calls WaitForRateLimit at most once
+// every interval, returning straight away in between. It is safe to use from
+// multiple goroutines; while the limit is low every caller waits for the reset.
+func RateLimitWaiter[T RateLimitClient](client T, minRemaining int, interval time.Duration) func(ctx context.Context) error {
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+	return func(ctx context.Context) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(last) < interval {
+			return
+		}
+		if err = WaitForRateLimit(ctx, client, minRemaining); err == nil {
+			last = time.Now()
+		}
+		return
+	}
+}
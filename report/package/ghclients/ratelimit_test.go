@@ -0,0 +1,64 @@
+package ghclients
+
+import (
+	"context"
+	"errors"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+type mockRateLimit struct {
+	remaining int
+	reset     time.Time
+	calls     int
+}
+
+func (self *mockRateLimit) Get(ctx context.Context) (*github.RateLimits, *github.Response, error) {
+	self.calls++
+	return &github.RateLimits{
+		Core: &github.Rate{Remaining: self.remaining, Reset: github.Timestamp{Time: self.reset}},
+	}, nil, nil
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+
+	// plenty remaining, returns straight away
+	if err := WaitForRateLimit(ctx, &mockRateLimit{remaining: 4000, reset: time.Now().Add(time.Hour)}, 100); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	// low remaining, waits until reset
+	start := time.Now()
+	if err := WaitForRateLimit(ctx, &mockRateLimit{remaining: 1, reset: time.Now().Add(50 * time.Millisecond)}, 100); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Errorf("expected to wait for the reset")
+	}
+	// cancelled context stops the wait
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := WaitForRateLimit(cctx, &mockRateLimit{remaining: 1, reset: time.Now().Add(time.Hour)}, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context error, got [%v]", err)
+	}
+}
+
+func TestRateLimitWaiter(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		client = &mockRateLimit{remaining: 4000, reset: time.Now().Add(time.Hour)}
+		wait   = RateLimitWaiter(client, 100, time.Hour)
+	)
+	for range 5 {
+		if err := wait(ctx); err != nil {
+			t.Errorf("unexpected error: [%s]", err.Error())
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("expected rate limit to be checked once within the interval, found [%d]", client.calls)
+	}
+}
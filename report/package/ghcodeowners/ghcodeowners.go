@@ -0,0 +1,238 @@
+// Package ghcodeowners parses github CODEOWNERS files into their ordered rules,
+// each with the pattern, the owners and the line it was declared on.
+//
+// Patterns follow the gitignore style github uses: a pattern without a `/` matches
+// at any depth, a leading or middle `/` anchors it to the root of the repository, a
+// trailing `/` matches everything within the directory, `*` and `?` stay within a
+// directory and `**` matches across them. As with github, the last rule that matches
+// a path decides its owners and a rule without owners leaves the path unowned.
+//
+// Syntax github rejects (negation, character ranges, malformed owners) is returned
+// as Errors on the File with the line number, rather than failing the whole parse,
+// so the rest of the file can still be used and reported on.
+//
+// Usage:
+//
+//	file = ghcodeowners.Parse(".github/CODEOWNERS", content)
+//	if rule := file.Match("src/main.go"); rule != nil { ... }
+package ghcodeowners
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Locations are the paths github reads a CODEOWNERS file from, in the order it
+// looks for them; only the first one found is used
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// kinds of owner
+const (
+	KindUser  string = "user"  // @username
+	KindTeam  string = "team"  // @org/team-slug
+	KindEmail string = "email" // user@example.com
+)
+
+// catchAll are the patterns that match every path in the repository
+var catchAll = []string{"*", "**", "/**"}
+
+var (
+	userRe  = regexp.MustCompile(`^@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+	teamRe  = regexp.MustCompile(`^@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?/[a-zA-Z0-9_.-]+$`)
+	emailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// File is a parsed CODEOWNERS file
+type File struct {
+	Path   string   `json:"path"`   // location of the file within the repository
+	Rules  []*Rule  `json:"rules"`  // rules in file order; later rules take precedence
+	Errors []*Error `json:"errors"` // problems found in the file, by line
+}
+
+// Rule is a single pattern and its owners
+type Rule struct {
+	Line    int      `json:"line"`    // line number within the file, starting at 1
+	Pattern string   `json:"pattern"` // path pattern as written
+	Owners  []string `json:"owners"`  // owners as written, including the leading @
+
+	re *regexp.Regexp // compiled pattern, see NewRule
+}
+
+// NewRule returns a rule with its pattern compiled once, so matching many paths
+// does not recompile it
+func NewRule(line int, pattern string, owners []string) *Rule {
+	return &Rule{Line: line, Pattern: pattern, Owners: owners, re: toRegexp(pattern)}
+}
+
+// Error is a problem with a line of the file
+type Error struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Error formats the problem with its line number
+func (self *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", self.Line, self.Message)
+}
+
+// OwnerKind returns the kind of the owner (see KindUser) or an empty string when
+// it is not a valid owner
+func OwnerKind(owner string) string {
+	switch {
+	case teamRe.MatchString(owner):
+		return KindTeam
+	case userRe.MatchString(owner):
+		return KindUser
+	case emailRe.MatchString(owner):
+		return KindEmail
+	}
+	return ""
+}
+
+// Parse converts the content of the file into its rules; comments and blank lines
+// are skipped and lines with invalid syntax are tracked in Errors. As with github,
+// a line with an invalid owner is skipped entirely rather than used without it.
+func Parse(path string, content []byte) (file *File) {
+	file = &File{Path: path, Rules: []*Rule{}, Errors: []*Error{}}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		var rule *Rule
+		var fields []string
+		var invalid bool
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields = strings.Fields(line)
+		// an escaped # is part of the pattern rather than a comment
+		rule = &Rule{Line: i + 1, Pattern: strings.ReplaceAll(fields[0], `\#`, "#"), Owners: []string{}}
+
+		for _, owner := range fields[1:] {
+			// the rest of the line is a comment
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			if OwnerKind(owner) == "" {
+				file.Errors = append(file.Errors, &Error{Line: rule.Line, Message: fmt.Sprintf("invalid owner [%s]", owner)})
+				invalid = true
+				continue
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+
+		switch {
+		case invalid:
+			// already tracked in the errors
+		case strings.HasPrefix(rule.Pattern, "!"):
+			file.Errors = append(file.Errors, &Error{Line: rule.Line, Message: fmt.Sprintf("negation is not supported [%s]", rule.Pattern)})
+		case strings.ContainsAny(rule.Pattern, "[]"):
+			file.Errors = append(file.Errors, &Error{Line: rule.Line, Message: fmt.Sprintf("character ranges are not supported [%s]", rule.Pattern)})
+		default:
+			file.Rules = append(file.Rules, NewRule(rule.Line, rule.Pattern, rule.Owners))
+		}
+	}
+	return
+}
+
+// Match returns the rule that decides the owners of the path; the last rule that
+// matches, or nil when none do
+func (self *File) Match(path string) (rule *Rule) {
+	for _, r := range self.Rules {
+		if r.Matches(path) {
+			rule = r
+		}
+	}
+	return
+}
+
+// CatchAll returns the last rule that matches every path and has owners, or nil
+// when there is not one
+func (self *File) CatchAll() (rule *Rule) {
+	for _, r := range self.Rules {
+		if slices.Contains(catchAll, r.Pattern) && len(r.Owners) > 0 {
+			rule = r
+		}
+	}
+	return
+}
+
+// Owners returns every owner used within the file without the leading @, sorted
+// and without duplicates
+func (self *File) Owners() (owners []string) {
+	owners = []string{}
+	for _, r := range self.Rules {
+		for _, o := range r.Owners {
+			owners = append(owners, strings.TrimPrefix(o, "@"))
+		}
+	}
+	slices.Sort(owners)
+	owners = slices.Compact(owners)
+	return
+}
+
+// Matches returns true when the rule pattern matches the path; rules not made
+// via NewRule (or Parse) compile their pattern on each call
+func (self *Rule) Matches(path string) bool {
+	var re = self.re
+	if re == nil {
+		re = toRegexp(self.Pattern)
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "./"), "/")
+	return re.MatchString(path)
+}
+
+// toRegexp converts the gitignore style pattern into a regular expression
+// matching file paths relative to the root of the repository; the pattern is
+// read a rune at a time so multi-byte characters are kept whole
+func toRegexp(pattern string) *regexp.Regexp {
+	var (
+		sb       strings.Builder
+		dirOnly  = strings.HasSuffix(pattern, "/")
+		anchored = strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+		p        = strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+	)
+	if anchored {
+		sb.WriteString(`^`)
+	} else {
+		sb.WriteString(`^(?:.*/)?`)
+	}
+	for i := 0; i < len(p); {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString(`(?:.*/)?`)
+			i += 3
+		case p[i:] == "/**":
+			sb.WriteString(`/.*`)
+			i += 3
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(`.*`)
+			i += 2
+		case p[i] == '*':
+			sb.WriteString(`[^/]*`)
+			i++
+		case p[i] == '?':
+			sb.WriteString(`[^/]`)
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(p[i:])
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			i += size
+		}
+	}
+	switch {
+	case dirOnly:
+		// everything within the directory
+		sb.WriteString(`/.*$`)
+	case strings.HasSuffix(p, "/*") && !strings.HasSuffix(p, "/**"):
+		// direct children only
+		sb.WriteString(`$`)
+	default:
+		// the file, or everything within it when it is a directory
+		sb.WriteString(`(?:/.*)?$`)
+	}
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,120 @@
+package ghcodeowners
+
+import (
+	"slices"
+	"testing"
+)
+
+const testCodeowners string = `
+# default owners
+*       @org/platform
+
+*.js    @org/frontend   # inline comment
+/build/logs/ @octocat
+docs/*  docs@example.com
+apps/   @org/apps @octocat
+**/terraform @org/webops
+/scripts/ bad-owner @org/webops
+!/secret @org/platform
+/vendor/[ab]/ @org/platform
+\#notes\#2 @octocat
+/generated/
+`
+
+func TestGHCodeownersParse(t *testing.T) {
+	file := Parse(".github/CODEOWNERS", []byte(testCodeowners))
+
+	// the line with an invalid owner is skipped, as github does
+	if file.Path != ".github/CODEOWNERS" || len(file.Rules) != 8 {
+		t.Fatalf("unexpected file: %+v", file)
+	}
+	if r := file.Rules[1]; r.Line != 5 || r.Pattern != "*.js" || !slices.Equal(r.Owners, []string{"@org/frontend"}) {
+		t.Errorf("expected inline comment to be removed: %+v", r)
+	}
+	if r := file.Rules[6]; r.Pattern != "#notes#2" || r.re == nil {
+		t.Errorf("expected every escaped # and a compiled pattern: %+v", r)
+	}
+	// invalid owner, negation and character range
+	if len(file.Errors) != 3 || file.Errors[0].Line != 10 || file.Errors[1].Line != 11 || file.Errors[2].Line != 12 {
+		t.Errorf("unexpected errors: %v", file.Errors)
+	}
+	if file.Errors[0].Error() != "line 10: invalid owner [bad-owner]" {
+		t.Errorf("unexpected error message: %s", file.Errors[0].Error())
+	}
+	if r := file.CatchAll(); r == nil || r.Line != 3 {
+		t.Errorf("expected catch all rule: %+v", r)
+	}
+	if o := file.Owners(); !slices.Equal(o, []string{"docs@example.com", "octocat", "org/apps", "org/frontend", "org/platform", "org/webops"}) {
+		t.Errorf("unexpected owners: %v", o)
+	}
+	if f := Parse("CODEOWNERS", []byte("/src/ @org/team\n")); f.CatchAll() != nil {
+		t.Errorf("expected no catch all rule")
+	}
+}
+
+func TestGHCodeownersMatch(t *testing.T) {
+	var file = Parse("CODEOWNERS", []byte(testCodeowners))
+	var tests = map[string]string{
+		"README.md":                    "*",
+		"src/app.js":                   "*.js",
+		"build/logs/today/out.log":     "/build/logs/",
+		"src/build/logs/out.log":       "*",
+		"docs/index.md":                "docs/*",
+		"docs/guides/index.md":         "*",
+		"apps/api/main.go":             "apps/",
+		"service/apps/main.go":         "apps/",
+		"terraform/main.tf":            "**/terraform",
+		"env/prod/terraform/main.tf":   "**/terraform",
+		"scripts/run.sh":               "*",
+		"./scripts/run.sh":             "*",
+		"generated/client/client.go":   "/generated/",
+		"src/generated/client/main.go": "*",
+	}
+	for path, expected := range tests {
+		rule := file.Match(path)
+		if rule == nil || rule.Pattern != expected {
+			t.Errorf("[%s] expected pattern [%s], found %+v", path, expected, rule)
+		}
+	}
+	// rule without owners leaves the path unowned
+	if rule := file.Match("generated/x.go"); len(rule.Owners) != 0 {
+		t.Errorf("expected no owners: %+v", rule)
+	}
+	if rule := Parse("CODEOWNERS", []byte("/src/ @org/team\n")).Match("README.md"); rule != nil {
+		t.Errorf("expected no match: %+v", rule)
+	}
+}
+
+func TestGHCodeownersMatchMultiByte(t *testing.T) {
+	var file = Parse("CODEOWNERS", []byte("docs/café.md @org/docs\n/données/ @org/data\n*.ü? @octocat\n"))
+	var tests = map[string]string{
+		"docs/café.md":        "docs/café.md",
+		"docs/cafe.md":        "",
+		"données/export.csv":  "/données/",
+		"src/file.üb":         "*.ü?",
+		"src/file.üéb":        "",
+		"src/données/main.go": "",
+	}
+	for path, expected := range tests {
+		rule := file.Match(path)
+		if (expected == "" && rule != nil) || (expected != "" && (rule == nil || rule.Pattern != expected)) {
+			t.Errorf("[%s] expected pattern [%s], found %+v", path, expected, rule)
+		}
+	}
+}
+
+func TestGHCodeownersOwnerKind(t *testing.T) {
+	var tests = map[string]string{
+		"@octocat":         KindUser,
+		"@org/team-a":      KindTeam,
+		"user@example.com": KindEmail,
+		"octocat":          "",
+		"@org/":            "",
+		"@-bad":            "",
+	}
+	for owner, expected := range tests {
+		if OwnerKind(owner) != expected {
+			t.Errorf("[%s] expected [%s], found [%s]", owner, expected, OwnerKind(owner))
+		}
+	}
+}
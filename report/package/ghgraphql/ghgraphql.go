@@ -0,0 +1,164 @@
+// Package ghgraphql fetches repository metadata from the github graphql api.
+//
+// It provides graphql versions of the team and repository services used by the
+// importers (see Teams and Repositories) so they can be swapped for the rest
+// ones without changing the importers.
+//
+// The rest api needs a call for each team list, directory and file of every
+// repository. Here, each page of team repositories also fetches the
+// CODEOWNERS files and the `.github` tree (with file content) of those
+// repositories in the same query, and team access is loaded for a whole org
+// at once. Later calls for that data are served from memory, so an import
+// makes a handful of queries rather than several calls per repository.
+//
+// Usage:
+//
+//	gql = ghgraphql.New(client.Client(), "")
+//	teams, repositories = gql.Teams(), gql.Repositories()
+package ghgraphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"strings"
+)
+
+// DefaultURL is the github graphql endpoint
+const DefaultURL string = "https://api.github.com/graphql"
+
+var (
+	ErrFailedQuery = errors.New("graphql query failed with error.")
+	ErrNotFound    = errors.New("not found.")
+)
+
+// StatusError is returned for non-200 responses; the status code is used by
+// retry.Classify to decide if the query should be retried
+type StatusError struct {
+	StatusCode int
+}
+
+func (self *StatusError) Error() string {
+	return fmt.Sprintf("graphql request failed with status [%d]", self.StatusCode)
+}
+
+func (self *StatusError) HTTPStatusCode() int {
+	return self.StatusCode
+}
+
+// NotFoundError is returned when a file or directory does not exist; the
+// message matches the rest client so importers that look for `404 Not Found`
+// treat it the same way
+type NotFoundError struct {
+	Repository string
+	Path       string
+}
+
+func (self *NotFoundError) Error() string {
+	return fmt.Sprintf("%s/%s: 404 Not Found", self.Repository, self.Path)
+}
+
+func (self *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// Client sends queries to the graphql api and holds the data fetched so far
+type Client struct {
+	HTTP *http.Client // authenticated client, such as the one from a *github.Client
+	URL  string       // graphql endpoint
+
+	store *store
+}
+
+// New returns a client using the http client (which handles authentication)
+// and endpoint; DefaultURL is used when url is empty
+func New(client *http.Client, url string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{HTTP: client, URL: url, store: newStore()}
+}
+
+// Teams returns the graphql version of the team service
+func (self *Client) Teams() *Teams {
+	return &Teams{client: self, cursors: map[string]string{}}
+}
+
+// Repositories returns the graphql version of the repository service
+func (self *Client) Repositories() *Repositories {
+	return &Repositories{client: self, loaded: map[string]bool{}}
+}
+
+// request is the body of a graphql call
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// response is the body of a graphql reply
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Query runs the query and decodes the data into out.
+//
+// Errors of type NOT_FOUND are ignored as the data for them is null, which is
+// how missing files and directories are represented; all others fail.
+func (self *Client) Query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) (err error) {
+	var (
+		body []byte
+		req  *http.Request
+		resp *http.Response
+		res  *response    = &response{}
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghgraphql", "func", "Query")
+	)
+	if body, err = json.Marshal(&request{Query: query, Variables: variables}); err != nil {
+		return
+	}
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, self.URL, bytes.NewReader(body)); err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Debug("sending query ...", "variables", variables)
+	if resp, err = self.HTTP.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		err = errors.Join(ErrFailedQuery, &StatusError{StatusCode: resp.StatusCode})
+		return
+	}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		err = errors.Join(ErrFailedQuery, err)
+		return
+	}
+	for _, e := range res.Errors {
+		if e.Type != "NOT_FOUND" {
+			err = errors.Join(err, errors.New(e.Message))
+		}
+	}
+	if err != nil {
+		log.Error("query returned errors", "err", err.Error())
+		err = errors.Join(ErrFailedQuery, err)
+		return
+	}
+	err = json.Unmarshal(res.Data, out)
+	return
+}
+
+// fullName returns the owner/name key used for repositories
+func fullName(owner, repo string) string {
+	return strings.ToLower(owner + "/" + repo)
+}
@@ -0,0 +1,136 @@
+package ghgraphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// repoJSON returns a repository node with a CODEOWNERS file in .github and a
+// single workflow
+func repoJSON(name string) string {
+	return fmt.Sprintf(`{
+		"databaseId": 1, "name": "%[1]s", "nameWithOwner": "mock-org/%[1]s", "url": "https://github.com/mock-org/%[1]s",
+		"isArchived": false, "isPrivate": false, "visibility": "PUBLIC",
+		"owner": {"login": "mock-org"}, "defaultBranchRef": {"name": "main"},
+		"codeowners": null,
+		"githubCodeowners": {"__typename": "Blob", "text": "* @mock-org/team-a", "isBinary": false},
+		"docsCodeowners": null,
+		"githubDir": {"__typename": "Tree", "entries": [
+			{"name": "CODEOWNERS", "path": ".github/CODEOWNERS", "type": "blob", "object": {"__typename": "Blob", "text": "* @mock-org/team-a", "isBinary": false}},
+			{"name": "workflows", "path": ".github/workflows", "type": "tree", "object": {"__typename": "Tree", "entries": [
+				{"name": "ci.yml", "path": ".github/workflows/ci.yml", "type": "blob", "object": {"__typename": "Blob", "text": "uses: aquasecurity/trivy-action", "isBinary": false}}
+			]}}
+		]}
+	}`, name)
+}
+
+// mockServer answers each query type with canned data, counting the queries
+func mockServer(t *testing.T) (server *httptest.Server, queries *atomic.Int32) {
+	queries = &atomic.Int32{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req = &request{}
+		json.NewDecoder(r.Body).Decode(req)
+		queries.Add(1)
+		switch {
+		case strings.Contains(req.Query, "childTeams"):
+			fmt.Fprint(w, `{"data": {"organization": {"team": {"childTeams": {"pageInfo": {"hasNextPage": false}, "nodes": [{"slug": "child", "name": "Child", "url": "https://github.com/orgs/mock-org/teams/child", "parentTeam": {"slug": "parent"}}]}}}}}`)
+		case strings.Contains(req.Query, "team(slug: $slug)") && strings.Contains(req.Query, "fragment repo"):
+			// two pages of one repository each
+			if req.Variables["after"] == nil {
+				fmt.Fprintf(w, `{"data": {"organization": {"team": {"repositories": {"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [%s]}}}}}`, repoJSON("repo-a"))
+			} else {
+				fmt.Fprintf(w, `{"data": {"organization": {"team": {"repositories": {"pageInfo": {"hasNextPage": false}, "nodes": [%s]}}}}}`, repoJSON("repo-b"))
+			}
+		case strings.Contains(req.Query, "teams(first"):
+			fmt.Fprint(w, `{"data": {"organization": {"teams": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"slug": "team-a", "name": "Team A", "url": "https://github.com/orgs/mock-org/teams/team-a", "parentTeam": {"slug": "parent"},
+				 "repositories": {"pageInfo": {"hasNextPage": false}, "edges": [{"permission": "WRITE", "node": {"nameWithOwner": "mock-org/repo-a"}}]}}
+			]}}}}`)
+		case strings.Contains(req.Query, "$expr"):
+			fmt.Fprint(w, `{"data": {"repository": {"object": null}}, "errors": [{"type": "NOT_FOUND", "message": "missing"}]}`)
+		default:
+			t.Errorf("unexpected query: %s", req.Query)
+		}
+	}))
+	return
+}
+
+func TestGHGraphQLServices(t *testing.T) {
+	var (
+		ctx             = cntxt.AddLogger(t.Context(), logger.New("error"))
+		server, queries = mockServer(t)
+		client          = New(server.Client(), server.URL)
+		teams, repos    = client.Teams(), client.Repositories()
+		list            []*github.Repository
+		resp            *github.Response
+		err             error
+		opts            = &github.ListOptions{Page: 1}
+	)
+	defer server.Close()
+
+	// paged list of repositories
+	for page := 1; page > 0; page = resp.NextPage {
+		var found []*github.Repository
+		opts.Page = page
+		if found, resp, err = teams.ListTeamReposBySlug(ctx, "mock-org", "parent", opts); err != nil {
+			t.Fatalf("unexpected error: [%s]", err.Error())
+		}
+		list = append(list, found...)
+	}
+	if len(list) != 2 || list[1].GetFullName() != "mock-org/repo-b" || list[0].GetVisibility() != "public" {
+		t.Errorf("unexpected repositories: %v", list)
+	}
+	children, _, err := teams.ListChildTeamsByParentSlug(ctx, "mock-org", "parent", nil)
+	if err != nil || len(children) != 1 || children[0].GetSlug() != "child" {
+		t.Errorf("unexpected child teams: [%v] [%v]", children, err)
+	}
+	before := queries.Load()
+
+	// files & directories come from the repository queries
+	rc, _, err := repos.DownloadContents(ctx, "mock-org", "repo-a", "./.github/CODEOWNERS", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if content, _ := io.ReadAll(rc); string(content) != "* @mock-org/team-a" {
+		t.Errorf("unexpected content: [%s]", string(content))
+	}
+	_, dir, _, err := repos.GetContents(ctx, "mock-org", "repo-a", "./.github/workflows/", nil)
+	if err != nil || len(dir) != 1 || dir[0].GetPath() != ".github/workflows/ci.yml" || dir[0].GetType() != "file" {
+		t.Errorf("unexpected directory: [%v] [%v]", dir, err)
+	}
+	// known to be missing
+	if _, _, err = repos.DownloadContents(ctx, "mock-org", "repo-a", "./CODEOWNERS", nil); err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected not found error, got [%v]", err)
+	}
+	if queries.Load() != before {
+		t.Errorf("expected stored data to be used, made [%d] queries", queries.Load()-before)
+	}
+
+	// not fetched with the repository, so queried
+	if _, _, _, err = repos.GetContents(ctx, "mock-org", "repo-a", "./docs/", nil); err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected not found error, got [%v]", err)
+	}
+	// team access is loaded once for the org
+	for _, name := range []string{"repo-a", "repo-b", "repo-a"} {
+		found, _, err := repos.ListTeams(ctx, "mock-org", name, nil)
+		if err != nil {
+			t.Errorf("unexpected error: [%s]", err.Error())
+		}
+		if name == "repo-a" && (len(found) != 1 || found[0].GetParent().GetSlug() != "parent" || found[0].GetPermission() != "write") {
+			t.Errorf("unexpected teams: %v", found)
+		}
+	}
+	if queries.Load() != before+2 {
+		t.Errorf("expected 2 more queries, made [%d]", queries.Load()-before)
+	}
+}
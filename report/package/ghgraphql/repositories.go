@@ -0,0 +1,283 @@
+package ghgraphql
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// orgTeamsQuery lists a page of teams in the org along with the first page
+// of repositories each team has access to
+const orgTeamsQuery string = `
+query($org: String!, $first: Int!, $after: String) {
+	organization(login: $org) {
+		teams(first: $first, after: $after) {
+			pageInfo { hasNextPage endCursor }
+			nodes {
+				slug name url parentTeam { slug }
+				repositories(first: 100) {
+					pageInfo { hasNextPage endCursor }
+					edges { permission node { nameWithOwner } }
+				}
+			}
+		}
+	}
+}
+`
+
+// teamAccessQuery lists further pages of repositories a team has access to
+const teamAccessQuery string = `
+query($org: String!, $slug: String!, $after: String) {
+	organization(login: $org) {
+		team(slug: $slug) {
+			repositories(first: 100, after: $after) {
+				pageInfo { hasNextPage endCursor }
+				edges { permission node { nameWithOwner } }
+			}
+		}
+	}
+}
+`
+
+// objectQuery fetches a single file, or a directory with its file content
+const objectQuery string = `
+query($owner: String!, $name: String!, $expr: String!) {
+	repository(owner: $owner, name: $name) {
+		object(expression: $expr) {
+			__typename ...blob
+			... on Tree { entries { name path type object { __typename ...blob } } }
+		}
+	}
+}
+fragment blob on Blob { text isBinary }
+`
+
+// teamOrgPageSize is the number of teams per org query
+const teamOrgPageSize int = 50
+
+// access is a page of repositories a team can access
+type access struct {
+	PageInfo pageInfo `json:"pageInfo"`
+	Edges    []struct {
+		Permission string `json:"permission"`
+		Node       struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"node"`
+	} `json:"edges"`
+}
+
+// Repositories is the graphql version of *github.RepositoriesService.
+//
+// Content is always read from the default branch.
+type Repositories struct {
+	client *Client
+
+	mu     sync.Mutex
+	loaded map[string]bool // orgs whose team access has been loaded
+}
+
+// ListTeams returns the teams with access to the repository. Team access for
+// the whole org is loaded by the first call, so all results are in one page.
+func (self *Repositories) ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) (teams []*github.Team, resp *github.Response, err error) {
+	if err = self.loadTeams(ctx, owner); err != nil {
+		return
+	}
+	teams = self.client.store.repoTeams(fullName(owner, repo))
+	resp = ok()
+	return
+}
+
+// DownloadContents returns the content of the file, using the data fetched
+// with the repository when there is some
+func (self *Repositories) DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (rc io.ReadCloser, resp *github.Response, err error) {
+	var (
+		content *string
+		known   bool
+		name    string = fullName(owner, repo)
+		p       string = clean(filepath)
+	)
+	if _, known = self.client.store.file(name, p); !known {
+		if err = self.fetch(ctx, owner, repo, p); err != nil {
+			return
+		}
+	}
+	if content, _ = self.client.store.file(name, p); content == nil {
+		err = &NotFoundError{Repository: name, Path: p}
+		return
+	}
+	rc = io.NopCloser(strings.NewReader(*content))
+	resp = ok()
+	return
+}
+
+// GetContents returns the entries of a directory, or the file, at the path;
+// using the data fetched with the repository when there is some
+func (self *Repositories) GetContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (file *github.RepositoryContent, dir []*github.RepositoryContent, resp *github.Response, err error) {
+	var (
+		name string = fullName(owner, repo)
+		p    string = clean(filepath)
+	)
+	if file, dir = self.lookup(name, p); file == nil && dir == nil && !self.known(name, p) {
+		if err = self.fetch(ctx, owner, repo, p); err != nil {
+			return
+		}
+		file, dir = self.lookup(name, p)
+	}
+	if file == nil && dir == nil {
+		err = &NotFoundError{Repository: name, Path: p}
+		return
+	}
+	resp = ok()
+	return
+}
+
+// lookup returns the stored directory entries or file at the path
+func (self *Repositories) lookup(name string, p string) (file *github.RepositoryContent, dir []*github.RepositoryContent) {
+	if dir, _ = self.client.store.dir(name, p); dir != nil {
+		return
+	}
+	if content, _ := self.client.store.file(name, p); content != nil {
+		file = &github.RepositoryContent{
+			Type:    github.Ptr("file"),
+			Name:    github.Ptr(path.Base(p)),
+			Path:    github.Ptr(p),
+			Content: content,
+		}
+	}
+	return
+}
+
+// known returns true when the path has been fetched, even if it does not exist
+func (self *Repositories) known(name string, p string) bool {
+	var _, file = self.client.store.file(name, p)
+	var _, dir = self.client.store.dir(name, p)
+	return file || dir
+}
+
+// fetch gets the object at the path and stores it
+func (self *Repositories) fetch(ctx context.Context, owner, repo, p string) (err error) {
+	var (
+		log  *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghgraphql", "func", "fetch", "repo", repo, "path", p)
+		expr string       = "HEAD:" + p
+		res  struct {
+			Repository *struct {
+				Object *object `json:"object"`
+			} `json:"repository"`
+		}
+	)
+	if p == "." {
+		expr = "HEAD:"
+	}
+	log.Debug("fetching content ...")
+	err = self.client.Query(ctx, objectQuery, map[string]interface{}{"owner": owner, "name": repo, "expr": expr}, &res)
+	if err != nil {
+		return
+	}
+	if res.Repository == nil {
+		err = &NotFoundError{Repository: fullName(owner, repo), Path: p}
+		return
+	}
+	self.client.store.addObject(fullName(owner, repo), p, res.Repository.Object)
+	return
+}
+
+// loadTeams fetches the repository access of every team in the org, once
+func (self *Repositories) loadTeams(ctx context.Context, org string) (err error) {
+	var (
+		found  = map[string][]*github.Team{}
+		after  interface{}
+		log    *slog.Logger = cntxt.GetLogger(ctx).With("package", "ghgraphql", "func", "loadTeams", "org", org)
+		follow              = map[*teamNode]pageInfo{}
+	)
+	// other calls wait for the first to finish loading
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.loaded[org] {
+		return
+	}
+	log.Debug("loading team access ...")
+
+	for {
+		var res struct {
+			Organization *struct {
+				Teams struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []*struct {
+						teamNode
+						Repositories access `json:"repositories"`
+					} `json:"nodes"`
+				} `json:"teams"`
+			} `json:"organization"`
+		}
+		err = self.client.Query(ctx, orgTeamsQuery, map[string]interface{}{"org": org, "first": teamOrgPageSize, "after": after}, &res)
+		if err != nil {
+			return
+		}
+		if res.Organization == nil {
+			err = &NotFoundError{Repository: org, Path: "teams"}
+			return
+		}
+		for _, node := range res.Organization.Teams.Nodes {
+			addAccess(found, org, &node.teamNode, &node.Repositories)
+			if node.Repositories.PageInfo.HasNextPage {
+				follow[&node.teamNode] = node.Repositories.PageInfo
+			}
+		}
+		if !res.Organization.Teams.PageInfo.HasNextPage {
+			break
+		}
+		after = res.Organization.Teams.PageInfo.EndCursor
+	}
+	// teams with access to more repositories than fit in the first page
+	for team, info := range follow {
+		for info.HasNextPage {
+			var res struct {
+				Organization *struct {
+					Team *struct {
+						Repositories access `json:"repositories"`
+					} `json:"team"`
+				} `json:"organization"`
+			}
+			err = self.client.Query(ctx, teamAccessQuery, map[string]interface{}{"org": org, "slug": team.Slug, "after": info.EndCursor}, &res)
+			if err != nil {
+				return
+			}
+			if res.Organization == nil || res.Organization.Team == nil {
+				break
+			}
+			addAccess(found, org, team, &res.Organization.Team.Repositories)
+			info = res.Organization.Team.Repositories.PageInfo
+		}
+	}
+
+	self.client.store.setTeams(found)
+	self.loaded[org] = true
+	log.Debug("loaded team access.", "repositories", len(found))
+	return
+}
+
+// addAccess adds the team to each repository in the page
+func addAccess(found map[string][]*github.Team, org string, team *teamNode, page *access) {
+	for _, edge := range page.Edges {
+		var name = strings.ToLower(edge.Node.NameWithOwner)
+		found[name] = append(found[name], team.team(org, edge.Permission))
+	}
+}
+
+// clean converts the paths used with the rest api (`./.github/`) into the
+// form used in git expressions (`.github`)
+func clean(p string) string {
+	return path.Clean(strings.TrimPrefix(p, "/"))
+}
+
+// ok returns a successful response with no further pages
+func ok() *github.Response {
+	return &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+}
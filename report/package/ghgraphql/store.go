@@ -0,0 +1,141 @@
+package ghgraphql
+
+import (
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// object is a git object (blob or tree) returned by `object(expression: ...)`;
+// trees beyond the depth of the query only have a Typename
+type object struct {
+	Typename string       `json:"__typename"`
+	Text     *string      `json:"text"`
+	IsBinary bool         `json:"isBinary"`
+	Entries  []*treeEntry `json:"entries"`
+}
+
+// treeEntry is an item within a tree
+type treeEntry struct {
+	Name   string  `json:"name"`
+	Path   string  `json:"path"`
+	Type   string  `json:"type"` // blob, tree or commit (submodule)
+	Object *object `json:"object"`
+}
+
+// contentType converts the git object type into the rest content type
+func (self *treeEntry) contentType() string {
+	switch self.Type {
+	case "blob":
+		return "file"
+	case "tree":
+		return "dir"
+	}
+	return "submodule"
+}
+
+// repoData is everything known about a repository; a path that is present
+// with a nil value is known not to exist
+type repoData struct {
+	files map[string]*string
+	dirs  map[string][]*github.RepositoryContent
+}
+
+// store holds the data fetched so far, shared by the services of a Client
+type store struct {
+	mu    sync.RWMutex
+	repos map[string]*repoData
+	teams map[string][]*github.Team // repository full name to the teams with access
+}
+
+func newStore() *store {
+	return &store{repos: map[string]*repoData{}, teams: map[string][]*github.Team{}}
+}
+
+// repo returns the data for the repository, creating it when needed; must
+// be called with the lock held
+func (self *store) repo(name string) (data *repoData) {
+	var ok bool
+	if data, ok = self.repos[name]; !ok {
+		data = &repoData{files: map[string]*string{}, dirs: map[string][]*github.RepositoryContent{}}
+		self.repos[name] = data
+	}
+	return
+}
+
+// addObject records the object found at the path, including any children
+// fetched with it; a nil object means nothing exists at the path
+func (self *store) addObject(name string, path string, obj *object) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.addObjectLocked(self.repo(name), path, obj)
+}
+
+func (self *store) addObjectLocked(data *repoData, path string, obj *object) {
+	switch {
+	case obj == nil:
+		data.files[path] = nil
+		data.dirs[path] = nil
+	case obj.Typename == "Blob":
+		var text string
+		if obj.Text != nil && !obj.IsBinary {
+			text = *obj.Text
+		}
+		data.files[path] = &text
+	case obj.Typename == "Tree" && obj.Entries != nil:
+		var list = []*github.RepositoryContent{}
+		for _, entry := range obj.Entries {
+			list = append(list, &github.RepositoryContent{
+				Type: github.Ptr(entry.contentType()),
+				Name: github.Ptr(entry.Name),
+				Path: github.Ptr(entry.Path),
+			})
+			if entry.Object != nil {
+				self.addObjectLocked(data, entry.Path, entry.Object)
+			}
+		}
+		data.dirs[path] = list
+	}
+}
+
+// file returns the content of the file; known is false when the file has not
+// been fetched
+func (self *store) file(name string, path string) (content *string, known bool) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	if data, ok := self.repos[name]; ok {
+		content, known = data.files[path]
+	}
+	return
+}
+
+// dir returns the entries of the directory; known is false when the directory
+// has not been fetched
+func (self *store) dir(name string, path string) (entries []*github.RepositoryContent, known bool) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	if data, ok := self.repos[name]; ok {
+		entries, known = data.dirs[path]
+	}
+	return
+}
+
+// setTeams records the teams with access to each repository
+func (self *store) setTeams(access map[string][]*github.Team) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for name, teams := range access {
+		self.teams[name] = teams
+	}
+}
+
+// repoTeams returns the teams with access to the repository
+func (self *store) repoTeams(name string) (teams []*github.Team) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	teams = self.teams[name]
+	if teams == nil {
+		teams = []*github.Team{}
+	}
+	return
+}
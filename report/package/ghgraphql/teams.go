@@ -0,0 +1,249 @@
+package ghgraphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// repoPageSize is the number of repositories per query; kept low as each
+// repository includes its `.github` tree
+const repoPageSize int = 25
+
+// teamPageSize is the number of teams per query
+const teamPageSize int = 100
+
+// repoFields is the data fetched for each repository: the details used by the
+// importers, the CODEOWNERS files and the `.github` tree (three levels deep,
+// which covers workflows and composite actions) with file content
+const repoFields string = `
+fragment blob on Blob { text isBinary }
+fragment repo on Repository {
+	databaseId name nameWithOwner url isArchived isPrivate visibility
+	owner { login }
+	defaultBranchRef { name }
+	codeowners: object(expression: "HEAD:CODEOWNERS") { __typename ...blob }
+	githubCodeowners: object(expression: "HEAD:.github/CODEOWNERS") { __typename ...blob }
+	docsCodeowners: object(expression: "HEAD:docs/CODEOWNERS") { __typename ...blob }
+	githubDir: object(expression: "HEAD:.github") {
+		__typename
+		... on Tree { entries { name path type object {
+			__typename ...blob
+			... on Tree { entries { name path type object {
+				__typename ...blob
+				... on Tree { entries { name path type object { __typename ...blob } } }
+			} } }
+		} } }
+	}
+}
+`
+
+// teamReposQuery lists a page of repositories of a team
+const teamReposQuery string = `
+query($org: String!, $slug: String!, $first: Int!, $after: String) {
+	organization(login: $org) {
+		team(slug: $slug) {
+			repositories(first: $first, after: $after) {
+				pageInfo { hasNextPage endCursor }
+				nodes { ...repo }
+			}
+		}
+	}
+}
+` + repoFields
+
+// childTeamsQuery lists a page of the direct child teams of a team
+const childTeamsQuery string = `
+query($org: String!, $slug: String!, $first: Int!, $after: String) {
+	organization(login: $org) {
+		team(slug: $slug) {
+			childTeams(first: $first, after: $after, immediateOnly: true) {
+				pageInfo { hasNextPage endCursor }
+				nodes { slug name url parentTeam { slug } }
+			}
+		}
+	}
+}
+`
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// repoNode is a repository as returned by the repoFields fragment
+type repoNode struct {
+	DatabaseID       int64   `json:"databaseId"`
+	Name             string  `json:"name"`
+	NameWithOwner    string  `json:"nameWithOwner"`
+	URL              string  `json:"url"`
+	IsArchived       bool    `json:"isArchived"`
+	IsPrivate        bool    `json:"isPrivate"`
+	Visibility       string  `json:"visibility"`
+	Owner            owner   `json:"owner"`
+	DefaultBranchRef *ref    `json:"defaultBranchRef"`
+	Codeowners       *object `json:"codeowners"`
+	GithubCodeowners *object `json:"githubCodeowners"`
+	DocsCodeowners   *object `json:"docsCodeowners"`
+	GithubDir        *object `json:"githubDir"`
+}
+
+type owner struct {
+	Login string `json:"login"`
+}
+
+type ref struct {
+	Name string `json:"name"`
+}
+
+// repository converts the node into the rest version used by the importers
+func (self *repoNode) repository() (repo *github.Repository) {
+	repo = &github.Repository{
+		ID:         github.Ptr(self.DatabaseID),
+		Name:       github.Ptr(self.Name),
+		FullName:   github.Ptr(self.NameWithOwner),
+		HTMLURL:    github.Ptr(self.URL),
+		Archived:   github.Ptr(self.IsArchived),
+		Private:    github.Ptr(self.IsPrivate),
+		Visibility: github.Ptr(strings.ToLower(self.Visibility)),
+		Owner:      &github.User{Login: github.Ptr(self.Owner.Login)},
+	}
+	if self.DefaultBranchRef != nil {
+		repo.DefaultBranch = github.Ptr(self.DefaultBranchRef.Name)
+	}
+	return
+}
+
+// teamNode is a team as returned by the queries
+type teamNode struct {
+	Slug       string `json:"slug"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	ParentTeam *struct {
+		Slug string `json:"slug"`
+	} `json:"parentTeam"`
+}
+
+// team converts the node into the rest version used by the importers
+func (self *teamNode) team(org string, permission string) (team *github.Team) {
+	team = &github.Team{
+		Slug:         github.Ptr(self.Slug),
+		Name:         github.Ptr(self.Name),
+		HTMLURL:      github.Ptr(self.URL),
+		Organization: &github.Organization{Login: github.Ptr(org)},
+	}
+	if permission != "" {
+		team.Permission = github.Ptr(strings.ToLower(permission))
+	}
+	if self.ParentTeam != nil {
+		team.Parent = &github.Team{Slug: github.Ptr(self.ParentTeam.Slug)}
+	}
+	return
+}
+
+// Teams is the graphql version of *github.TeamsService
+type Teams struct {
+	client *Client
+
+	mu      sync.Mutex
+	cursors map[string]string // query, org, team & page number to the cursor for that page
+}
+
+// ListTeamReposBySlug returns a page of repositories for the team; their
+// CODEOWNERS files and `.github` trees are stored for the Repositories service
+func (self *Teams) ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) (list []*github.Repository, resp *github.Response, err error) {
+	var res struct {
+		Organization *struct {
+			Team *struct {
+				Repositories struct {
+					PageInfo pageInfo    `json:"pageInfo"`
+					Nodes    []*repoNode `json:"nodes"`
+				} `json:"repositories"`
+			} `json:"team"`
+		} `json:"organization"`
+	}
+	var page, vars = self.page("repos", org, slug, opts, repoPageSize)
+
+	list = []*github.Repository{}
+	if err = self.client.Query(ctx, teamReposQuery, vars, &res); err != nil {
+		return
+	}
+	if res.Organization == nil || res.Organization.Team == nil {
+		err = &NotFoundError{Repository: org, Path: "teams/" + slug}
+		return
+	}
+	for _, node := range res.Organization.Team.Repositories.Nodes {
+		var name = fullName(node.Owner.Login, node.Name)
+		list = append(list, node.repository())
+		self.client.store.addObject(name, "CODEOWNERS", node.Codeowners)
+		self.client.store.addObject(name, ".github/CODEOWNERS", node.GithubCodeowners)
+		self.client.store.addObject(name, "docs/CODEOWNERS", node.DocsCodeowners)
+		self.client.store.addObject(name, ".github", node.GithubDir)
+	}
+	resp = self.next("repos", org, slug, page, res.Organization.Team.Repositories.PageInfo)
+	return
+}
+
+// ListChildTeamsByParentSlug returns a page of the direct child teams of the team
+func (self *Teams) ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) (list []*github.Team, resp *github.Response, err error) {
+	var res struct {
+		Organization *struct {
+			Team *struct {
+				ChildTeams struct {
+					PageInfo pageInfo    `json:"pageInfo"`
+					Nodes    []*teamNode `json:"nodes"`
+				} `json:"childTeams"`
+			} `json:"team"`
+		} `json:"organization"`
+	}
+	var page, vars = self.page("children", org, slug, opts, teamPageSize)
+
+	list = []*github.Team{}
+	if err = self.client.Query(ctx, childTeamsQuery, vars, &res); err != nil {
+		return
+	}
+	if res.Organization == nil || res.Organization.Team == nil {
+		err = &NotFoundError{Repository: org, Path: "teams/" + slug}
+		return
+	}
+	for _, node := range res.Organization.Team.ChildTeams.Nodes {
+		list = append(list, node.team(org, ""))
+	}
+	resp = self.next("children", org, slug, page, res.Organization.Team.ChildTeams.PageInfo)
+	return
+}
+
+// page converts the page number of the options into the cursor recorded by
+// the call for the page before, returning the query variables
+func (self *Teams) page(query, org, slug string, opts *github.ListOptions, size int) (page int, vars map[string]interface{}) {
+	page = 1
+	if opts != nil && opts.Page > 1 {
+		page = opts.Page
+	}
+	vars = map[string]interface{}{"org": org, "slug": slug, "first": size}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if cursor, ok := self.cursors[fmt.Sprintf("%s/%s/%s/%d", query, org, slug, page)]; ok {
+		vars["after"] = cursor
+	}
+	return
+}
+
+// next records the cursor for the following page and returns a response with
+// NextPage set, as the rest api would
+func (self *Teams) next(query, org, slug string, page int, info pageInfo) (resp *github.Response) {
+	resp = &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	if !info.HasNextPage {
+		return
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.cursors[fmt.Sprintf("%s/%s/%s/%d", query, org, slug, page+1)] = info.EndCursor
+	resp.NextPage = page + 1
+	return
+}
@@ -0,0 +1,310 @@
+// Package ghworkflows parses github workflow and composite action files into a
+// typed model of triggers, jobs, steps, `uses`, `with` and environments.
+//
+// Workflows (`.github/workflows/*.yml`) have jobs, each with steps or a reusable
+// workflow in `uses`; actions (`action.yml`) have their steps under `runs`. Both
+// are returned as a File, so callers can use Steps to look at every step no matter
+// where it came from.
+//
+// Values that github allows in several shapes (`on` as a string, list or map,
+// `runs-on` as a string or list, `environment` as a string or map) are normalised,
+// yaml anchors, aliases and merge keys are resolved, and every `with` / `env` value
+// is kept as a string. Files that are not valid yaml, or that have neither jobs nor
+// runs, return an error rather than an empty model.
+//
+// Usage:
+//
+//	file, err = ghworkflows.Parse(".github/workflows/ci.yml", content)
+//	for _, step := range file.Uses("aquasecurity/trivy-action") { ... }
+package ghworkflows
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// kinds of file
+const (
+	KindWorkflow string = "workflow" // file within .github/workflows
+	KindAction   string = "action"   // composite (or other) action definition
+)
+
+var (
+	ErrInvalidYAML = errors.New("workflow file is not valid yaml.")
+	ErrNoJobs      = errors.New("workflow file has no jobs.")
+	ErrNoRuns      = errors.New("action file has no runs.")
+)
+
+// File is a parsed workflow or action file
+type File struct {
+	Path        string   `yaml:"-"`           // path of the file within the repository
+	Kind        string   `yaml:"-"`           // workflow or action, see KindWorkflow
+	Name        string   `yaml:"name"`        // name of the workflow or action
+	Description string   `yaml:"description"` // actions only
+	On          Triggers `yaml:"on"`          // events that trigger the workflow
+	Env         Values   `yaml:"env"`         // workflow level environment variables
+	Jobs        Jobs     `yaml:"jobs"`        // workflow jobs, in file order
+	Runs        *Runs    `yaml:"runs"`        // actions only; how the action runs
+}
+
+// Job is a single job of a workflow
+type Job struct {
+	ID          string      `yaml:"-"`           // key of the job within the workflow
+	Name        string      `yaml:"name"`        // display name
+	RunsOn      StringList  `yaml:"runs-on"`     // runner labels
+	Needs       StringList  `yaml:"needs"`       // jobs that have to finish first
+	If          string      `yaml:"if"`          // condition to run the job
+	Environment Environment `yaml:"environment"` // deployment environment
+	Env         Values      `yaml:"env"`         // job level environment variables
+	Uses        string      `yaml:"uses"`        // reusable workflow called by the job
+	With        Values      `yaml:"with"`        // inputs of the reusable workflow
+	Steps       []*Step     `yaml:"steps"`       // steps, in order
+}
+
+// Step is a single step of a job or composite action
+type Step struct {
+	ID    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	If    string `yaml:"if"`    // condition to run the step
+	Uses  string `yaml:"uses"`  // action used, including the version (owner/name@v1)
+	Run   string `yaml:"run"`   // shell script run by the step
+	Shell string `yaml:"shell"` // shell used for run
+	With  Values `yaml:"with"`  // inputs of the action
+	Env   Values `yaml:"env"`   // step level environment variables
+}
+
+// Runs is how an action is run; only composite actions have steps
+type Runs struct {
+	Using string  `yaml:"using"` // composite, node20, docker etc
+	Image string  `yaml:"image"` // docker actions only
+	Main  string  `yaml:"main"`  // javascript actions only
+	Steps []*Step `yaml:"steps"` // composite actions only
+}
+
+// Environment is the deployment environment of a job; either just a name or a
+// name and url
+type Environment struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// Triggers are the event names that run a workflow, in file order
+type Triggers []string
+
+// StringList is a value that can be written as a single string or a list
+type StringList []string
+
+// Values are string keys and values, used for `with` and `env`
+type Values map[string]string
+
+// Jobs are the jobs of a workflow, in file order
+type Jobs []*Job
+
+// IsWorkflowFile returns true for yaml files github reads as workflows or actions;
+// those within `.github/workflows/` or named `action.yml` / `action.yaml`
+func IsWorkflowFile(file string) bool {
+	var ext = path.Ext(file)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+	return strings.HasPrefix(file, ".github/workflows/") || strings.TrimSuffix(path.Base(file), ext) == "action"
+}
+
+// Parse converts the content of the file into a File; the path decides if it
+// is treated as a workflow or an action
+func Parse(file string, content []byte) (parsed *File, err error) {
+	var kind = KindAction
+	if strings.HasPrefix(file, ".github/workflows/") {
+		kind = KindWorkflow
+	}
+	parsed = &File{}
+	if err = yaml.Unmarshal(content, parsed); err != nil {
+		err = errors.Join(ErrInvalidYAML, fmt.Errorf("file [%s]", file), err)
+		return nil, err
+	}
+	parsed.Path = file
+	parsed.Kind = kind
+
+	if kind == KindWorkflow && len(parsed.Jobs) == 0 {
+		return nil, errors.Join(ErrNoJobs, fmt.Errorf("file [%s]", file))
+	}
+	if kind == KindAction && parsed.Runs == nil {
+		return nil, errors.Join(ErrNoRuns, fmt.Errorf("file [%s]", file))
+	}
+	return
+}
+
+// Steps returns every step in the file; those of each job in order, or those of
+// a composite action
+func (self *File) Steps() (steps []*Step) {
+	steps = []*Step{}
+	for _, job := range self.Jobs {
+		steps = append(steps, job.Steps...)
+	}
+	if self.Runs != nil {
+		steps = append(steps, self.Runs.Steps...)
+	}
+	return
+}
+
+// Uses returns the steps that use the action, at any version; sub path actions
+// (github/codeql-action/init) match their repository (github/codeql-action)
+func (self *File) Uses(action string) (steps []*Step) {
+	steps = []*Step{}
+	for _, step := range self.Steps() {
+		if step.UsesAction(action) {
+			steps = append(steps, step)
+		}
+	}
+	return
+}
+
+// Action returns the action used by the step without the version
+func (self *Step) Action() (action string) {
+	action, _, _ = strings.Cut(self.Uses, "@")
+	return strings.ToLower(strings.TrimSpace(action))
+}
+
+// UsesAction returns true when the step uses the action, at any version
+func (self *Step) UsesAction(action string) bool {
+	var used = self.Action()
+	action = strings.ToLower(action)
+	return used != "" && (used == action || strings.HasPrefix(used, action+"/"))
+}
+
+// Commands returns the lines of the run script without blank lines or comments
+func (self *Step) Commands() (lines []string) {
+	lines = []string{}
+	for _, line := range strings.Split(self.Run, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
+
+// UnmarshalYAML accepts a single event, a list of events or a map of events
+// with their settings
+func (self *Triggers) UnmarshalYAML(node *yaml.Node) (err error) {
+	node = resolve(node)
+	*self = Triggers{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*self = append(*self, node.Value)
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			*self = append(*self, resolve(n).Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			*self = append(*self, node.Content[i].Value)
+		}
+	}
+	return
+}
+
+// UnmarshalYAML accepts a string or a list of strings
+func (self *StringList) UnmarshalYAML(node *yaml.Node) (err error) {
+	node = resolve(node)
+	*self = StringList{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*self = append(*self, node.Value)
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			*self = append(*self, resolve(n).Value)
+		}
+	case yaml.MappingNode:
+		// runs-on with a runner group and labels
+		var group struct {
+			Group  string     `yaml:"group"`
+			Labels StringList `yaml:"labels"`
+		}
+		if err = node.Decode(&group); err == nil {
+			*self = append(StringList{group.Group}, group.Labels...)
+		}
+	}
+	return
+}
+
+// UnmarshalYAML accepts just the environment name or a map with the name and url
+func (self *Environment) UnmarshalYAML(node *yaml.Node) (err error) {
+	type plain Environment
+	node = resolve(node)
+	if node.Kind == yaml.ScalarNode {
+		self.Name = node.Value
+		return
+	}
+	return node.Decode((*plain)(self))
+}
+
+// UnmarshalYAML converts every value into a string; scalars keep their written
+// value and anything else is kept as yaml. Merge keys (`<<: *anchor`) are applied
+// before the other keys so they can be overwritten.
+func (self *Values) UnmarshalYAML(node *yaml.Node) (err error) {
+	node = resolve(node)
+	*self = Values{}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a map of values", node.Line)
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		var key, value = node.Content[i], resolve(node.Content[i+1])
+		if key.Value != "<<" || key.Tag != "!!merge" {
+			continue
+		}
+		var merged = Values{}
+		if err = merged.UnmarshalYAML(value); err != nil {
+			return
+		}
+		for k, v := range merged {
+			(*self)[k] = v
+		}
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		var key, value = node.Content[i], resolve(node.Content[i+1])
+		if key.Value == "<<" && key.Tag == "!!merge" {
+			continue
+		}
+		if value.Kind == yaml.ScalarNode {
+			(*self)[key.Value] = value.Value
+			continue
+		}
+		var out []byte
+		if out, err = yaml.Marshal(value); err != nil {
+			return
+		}
+		(*self)[key.Value] = strings.TrimSpace(string(out))
+	}
+	return
+}
+
+// UnmarshalYAML keeps the jobs in file order with their id set
+func (self *Jobs) UnmarshalYAML(node *yaml.Node) (err error) {
+	node = resolve(node)
+	*self = Jobs{}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a map of jobs", node.Line)
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		var job = &Job{}
+		if err = resolve(node.Content[i+1]).Decode(job); err != nil {
+			return
+		}
+		job.ID = node.Content[i].Value
+		*self = append(*self, job)
+	}
+	return
+}
+
+// resolve follows aliases back to the anchored node
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
@@ -0,0 +1,153 @@
+package ghworkflows
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+const testWorkflow string = `
+name: path to live
+on:
+  push:
+    branches: [main]
+  workflow_dispatch:
+env:
+  REGION: eu-west-1
+x-scan: &scan
+  severity: HIGH,CRITICAL
+  format: sarif
+jobs:
+  scan:
+    runs-on: [self-hosted, linux]
+    steps:
+      - uses: actions/checkout@v4
+      # - uses: snyk/actions/node@master
+      - name: trivy
+        uses: aquasecurity/trivy-action@0.28.0
+        with:
+          <<: *scan
+          format: cyclonedx
+          output: "app.sbom.json"
+          skip-dirs: |
+            ./node_modules
+            ./vendor
+      - uses: github/codeql-action/init@v3
+  deploy:
+    needs: scan
+    runs-on: ubuntu-latest
+    environment:
+      name: production
+      url: https://example.com
+    steps:
+      - run: |
+          # trivy fs .
+          make deploy
+  shared:
+    uses: org/repo/.github/workflows/shared.yml@main
+    with:
+      retries: 3
+`
+
+const testAction string = `
+name: scan
+description: scan the image
+runs:
+  using: composite
+  steps:
+    - shell: bash
+      run: trivy image --format spdx-json app
+`
+
+func TestGHWorkflowsParseWorkflow(t *testing.T) {
+	file, err := Parse(".github/workflows/path-to-live.yml", []byte(testWorkflow))
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if file.Kind != KindWorkflow || file.Name != "path to live" || file.Env["REGION"] != "eu-west-1" {
+		t.Errorf("unexpected file: %+v", file)
+	}
+	if !slices.Equal(file.On, []string{"push", "workflow_dispatch"}) {
+		t.Errorf("unexpected triggers: %v", file.On)
+	}
+	if len(file.Jobs) != 3 || file.Jobs[0].ID != "scan" || file.Jobs[1].ID != "deploy" || file.Jobs[2].ID != "shared" {
+		t.Fatalf("expected jobs in file order: %+v", file.Jobs)
+	}
+	scan, deploy, shared := file.Jobs[0], file.Jobs[1], file.Jobs[2]
+	if !slices.Equal(scan.RunsOn, []string{"self-hosted", "linux"}) || len(scan.Steps) != 3 {
+		t.Errorf("unexpected scan job: %+v", scan)
+	}
+	if deploy.Environment.Name != "production" || !slices.Equal(deploy.Needs, []string{"scan"}) {
+		t.Errorf("unexpected deploy job: %+v", deploy)
+	}
+	if shared.Uses == "" || shared.With["retries"] != "3" {
+		t.Errorf("unexpected reusable workflow job: %+v", shared)
+	}
+
+	trivy := file.Uses("aquasecurity/trivy-action")
+	if len(trivy) != 1 {
+		t.Fatalf("expected one trivy step, found [%d]", len(trivy))
+	}
+	// merge key applied and then overwritten
+	with := trivy[0].With
+	if with["severity"] != "HIGH,CRITICAL" || with["format"] != "cyclonedx" || with["output"] != "app.sbom.json" {
+		t.Errorf("unexpected with values: %v", with)
+	}
+	if with["skip-dirs"] != "./node_modules\n./vendor\n" {
+		t.Errorf("expected multi line value to be kept: %q", with["skip-dirs"])
+	}
+	// commented out steps are not steps, sub path actions match
+	if len(file.Uses("snyk/actions")) != 0 || len(file.Uses("github/codeql-action")) != 1 {
+		t.Errorf("unexpected uses matches")
+	}
+	if cmds := deploy.Steps[0].Commands(); !slices.Equal(cmds, []string{"make deploy"}) {
+		t.Errorf("expected comments to be removed from commands: %v", cmds)
+	}
+}
+
+func TestGHWorkflowsParseAction(t *testing.T) {
+	file, err := Parse(".github/actions/scan/action.yml", []byte(testAction))
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if file.Kind != KindAction || file.Runs.Using != "composite" || len(file.Steps()) != 1 {
+		t.Errorf("unexpected action: %+v", file)
+	}
+}
+
+func TestGHWorkflowsParseErrors(t *testing.T) {
+	var tests = []struct {
+		file     string
+		content  string
+		expected error
+	}{
+		{".github/workflows/bad.yml", "jobs:\n  build:\n    steps:\n  - bad: [", ErrInvalidYAML},
+		{".github/workflows/empty.yml", "name: nothing\non: push\n", ErrNoJobs},
+		{".github/actions/x/action.yml", "name: nothing\n", ErrNoRuns},
+		{".github/workflows/jobs.yml", "jobs: [a, b]\n", ErrInvalidYAML},
+	}
+	for _, test := range tests {
+		file, err := Parse(test.file, []byte(test.content))
+		if file != nil || !errors.Is(err, test.expected) {
+			t.Errorf("[%s] expected [%v], found [%v]", test.file, test.expected, err)
+		}
+	}
+}
+
+func TestGHWorkflowsIsWorkflowFile(t *testing.T) {
+	var tests = map[string]bool{
+		".github/workflows/ci.yml":         true,
+		".github/workflows/ci.yaml":        true,
+		".github/actions/scan/action.yml":  true,
+		"action.yaml":                      true,
+		".github/dependabot.yml":           false,
+		".github/ISSUE_TEMPLATE/bug.yml":   false,
+		".github/workflows/README.md":      false,
+		".github/actions/scan/scripts.yml": false,
+	}
+	for file, expected := range tests {
+		if IsWorkflowFile(file) != expected {
+			t.Errorf("[%s] expected [%v]", file, expected)
+		}
+	}
+}
@@ -14,16 +14,35 @@ type Args struct {
 }
 
 type HTMLPage struct {
-	Title        string   // page title (<title>)
-	Name         string   // Name is used for page title
-	GovUKVersion string   // GovUKVersion is the version number (minus v) that we're using in this front end
-	SemVer       string   // sematic version
-	Teams        []string // Teams are used for the page navigation
+	Title        string       // page title (<title>)
+	Name         string       // Name is used for page title
+	GovUKVersion string       // GovUKVersion is the version number (minus v) that we're using in this front end
+	SemVer       string       // sematic version
+	Teams        []string     // Teams are used for the page navigation
+	Freshness    []*Freshness // Freshness is when each dataset was last imported, used in the page banner
 
 	request *http.Request
 	paths   []string
 }
 
+// Freshness is when a dataset was last successfully imported
+type Freshness struct {
+	Dataset     string `json:"dataset"`
+	LastSuccess string `json:"last_success"`
+	Stale       bool   `json:"stale"`
+}
+
+// StaleData returns only the datasets that are out of date
+func (self *HTMLPage) StaleData() (stale []*Freshness) {
+	stale = []*Freshness{}
+	for _, f := range self.Freshness {
+		if f.Stale {
+			stale = append(stale, f)
+		}
+	}
+	return
+}
+
 // RequestPath returns the segment of the current request
 func (self *HTMLPage) RequestPath(i int) (v string) {
 	v = ""
@@ -0,0 +1,323 @@
+// Package httpcache is an on-disk http cache that uses conditional requests.
+//
+// Successful GET responses with an ETag or Last-Modified header are saved to
+// a directory. The next request for the same url sends If-None-Match /
+// If-Modified-Since and, when the api replies with 304 Not Modified, the saved
+// response is returned instead. GitHub does not count 304s against the rate
+// limit, so unchanged files & directory listings are free to fetch again.
+//
+// The cache is attached to the context with WithCache so ghclients.New can add
+// it to the transport chain.
+//
+// Usage:
+//
+//	cache, err = httpcache.New("./database/http-cache")
+//	client = &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const contextKey string = "http-cache"
+
+// ext is the file extension of every cache entry, used so Clear only removes
+// cache files
+const ext string = ".cache.json"
+
+var (
+	ErrNoDirectory    = errors.New("http cache directory is required.")
+	ErrFailedClearing = errors.New("failed to clear http cache with error.")
+)
+
+// skipPaths are never cached as they must always be current
+var skipPaths = []string{
+	"/rate_limit",
+}
+
+// Stats counts how requests were handled by the cache
+type Stats struct {
+	Requests int64 `json:"requests"` // GET requests seen
+	Hits     int64 `json:"hits"`     // 304 responses served from the cache
+	Misses   int64 `json:"misses"`   // full responses fetched from the api
+	Stored   int64 `json:"stored"`   // responses written to the cache
+	Errors   int64 `json:"errors"`   // failures reading or writing cache files; the request still goes ahead
+}
+
+// Sub returns the difference between two sets of stats; used to report on a
+// single import when the cache is shared between several
+func (self Stats) Sub(other Stats) Stats {
+	return Stats{
+		Requests: self.Requests - other.Requests,
+		Hits:     self.Hits - other.Hits,
+		Misses:   self.Misses - other.Misses,
+		Stored:   self.Stored - other.Stored,
+		Errors:   self.Errors - other.Errors,
+	}
+}
+
+// entry is a saved response
+type entry struct {
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt string      `json:"stored_at"`
+}
+
+// Cache stores responses as json files within Dir
+type Cache struct {
+	Dir string
+
+	requests, hits, misses, stored, errors atomic.Int64
+}
+
+// New returns a cache using dir, creating it if needed
+func New(dir string) (cache *Cache, err error) {
+	if dir == "" {
+		err = ErrNoDirectory
+		return
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	cache = &Cache{Dir: dir}
+	return
+}
+
+// Stats returns the counts since the cache was created
+func (self *Cache) Stats() Stats {
+	return Stats{
+		Requests: self.requests.Load(),
+		Hits:     self.hits.Load(),
+		Misses:   self.misses.Load(),
+		Stored:   self.stored.Load(),
+		Errors:   self.errors.Load(),
+	}
+}
+
+// Clear removes every entry from the cache directory, returning how many
+// were removed
+func (self *Cache) Clear() (removed int, err error) {
+	var files []string
+	if files, err = filepath.Glob(filepath.Join(self.Dir, "*"+ext)); err != nil {
+		err = errors.Join(ErrFailedClearing, err)
+		return
+	}
+	for _, file := range files {
+		if e := os.Remove(file); e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+		removed++
+	}
+	if err != nil {
+		err = errors.Join(ErrFailedClearing, err)
+	}
+	return
+}
+
+// Transport returns a round tripper that uses the cache for GET requests and
+// base (http.DefaultTransport when nil) for everything else
+func (self *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{cache: self, base: base}
+}
+
+type transport struct {
+	cache *Cache
+	base  http.RoundTripper
+}
+
+// RoundTrip sends a conditional request when there is a saved response for
+// the url and returns the saved response when the api replies 304
+func (self *transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	var (
+		key    string
+		cached *entry
+		cache  *Cache       = self.cache
+		log    *slog.Logger = cntxt.GetLogger(req.Context()).With("package", "httpcache", "func", "RoundTrip")
+	)
+	if req.Method != http.MethodGet || slices.Contains(skipPaths, req.URL.Path) {
+		return self.base.RoundTrip(req)
+	}
+	cache.requests.Add(1)
+	key = requestKey(req)
+	if cached, err = cache.load(key); err != nil {
+		log.Warn("error reading cache entry", "url", req.URL.String(), "err", err.Error())
+		cache.errors.Add(1)
+		cached, err = nil, nil
+	}
+	// round trippers must not change the original request
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	if resp, err = self.base.RoundTrip(req); err != nil {
+		return
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		cache.hits.Add(1)
+		resp = cached.response(req, resp)
+	case resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""):
+		cache.misses.Add(1)
+		resp, err = cache.save(key, req, resp)
+	default:
+		cache.misses.Add(1)
+	}
+	return
+}
+
+// response converts the saved entry into a response for req; headers from
+// the 304 (such as the rate limit values) replace the saved ones
+func (self *entry) response(req *http.Request, notModified *http.Response) (resp *http.Response) {
+	var header = self.Header.Clone()
+
+	io.Copy(io.Discard, notModified.Body)
+	notModified.Body.Close()
+	for k, v := range notModified.Header {
+		if k != "Content-Length" {
+			header[k] = v
+		}
+	}
+	resp = &http.Response{
+		Status:        fmt.Sprintf("%d %s", self.Status, http.StatusText(self.Status)),
+		StatusCode:    self.Status,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(self.Body)),
+		ContentLength: int64(len(self.Body)),
+		Request:       req,
+	}
+	return
+}
+
+// save reads the body of the response and writes it to the cache, replacing
+// the response body so it can still be read.
+//
+// Failing to write is not an error for the request, it is only counted.
+func (self *Cache) save(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	var (
+		body []byte
+		err  error
+		log  *slog.Logger = cntxt.GetLogger(req.Context()).With("package", "httpcache", "func", "save")
+	)
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = self.write(key, &entry{
+		URL:      req.URL.String(),
+		Status:   resp.StatusCode,
+		Header:   resp.Header,
+		Body:     body,
+		StoredAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Warn("error writing cache entry", "url", req.URL.String(), "err", err.Error())
+		self.errors.Add(1)
+	} else {
+		self.stored.Add(1)
+	}
+	return resp, nil
+}
+
+// load returns the saved entry for the key, or nil when there is none
+func (self *Cache) load(key string) (cached *entry, err error) {
+	var content []byte
+	if content, err = os.ReadFile(self.path(key)); errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+	cached = &entry{}
+	if err = json.Unmarshal(content, cached); err != nil {
+		cached = nil
+	}
+	return
+}
+
+// write saves the entry via a temporary file so concurrent requests for the
+// same url never see a partly written file
+func (self *Cache) write(key string, cached *entry) (err error) {
+	var (
+		content []byte
+		tmp     *os.File
+	)
+	if content, err = json.Marshal(cached); err != nil {
+		return
+	}
+	if tmp, err = os.CreateTemp(self.Dir, key+"-*.tmp"); err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), self.path(key))
+	return
+}
+
+// path returns the file path for the key
+func (self *Cache) path(key string) string {
+	return filepath.Join(self.Dir, key+ext)
+}
+
+// requestKey identifies the request by its url, accepted content type and
+// credentials, so different tokens or media types never share an entry
+func requestKey(req *http.Request) string {
+	var hash = sha256.Sum256([]byte(strings.Join([]string{
+		req.URL.String(),
+		req.Header.Get("Accept"),
+		req.Header.Get("Authorization"),
+	}, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// WithCache returns a context that uses the cache for all clients created
+// with it
+func WithCache(ctx context.Context, cache *Cache) context.Context {
+	return context.WithValue(ctx, contextKey, cache)
+}
+
+// GetCache returns the cache attached to the context, or nil
+func GetCache(ctx context.Context) (cache *Cache) {
+	cache, _ = ctx.Value(contextKey).(*Cache)
+	return
+}
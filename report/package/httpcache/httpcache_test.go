@@ -0,0 +1,109 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHttpCacheConditional(t *testing.T) {
+	var (
+		full, notModified atomic.Int32
+		ctx               = cntxt.AddLogger(t.Context(), logger.New("error"))
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "100")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	for range 3 {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos/a/b/contents", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: [%s]", err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "content" {
+			t.Errorf("unexpected response: [%d] [%s]", resp.StatusCode, string(body))
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") != "100" {
+			t.Errorf("expected headers from the api response")
+		}
+	}
+	if full.Load() != 1 || notModified.Load() != 2 {
+		t.Errorf("expected 1 full and 2 conditional responses, got [%d] [%d]", full.Load(), notModified.Load())
+	}
+	stats := cache.Stats()
+	if stats.Requests != 3 || stats.Hits != 2 || stats.Misses != 1 || stats.Stored != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if d := stats.Sub(Stats{Requests: 1, Hits: 1}); d.Requests != 2 || d.Hits != 1 {
+		t.Errorf("unexpected stats difference: %+v", d)
+	}
+
+	removed, err := cache.Clear()
+	if err != nil || removed != 1 {
+		t.Errorf("expected 1 entry removed, got [%d] [%v]", removed, err)
+	}
+	// cleared, so a full response again
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos/a/b/contents", nil)
+	resp, _ := client.Do(req)
+	resp.Body.Close()
+	if full.Load() != 2 {
+		t.Errorf("expected a full response after clearing")
+	}
+}
+
+func TestHttpCacheSkipped(t *testing.T) {
+	var (
+		calls atomic.Int32
+		ctx   = cntxt.AddLogger(t.Context(), logger.New("error"))
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected conditional request for [%s %s]", r.Method, r.URL.Path)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	cache, _ := New(t.TempDir())
+	client := &http.Client{Transport: cache.Transport(nil)}
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/rate_limit"},
+		{http.MethodGet, "/rate_limit"},
+		{http.MethodPost, "/repos"},
+		{http.MethodPost, "/repos"},
+	} {
+		req, _ := http.NewRequestWithContext(ctx, r.method, server.URL+r.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: [%s]", err.Error())
+		}
+		resp.Body.Close()
+	}
+	if calls.Load() != 4 || cache.Stats().Requests != 0 {
+		t.Errorf("expected all requests to bypass the cache: [%d] %+v", calls.Load(), cache.Stats())
+	}
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"opg-reports/report/package/logger"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testRow struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+// TestPipelineRunConcurrentWrites runs several tasks at the same time that each
+// clear and insert their own rows in the same sqlite file, like the import
+// groups do. There is no busy timeout, so any overlapping writes would fail.
+func TestPipelineRunConcurrentWrites(t *testing.T) {
+	var (
+		err     error
+		count   int
+		results []*Result
+		ctx     = cntxt.AddLogger(t.Context(), logger.New("error"))
+		tasks   = []*Task{}
+		dbpath  = filepath.Join(t.TempDir(), "concurrent.db")
+		params  = "?_journal=WAL&_busy_timeout=0"
+		execIn  = &dbx.ExecArgs{DB: dbpath, Driver: "sqlite3", Params: params}
+		insert  = `INSERT INTO items (name, size) VALUES (:name, :size) ON CONFLICT (name) DO UPDATE SET size=excluded.size;`
+	)
+	if err = dbx.Exec(ctx, `CREATE TABLE items (name TEXT PRIMARY KEY, size INTEGER);`, execIn); err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	for i := range 6 {
+		var prefix = fmt.Sprintf("task-%d-", i)
+		tasks = append(tasks, &Task{Name: prefix, Run: func(ctx context.Context) (err error) {
+			var rows = []*testRow{}
+			for j := range 200 {
+				rows = append(rows, &testRow{Name: fmt.Sprintf("%s%d", prefix, j), Size: j})
+			}
+			if err = dbx.Exec(ctx, `DELETE FROM items WHERE name LIKE ?;`, execIn, prefix+"%"); err != nil {
+				return
+			}
+			return dbx.Insert(ctx, insert, rows, &dbx.InsertArgs{DB: dbpath, Driver: "sqlite3", Params: params})
+		}})
+	}
+	if results, err = Run(ctx, tasks); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	for _, res := range results {
+		if res.Status != StatusSuccess || res.Rows != 200 {
+			t.Errorf("unexpected result for [%s]: %+v", res.Name, res)
+		}
+	}
+	dbx.Select(ctx, `SELECT count(*) FROM items;`, &dbx.SelectArgs{
+		DB:      dbpath,
+		Driver:  "sqlite3",
+		Params:  params,
+		BindMap: map[string]interface{}{},
+		ScanF:   func(rows *sql.Rows) error { return rows.Scan(&count) },
+	})
+	if count != 1200 {
+		t.Errorf("expected 1200 rows, got [%d]", count)
+	}
+}
@@ -0,0 +1,202 @@
+// Package pipeline runs a set of named tasks in dependency order.
+//
+// Each task starts as soon as all of the tasks it runs `After` have finished,
+// so independent tasks run concurrently. A task that fails (or is blocked) will
+// block anything that depends on it, but has no effect on any other task.
+//
+// Rows written via dbx.Insert during each task are counted and included in the
+// results along with the status and duration.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/dbx"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Status of a task once the pipeline has finished
+type Status string
+
+const (
+	StatusSuccess Status = "success" // task ran without error
+	StatusFailed  Status = "failed"  // task returned an error
+	StatusSkipped Status = "skipped" // task returned ErrSkip, this does not block other tasks
+	StatusBlocked Status = "blocked" // a task this depends on failed or was blocked, so this was not run
+)
+
+var (
+	ErrSkip              = errors.New("task skipped.")
+	ErrDuplicateTask     = errors.New("duplicate task name.")
+	ErrUnknownDependency = errors.New("task depends on an unknown task.")
+	ErrCycle             = errors.New("task dependencies contain a cycle.")
+	ErrTasksFailed       = errors.New("one or more tasks failed.")
+)
+
+// TaskF is the function a task runs
+type TaskF func(ctx context.Context) (err error)
+
+// Task is a single named unit of work
+type Task struct {
+	Name  string   // unique name of the task
+	After []string // names of tasks that must complete before this one runs
+	Run   TaskF
+}
+
+// Result contains the outcome of a single task
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Rows     int64         `json:"rows"`     // rows written by dbx.Insert
+	Duration time.Duration `json:"duration"` // how long the task ran for
+	Err      error         `json:"-"`
+}
+
+// Run executes all of the tasks and returns a result for each one in the same
+// order as tasks were passed. err is only set for invalid tasks or when one or more
+// of the tasks failed, in which case all the errors are joined together.
+func Run(ctx context.Context, tasks []*Task) (results []*Result, err error) {
+	var (
+		done   map[string]chan struct{} = map[string]chan struct{}{}
+		byName map[string]*Result       = map[string]*Result{}
+		log    *slog.Logger             = cntxt.GetLogger(ctx).With("package", "pipeline", "func", "Run")
+	)
+	log.Info("starting ...", "tasks", len(tasks))
+	if err = validate(tasks); err != nil {
+		log.Error("invalid tasks", "err", err.Error())
+		return
+	}
+
+	results = []*Result{}
+	for _, task := range tasks {
+		var res = &Result{Name: task.Name}
+		results = append(results, res)
+		byName[task.Name] = res
+		done[task.Name] = make(chan struct{})
+	}
+
+	for _, task := range tasks {
+		go func(task *Task, res *Result) {
+			defer close(done[task.Name])
+			// wait for all dependencies
+			for _, dep := range task.After {
+				<-done[dep]
+				if s := byName[dep].Status; s == StatusFailed || s == StatusBlocked {
+					res.Status = StatusBlocked
+				}
+			}
+			if res.Status == StatusBlocked {
+				log.Warn("task blocked by failed dependency", "task", task.Name)
+				return
+			}
+			runTask(ctx, task, res)
+		}(task, byName[task.Name])
+	}
+	// wait for everything to finish
+	for _, task := range tasks {
+		<-done[task.Name]
+	}
+
+	for _, res := range results {
+		if res.Status == StatusFailed {
+			err = errors.Join(err, fmt.Errorf("%s: %w", res.Name, res.Err))
+		}
+	}
+	if err != nil {
+		err = errors.Join(ErrTasksFailed, err)
+	}
+	log.Info("complete.")
+	return
+}
+
+// runTask calls the task function and records the outcome on res
+func runTask(ctx context.Context, task *Task, res *Result) {
+	var (
+		e     error
+		tally *dbx.Tally
+		start time.Time    = time.Now()
+		log   *slog.Logger = cntxt.GetLogger(ctx).With("package", "pipeline", "func", "runTask", "task", task.Name)
+	)
+	log.Info("starting task ...")
+	ctx, tally = dbx.WithTally(ctx)
+	e = task.Run(ctx)
+
+	res.Duration = time.Since(start)
+	res.Rows = tally.Rows()
+	switch {
+	case e == nil:
+		res.Status = StatusSuccess
+	case errors.Is(e, ErrSkip):
+		res.Status = StatusSkipped
+	default:
+		res.Status = StatusFailed
+		res.Err = e
+		log.Error("task failed", "err", e.Error())
+	}
+	log.Info("task complete.", "status", res.Status, "rows", res.Rows, "duration", res.Duration.String())
+}
+
+// validate checks names are unique, all dependencies exist and there are no cycles
+func validate(tasks []*Task) (err error) {
+	var (
+		known   map[string]*Task = map[string]*Task{}
+		visited map[string]int   = map[string]int{} // 1 = in progress, 2 = done
+		visit   func(name string) error
+	)
+	for _, task := range tasks {
+		if _, ok := known[task.Name]; ok {
+			return fmt.Errorf("%w [%s]", ErrDuplicateTask, task.Name)
+		}
+		known[task.Name] = task
+	}
+	for _, task := range tasks {
+		for _, dep := range task.After {
+			if _, ok := known[dep]; !ok {
+				return fmt.Errorf("%w [%s -> %s]", ErrUnknownDependency, task.Name, dep)
+			}
+		}
+	}
+	visit = func(name string) error {
+		switch visited[name] {
+		case 1:
+			return fmt.Errorf("%w [%s]", ErrCycle, name)
+		case 2:
+			return nil
+		}
+		visited[name] = 1
+		for _, dep := range known[name].After {
+			if e := visit(dep); e != nil {
+				return e
+			}
+		}
+		visited[name] = 2
+		return nil
+	}
+	for _, task := range tasks {
+		if err = visit(task.Name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteSummary writes a plain text table of the results
+func WriteSummary(w io.Writer, results []*Result) {
+	var tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TASK\tSTATUS\tROWS\tDURATION\tERROR")
+	for _, res := range results {
+		var msg = ""
+		if res.Err != nil {
+			msg = strings.ReplaceAll(res.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", res.Name, res.Status, res.Rows, res.Duration.Round(time.Millisecond), msg)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPipelineRunOrderAndFailures(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+		ctx   = cntxt.AddLogger(t.Context(), logger.New("error"))
+		ok    = func(name string) TaskF {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return nil
+			}
+		}
+	)
+	tasks := []*Task{
+		{Name: "teams", Run: ok("teams")},
+		{Name: "accounts", After: []string{"teams"}, Run: ok("accounts")},
+		{Name: "costs", After: []string{"accounts"}, Run: ok("costs")},
+		{Name: "codebases", Run: func(ctx context.Context) error { return errors.New("boom") }},
+		{Name: "codeowners", After: []string{"codebases"}, Run: ok("codeowners")},
+		{Name: "optional", Run: func(ctx context.Context) error { return ErrSkip }},
+		{Name: "after-optional", After: []string{"optional"}, Run: ok("after-optional")},
+	}
+	results, err := Run(ctx, tasks)
+	if !errors.Is(err, ErrTasksFailed) {
+		t.Errorf("expected failure error, got [%v]", err)
+	}
+	expected := map[string]Status{
+		"teams":          StatusSuccess,
+		"accounts":       StatusSuccess,
+		"costs":          StatusSuccess,
+		"codebases":      StatusFailed,
+		"codeowners":     StatusBlocked,
+		"optional":       StatusSkipped,
+		"after-optional": StatusSuccess,
+	}
+	if len(results) != len(tasks) {
+		t.Errorf("expected a result per task")
+	}
+	for i, res := range results {
+		if res.Name != tasks[i].Name {
+			t.Errorf("results not in task order")
+		}
+		if res.Status != expected[res.Name] {
+			t.Errorf("[%s] expected status [%s] got [%s]", res.Name, expected[res.Name], res.Status)
+		}
+	}
+	// check the dependency order
+	pos := map[string]int{}
+	for i, n := range order {
+		pos[n] = i
+	}
+	if !(pos["teams"] < pos["accounts"] && pos["accounts"] < pos["costs"]) {
+		t.Errorf("tasks ran out of order: %v", order)
+	}
+
+	buf := &bytes.Buffer{}
+	WriteSummary(buf, results)
+	if !strings.Contains(buf.String(), "boom") || !strings.Contains(buf.String(), "blocked") {
+		t.Errorf("summary missing details:\n%s", buf.String())
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+	var noop = func(ctx context.Context) error { return nil }
+
+	_, err := Run(ctx, []*Task{{Name: "a", After: []string{"b"}, Run: noop}, {Name: "b", After: []string{"a"}, Run: noop}})
+	if !errors.Is(err, ErrCycle) {
+		t.Errorf("expected cycle error, got [%v]", err)
+	}
+	_, err = Run(ctx, []*Task{{Name: "a", After: []string{"missing"}, Run: noop}})
+	if !errors.Is(err, ErrUnknownDependency) {
+		t.Errorf("expected unknown dependency error, got [%v]", err)
+	}
+	_, err = Run(ctx, []*Task{{Name: "a", Run: noop}, {Name: "a", Run: noop}})
+	if !errors.Is(err, ErrDuplicateTask) {
+		t.Errorf("expected duplicate error, got [%v]", err)
+	}
+}
@@ -0,0 +1,87 @@
+package replay
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// cloudwatchClient is a proxy for *cloudwatch.Client
+type cloudwatchClient interface {
+	DescribeAlarmHistory(ctx context.Context, params *cloudwatch.DescribeAlarmHistoryInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmHistoryOutput, error)
+	ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error)
+	GetMetricStatistics(ctx context.Context, params *cloudwatch.GetMetricStatisticsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricStatisticsOutput, error)
+	Options() cloudwatch.Options
+}
+
+// route53Client is a proxy for *route53.Client
+type route53Client interface {
+	ListTagsForResources(ctx context.Context, params *route53.ListTagsForResourcesInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourcesOutput, error)
+}
+
+// costExplorerClient is a proxy for *costexplorer.Client
+type costExplorerClient interface {
+	GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error)
+}
+
+// CloudWatch implements the cloudwatch client methods used by the importers;
+// Client can be nil when replaying
+type CloudWatch struct {
+	Client cloudwatchClient
+	Store  *Store
+	Region string // region reported by Options when there is no client
+}
+
+// Options returns the client options, or just the region when replaying
+func (self *CloudWatch) Options() cloudwatch.Options {
+	if self.Client == nil {
+		return cloudwatch.Options{Region: self.Region}
+	}
+	return self.Client.Options()
+}
+
+func (self *CloudWatch) DescribeAlarmHistory(ctx context.Context, params *cloudwatch.DescribeAlarmHistoryInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmHistoryOutput, error) {
+	return Call(ctx, self.Store, "aws.CloudWatch.DescribeAlarmHistory", params, func() (*cloudwatch.DescribeAlarmHistoryOutput, error) {
+		return self.Client.DescribeAlarmHistory(ctx, params, optFns...)
+	})
+}
+
+func (self *CloudWatch) ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error) {
+	return Call(ctx, self.Store, "aws.CloudWatch.ListMetrics", params, func() (*cloudwatch.ListMetricsOutput, error) {
+		return self.Client.ListMetrics(ctx, params, optFns...)
+	})
+}
+
+func (self *CloudWatch) GetMetricStatistics(ctx context.Context, params *cloudwatch.GetMetricStatisticsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	return Call(ctx, self.Store, "aws.CloudWatch.GetMetricStatistics", params, func() (*cloudwatch.GetMetricStatisticsOutput, error) {
+		return self.Client.GetMetricStatistics(ctx, params, optFns...)
+	})
+}
+
+// Route53 implements the route53 client methods used by the importers;
+// Client can be nil when replaying
+type Route53 struct {
+	Client route53Client
+	Store  *Store
+}
+
+func (self *Route53) ListTagsForResources(ctx context.Context, params *route53.ListTagsForResourcesInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourcesOutput, error) {
+	return Call(ctx, self.Store, "aws.Route53.ListTagsForResources", params, func() (*route53.ListTagsForResourcesOutput, error) {
+		return self.Client.ListTagsForResources(ctx, params, optFns...)
+	})
+}
+
+// CostExplorer implements the cost explorer client methods used by the importers;
+// Client can be nil when replaying
+type CostExplorer struct {
+	Client costExplorerClient
+	Store  *Store
+}
+
+func (self *CostExplorer) GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error) {
+	return Call(ctx, self.Store, "aws.CostExplorer.GetCostAndUsage", params, func() (*costexplorer.GetCostAndUsageOutput, error) {
+		return self.Client.GetCostAndUsage(ctx, params, optFns...)
+	})
+}
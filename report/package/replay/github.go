@@ -0,0 +1,419 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// teamsClient is a proxy for *github.TeamsService
+type teamsClient interface {
+	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
+// repositoriesClient is a proxy for *github.RepositoriesService
+type repositoriesClient interface {
+	ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
+	DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (fileContent *github.RepositoryContent, directoryContent []*github.RepositoryContent, resp *github.Response, err error)
+}
+
+// branchProtectionClient is a proxy for the branch protection & ruleset methods of
+// *github.RepositoriesService; kept apart from repositoriesClient as they always use
+// the rest api
+type branchProtectionClient interface {
+	GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error)
+	GetRulesForBranch(ctx context.Context, owner, repo, branch string, opts *github.ListOptions) (*github.BranchRules, *github.Response, error)
+}
+
+// gitClient is a proxy for *github.GitService
+type gitClient interface {
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error)
+}
+
+// actionsClient is a proxy for *github.ActionsService
+type actionsClient interface {
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error)
+}
+
+// pullRequestsClient is a proxy for *github.PullRequestsService
+type pullRequestsClient interface {
+	List(ctx context.Context, owner string, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+}
+
+// dependabotClient is a proxy for *github.DependabotService
+type dependabotClient interface {
+	ListRepoAlerts(ctx context.Context, owner, repo string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error)
+}
+
+// codeScanningClient is a proxy for *github.CodeScanningService
+type codeScanningClient interface {
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error)
+}
+
+// secretScanningClient is a proxy for *github.SecretScanningService
+type secretScanningClient interface {
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error)
+}
+
+// teamLookupClient is a proxy for the single team lookup of *github.TeamsService;
+// kept apart from teamsClient as it always uses the rest api
+type teamLookupClient interface {
+	GetTeamBySlug(ctx context.Context, org, slug string) (*github.Team, *github.Response, error)
+}
+
+// usersClient is a proxy for *github.UsersService
+type usersClient interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
+// GitHub contains the recordable versions of each github service used by
+// the importers
+type GitHub struct {
+	Teams          *GitHubTeams
+	Repositories   *GitHubRepositories
+	Branches       *GitHubBranches
+	Git            *GitHubGit
+	Actions        *GitHubActions
+	PullRequests   *GitHubPullRequests
+	Dependabot     *GitHubDependabot
+	CodeScanning   *GitHubCodeScanning
+	SecretScanning *GitHubSecretScanning
+	TeamLookup     *GitHubTeamLookup
+	Users          *GitHubUsers
+}
+
+// NewGitHub wraps the services of the client; client can be nil when replaying
+func NewGitHub(client *github.Client, store *Store) (gh *GitHub) {
+	gh = &GitHub{
+		Teams:          &GitHubTeams{Store: store},
+		Repositories:   &GitHubRepositories{Store: store},
+		Branches:       &GitHubBranches{Store: store},
+		Git:            &GitHubGit{Store: store},
+		Actions:        &GitHubActions{Store: store},
+		PullRequests:   &GitHubPullRequests{Store: store},
+		Dependabot:     &GitHubDependabot{Store: store},
+		CodeScanning:   &GitHubCodeScanning{Store: store},
+		SecretScanning: &GitHubSecretScanning{Store: store},
+		TeamLookup:     &GitHubTeamLookup{Store: store},
+		Users:          &GitHubUsers{Store: store},
+	}
+	if client != nil {
+		gh.Teams.Client = client.Teams
+		gh.Repositories.Client = client.Repositories
+		gh.Branches.Client = client.Repositories
+		gh.Git.Client = client.Git
+		gh.Actions.Client = client.Actions
+		gh.PullRequests.Client = client.PullRequests
+		gh.Dependabot.Client = client.Dependabot
+		gh.CodeScanning.Client = client.CodeScanning
+		gh.SecretScanning.Client = client.SecretScanning
+		gh.TeamLookup.Client = client.Teams
+		gh.Users.Client = client.Users
+	}
+	return
+}
+
+// page is the part of *github.Response used by the importers
+type page struct {
+	StatusCode int    `json:"status_code"`
+	NextPage   int    `json:"next_page"`
+	PrevPage   int    `json:"prev_page"`
+	FirstPage  int    `json:"first_page"`
+	LastPage   int    `json:"last_page"`
+	After      string `json:"after"` // cursor for apis that use cursor pagination
+}
+
+// result is a recorded github call
+type result[T any] struct {
+	Data T     `json:"data"`
+	Page *page `json:"page"`
+}
+
+// fromResponse converts the response into a page
+func fromResponse(resp *github.Response) (p *page) {
+	if resp == nil {
+		return
+	}
+	p = &page{NextPage: resp.NextPage, PrevPage: resp.PrevPage, FirstPage: resp.FirstPage, LastPage: resp.LastPage, After: resp.After}
+	if resp.Response != nil {
+		p.StatusCode = resp.StatusCode
+	}
+	return
+}
+
+// response converts the page back into a *github.Response
+func (self *page) response() *github.Response {
+	if self == nil {
+		return nil
+	}
+	return &github.Response{
+		Response:  &http.Response{StatusCode: self.StatusCode},
+		NextPage:  self.NextPage,
+		PrevPage:  self.PrevPage,
+		FirstPage: self.FirstPage,
+		LastPage:  self.LastPage,
+		After:     self.After,
+	}
+}
+
+// GitHubTeams implements the teams client methods used by the importers
+type GitHubTeams struct {
+	Client teamsClient
+	Store  *Store
+}
+
+func (self *GitHubTeams) ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Teams.ListTeamReposBySlug", []any{org, slug, opts}, func() (r result[[]*github.Repository], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListTeamReposBySlug(ctx, org, slug, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+func (self *GitHubTeams) ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Teams.ListChildTeamsByParentSlug", []any{org, slug, opts}, func() (r result[[]*github.Team], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListChildTeamsByParentSlug(ctx, org, slug, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubRepositories implements the repository client methods used by the importers
+type GitHubRepositories struct {
+	Client repositoriesClient
+	Store  *Store
+}
+
+func (self *GitHubRepositories) ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Repositories.ListTeams", []any{owner, repo, opts}, func() (r result[[]*github.Team], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListTeams(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// DownloadContents reads the full content so it can be recorded
+func (self *GitHubRepositories) DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Repositories.DownloadContents", []any{owner, repo, filepath, opts}, func() (r result[[]byte], e error) {
+		var resp *github.Response
+		var rc io.ReadCloser
+		rc, resp, e = self.Client.DownloadContents(ctx, owner, repo, filepath, opts)
+		r.Page = fromResponse(resp)
+		if rc != nil {
+			defer rc.Close()
+			r.Data, e = io.ReadAll(rc)
+		}
+		return
+	})
+	if err != nil {
+		return nil, res.Page.response(), err
+	}
+	return io.NopCloser(bytes.NewReader(res.Data)), res.Page.response(), err
+}
+
+// contents is the file and directory result of GetContents
+type contents struct {
+	File      *github.RepositoryContent   `json:"file"`
+	Directory []*github.RepositoryContent `json:"directory"`
+}
+
+func (self *GitHubRepositories) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Repositories.GetContents", []any{owner, repo, path, opts}, func() (r result[*contents], e error) {
+		var resp *github.Response
+		r.Data = &contents{}
+		r.Data.File, r.Data.Directory, resp, e = self.Client.GetContents(ctx, owner, repo, path, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	if res.Data == nil {
+		res.Data = &contents{}
+	}
+	return res.Data.File, res.Data.Directory, res.Page.response(), err
+}
+
+// GitHubBranches implements the branch protection & ruleset client methods used by the importers
+type GitHubBranches struct {
+	Client branchProtectionClient
+	Store  *Store
+}
+
+func (self *GitHubBranches) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Repositories.GetBranchProtection", []any{owner, repo, branch}, func() (r result[*github.Protection], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.GetBranchProtection(ctx, owner, repo, branch)
+		r.Page = fromResponse(resp)
+		return
+	})
+	// replayed errors are only the message, so restore the not protected error
+	// that callers check for
+	if err != nil && err.Error() == github.ErrBranchNotProtected.Error() {
+		err = github.ErrBranchNotProtected
+	}
+	return res.Data, res.Page.response(), err
+}
+
+// branchRules drops the custom json decoding of *github.BranchRules, which
+// expects the api's list of rules, so it is recorded and replayed as a struct
+type branchRules github.BranchRules
+
+func (self *GitHubBranches) GetRulesForBranch(ctx context.Context, owner, repo, branch string, opts *github.ListOptions) (*github.BranchRules, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Repositories.GetRulesForBranch", []any{owner, repo, branch, opts}, func() (r result[*branchRules], e error) {
+		var resp *github.Response
+		var rules *github.BranchRules
+		rules, resp, e = self.Client.GetRulesForBranch(ctx, owner, repo, branch, opts)
+		r.Data = (*branchRules)(rules)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return (*github.BranchRules)(res.Data), res.Page.response(), err
+}
+
+// GitHubGit implements the git client methods used by the importers
+type GitHubGit struct {
+	Client gitClient
+	Store  *Store
+}
+
+func (self *GitHubGit) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Git.GetTree", []any{owner, repo, sha, recursive}, func() (r result[*github.Tree], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.GetTree(ctx, owner, repo, sha, recursive)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubActions implements the actions client methods used by the importers
+type GitHubActions struct {
+	Client actionsClient
+	Store  *Store
+}
+
+func (self *GitHubActions) ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Actions.ListRepositoryWorkflowRuns", []any{owner, repo, opts}, func() (r result[*github.WorkflowRuns], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListRepositoryWorkflowRuns(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+func (self *GitHubActions) GetWorkflowRunUsageByID(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRunUsage, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Actions.GetWorkflowRunUsageByID", []any{owner, repo, runID}, func() (r result[*github.WorkflowRunUsage], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.GetWorkflowRunUsageByID(ctx, owner, repo, runID)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubPullRequests implements the pull request client methods used by the importers
+type GitHubPullRequests struct {
+	Client pullRequestsClient
+	Store  *Store
+}
+
+func (self *GitHubPullRequests) List(ctx context.Context, owner string, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.PullRequests.List", []any{owner, repo, opts}, func() (r result[[]*github.PullRequest], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.List(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubDependabot implements the dependabot client methods used by the importers
+type GitHubDependabot struct {
+	Client dependabotClient
+	Store  *Store
+}
+
+func (self *GitHubDependabot) ListRepoAlerts(ctx context.Context, owner, repo string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Dependabot.ListRepoAlerts", []any{owner, repo, opts}, func() (r result[[]*github.DependabotAlert], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListRepoAlerts(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubCodeScanning implements the code scanning client methods used by the importers
+type GitHubCodeScanning struct {
+	Client codeScanningClient
+	Store  *Store
+}
+
+func (self *GitHubCodeScanning) ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.CodeScanning.ListAlertsForRepo", []any{owner, repo, opts}, func() (r result[[]*github.Alert], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListAlertsForRepo(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubSecretScanning implements the secret scanning client methods used by the importers
+type GitHubSecretScanning struct {
+	Client secretScanningClient
+	Store  *Store
+}
+
+func (self *GitHubSecretScanning) ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.SecretScanning.ListAlertsForRepo", []any{owner, repo, opts}, func() (r result[[]*github.SecretScanningAlert], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.ListAlertsForRepo(ctx, owner, repo, opts)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubTeamLookup implements the single team lookup used by the importers
+type GitHubTeamLookup struct {
+	Client teamLookupClient
+	Store  *Store
+}
+
+func (self *GitHubTeamLookup) GetTeamBySlug(ctx context.Context, org, slug string) (*github.Team, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Teams.GetTeamBySlug", []any{org, slug}, func() (r result[*github.Team], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.GetTeamBySlug(ctx, org, slug)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
+
+// GitHubUsers implements the users client methods used by the importers
+type GitHubUsers struct {
+	Client usersClient
+	Store  *Store
+}
+
+func (self *GitHubUsers) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	res, err := Call(ctx, self.Store, "github.Users.Get", []any{user}, func() (r result[*github.User], e error) {
+		var resp *github.Response
+		r.Data, resp, e = self.Client.Get(ctx, user)
+		r.Page = fromResponse(resp)
+		return
+	})
+	return res.Data, res.Page.response(), err
+}
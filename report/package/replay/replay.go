@@ -0,0 +1,131 @@
+// Package replay records api responses to disk and serves them back, so
+// an import can be reproduced offline without credentials.
+//
+// The GitHub and AWS types in this package wrap the real clients and implement
+// the same methods as the client interfaces the importers use. With a nil
+// Store they pass calls straight through; in record mode each response is also
+// saved to a json file, and in replay mode the saved file is returned without
+// calling the real client at all.
+//
+// Files are named from the method and a hash of its arguments, so the same
+// call will always find the same file.
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrRecordAndReplay = errors.New("record and replay can not be used at the same time.")
+	ErrNotRecorded     = errors.New("no recorded response found for call.")
+	ErrFailedRecording = errors.New("failed to record response with error.")
+)
+
+// Store is the directory responses are saved to or read from
+type Store struct {
+	Dir    string `json:"dir"`
+	Replay bool   `json:"replay"` // when true, responses are only read from disk
+}
+
+// recording is the content of each saved file
+type recording struct {
+	Method string          `json:"method"`
+	Args   json.RawMessage `json:"args"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// New returns a store for whichever of the directories is set; nil is returned
+// when neither is set so the clients pass calls through unchanged
+func New(recordDir string, replayDir string) (store *Store, err error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		err = ErrRecordAndReplay
+	case recordDir != "":
+		store = &Store{Dir: recordDir}
+	case replayDir != "":
+		store = &Store{Dir: replayDir, Replay: true}
+	}
+	return
+}
+
+// Call runs fetch (recording the result) or reads the previously recorded
+// result, depending on the store. A nil store simply calls fetch.
+//
+// Errors returned by fetch are recorded as their message and returned as a
+// plain error when replayed.
+func Call[T any](ctx context.Context, store *Store, method string, args any, fetch func() (T, error)) (result T, err error) {
+	var (
+		file    string
+		content []byte
+		rec     *recording   = &recording{Method: method}
+		log     *slog.Logger = cntxt.GetLogger(ctx).With("package", "replay", "func", "Call", "method", method)
+	)
+	if store == nil {
+		return fetch()
+	}
+	if rec.Args, err = json.Marshal(args); err != nil {
+		return
+	}
+	file = store.path(method, rec.Args)
+
+	if store.Replay {
+		log.Debug("replaying response ...", "file", file)
+		if content, err = os.ReadFile(file); err != nil {
+			err = errors.Join(ErrNotRecorded, fmt.Errorf("method [%s] args [%s]", method, string(rec.Args)), err)
+			return
+		}
+		if err = json.Unmarshal(content, rec); err != nil {
+			return
+		}
+		if err = json.Unmarshal(rec.Result, &result); err != nil {
+			return
+		}
+		if rec.Error != "" {
+			err = errors.New(rec.Error)
+		}
+		return
+	}
+
+	result, err = fetch()
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	log.Debug("recording response ...", "file", file)
+	if e := store.save(file, rec, result); e != nil {
+		log.Error("failed to record response", "err", e.Error())
+		err = errors.Join(err, ErrFailedRecording, e)
+	}
+	return
+}
+
+// path generates the file path for the method and its arguments
+func (self *Store) path(method string, args []byte) string {
+	var hash = sha256.Sum256(args)
+	return filepath.Join(self.Dir, method, hex.EncodeToString(hash[:])[:16]+".json")
+}
+
+// save writes the recording to the file
+func (self *Store) save(file string, rec *recording, result any) (err error) {
+	var content []byte
+	if rec.Result, err = json.Marshal(result); err != nil {
+		return
+	}
+	if content, err = json.MarshalIndent(rec, "", "  "); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return
+	}
+	err = os.WriteFile(file, content, 0644)
+	return
+}
@@ -0,0 +1,218 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/ptr"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/google/go-github/v84/github"
+)
+
+type mockTeams struct{ calls int }
+
+func (self *mockTeams) ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error) {
+	self.calls++
+	if slug == "missing" {
+		return nil, nil, errors.New("not found")
+	}
+	if opts.Page == 1 {
+		return []*github.Repository{{Name: ptr.Ptr("repo-a")}}, &github.Response{NextPage: 2}, nil
+	}
+	return []*github.Repository{{Name: ptr.Ptr("repo-b")}}, &github.Response{}, nil
+}
+
+func (self *mockTeams) ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	return nil, &github.Response{}, nil
+}
+
+type mockCosts struct{}
+
+func (self *mockCosts) GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error) {
+	return &costexplorer.GetCostAndUsageOutput{
+		ResultsByTime: []types.ResultByTime{{
+			TimePeriod: &types.DateInterval{Start: params.TimePeriod.Start, End: params.TimePeriod.End},
+			Groups: []types.Group{{
+				Keys:    []string{"123", "Amazon S3"},
+				Metrics: map[string]types.MetricValue{"UnblendedCost": {Amount: ptr.Ptr("1.23"), Unit: ptr.Ptr("USD")}},
+			}},
+		}},
+	}, nil
+}
+
+type mockContents struct{}
+
+func (self *mockContents) ListTeams(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	return nil, nil, nil
+}
+func (self *mockContents) DownloadContents(ctx context.Context, owner, repo, filepath string, opts *github.RepositoryContentGetOptions) (io.ReadCloser, *github.Response, error) {
+	return io.NopCloser(&readerString{s: "* @team"}), &github.Response{}, nil
+}
+func (self *mockContents) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	return nil, nil, nil, nil
+}
+
+type mockBranches struct{}
+
+func (self *mockBranches) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error) {
+	if repo == "open" {
+		return nil, &github.Response{}, github.ErrBranchNotProtected
+	}
+	return nil, nil, nil
+}
+func (self *mockBranches) GetRulesForBranch(ctx context.Context, owner, repo, branch string, opts *github.ListOptions) (*github.BranchRules, *github.Response, error) {
+	return &github.BranchRules{
+		RequiredSignatures: []*github.BranchRuleMetadata{{RulesetID: 1}},
+		PullRequest:        []*github.PullRequestBranchRule{{Parameters: github.PullRequestRuleParameters{RequiredApprovingReviewCount: 2}}},
+	}, &github.Response{}, nil
+}
+
+type readerString struct {
+	s    string
+	done bool
+}
+
+func (self *readerString) Read(p []byte) (int, error) {
+	if self.done {
+		return 0, io.EOF
+	}
+	self.done = true
+	return copy(p, self.s), nil
+}
+
+func TestReplayGitHubRecordAndReplay(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		mock   = &mockTeams{}
+		record = &GitHubTeams{Client: mock, Store: &Store{Dir: dir}}
+		replay = &GitHubTeams{Store: &Store{Dir: dir, Replay: true}}
+	)
+	// record two pages and a failure
+	for _, page := range []int{1, 2} {
+		if _, _, err := record.ListTeamReposBySlug(ctx, "org", "team", &github.ListOptions{Page: page}); err != nil {
+			t.Errorf("unexpected error: [%s]", err.Error())
+		}
+	}
+	record.ListTeamReposBySlug(ctx, "org", "missing", &github.ListOptions{Page: 1})
+
+	// replay without a client
+	repos, resp, err := replay.ListTeamReposBySlug(ctx, "org", "team", &github.ListOptions{Page: 1})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	if len(repos) != 1 || *repos[0].Name != "repo-a" || resp.NextPage != 2 {
+		t.Errorf("unexpected replay result: %v %+v", repos, resp)
+	}
+	repos, _, _ = replay.ListTeamReposBySlug(ctx, "org", "team", &github.ListOptions{Page: 2})
+	if len(repos) != 1 || *repos[0].Name != "repo-b" {
+		t.Errorf("unexpected replay result for page 2")
+	}
+	if _, _, err = replay.ListTeamReposBySlug(ctx, "org", "missing", &github.ListOptions{Page: 1}); err == nil || err.Error() != "not found" {
+		t.Errorf("expected recorded error to be replayed, got [%v]", err)
+	}
+	if _, _, err = replay.ListTeamReposBySlug(ctx, "org", "other", &github.ListOptions{Page: 1}); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected not recorded error, got [%v]", err)
+	}
+	if mock.calls != 3 {
+		t.Errorf("replay should not call the client, calls [%d]", mock.calls)
+	}
+}
+
+func TestReplayGitHubDownloadContents(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		record = &GitHubRepositories{Client: &mockContents{}, Store: &Store{Dir: dir}}
+		replay = &GitHubRepositories{Store: &Store{Dir: dir, Replay: true}}
+	)
+	rc, _, _ := record.DownloadContents(ctx, "org", "repo", "CODEOWNERS", nil)
+	content, _ := io.ReadAll(rc)
+	if string(content) != "* @team" {
+		t.Errorf("recording should return the original content, got [%s]", string(content))
+	}
+	rc, _, err := replay.DownloadContents(ctx, "org", "repo", "CODEOWNERS", nil)
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	content, _ = io.ReadAll(rc)
+	if string(content) != "* @team" {
+		t.Errorf("unexpected replayed content [%s]", string(content))
+	}
+}
+
+func TestReplayGitHubRulesForBranch(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		record = &GitHubBranches{Client: &mockBranches{}, Store: &Store{Dir: dir}}
+		replay = &GitHubBranches{Store: &Store{Dir: dir, Replay: true}}
+	)
+	record.GetRulesForBranch(ctx, "org", "repo", "main", &github.ListOptions{Page: 1})
+	rules, _, err := replay.GetRulesForBranch(ctx, "org", "repo", "main", &github.ListOptions{Page: 1})
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	if len(rules.RequiredSignatures) != 1 || len(rules.PullRequest) != 1 || rules.PullRequest[0].Parameters.RequiredApprovingReviewCount != 2 {
+		t.Errorf("unexpected replayed rules: %+v", rules)
+	}
+}
+
+func TestReplayGitHubBranchNotProtected(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		record = &GitHubBranches{Client: &mockBranches{}, Store: &Store{Dir: dir}}
+		replay = &GitHubBranches{Store: &Store{Dir: dir, Replay: true}}
+	)
+	record.GetBranchProtection(ctx, "org", "open", "main")
+	if _, _, err := replay.GetBranchProtection(ctx, "org", "open", "main"); !errors.Is(err, github.ErrBranchNotProtected) {
+		t.Errorf("expected replayed not protected error, got [%v]", err)
+	}
+}
+
+func TestReplayAWSRecordAndReplay(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		dir    = t.TempDir()
+		record = &CostExplorer{Client: &mockCosts{}, Store: &Store{Dir: dir}}
+		replay = &CostExplorer{Store: &Store{Dir: dir, Replay: true}}
+		params = &costexplorer.GetCostAndUsageInput{
+			TimePeriod:  &types.DateInterval{Start: ptr.Ptr("2025-01-01"), End: ptr.Ptr("2025-02-01")},
+			Granularity: types.GranularityMonthly,
+		}
+	)
+	if _, err := record.GetCostAndUsage(ctx, params); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+	out, err := replay.GetCostAndUsage(ctx, params)
+	if err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+		t.FailNow()
+	}
+	if len(out.ResultsByTime) != 1 || *out.ResultsByTime[0].Groups[0].Metrics["UnblendedCost"].Amount != "1.23" {
+		t.Errorf("unexpected replayed output: %+v", out)
+	}
+}
+
+func TestReplayPassThrough(t *testing.T) {
+	var ctx = cntxt.AddLogger(t.Context(), logger.New("error"))
+	var mock = &mockTeams{}
+	var client = &GitHubTeams{Client: mock}
+
+	client.ListTeamReposBySlug(ctx, "org", "team", &github.ListOptions{Page: 1})
+	if mock.calls != 1 {
+		t.Errorf("expected call to be passed through")
+	}
+	if _, err := New("a", "b"); !errors.Is(err, ErrRecordAndReplay) {
+		t.Errorf("expected error when both record and replay are set")
+	}
+}
@@ -3,19 +3,128 @@ package repos
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/retry"
+	"strings"
 
 	"github.com/google/go-github/v84/github"
 )
 
-// GetList iterates over paginated data set from github api and merges all data
-// into one block
+// Source is a github organisation and team that repositories are listed from
+type Source struct {
+	Org  string `json:"org"`  // github org slug
+	Team string `json:"team"` // github team slug
+}
+
+// Sourced is a repository along with the source it was first found in
+type Sourced struct {
+	Repository *github.Repository
+	Org        string `json:"org"`  // org of the team the repository was found in
+	Team       string `json:"team"` // team the repository was found in
+}
+
+// ParseSources converts a list of `org/team` strings into sources
+func ParseSources(values []string) (sources []*Source, err error) {
+	sources = []*Source{}
+	for _, value := range values {
+		var parts = strings.Split(strings.TrimSpace(value), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			err = errors.Join(ErrInvalidSource, fmt.Errorf("source [%s] should be in the form org/team", value))
+			return
+		}
+		sources = append(sources, &Source{Org: parts[0], Team: parts[1]})
+	}
+	return
+}
+
+// GetList returns the repositories from all sources in the options, without
+// duplicates
 func GetList(ctx context.Context, client teamClient, options *Args) (repositories []*github.Repository, err error) {
+	var list []*Sourced
+
+	repositories = []*github.Repository{}
+	if list, err = GetSourcedList(ctx, client, options); err != nil {
+		return
+	}
+	for _, item := range list {
+		repositories = append(repositories, item.Repository)
+	}
+	return
+}
+
+// GetSourcedList lists the repositories for each source (Sources, or
+// OrgSlug & ParentSlug when there are none) and, when Recursive is set, all of
+// their child teams.
+//
+// Repositories are de-duplicated by their full name, the first source (in the
+// order they are listed) they are found in is kept.
+func GetSourcedList(ctx context.Context, client teamClient, options *Args) (repositories []*Sourced, err error) {
+	var (
+		sources []*Source
+		seen    map[string]bool = map[string]bool{}
+		visited map[string]bool = map[string]bool{}
+		log     *slog.Logger    = cntxt.GetLogger(ctx).With("package", "repos", "func", "GetSourcedList")
+	)
+	log.Debug("starting ...")
+	repositories = []*Sourced{}
+
+	if sources, err = options.sources(); err != nil {
+		return
+	}
+	for len(sources) > 0 {
+		var list []*github.Repository
+		var source = sources[0]
+		var key = source.Org + "/" + source.Team
+
+		sources = sources[1:]
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		if list, err = listTeamRepos(ctx, client, source, options.FilterByName); err != nil {
+			return
+		}
+		for _, repo := range list {
+			if seen[repo.GetFullName()] {
+				log.With("repo", repo.GetFullName(), "source", key).Debug("skipping duplicate repository ...")
+				continue
+			}
+			seen[repo.GetFullName()] = true
+			repositories = append(repositories, &Sourced{Repository: repo, Org: source.Org, Team: source.Team})
+		}
+		// child teams are added to the queue so they are listed after their parent
+		if options.Recursive {
+			var children []*Source
+			if children, err = listChildTeams(ctx, client, source); err != nil {
+				return
+			}
+			sources = append(sources, children...)
+		}
+	}
+
+	log.With("count", len(repositories)).Debug("complete.")
+	return
+}
+
+// sources returns the sources to list from
+func (self *Args) sources() (sources []*Source, err error) {
+	if len(self.Sources) == 0 {
+		sources = []*Source{{Org: self.OrgSlug, Team: self.ParentSlug}}
+		return
+	}
+	return ParseSources(self.Sources)
+}
+
+// listTeamRepos iterates over paginated data set from github api and merges all data
+// into one block
+func listTeamRepos(ctx context.Context, client teamClient, source *Source, filterByName string) (repositories []*github.Repository, err error) {
 	var (
 		page int                 = 1
 		opts *github.ListOptions = &github.ListOptions{PerPage: 200}
-		log  *slog.Logger        = cntxt.GetLogger(ctx).With("package", "codebasesimport", "func", "getRepositoryList")
+		log  *slog.Logger        = cntxt.GetLogger(ctx).With("package", "repos", "func", "listTeamRepos", "org", source.Org, "team", source.Team)
 	)
 	log.Debug("starting ...")
 
@@ -26,17 +135,19 @@ func GetList(ctx context.Context, client teamClient, options *Args) (repositorie
 		opts.Page = page
 		log.With("page", page).Debug("getting page of repositories ...")
 		// fetch data from api
-		list, response, err = client.ListTeamReposBySlug(ctx, options.OrgSlug, options.ParentSlug, opts)
+		err = retry.Do(ctx, func() (e error) {
+			list, response, e = client.ListTeamReposBySlug(ctx, source.Org, source.Team, opts)
+			return
+		})
 		if err != nil {
-			err = errors.Join(ErrFailedGettingRepositoryPage, err)
+			err = errors.Join(ErrFailedGettingRepositoryPage, fmt.Errorf("source [%s/%s]", source.Org, source.Team), err)
 			return
 		}
-		// only add non archived repos
 		log.With("page", page, "count", len(list)).Debug("found repositories ...")
 
 		for _, repo := range list {
 			log.With("repo", *repo.FullName).Debug("found repository ...")
-			if options.FilterByName == "" || (options.FilterByName == *repo.Name) {
+			if filterByName == "" || (filterByName == *repo.Name) {
 				repositories = append(repositories, repo)
 				log.With("repo", *repo.FullName).Debug("added repository ...")
 			}
@@ -47,3 +158,33 @@ func GetList(ctx context.Context, client teamClient, options *Args) (repositorie
 	log.With("count", len(repositories)).Debug("complete.")
 	return
 }
+
+// listChildTeams returns a source for each direct child team of the source
+func listChildTeams(ctx context.Context, client teamClient, source *Source) (children []*Source, err error) {
+	var (
+		page int                 = 1
+		opts *github.ListOptions = &github.ListOptions{PerPage: 100}
+		log  *slog.Logger        = cntxt.GetLogger(ctx).With("package", "repos", "func", "listChildTeams", "org", source.Org, "team", source.Team)
+	)
+	children = []*Source{}
+	for page > 0 {
+		var response *github.Response
+		var teams []*github.Team
+
+		opts.Page = page
+		err = retry.Do(ctx, func() (e error) {
+			teams, response, e = client.ListChildTeamsByParentSlug(ctx, source.Org, source.Team, opts)
+			return
+		})
+		if err != nil {
+			err = errors.Join(ErrFailedGettingChildTeams, fmt.Errorf("source [%s/%s]", source.Org, source.Team), err)
+			return
+		}
+		for _, team := range teams {
+			log.With("child", team.GetSlug()).Debug("found child team ...")
+			children = append(children, &Source{Org: source.Org, Team: team.GetSlug()})
+		}
+		page = response.NextPage
+	}
+	return
+}
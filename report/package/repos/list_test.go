@@ -0,0 +1,85 @@
+package repos
+
+import (
+	"context"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"opg-reports/report/package/ptr"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// mockTeams returns the repos & child teams for each org/team
+type mockTeams struct {
+	repos    map[string][]string
+	children map[string][]string
+}
+
+func (self *mockTeams) ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error) {
+	var list = []*github.Repository{}
+	for _, name := range self.repos[org+"/"+slug] {
+		list = append(list, &github.Repository{Name: ptr.Ptr(name), FullName: ptr.Ptr(org + "/" + name)})
+	}
+	return list, &github.Response{}, nil
+}
+
+func (self *mockTeams) ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	var list = []*github.Team{}
+	for _, child := range self.children[org+"/"+slug] {
+		list = append(list, &github.Team{Slug: ptr.Ptr(child)})
+	}
+	return list, &github.Response{}, nil
+}
+
+func TestReposGetSourcedList(t *testing.T) {
+	var (
+		ctx    = cntxt.AddLogger(t.Context(), logger.New("error"))
+		client = &mockTeams{
+			repos: map[string][]string{
+				"org-a/parent": {"repo-1", "repo-2"},
+				"org-a/child":  {"repo-2", "repo-3"},
+				"org-b/other":  {"repo-4"},
+			},
+			children: map[string][]string{
+				"org-a/parent": {"child"},
+			},
+		}
+	)
+	// default to org & parent
+	list, err := GetSourcedList(ctx, client, &Args{OrgSlug: "org-a", ParentSlug: "parent"})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if len(list) != 2 {
+		t.Errorf("expected 2 repos, found [%d]", len(list))
+	}
+
+	// multiple sources with recursion, repo-2 should only be included once
+	// against the parent team
+	list, err = GetSourcedList(ctx, client, &Args{Sources: []string{"org-a/parent", "org-b/other"}, Recursive: true})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+	if len(list) != 4 {
+		t.Errorf("expected 4 repos, found [%d]", len(list))
+	}
+	found := map[string]*Sourced{}
+	for _, item := range list {
+		found[item.Repository.GetFullName()] = item
+	}
+	if found["org-a/repo-2"].Team != "parent" {
+		t.Errorf("duplicate repo should keep first source: %+v", found["org-a/repo-2"])
+	}
+	if found["org-a/repo-3"].Team != "child" {
+		t.Errorf("child team repo should be included: %+v", found["org-a/repo-3"])
+	}
+	if found["org-b/repo-4"].Org != "org-b" {
+		t.Errorf("second org should be included: %+v", found["org-b/repo-4"])
+	}
+
+	// invalid source
+	if _, err = GetSourcedList(ctx, client, &Args{Sources: []string{"no-team"}}); err == nil {
+		t.Errorf("expected error for invalid source")
+	}
+}
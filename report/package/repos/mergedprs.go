@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/retry"
 	"opg-reports/report/package/times"
 	"time"
 
@@ -30,10 +31,9 @@ func GetMergedPRs(ctx context.Context, client prClient, repo *github.Repository,
 // avoiding duplicates by checking the  run id
 func paginatedMergedPRs(ctx context.Context, client prClient, repo *github.Repository, in *Args, opts *github.PullRequestListOptions) (result []*github.PullRequest, err error) {
 	var (
-		maxRetry int                           = 3
-		page     int                           = 1
-		all      map[int64]*github.PullRequest = map[int64]*github.PullRequest{}
-		log      *slog.Logger                  = cntxt.GetLogger(ctx).With("package", "repos", "func", "paginatedMergedPRs")
+		page int                           = 1
+		all  map[int64]*github.PullRequest = map[int64]*github.PullRequest{}
+		log  *slog.Logger                  = cntxt.GetLogger(ctx).With("package", "repos", "func", "paginatedMergedPRs")
 	)
 	result = []*github.PullRequest{}
 	// force the max per page
@@ -44,20 +44,14 @@ func paginatedMergedPRs(ctx context.Context, client prClient, repo *github.Repos
 			prs      []*github.PullRequest
 			response *github.Response
 			found    map[int64]*github.PullRequest
-			retry    = 0
 		)
 
 		opts.Page = page
 		log.Debug("getting page of results ...", "page", page)
-		prs, response, err = client.List(ctx, *repo.Owner.Login, *repo.Name, opts)
-		// simple re-try loop as we get sporadic failures
-		for err != nil && retry < maxRetry {
-			retry += 1
-			log.Warn("error getting pull request data, retrying in 1 second ...", "err", err.Error())
-			time.Sleep(time.Second * 1)
-			prs, response, err = client.List(ctx, *repo.Owner.Login, *repo.Name, opts)
-		}
-
+		err = retry.Do(ctx, func() (e error) {
+			prs, response, e = client.List(ctx, *repo.Owner.Login, *repo.Name, opts)
+			return
+		})
 		if err != nil {
 			log.Error("error getting pull request data", "err", err.Error())
 			return
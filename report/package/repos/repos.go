@@ -11,6 +11,7 @@ import (
 // teamClient wrapper around *github.TeamsService
 type teamClient interface {
 	ListTeamReposBySlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Repository, *github.Response, error)
+	ListChildTeamsByParentSlug(ctx context.Context, org, slug string, opts *github.ListOptions) ([]*github.Team, *github.Response, error)
 }
 
 // actionClient wrapper for *github.ActionsService
@@ -29,9 +30,20 @@ type prClient interface {
 type Args struct {
 	OrgSlug      string    `json:"org_slug"`       // github org name
 	ParentSlug   string    `json:"parent_slug"`    // parent slug
+	Sources      []string  `json:"sources"`        // optional list of org/team slugs to use instead of OrgSlug & ParentSlug
+	Recursive    bool      `json:"recursive"`      // also list repositories from all child teams of each source
 	FilterByName string    `json:"filter_by_name"` // used to limit the repos to those that exactly match this name
 	DateStart    time.Time `json:"date_start"`     // start date
 	DateEnd      time.Time `json:"date_end"`       // end date
+	Status       string    `json:"status"`         // status of workflow runs to fetch; path to live runs default to success
+	Event        string    `json:"event"`          // event of workflow runs to fetch when not path to live only; defaults to pull_request, AllEvents for any
 }
 
-var ErrFailedGettingRepositoryPage = errors.New("error getting page of repositories")
+// AllEvents is used as the Event to fetch workflow runs for every event type
+const AllEvents string = "*"
+
+var (
+	ErrFailedGettingRepositoryPage = errors.New("error getting page of repositories")
+	ErrFailedGettingChildTeams     = errors.New("error getting child teams")
+	ErrInvalidSource               = errors.New("invalid repository source")
+)
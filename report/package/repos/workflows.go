@@ -5,10 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/retry"
 	"opg-reports/report/package/times"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/google/go-github/v84/github"
 )
@@ -18,7 +18,9 @@ import (
 //
 // `pathToLiveOnly` detemines if we are fetching only workflows that ran the main branch called
 // path to live. If its false then pull requests are fetched as well - which is a heavier
-// operation.
+// operation. Path to live runs are limited to those that succeeded unless `Status` is set
+// (such as `completed` to include failures). Other runs are those of pull requests unless
+// `Event` is set (AllEvents for every event).
 //
 // use a map based on workflow id to avoid duplicates in date ranges
 //
@@ -45,6 +47,13 @@ func GetWorkflowRuns(ctx context.Context, client actionClient, repo *github.Repo
 		opts.Branch = *repo.DefaultBranch
 		opts.Event = "push"
 		opts.Status = "success"
+	} else if in.Event == AllEvents {
+		opts.Event = ""
+	} else if in.Event != "" {
+		opts.Event = in.Event
+	}
+	if in.Status != "" {
+		opts.Status = in.Status
 	}
 	// use date intervals to call the api in smaller, 3 day chunks
 	// as the api result will contain a max of 1k, regardless of
@@ -86,10 +95,9 @@ func GetWorkflowRuns(ctx context.Context, client actionClient, repo *github.Repo
 // avoiding duplicates by checking the the workflow run id
 func paginatedWorkflowRuns(ctx context.Context, client actionClient, repo *github.Repository, in *Args, opts *github.ListWorkflowRunsOptions) (workflowRuns []*github.WorkflowRun, err error) {
 	var (
-		maxRetry int                           = 3
-		page     int                           = 1
-		allRuns  map[int64]*github.WorkflowRun = map[int64]*github.WorkflowRun{}
-		log      *slog.Logger                  = cntxt.GetLogger(ctx).With("package", "repos", "func", "paginatedWorkflowRuns", "repo", *repo.Name)
+		page    int                           = 1
+		allRuns map[int64]*github.WorkflowRun = map[int64]*github.WorkflowRun{}
+		log     *slog.Logger                  = cntxt.GetLogger(ctx).With("package", "repos", "func", "paginatedWorkflowRuns", "repo", *repo.Name)
 	)
 	workflowRuns = []*github.WorkflowRun{}
 	// force the max per page
@@ -98,26 +106,13 @@ func paginatedWorkflowRuns(ctx context.Context, client actionClient, repo *githu
 	for page > 0 {
 		var runs *github.WorkflowRuns
 		var response *github.Response
-		var retry = 0
 		log.Debug("getting page of results ...", "page", page)
 
 		opts.Page = page
-		runs, response, err = client.ListRepositoryWorkflowRuns(ctx, *repo.Owner.Login, *repo.Name, opts)
-
-		// simple re-try loop as we get sporadic failures
-		for err != nil && retry < maxRetry {
-			retry += 1
-			log.Warn("error getting pull request data, retrying in 1 second ...", "err", err.Error())
-			time.Sleep(time.Second * 1)
-			runs, response, err = client.ListRepositoryWorkflowRuns(ctx, *repo.Owner.Login, *repo.Name, opts)
-		}
-
-		if err != nil {
-			log.Warn("error getting workflow runs, retrying in 1 second.", "err", err.Error())
-			time.Sleep(time.Second * 1)
-			runs, response, err = client.ListRepositoryWorkflowRuns(ctx, *repo.Owner.Login, *repo.Name, opts)
-		}
-
+		err = retry.Do(ctx, func() (e error) {
+			runs, response, e = client.ListRepositoryWorkflowRuns(ctx, *repo.Owner.Login, *repo.Name, opts)
+			return
+		})
 		if err != nil {
 			log.Error("error getting workflow runs", "err", err.Error())
 			return
@@ -155,7 +150,7 @@ func intervalChunks(ctx context.Context, in *Args) (dates []string) {
 		list = append(list, fmt.Sprintf("%s..%s", times.AsYMDString(date), times.AsYMDString(end)))
 	}
 	slices.Sort(list)
-	slices.Compact(list)
+	list = slices.Compact(list)
 	// remove any empty values
 	for _, item := range list {
 		if item != "" {
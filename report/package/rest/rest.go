@@ -9,11 +9,27 @@ import (
 	"log/slog"
 	"net/http"
 	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/retry"
 	"time"
 )
 
 var ErrRequestFailed = errors.New("request failed.")
 
+// StatusError is returned when the response is not a 200, the status code is
+// used to decide if the request should be retried
+type StatusError struct {
+	StatusCode int
+}
+
+func (self *StatusError) Error() string {
+	return fmt.Sprintf("returned status code [%d]", self.StatusCode)
+}
+
+// HTTPStatusCode returns the status code of the response
+func (self *StatusError) HTTPStatusCode() int {
+	return self.StatusCode
+}
+
 func FromApi[R any](ctx context.Context, apiHost string, endpoint string, current *http.Request, params ...*Param) (response R, err error) {
 	var timeout time.Duration = (2 * time.Second)
 
@@ -73,13 +89,12 @@ func GetStr(ctx context.Context, current *http.Request, req *Request) (result st
 // get is a helper to fetch json based data from an endpoint, mixing default parameters
 // (both path & query string) with values from the current request - allowing the front
 // end to voerwrite things like start dates directly.
+//
+// Transient failures (timeouts, 5xx etc) are retried using the retry policy of the ctx.
 func get(ctx context.Context, current *http.Request, req *Request) (content []byte, statusCode int, calledURL string, err error) {
 	var (
-		request  *http.Request
-		response *http.Response
-		uri      string
-		client   http.Client  = http.Client{Timeout: req.Timeout}
-		log      *slog.Logger = cntxt.GetLogger(ctx).With("package", "rest", "func", "get")
+		uri string
+		log *slog.Logger = cntxt.GetLogger(ctx).With("package", "rest", "func", "get")
 	)
 	// generate the url to call
 	uri, err = req.URL(current)
@@ -88,6 +103,21 @@ func get(ctx context.Context, current *http.Request, req *Request) (content []by
 	}
 	calledURL = uri
 	log.Debug("calling uri ...", "uri", uri)
+	err = retry.Do(ctx, func() (e error) {
+		content, statusCode, e = call(uri, req.Timeout)
+		return
+	})
+	return
+}
+
+// call makes a single GET request to the uri and returns the body when the
+// status is 200
+func call(uri string, timeout time.Duration) (content []byte, statusCode int, err error) {
+	var (
+		request  *http.Request
+		response *http.Response
+		client   http.Client = http.Client{Timeout: timeout}
+	)
 	// create request
 	request, err = http.NewRequest(http.MethodGet, uri, nil)
 	if err != nil {
@@ -103,7 +133,7 @@ func get(ctx context.Context, current *http.Request, req *Request) (content []by
 	// check status
 	statusCode = response.StatusCode
 	if statusCode != http.StatusOK {
-		err = errors.Join(ErrRequestFailed, fmt.Errorf("returned status code [%d]", statusCode))
+		err = errors.Join(ErrRequestFailed, &StatusError{StatusCode: statusCode})
 		return
 	}
 	// read
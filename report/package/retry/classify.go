@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/google/go-github/v84/github"
+)
+
+// throttleCodes are aws error codes returned when requests are throttled
+var throttleCodes = []string{
+	"Throttling",
+	"ThrottlingException",
+	"ThrottledException",
+	"RequestThrottled",
+	"RequestThrottledException",
+	"TooManyRequestsException",
+	"RequestLimitExceeded",
+	"LimitExceededException",
+	"SlowDown",
+	"PriorRequestNotComplete",
+}
+
+// statusCoder is met by errors that carry an http status code, such as the aws
+// response errors and rest.StatusError
+type statusCoder interface {
+	HTTPStatusCode() int
+}
+
+// Classify decides if an error is transient and worth retrying, along with any
+// delay the api asked for.
+//
+// Retried:
+//   - github primary & secondary rate limits (after the reset / retry after time)
+//   - aws throttling error codes
+//   - http 408, 429 & 5xx status codes
+//   - network timeouts, resets and unexpected EOFs
+//
+// Everything else, including cancelled contexts and other 4xx responses, is
+// returned straight away.
+func Classify(err error) (retry bool, after time.Duration) {
+	var (
+		rateErr  *github.RateLimitError
+		abuseErr *github.AbuseRateLimitError
+		ghErr    *github.ErrorResponse
+		apiErr   smithy.APIError
+		status   statusCoder
+		netErr   net.Error
+	)
+	switch {
+	case err == nil || errors.Is(err, context.Canceled):
+		return
+	case errors.As(err, &rateErr):
+		retry, after = true, time.Until(rateErr.Rate.Reset.Time)
+	case errors.As(err, &abuseErr):
+		retry = true
+		if abuseErr.RetryAfter != nil {
+			after = *abuseErr.RetryAfter
+		}
+	case errors.As(err, &ghErr) && ghErr.Response != nil:
+		retry = retryableStatus(ghErr.Response.StatusCode)
+	case errors.As(err, &apiErr) && slices.Contains(throttleCodes, apiErr.ErrorCode()):
+		retry = true
+	case errors.As(err, &status):
+		retry = retryableStatus(status.HTTPStatusCode())
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED):
+		retry = true
+	case errors.As(err, &netErr):
+		retry = netErr.Timeout()
+	}
+	return
+}
+
+// retryableStatus returns true for status codes that are likely to be temporary
+func retryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,153 @@
+// Package retry re-runs calls to external apis that fail with a transient
+// error, waiting an exponentially increasing (jittered) time between attempts.
+//
+// The policy to use is attached to the context with WithPolicy so it can be
+// configured once by a command and used by every call it makes; Default is
+// used when there is none.
+//
+// Usage:
+//
+//	err = retry.Do(ctx, func() (e error) {
+//		list, resp, e = client.ListTeamReposBySlug(ctx, org, slug, opts)
+//		return
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"opg-reports/report/package/cntxt"
+	"time"
+)
+
+const policyKey string = "retry-policy"
+
+var ErrInvalidPolicy = errors.New("invalid retry policy.")
+
+// Policy controls how many times and how long to wait between attempts
+type Policy struct {
+	Attempts  int           `json:"attempts"`   // total number of attempts, including the first
+	BaseDelay time.Duration `json:"base_delay"` // delay before the first retry, doubled for each retry after
+	MaxDelay  time.Duration `json:"max_delay"`  // upper limit on the delay between attempts
+	Jitter    float64       `json:"jitter"`     // fraction (0-1) of each delay that is randomised
+
+	// Retryable decides if the error should be retried and any minimum delay
+	// the api has asked for; uses Classify when nil
+	Retryable func(err error) (retry bool, after time.Duration) `json:"-"`
+}
+
+// Default is used when no policy has been attached to the context
+var Default = &Policy{
+	Attempts:  4,
+	BaseDelay: 500 * time.Millisecond,
+	MaxDelay:  30 * time.Second,
+	Jitter:    0.2,
+}
+
+// Validate checks the policy values are usable
+func (self *Policy) Validate() (err error) {
+	switch {
+	case self.Attempts < 1:
+		err = fmt.Errorf("attempts must be at least 1, got [%d]", self.Attempts)
+	case self.BaseDelay < 0 || self.MaxDelay < 0:
+		err = fmt.Errorf("delays can not be negative")
+	case self.Jitter < 0 || self.Jitter > 1:
+		err = fmt.Errorf("jitter must be between 0 and 1, got [%v]", self.Jitter)
+	}
+	if err != nil {
+		err = errors.Join(ErrInvalidPolicy, err)
+	}
+	return
+}
+
+// Delay returns the time to wait before the retry following attempt n
+// (starting at 1): BaseDelay * 2^(n-1), capped at MaxDelay, with up to Jitter
+// of it removed at random so concurrent callers do not retry in step
+func (self *Policy) Delay(n int) (delay time.Duration) {
+	delay = self.BaseDelay
+	for i := 1; i < n && delay < self.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > self.MaxDelay {
+		delay = self.MaxDelay
+	}
+	if self.Jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * self.Jitter * float64(delay))
+	}
+	return
+}
+
+// classify returns the policy classifier, or the package one
+func (self *Policy) classify(err error) (bool, time.Duration) {
+	if self.Retryable != nil {
+		return self.Retryable(err)
+	}
+	return Classify(err)
+}
+
+// WithPolicy returns a context that uses the policy for all calls made with it
+func WithPolicy(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, policyKey, policy)
+}
+
+// GetPolicy returns the policy attached to the context, or Default
+func GetPolicy(ctx context.Context) (policy *Policy) {
+	var ok bool
+	if policy, ok = ctx.Value(policyKey).(*Policy); !ok || policy == nil {
+		policy = Default
+	}
+	return
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, the
+// attempts run out or the context is cancelled; the last error is returned.
+//
+// When the api asks for a longer wait than the MaxDelay (like a github rate
+// limit reset) the error is returned rather than blocking.
+func Do(ctx context.Context, fn func() error) (err error) {
+	var (
+		policy *Policy      = GetPolicy(ctx)
+		log    *slog.Logger = cntxt.GetLogger(ctx).With("package", "retry", "func", "Do")
+	)
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		var after, delay time.Duration
+
+		if err = fn(); err == nil {
+			return
+		}
+		if attempt >= policy.Attempts || ctx.Err() != nil {
+			return
+		}
+		if retry, after = policy.classify(err); !retry {
+			return
+		}
+		if delay = policy.Delay(attempt); after > delay {
+			if after > policy.MaxDelay {
+				log.Warn("retry delay requested is too long, not retrying", "after", after.String(), "err", err.Error())
+				return
+			}
+			delay = after
+		}
+		log.Warn("retrying after error ...", "attempt", attempt, "delay", delay.String(), "err", err.Error())
+
+		select {
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Get is Do for functions that return a value
+func Get[T any](ctx context.Context, fn func() (T, error)) (result T, err error) {
+	err = Do(ctx, func() (e error) {
+		result, e = fn()
+		return
+	})
+	return
+}
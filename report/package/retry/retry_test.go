@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/google/go-github/v84/github"
+)
+
+// statusErr is a stand in for errors with http status codes (rest.StatusError)
+type statusErr int
+
+func (self statusErr) Error() string       { return fmt.Sprintf("status [%d]", int(self)) }
+func (self statusErr) HTTPStatusCode() int { return int(self) }
+
+var testPolicy = &Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryDo(t *testing.T) {
+	var (
+		ctx       = WithPolicy(cntxt.AddLogger(t.Context(), logger.New("error")), testPolicy)
+		calls     = 0
+		transient = &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}
+	)
+	// succeeds on the last attempt
+	err := Do(ctx, func() error {
+		if calls++; calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got [%d] [%v]", calls, err)
+	}
+	// attempts run out
+	calls = 0
+	err = Do(ctx, func() error { calls++; return transient })
+	if !errors.As(err, &transient) || calls != 3 {
+		t.Errorf("expected last error after 3 calls, got [%d] [%v]", calls, err)
+	}
+	// not retryable
+	calls = 0
+	err = Do(ctx, func() error {
+		calls++
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a single call for a 404, got [%d]", calls)
+	}
+	// value version
+	calls = 0
+	v, err := Get(ctx, func() (int, error) {
+		if calls++; calls < 2 {
+			return 0, transient
+		}
+		return 10, nil
+	})
+	if err != nil || v != 10 {
+		t.Errorf("unexpected result: [%d] [%v]", v, err)
+	}
+}
+
+func TestRetryDoCancelled(t *testing.T) {
+	var (
+		calls       = 0
+		ctx, cancel = context.WithCancel(cntxt.AddLogger(t.Context(), logger.New("error")))
+	)
+	ctx = WithPolicy(ctx, &Policy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := Do(ctx, func() error {
+		calls++
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}}
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("expected cancellation after 1 call, got [%d] [%v]", calls, err)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	var p = &Policy{Attempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.5}
+	for n, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 8: time.Second} {
+		d := p.Delay(n)
+		if d > max || d < max/2 {
+			t.Errorf("attempt [%d] delay [%s] outside of [%s - %s]", n, d, max/2, max)
+		}
+	}
+	if err := (&Policy{Attempts: 0}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("expected invalid policy error")
+	}
+}
+
+func TestRetryClassify(t *testing.T) {
+	var wait = 2 * time.Second
+	var tests = []struct {
+		err   error
+		retry bool
+		after time.Duration
+	}{
+		{nil, false, 0},
+		{errors.New("plain"), false, 0},
+		{context.Canceled, false, 0},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), true, 0},
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, true, 0},
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}}, false, 0},
+		{&github.AbuseRateLimitError{RetryAfter: &wait}, true, wait},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, true, 0},
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, false, 0},
+		{fmt.Errorf("wrapped: %w", statusErr(http.StatusTooManyRequests)), true, 0},
+		{statusErr(http.StatusBadRequest), false, 0},
+	}
+	for i, test := range tests {
+		retry, after := Classify(test.err)
+		if retry != test.retry || after != test.after {
+			t.Errorf("[%d] expected [%v %s] got [%v %s] for [%v]", i, test.retry, test.after, retry, after, test.err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package workers processes a list of items concurrently, using a bounded
+// number of goroutines.
+//
+// Results are always returned in the same order as the items, so output does
+// not depend on which item finished first. An item that fails does not stop
+// the others; its error is returned in its result and added to the Failures
+// attached to the context (see WithFailures) so the caller can report on them.
+//
+// Separate Map calls that run at the same time can share one limit on the items
+// being processed via a context created by WithLimit.
+package workers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"opg-reports/report/package/cntxt"
+	"sync"
+)
+
+const failuresKey string = "workers-failures"
+const limitKey string = "workers-limit"
+const holdingKey string = "workers-holding"
+
+// WaitF is called before each item and should block until it is ok to
+// continue; used to stay within api rate limits
+type WaitF func(ctx context.Context) (err error)
+
+type Args struct {
+	Concurrency int   `json:"concurrency"` // most items processed at once; 1 when not set
+	Wait        WaitF `json:"-"`           // optional, called before each item
+}
+
+// Result is the outcome of processing a single item
+type Result[R any] struct {
+	Value R
+	Err   error
+}
+
+// Map calls fn for every item, with at most in.Concurrency running at the same
+// time, and returns a result for each item in the same order as items.
+//
+// Once the context is cancelled no more items are started and those remaining
+// have the context error as their result.
+func Map[T any, R any](ctx context.Context, items []T, fn func(ctx context.Context, item T) (R, error), in *Args) (results []*Result[R]) {
+	var (
+		wg          sync.WaitGroup
+		concurrency int           = 1
+		sem         chan struct{} = nil
+		log         *slog.Logger  = cntxt.GetLogger(ctx).With("package", "workers", "func", "Map")
+	)
+	if in != nil && in.Concurrency > 1 {
+		concurrency = in.Concurrency
+	}
+	log.Debug("starting ...", "items", len(items), "concurrency", concurrency)
+
+	sem = make(chan struct{}, concurrency)
+	results = make([]*Result[R], len(items))
+	for i, item := range items {
+		var res = &Result[R]{}
+		results[i] = res
+		// wait for a free slot, unless cancelled
+		select {
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// cancelled while waiting for the slot
+			if res.Err = ctx.Err(); res.Err != nil {
+				return
+			}
+			// wait for a slot in the shared limit, if there is one
+			var release func()
+			if release, res.Err = acquire(ctx); res.Err != nil {
+				return
+			}
+			defer release()
+			// mark the slot as held for any nested calls
+			var itemCtx = context.WithValue(ctx, holdingKey, true)
+			if in != nil && in.Wait != nil {
+				if res.Err = in.Wait(itemCtx); res.Err != nil {
+					addFailure(ctx, res.Err)
+					return
+				}
+			}
+			if res.Value, res.Err = fn(itemCtx, item); res.Err != nil {
+				addFailure(ctx, res.Err)
+			}
+		}()
+	}
+	wg.Wait()
+	log.Debug("complete.")
+	return
+}
+
+// WithLimit returns a context that shares a single limit of n items being
+// processed between every Map call made with it (or a context derived from it),
+// on top of the Concurrency of each call; used so importers running at the same
+// time stay within n repositories at once in total.
+//
+// A Map called from within an item that already holds a slot does not wait for
+// another, so nested calls cannot block each other.
+func WithLimit(ctx context.Context, n int) context.Context {
+	if n < 1 {
+		n = 1
+	}
+	return context.WithValue(ctx, limitKey, make(chan struct{}, n))
+}
+
+// acquire waits for a slot in the shared limit of the context and returns the
+// func to release it; when there is no limit, or the item is nested within one
+// already holding a slot, it returns straight away
+func acquire(ctx context.Context) (release func(), err error) {
+	var limit, ok = ctx.Value(limitKey).(chan struct{})
+
+	release = func() {}
+	if !ok || ctx.Value(holdingKey) != nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case limit <- struct{}{}:
+		release = func() { <-limit }
+	}
+	return
+}
+
+// Values returns the values of the successful results, in order, along with
+// the errors of the failed results joined together
+func Values[R any](results []*Result[R]) (values []R, err error) {
+	var errs = []error{}
+	values = []R{}
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+			continue
+		}
+		values = append(values, res.Value)
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// Collect returns the values of the successful results, in order. Failed
+// results are logged as a warning and skipped so one bad item does not stop the
+// others; an error is only returned when every item failed.
+func Collect[R any](ctx context.Context, results []*Result[R]) (values []R, err error) {
+	var log *slog.Logger = cntxt.GetLogger(ctx).With("package", "workers", "func", "Collect")
+
+	values, err = Values(results)
+	if err != nil && len(values) == 0 && len(results) > 0 {
+		log.Error("all items failed", "err", err.Error())
+		return
+	} else if err != nil {
+		log.Warn("some items failed, skipping them", "failed", len(results)-len(values), "err", err.Error())
+		err = nil
+	}
+	return
+}
+
+// Succeeded returns the items whose result has no error, in order; results
+// must be those returned by Map for the same items
+func Succeeded[T any, R any](items []T, results []*Result[R]) (done []T) {
+	done = []T{}
+	for i, res := range results {
+		if res.Err == nil && i < len(items) {
+			done = append(done, items[i])
+		}
+	}
+	return
+}
+
+// Failures collects the errors of failed items from every Map call made with
+// a context
+type Failures struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithFailures returns a context that records every failed item into the
+// returned Failures
+func WithFailures(ctx context.Context) (context.Context, *Failures) {
+	var f = &Failures{errs: []error{}}
+	return context.WithValue(ctx, failuresKey, f), f
+}
+
+// Errors returns a copy of the errors recorded
+func (self *Failures) Errors() (errs []error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	errs = append([]error{}, self.errs...)
+	return
+}
+
+// Err returns all errors recorded joined together, or nil if there are none
+func (self *Failures) Err() error {
+	return errors.Join(self.Errors()...)
+}
+
+// addFailure records the error in the Failures of the context, if there is one
+func addFailure(ctx context.Context, err error) {
+	if f, ok := ctx.Value(failuresKey).(*Failures); ok {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.errs = append(f.errs, err)
+	}
+}
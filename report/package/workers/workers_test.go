@@ -0,0 +1,145 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"opg-reports/report/package/cntxt"
+	"opg-reports/report/package/logger"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkersMap(t *testing.T) {
+	var (
+		running, most atomic.Int32
+		waits         atomic.Int32
+		items         = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		ctx, failures = WithFailures(cntxt.AddLogger(t.Context(), logger.New("error")))
+	)
+	results := Map(ctx, items, func(ctx context.Context, item int) (string, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		if now > most.Load() {
+			most.Store(now)
+		}
+		// finish in reverse order to check the results are still in order
+		time.Sleep(time.Duration(10-item) * time.Millisecond)
+		if item == 4 {
+			return "", fmt.Errorf("item [%d] failed", item)
+		}
+		return fmt.Sprintf("item-%d", item), nil
+	}, &Args{Concurrency: 3, Wait: func(ctx context.Context) error {
+		waits.Add(1)
+		return nil
+	}})
+
+	if most.Load() > 3 {
+		t.Errorf("expected at most 3 running, found [%d]", most.Load())
+	}
+	if waits.Load() != int32(len(items)) {
+		t.Errorf("expected wait to be called for each item, called [%d]", waits.Load())
+	}
+	for i, res := range results {
+		if i == 3 {
+			if res.Err == nil {
+				t.Errorf("expected item 4 to fail")
+			}
+			continue
+		}
+		if res.Value != fmt.Sprintf("item-%d", items[i]) {
+			t.Errorf("result [%d] out of order: [%s]", i, res.Value)
+		}
+	}
+	values, err := Values(results)
+	if len(values) != 9 || err == nil {
+		t.Errorf("expected 9 values and an error, got [%d] [%v]", len(values), err)
+	}
+	if len(failures.Errors()) != 1 || failures.Err() == nil {
+		t.Errorf("expected failure to be recorded: %v", failures.Errors())
+	}
+}
+
+func TestWorkersMapCancelled(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(cntxt.AddLogger(t.Context(), logger.New("error")))
+		calls       atomic.Int32
+	)
+	results := Map(ctx, []int{1, 2, 3, 4}, func(ctx context.Context, item int) (int, error) {
+		calls.Add(1)
+		cancel()
+		return item, nil
+	}, &Args{Concurrency: 1})
+
+	if calls.Load() != 1 {
+		t.Errorf("expected one call before cancel, found [%d]", calls.Load())
+	}
+	if !errors.Is(results[3].Err, context.Canceled) {
+		t.Errorf("expected remaining items to be cancelled: %v", results[3].Err)
+	}
+}
+
+func TestWorkersCollect(t *testing.T) {
+	var (
+		ctx   = cntxt.AddLogger(t.Context(), logger.New("error"))
+		items = []int{1, 2, 3}
+	)
+	results := Map(ctx, items, func(ctx context.Context, item int) (int, error) {
+		if item == 2 {
+			return 0, fmt.Errorf("item [%d] failed", item)
+		}
+		return item * 10, nil
+	}, nil)
+	// some failed, so no error
+	values, err := Collect(ctx, results)
+	if err != nil || len(values) != 2 || values[0] != 10 || values[1] != 30 {
+		t.Errorf("expected 2 values without error, got [%v] [%v]", values, err)
+	}
+	if done := Succeeded(items, results); len(done) != 2 || done[0] != 1 || done[1] != 3 {
+		t.Errorf("unexpected succeeded items: %v", done)
+	}
+	// all failed, so an error
+	results = Map(ctx, items, func(ctx context.Context, item int) (int, error) {
+		return 0, fmt.Errorf("item [%d] failed", item)
+	}, nil)
+	if values, err = Collect(ctx, results); err == nil || len(values) != 0 {
+		t.Errorf("expected an error when every item fails, got [%v] [%v]", values, err)
+	}
+	// nothing to do is not an error
+	if _, err = Collect(ctx, []*Result[int]{}); err != nil {
+		t.Errorf("unexpected error: [%s]", err.Error())
+	}
+}
+
+func TestWorkersWithLimit(t *testing.T) {
+	var (
+		running, most atomic.Int32
+		wg            sync.WaitGroup
+		ctx           = WithLimit(cntxt.AddLogger(t.Context(), logger.New("error")), 3)
+		items         = []int{1, 2, 3, 4, 5, 6}
+	)
+	// several concurrent calls each allowed 3 at once share the limit of 3
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Map(ctx, items, func(ctx context.Context, item int) (int, error) {
+				now := running.Add(1)
+				defer running.Add(-1)
+				if now > most.Load() {
+					most.Store(now)
+				}
+				// nested calls do not wait for another slot
+				Map(ctx, []int{item}, func(ctx context.Context, item int) (int, error) { return item, nil }, nil)
+				time.Sleep(2 * time.Millisecond)
+				return item, nil
+			}, &Args{Concurrency: 3})
+		}()
+	}
+	wg.Wait()
+	if most.Load() > 3 {
+		t.Errorf("expected at most 3 running across all calls, found [%d]", most.Load())
+	}
+}